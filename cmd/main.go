@@ -2,11 +2,15 @@ package main
 
 import (
 	"context"
+	"crypto/x509"
 	"errors" // Import errors
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sync/atomic"
 	"syscall"
 	"time"
 
@@ -19,12 +23,24 @@ import (
 
 	"sui_ai_server/config"
 	"sui_ai_server/internal/ai"
+	"sui_ai_server/internal/ai/prompts"
+	aiutils "sui_ai_server/internal/ai/utils"
 	"sui_ai_server/internal/api"
+	"sui_ai_server/internal/hooks"
+	"sui_ai_server/internal/ipfs"
+	"sui_ai_server/internal/jobs"
+	"sui_ai_server/internal/project"
+	"sui_ai_server/internal/rag"
+	"sui_ai_server/internal/ratelimit"
+	"sui_ai_server/internal/secrets"
+	"sui_ai_server/internal/storage"
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
 
 	// neo4jRepo "sui_ai_server/db/neo4j" // Alias to avoid name collision
-	// "sui_ai_server/events"
-	// "sui_ai_server/rag"
-	// "sui_ai_server/sui/seal" // Import sui service package
+	"sui_ai_server/internal/events"
+	"sui_ai_server/internal/sui"
+	"sui_ai_server/internal/sui/seal"
 	"sui_ai_server/internal/sui/walrus"
 )
 
@@ -53,8 +69,9 @@ func main() {
 	}
 
 	// --- Dependency Initialization ---
-	// _ctx, cancel := context.WithCancel(context.Background())
-	// defer cancel()
+	// Main application context: cancelled on shutdown to stop background workers
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	// Neo4j Driver
 	// driver, err := neo4j.NewDriverWithContext(
@@ -91,70 +108,275 @@ func main() {
 	// 	log.Println("Neo4j indexes setup successfully.")
 	// }
 
+	// Secret scanner for generated files (built-in patterns plus any configured extras)
+	extraPatterns, err := secrets.ParsePatterns(cfg.SecretPatterns)
+	if err != nil {
+		log.Fatalf("Invalid SECRET_PATTERNS: %v", err)
+	}
+	secretScanMode, err := secrets.ParseMode(cfg.SecretScanMode)
+	if err != nil {
+		log.Fatalf("Invalid SECRET_SCAN_MODE: %v", err)
+	}
+	secretScanner := secrets.NewScanner(append(secrets.DefaultPatterns, extraPatterns...), secretScanMode)
+
+	// Per-type size caps for generated files; oversized files are skipped when saving
+	fileMaxBytes, err := aiutils.ParseMaxFileBytes(cfg.FileMaxBytes)
+	if err != nil {
+		log.Fatalf("Invalid FILE_MAX_BYTES: %v", err)
+	}
+	aiutils.SetMaxFileBytes(fileMaxBytes)
+
 	// Initialize AI Client (OpenAI or local)
-	aiGenerator := ai.NewGenerator(cfg.OpenAIKey, cfg.EmbeddingModelID) // Pass Neo4j service for storage
+	modelPricing, err := ai.ParsePricing(cfg.ModelPricing)
+	if err != nil {
+		log.Fatalf("Invalid MODEL_PRICING: %v", err)
+	}
+	if err := prompts.ValidateTailwind(cfg.TailwindVersion, cfg.TailwindPlugins); err != nil {
+		log.Fatalf("Invalid TAILWIND_VERSION/TAILWIND_PLUGINS: %v", err)
+	}
+	if err := api.ValidateProfiles(cfg.GenerationProfiles); err != nil {
+		log.Fatalf("Invalid GENERATION_PROFILES: %v", err)
+	}
+	if len(cfg.GenerationProfiles) > 0 {
+		log.Printf("Loaded %d generation profiles", len(cfg.GenerationProfiles))
+	}
+	if err := api.ValidateProjectTypes(cfg.AllowedProjectTypes); err != nil {
+		log.Fatalf("Invalid ALLOWED_PROJECT_TYPES: %v", err)
+	}
+	if err := hooks.Validate(cfg.PostGenerationHooks); err != nil {
+		log.Fatalf("Invalid POST_GENERATION_HOOKS: %v", err)
+	}
+	postHooks := hooks.NewRunner(cfg.PostGenerationHooks)
+	if err := ai.ValidateEmbeddingDimensions(cfg.EmbeddingModelID, cfg.EmbeddingDims); err != nil {
+		log.Fatalf("Invalid EMBEDDING_DIMENSIONS: %v", err)
+	}
+	openAIHeaders, err := ai.ParseHeaders(cfg.OpenAIExtraHeaders)
+	if err != nil {
+		log.Fatalf("Invalid OPENAI_EXTRA_HEADERS: %v", err)
+	}
+	openAIProxy, err := ai.ParseProxyURL(cfg.OpenAIProxyURL)
+	if err != nil {
+		log.Fatalf("Invalid OPENAI_PROXY_URL: %v", err)
+	}
+	var openAIRoots *x509.CertPool // Nil keeps the system roots
+	if cfg.OpenAICABundle != "" {
+		if openAIRoots, err = ai.LoadCABundle(cfg.OpenAICABundle); err != nil {
+			log.Fatalf("Invalid OPENAI_CA_BUNDLE: %v", err)
+		}
+	}
+	if cfg.MonthlyBudgetUSD < 0 {
+		log.Fatalf("Invalid MONTHLY_BUDGET_USD: must not be negative")
+	}
+	// Spend is tracked (and reported in /metrics) even without a budget
+	spendTracker, err := ai.NewSpendTracker(filepath.Join(utils.WorkDir, ".spend.json"), cfg.MonthlyBudgetUSD)
+	if err != nil {
+		log.Fatalf("Failed to load spend record: %v", err)
+	}
+	if cfg.MonthlyBudgetUSD > 0 {
+		log.Printf("Monthly OpenAI budget: $%.2f", cfg.MonthlyBudgetUSD)
+	}
+	aiGenerator := ai.NewGenerator(
+		cfg.OpenAIKey,
+		cfg.EmbeddingModelID,
+		ai.WithOrganization(cfg.OpenAIOrgID),
+		ai.WithProject(cfg.OpenAIProjectID),
+		ai.WithExtraHeaders(openAIHeaders),
+		ai.WithProxy(openAIProxy),
+		ai.WithRootCAs(openAIRoots),
+		ai.WithAnswerTokens(cfg.AnswerTokens),
+		ai.WithEmbeddingDimensions(cfg.EmbeddingDims),
+		ai.WithEmbeddingChunks(cfg.EmbedChunkChars, cfg.EmbedChunkOverlap),
+		ai.WithModelFallbacks(cfg.ModelFallbacks...),
+		ai.WithPricing(modelPricing),
+		ai.WithSpendTracker(spendTracker),
+		ai.WithMaxResponseBytes(cfg.MaxResponseBytes),
+		ai.WithMaxOutputTokens(cfg.MaxOutputTokens),
+		ai.WithSiteMaxTokens(cfg.SiteMaxTokens),
+		ai.WithContentLogging(cfg.LogPrompts, cfg.LogLLMOutput),
+		ai.WithTailwind(cfg.TailwindVersion, cfg.TailwindPlugins),
+		ai.WithSecretScanner(secretScanner),
+	)
 	// aiGenerator := ai.NewGenerator(cfg.OpenAIKey, neo4jService, cfg.EmbeddingModelID) // Pass Neo4j service for storage
 
-	// Initialize RAG Service
-	// ragService := rag.NewRAGService(neo4jService, aiGenerator, cfg.EmbeddingModelID) // AI Generator needed for embeddings
+	// Project metadata lives alongside the generated files in the work directory
+	projectStore := project.NewStore(utils.WorkDir)
+
+	// Per-wallet generation rate limiter (in-memory; swap for a shared store when running multiple instances)
+	var generateLimiter ratelimit.Limiter
+	if cfg.GenRatePerWallet > 0 {
+		memLimiter := ratelimit.NewMemoryLimiter(cfg.GenRatePerWallet, 10*time.Minute)
+		go memLimiter.RunCleanup(ctx, time.Minute)
+		generateLimiter = memLimiter
+	} else {
+		log.Println("Per-wallet generation rate limit disabled (GEN_RATE_PER_WALLET=0).")
+	}
 
 	// Initialize Walrus Deployer
-	walrusDeployer := walrus.NewDeployer(cfg.SiteBuilderPath, cfg.WalrusCLIPath) // Add wallet/token logic if needed
+	walrusDeployer := walrus.NewDeployer(cfg.SiteBuilderPath, cfg.WalrusCLIPath, // Add wallet/token logic if needed
+		walrus.WithNodeToolchain(cfg.NpmBinPath, cfg.NodeBinPath),
+		walrus.WithSitesConfig(cfg.SitesConfigPath),
+		walrus.WithBundleWarnBytes(cfg.BundleWarnBytes),
+		walrus.WithGetWalRetry(cfg.GetWalAttempts, cfg.GetWalBackoff))
+	var siteDeployer api.SiteDeployer = walrusDeployer
+	switch cfg.DeployBackend {
+	case types.DeployBackendWalrus:
+		// Generation-only servers have no site-builder; they start anyway and refuse deploys with a 503
+		if err := walrusDeployer.CheckTools(); err != nil {
+			log.Printf("WARN: %v; deploys are disabled until SITE_BUILDER_PATH and WALRUS_CLI_PATH point at executables", err)
+		} else if err := walrusDeployer.CheckConfig(); err != nil {
+			log.Fatalf("Invalid SITES_CONFIG_PATH: %v", err)
+		}
+	case types.DeployBackendIPFS:
+		if cfg.IPFSAPIURL == "" {
+			log.Fatalf("DEPLOY_BACKEND=ipfs requires IPFS_API_URL")
+		}
+		// IPFS deploys still build with npm through the Walrus deployer, then pin dist instead of publishing it
+		siteDeployer = ipfs.NewDeployer(cfg.IPFSAPIURL, cfg.IPFSPinToken, walrusDeployer, ipfs.WithGateway(cfg.IPFSGatewayURL))
+		log.Printf("Deploying sites to IPFS via %s", cfg.IPFSAPIURL)
+	default:
+		log.Fatalf("Invalid DEPLOY_BACKEND %q: must be walrus or ipfs", cfg.DeployBackend)
+	}
+
+	// Generated file store: local disk for a single instance, or a shared S3-compatible bucket
+	var fileStore storage.FileStore
+	switch cfg.FileStore {
+	case storage.BackendLocal, "":
+		fileStore = storage.NewLocalStore()
+	case storage.BackendS3:
+		s3Store, err := storage.NewS3Store(storage.S3Config{
+			Endpoint:        cfg.S3Endpoint,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			Region:          cfg.S3Region,
+			UseSSL:          cfg.S3UseSSL,
+			Prefix:          cfg.S3Prefix,
+		})
+		if err != nil {
+			log.Fatalf("Failed to initialize S3 file store: %v", err)
+		}
+		fileStore = s3Store
+	default:
+		log.Fatalf("Invalid FILE_STORE %q: expected %q or %q", cfg.FileStore, storage.BackendLocal, storage.BackendS3)
+	}
+
+	// Initialize RAG Service
+	// ragService := rag.NewRAGService(neo4jService, aiGenerator, cfg.EmbeddingModelID) // AI Generator needed for embeddings
+	if err := rag.ValidateExcludePatterns(cfg.RAGExclude); err != nil {
+		log.Fatalf("Invalid RAG_EXCLUDE: %v", err)
+	}
+	// Projects whose local source was purged are restored from the file store on first use.
+	loadProjectFiles := func(projectID string) ([]types.GeneratedFile, error) {
+		return storage.LoadOrRestore(ctx, fileStore, projectID, aiutils.LoadFilesDisk)
+	}
+	ragService := rag.NewRAGService(aiGenerator, loadProjectFiles, cfg.RAGContextTokens, cfg.RAGExclude,
+		rag.WithEmbeddingConcurrency(cfg.EmbeddingConcurrency), rag.WithMaxCachedIndexes(cfg.RAGMaxCachedIndexes))
+
+	// Job queues get their own context: on shutdown they are drained first and only cancelled once the
+	// shutdown timeout runs out
+	jobsCtx, cancelJobs := context.WithCancel(context.Background())
+	defer cancelJobs()
+
+	// Deploy job queue: caps concurrent npm builds and reports queue positions
+	deployJobs := jobs.NewManager(cfg.DeployConcurrency)
+	deployJobs.Run(jobsCtx)
+
+	// Batch API generations get their own queue so hours-long batches never hold up deploys
+	batchJobs := jobs.NewManager(cfg.BatchConcurrency)
+	batchJobs.Run(jobsCtx)
+	refineJobs := jobs.NewManager(cfg.RefineConcurrency)
+	refineJobs.Run(jobsCtx)
+
+	// Janitor: frees disk held by abandoned generations, leaving deployed and busy projects alone
+	if cfg.JanitorEnabled {
+		if cfg.JanitorTTL <= 0 {
+			log.Fatalf("Invalid JANITOR_TTL %s: must be positive when the janitor is enabled", cfg.JanitorTTL)
+		}
+		janitor := project.NewJanitor(projectStore, cfg.JanitorTTL,
+			project.WithSweepInterval(cfg.JanitorInterval),
+			project.WithBusyCheck(func(projectID string) bool {
+				return deployJobs.Busy(projectID) || batchJobs.Busy(projectID) || refineJobs.Busy(projectID)
+			}),
+		)
+		go janitor.Run(ctx)
+	} else {
+		log.Println("Stale project janitor disabled (JANITOR_ENABLED=false).")
+	}
 
 	// Initialize Seal Client
-	// sealClient := seal.NewClient(cfg.SealAPIKey, cfg.SealEndpoint) // Adjust with actual SDK/API details
-
-	// Initialize Sui Event Listener
-	// Ensure the event type string from config is correct
-	// eventListener := events.NewSuiEventListener(
-	// 	cfg.SuiRPC,
-	// 	cfg.SiteDeployedEventType, // Use specific config key
-	// 	walrusDeployer,
-	// 	sealClient,
-	// 	neo4jService,
-	// 	cfg.SuiNetwork, // Pass network for context if needed by handlers
-	// )
+	sealClient := seal.NewClient(cfg.SealAPIKey, cfg.SealEndpoint, seal.WithPingPath(cfg.SealPingPath)) // Adjust with actual SDK/API details
+	if sealClient.Configured() {
+		go sealClient.RunPing(ctx, time.Minute) // Logs when Seal goes down or comes back
+	} else {
+		log.Println("WARN: Seal API Key or Endpoint not configured. Access policies will not be registered.")
+	}
 
 	// Initialize API Handlers (pass all dependencies)
 	apiHandler := api.NewAPIHandler(
 		aiGenerator,
+		projectStore,
+		generateLimiter,
 		// neo4jService,
-		walrusDeployer,
-		// sealClient,
-		// ragService,
+		siteDeployer,
+		deployJobs,
+		batchJobs,
+		refineJobs,
+		fileStore,
+		sealClient,
+		ragService,
+		api.Timeouts{Generate: cfg.GenerateTimeout, Deploy: cfg.DeployTimeout},
 		cfg.SuiNetwork,           // Pass network name
 		cfg.SuiRPC,               // Pass RPC URL for Sui Service
 		cfg.SuinsContractAddress, // Pass SUINS contract address
-		cfg.SuinsNftType,         // Pass SUINS NFT type string
+		cfg.SuinsNftTypes,        // Pass SUINS NFT types
+		cfg.SuinsObjectID,        // Pass SuiNS registry object for subdomains
+		cfg.DeployRequiredNFT,    // Pass NFT type gating deploys (empty = open)
+		cfg.AllowDebug,           // Pass whether debugging endpoints are served
+		cfg.GenerationProfiles,   // Pass the validated generation presets
+		postHooks,                // Pass post-generation checks (nil when none are configured)
+		cfg.AllowedProjectTypes,  // Pass the project types that may be generated and deployed
+		cfg.AllowSystemPrompt,    // Pass whether requests may override the system prompt
 	)
 
 	// --- Start Services ---
 
 	// Start Event Listener in a separate goroutine
-	// eventListenerActive := true // Assume active unless config/init fails
-	// if cfg.SuiRPC == "" || cfg.SiteDeployedEventType == "" {
-	// 	log.Println("WARN: Sui RPC endpoint or SiteDeployed Event Type not configured. Event listener will be inactive.")
-	// 	eventListenerActive = false
-	// }
+	eventListenerActive := true // Assume active unless config/init fails
+	if cfg.SuiRPC == "" || cfg.SiteDeployedEventType == "" {
+		log.Println("Sui RPC endpoint or SUI_SITE_DEPLOYED_EVENT_TYPE not set; the SiteDeployed event listener is off.")
+		eventListenerActive = false
+	}
 
-	// if eventListenerActive {
-	// 	go func() {
-	// 		log.Println("Starting Sui Event Listener...")
-	// 		// Pass the main context to the listener
-	// 		listenerErr := eventListener.StartListening(ctx)
-	// 		// Only log error if the context wasn't cancelled externally
-	// 		if listenerErr != nil && !errors.Is(listenerErr, context.Canceled) {
-	// 			log.Printf("ERROR: Sui Event Listener stopped unexpectedly: %v", listenerErr)
-	// 			// Consider more robust handling: panic, trigger alert, attempt restart
-	// 			// If listener is critical, cancel the main context to stop the app
-	// 			cancel()
-	// 		} else if errors.Is(listenerErr, context.Canceled) {
-	// 			log.Println("Sui Event Listener stopping due to context cancellation.")
-	// 		} else {
-	// 			log.Println("Sui Event Listener stopped.")
-	// 		}
-	// 	}()
-	// }
+	if eventListenerActive {
+		eventSui, err := sui.NewService(cfg.SuiRPC, "", nil)
+		if err != nil {
+			log.Fatalf("Failed to initialize Sui Service for the event listener: %v", err)
+		}
+		// On-chain SiteDeployed events trigger the same deploy (plus Seal registration) as the API
+		eventListener := events.NewSuiEventListener(
+			eventSui,
+			cfg.SiteDeployedEventType, // Use specific config key
+			func(ctx context.Context, event events.SiteDeployedEvent) error {
+				return apiHandler.DeployForEvent(ctx, event.ProjectID, event.Deployer)
+			},
+			events.WithPollInterval(cfg.SuiEventPollInterval),
+		)
+		go func() {
+			log.Println("Starting Sui Event Listener...")
+			// Pass the main context to the listener
+			listenerErr := eventListener.StartListening(ctx)
+			// Only log error if the context wasn't cancelled externally
+			if listenerErr != nil && !errors.Is(listenerErr, context.Canceled) {
+				log.Printf("ERROR: Sui Event Listener stopped unexpectedly: %v", listenerErr)
+				// If listener is critical, cancel the main context to stop the app
+				cancel()
+			} else if errors.Is(listenerErr, context.Canceled) {
+				log.Println("Sui Event Listener stopping due to context cancellation.")
+			} else {
+				log.Println("Sui Event Listener stopped.")
+			}
+		}()
+	}
 
 	// Start API Server
 	// Select Gin mode based on an environment variable or config (e.g., APP_ENV=production)
@@ -166,9 +388,26 @@ func main() {
 		log.Println("Running in Gin Debug Mode")
 	}
 
-	router := gin.New()        // Use gin.New() for more control over middleware
-	router.Use(gin.Logger())   // Add structured logger middleware
-	router.Use(gin.Recovery()) // Add panic recovery middleware
+	securityHeaders := api.SecurityHeaders{
+		NoSniff:               cfg.HeaderNoSniff,
+		FrameOptions:          cfg.HeaderFrameOptions,
+		ContentSecurityPolicy: cfg.HeaderCSP,
+		ReferrerPolicy:        cfg.HeaderReferrerPolicy,
+	}
+	if err := securityHeaders.Validate(); err != nil {
+		log.Fatalf("Invalid security header settings: %v", err)
+	}
+
+	accessLogger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+	router := gin.New()                                    // Use gin.New() for more control over middleware
+	router.Use(api.AccessLog(accessLogger, cfg.LogBodies)) // One structured line per request, replacing gin.Logger()
+	router.Use(gin.Recovery())                             // Add panic recovery middleware
+	router.Use(api.SecurityHeadersMiddleware(securityHeaders))
+
+	// Set on SIGTERM/SIGINT so new requests get 503 while in-flight ones finish
+	var shuttingDown atomic.Bool
+	router.Use(api.RejectWhileShuttingDown(&shuttingDown))
 
 	// Configure CORS properly for your frontend origin
 	// import "github.com/gin-contrib/cors"
@@ -177,8 +416,12 @@ func main() {
 	// config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
 	// router.Use(cors.New(config))
 
-	api.RegisterRoutes(router, apiHandler) // Register API endpoints
+	api.RegisterRoutes(router, apiHandler, cfg.RoutePrefix) // Register API endpoints
 
+	if cfg.ShutdownTimeout <= 0 || cfg.ShutdownDrain < 0 {
+		log.Fatalf("Invalid SHUTDOWN_TIMEOUT %s / SHUTDOWN_DRAIN_PERIOD %s: the timeout must be positive and the drain period not negative",
+			cfg.ShutdownTimeout, cfg.ShutdownDrain)
+	}
 	server := &http.Server{
 		Addr:    cfg.ServerAddress,
 		Handler: router,
@@ -204,16 +447,19 @@ func main() {
 	// Block until a signal is received
 	sig := <-quit
 	log.Printf("Received signal: %s. Shutting down server...", sig)
+	shuttingDown.Store(true) // Turn away new requests (and fail /health) before anything stops
+
+	// Give load balancers time to see the failing health check and stop routing here
+	if cfg.ShutdownDrain > 0 {
+		log.Printf("Draining for %s before closing the listener...", cfg.ShutdownDrain)
+		time.Sleep(cfg.ShutdownDrain)
+	}
 
 	// Create a context with timeout for shutdown
-	shutdownCtx, serverCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	shutdownCtx, serverCancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer serverCancel()
 
-	// Signal background tasks (like event listener) to stop by cancelling the main context
-	log.Println("Cancelling main application context...")
-	// cancel()
-
-	// Attempt to gracefully shutdown the HTTP server
+	// Stop accepting connections and let in-flight requests finish; jobs they wait on keep running
 	log.Println("Shutting down API server...")
 	if err := server.Shutdown(shutdownCtx); err != nil {
 		// Error from closing listeners, or context timeout:
@@ -222,8 +468,17 @@ func main() {
 		log.Println("API server gracefully stopped.")
 	}
 
-	// Optional: Add WaitGroup or similar mechanism to wait for critical goroutines (like listener) to finish cleanup
-	// e.g., listener.Wait()
+	// Let running deploys, batches and refinements finish; queued ones are not started
+	for name, m := range map[string]*jobs.Manager{"deploy": deployJobs, "batch": batchJobs, "refine": refineJobs} {
+		if err := m.Drain(shutdownCtx); err != nil {
+			log.Printf("WARN: %s jobs still running at the shutdown timeout are cancelled: %v", name, err)
+		}
+	}
+	cancelJobs()
+
+	// Signal background tasks (like event listener) to stop by cancelling the main context
+	log.Println("Cancelling main application context...")
+	cancel()
 
 	log.Println("Application exiting.")
 }
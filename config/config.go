@@ -3,6 +3,10 @@ package config
 import (
 	"fmt"
 	"log" // Import log
+	"time"
+
+	"sui_ai_server/internal/hooks"
+	"sui_ai_server/internal/types"
 
 	"github.com/spf13/viper"
 )
@@ -11,7 +15,21 @@ import (
 // Mapstructure tags are used to map environment variables and config file keys.
 type Config struct {
 	// Server Configuration
-	ServerAddress string `mapstructure:"SERVER_ADDRESS"` // e.g., ":8080"
+	ServerAddress string `mapstructure:"SERVER_ADDRESS"`     // e.g., ":8080"
+	RoutePrefix   string `mapstructure:"ROUTE_PREFIX"`       // Path prefix for all API routes when served behind a proxy, e.g. "/api"
+	AllowDebug    bool   `mapstructure:"ALLOW_DEBUG_OUTPUT"` // Enables debugging endpoints such as POST /project/prompt-preview; keep off in production
+	LogBodies     bool   `mapstructure:"LOG_REQUEST_BODIES"` // Include (truncated) request bodies, which hold user prompts, in the access log
+
+	// Graceful shutdown: on SIGTERM /health turns 503 for the drain period, then the listener closes and
+	// in-flight requests and running jobs get the shutdown timeout to finish
+	ShutdownDrain   time.Duration `mapstructure:"SHUTDOWN_DRAIN_PERIOD"` // e.g. "5s"; long enough for load balancers to see the failing health check
+	ShutdownTimeout time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`      // e.g. "30s"; whatever is still running afterwards is cancelled
+
+	// Security headers set on every response; empty values omit the header
+	HeaderNoSniff        bool   `mapstructure:"HEADER_NOSNIFF"`         // X-Content-Type-Options: nosniff
+	HeaderFrameOptions   string `mapstructure:"HEADER_FRAME_OPTIONS"`   // X-Frame-Options: "DENY", "SAMEORIGIN" or empty (e.g. for a preview iframe)
+	HeaderCSP            string `mapstructure:"HEADER_CSP"`             // Content-Security-Policy
+	HeaderReferrerPolicy string `mapstructure:"HEADER_REFERRER_POLICY"` // Referrer-Policy
 
 	// Neo4j Configuration
 	Neo4jURI      string `mapstructure:"NEO4J_URI"`      // e.g., "neo4j://localhost:7687" or "neo4j+s://instance.databases.neo4j.io"
@@ -19,25 +37,104 @@ type Config struct {
 	Neo4jPassword string `mapstructure:"NEO4J_PASSWORD"` // Database user password
 
 	// AI Configuration
-	OpenAIKey        string `mapstructure:"OPENAI_API_KEY"`     // API key for OpenAI
-	EmbeddingModelID string `mapstructure:"EMBEDDING_MODEL_ID"` // e.g., "text-embedding-ada-002", "text-embedding-3-small"
+	OpenAIKey            string        `mapstructure:"OPENAI_API_KEY"`         // API key for OpenAI
+	OpenAIOrgID          string        `mapstructure:"OPENAI_ORG_ID"`          // Optional organization for billing attribution
+	OpenAIProjectID      string        `mapstructure:"OPENAI_PROJECT_ID"`      // Optional project for billing attribution
+	OpenAIExtraHeaders   []string      `mapstructure:"OPENAI_EXTRA_HEADERS"`   // "Name: value" headers added to every OpenAI request, e.g. for an egress proxy
+	OpenAIProxyURL       string        `mapstructure:"OPENAI_PROXY_URL"`       // Proxy for OpenAI requests; empty uses HTTPS_PROXY/NO_PROXY
+	OpenAICABundle       string        `mapstructure:"OPENAI_CA_BUNDLE"`       // PEM file of extra CAs to trust for OpenAI, e.g. a TLS-intercepting proxy's
+	EmbeddingModelID     string        `mapstructure:"EMBEDDING_MODEL_ID"`     // e.g., "text-embedding-ada-002", "text-embedding-3-small"
+	EmbeddingDims        int           `mapstructure:"EMBEDDING_DIMENSIONS"`   // Shorter embeddings from text-embedding-3 models (e.g. 256); 0 uses the model's size
+	ModelFallbacks       []string      `mapstructure:"OPENAI_MODEL_FALLBACKS"` // Ordered chat models tried when the primary model is unavailable
+	EmbedChunkChars      int           `mapstructure:"EMBED_CHUNK_CHARS"`      // Window size when embedding large files in chunks
+	EmbedChunkOverlap    int           `mapstructure:"EMBED_CHUNK_OVERLAP"`    // Characters repeated between consecutive chunks
+	RAGContextTokens     int           `mapstructure:"RAG_CONTEXT_TOKENS"`     // Token budget for project files packed into query/refine prompts
+	EmbeddingConcurrency int           `mapstructure:"EMBEDDING_CONCURRENCY"`  // Files embedded at once when indexing a project for RAG
+	RAGExclude           []string      `mapstructure:"RAG_EXCLUDE"`            // Glob patterns of files kept out of query/refine context; empty uses the built-in list
+	RAGMaxCachedIndexes  int           `mapstructure:"RAG_MAX_CACHED_INDEXES"` // Project embedding indexes kept in memory; the least recently used is dropped beyond this
+	AnswerTokens         int           `mapstructure:"CONTEXT_ANSWER_TOKENS"`  // Tokens reserved for RAG answers; context is truncated to leave room
+	GenerateTimeout      time.Duration `mapstructure:"GENERATE_TIMEOUT"`       // Server-side limit for one site generation (e.g. "120s"); 0 disables it
+	ModelPricing         []string      `mapstructure:"MODEL_PRICING"`          // "model:input:output" USD per 1M tokens, overriding built-in prices
+	LogPrompts           bool          `mapstructure:"LOG_PROMPTS"`            // Log full prompts sent to the model; off logs only a digest
+	LogLLMOutput         bool          `mapstructure:"LOG_LLM_OUTPUT"`         // Log the model's raw output; off logs only a digest
+	MaxResponseBytes     int64         `mapstructure:"MAX_RESPONSE_BYTES"`     // Cap on one model response (body, or streamed content); 0 disables it
+	MaxOutputTokens      int           `mapstructure:"MAX_OUTPUT_TOKENS"`      // Highest max_tokens an answer cut off at its limit is retried with
+	SiteMaxTokens        int           `mapstructure:"SITE_MAX_TOKENS"`        // max_tokens a site generation starts with; raised up to MAX_OUTPUT_TOKENS when cut off
+	MonthlyBudgetUSD     float64       `mapstructure:"MONTHLY_BUDGET_USD"`     // Generation is refused once this month's OpenAI spend reaches it; 0 disables the budget
+	TailwindVersion      int           `mapstructure:"TAILWIND_VERSION"`       // Default Tailwind major version for React sites (3 or 4)
+	TailwindPlugins      []string      `mapstructure:"TAILWIND_PLUGINS"`       // Default Tailwind plugins, e.g. "forms,typography"
+
+	// Named presets requests select with "profile" (framework, default pages, theme, extra instructions).
+	// Only settable from config.yaml; names are lowercased like every config key.
+	GenerationProfiles map[string]types.GenerationProfile `mapstructure:"GENERATION_PROFILES"`
+	// Project types ("react", "static") that may be generated and deployed; empty allows both
+	AllowedProjectTypes []string `mapstructure:"ALLOWED_PROJECT_TYPES"`
+	// Lets generate requests replace the default system prompt with "systemPromptOverride"; for trusted users
+	AllowSystemPrompt bool `mapstructure:"ALLOW_SYSTEM_PROMPT_OVERRIDE"`
+
+	// Commands run over every generated project before it is stored and deployed (formatters, linters, type
+	// checks). Only settable from config.yaml.
+	PostGenerationHooks []hooks.Hook `mapstructure:"POST_GENERATION_HOOKS"`
+
+	// Generated Content Safety
+	SecretScanMode string   `mapstructure:"SECRET_SCAN_MODE"` // "redact" (default), "warn" or "off"
+	SecretPatterns []string `mapstructure:"SECRET_PATTERNS"`  // Extra "name:regex" patterns added to the built-in set
+	FileMaxBytes   []string `mapstructure:"FILE_MAX_BYTES"`   // "type:bytes" per-file size caps over the built-in ones; 0 removes a cap
+
+	// Rate Limiting
+	GenRatePerWallet int `mapstructure:"GEN_RATE_PER_WALLET"` // Generations allowed per wallet per minute; 0 disables the limit
 
 	// Deployment Tools Configuration
-	SiteBuilderPath string `mapstructure:"SITE_BUILDER_PATH"` // Path to the site-builder executable
-	WalrusCLIPath   string `mapstructure:"WALRUS_CLI_PATH"`   // Path to the walrus CLI executable
+	SiteBuilderPath   string        `mapstructure:"SITE_BUILDER_PATH"`        // Path to the site-builder executable
+	WalrusCLIPath     string        `mapstructure:"WALRUS_CLI_PATH"`          // Path to the walrus CLI executable
+	SitesConfigPath   string        `mapstructure:"SITES_CONFIG_PATH"`        // site-builder config (contexts and package IDs)
+	BundleWarnBytes   int64         `mapstructure:"BUNDLE_WARN_BYTES"`        // Published size that adds a warning to the deploy's manifest; 0 disables it
+	GetWalAttempts    int           `mapstructure:"GET_WAL_ATTEMPTS"`         // Tries of "walrus get-wal" before a deploy is aborted; 0 skips funding
+	GetWalBackoff     time.Duration `mapstructure:"GET_WAL_BACKOFF"`          // Wait before the first get-wal retry (e.g. "2s"), doubled after each failure
+	NpmBinPath        string        `mapstructure:"NPM_BIN_PATH"`             // npm used for builds; empty uses npm from PATH
+	DeployBackend     string        `mapstructure:"DEPLOY_BACKEND"`           // "walrus" (default) or "ipfs"
+	IPFSAPIURL        string        `mapstructure:"IPFS_API_URL"`             // IPFS HTTP API (/api/v0/add) of a node or pinning service, for DEPLOY_BACKEND=ipfs
+	IPFSPinToken      string        `mapstructure:"IPFS_PIN_TOKEN"`           // Bearer token for the IPFS API; empty for an unauthenticated local node
+	IPFSGatewayURL    string        `mapstructure:"IPFS_GATEWAY_URL"`         // Gateway used for deployed site URLs
+	NodeBinPath       string        `mapstructure:"NODE_BIN_PATH"`            // node used for builds (not NODE_PATH, which node reads itself); empty uses PATH
+	DeployConcurrency int           `mapstructure:"DEPLOY_CONCURRENCY"`       // Max deploys (npm builds) running at once; the rest wait in a FIFO queue
+	DeployTimeout     time.Duration `mapstructure:"DEPLOY_TIMEOUT"`           // Limit for one build and publish (e.g. "10m"); 0 disables it
+	BatchConcurrency  int           `mapstructure:"BATCH_CONCURRENCY"`        // Max Batch API generations in flight; they mostly wait on OpenAI
+	RefineConcurrency int           `mapstructure:"REFINE_CONCURRENCY"`       // Max streamed/polled refinements running at once; the rest queue
+	DeployRequiredNFT string        `mapstructure:"DEPLOY_REQUIRED_NFT_TYPE"` // NFT type ("0xPKG::module::Struct") a wallet must hold to deploy; empty allows anyone
+
+	// Generated File Storage
+	FileStore         string `mapstructure:"FILE_STORE"`           // "local" (default, single instance) or "s3" (shared across instances)
+	S3Endpoint        string `mapstructure:"S3_ENDPOINT"`          // Host[:port] of the S3-compatible service, without scheme
+	S3Bucket          string `mapstructure:"S3_BUCKET"`            // Existing bucket for project files
+	S3AccessKeyID     string `mapstructure:"S3_ACCESS_KEY_ID"`     // Access key for the bucket
+	S3SecretAccessKey string `mapstructure:"S3_SECRET_ACCESS_KEY"` // Secret key for the bucket
+	S3Region          string `mapstructure:"S3_REGION"`            // Optional region
+	S3UseSSL          bool   `mapstructure:"S3_USE_SSL"`           // Use HTTPS (default true)
+	S3Prefix          string `mapstructure:"S3_PREFIX"`            // Key prefix for project files (default "projects/")
+
+	// Stale Project Cleanup
+	JanitorEnabled  bool          `mapstructure:"JANITOR_ENABLED"`  // Periodically remove never-deployed project directories
+	JanitorInterval time.Duration `mapstructure:"JANITOR_INTERVAL"` // How often the work directory is swept (e.g. "1h")
+	JanitorTTL      time.Duration `mapstructure:"JANITOR_TTL"`      // Idle time after which an undeployed project is removed (e.g. "72h")
 
 	// Seal Access Control Configuration
-	SealAPIKey   string `mapstructure:"SEAL_API_KEY"`  // API key for Seal service
-	SealEndpoint string `mapstructure:"SEAL_ENDPOINT"` // API endpoint for Seal service (e.g., "https://api.seal.xyz")
+	SealAPIKey   string `mapstructure:"SEAL_API_KEY"`   // API key for Seal service
+	SealEndpoint string `mapstructure:"SEAL_ENDPOINT"`  // API endpoint for Seal service (e.g., "https://api.seal.xyz")
+	SealPingPath string `mapstructure:"SEAL_PING_PATH"` // Health/status path used to check Seal is reachable (default "/v1/health")
 
 	// Sui Blockchain Configuration
-	SuiRPC                string `mapstructure:"SUI_RPC_ENDPOINT"`             // Sui network RPC endpoint URL
-	SuiNetwork            string `mapstructure:"SUI_NETWORK"`                  // Network identifier (e.g., "devnet", "testnet", "mainnet")
-	SiteDeployedEventType string `mapstructure:"SUI_SITE_DEPLOYED_EVENT_TYPE"` // Full event type string (e.g., "0xPKG::MODULE::SiteDeployed")
+	SuiRPC                string        `mapstructure:"SUI_RPC_ENDPOINT"`             // Sui network RPC endpoint URL
+	SuiNetwork            string        `mapstructure:"SUI_NETWORK"`                  // Network identifier (e.g., "devnet", "testnet", "mainnet")
+	SiteDeployedEventType string        `mapstructure:"SUI_SITE_DEPLOYED_EVENT_TYPE"` // Full event type string (e.g., "0xPKG::MODULE::SiteDeployed")
+	SuiEventPollInterval  time.Duration `mapstructure:"SUI_EVENT_POLL_INTERVAL"`      // How often the event listener polls for new events (default 10s)
 
 	// SUINS Integration Configuration
 	SuinsContractAddress string `mapstructure:"SUINS_CONTRACT_ADDRESS"` // Package/Object ID of the SUINS registry contract
-	SuinsNftType         string `mapstructure:"SUINS_NFT_TYPE"`         // Full NFT Type string for SUINS ownership (e.g., "0xPKG::suins::Suins")
+	SuinsObjectID        string `mapstructure:"SUINS_OBJECT_ID"`        // Shared SuiNS registry object, needed to create subdomains
+	// Full NFT type strings a SUINS registration may have (e.g. "0xPKG::suins_registration::SuinsRegistration"),
+	// comma-separated; a wallet owns a name if it holds it under any of them. SUINS_NFT_TYPE is read when unset.
+	SuinsNftTypes []string `mapstructure:"SUINS_NFT_TYPES"`
 }
 
 // LoadConfig reads configuration from file and environment variables.
@@ -48,6 +145,79 @@ func LoadConfig(path string) (config Config, err error) {
 
 	viper.AutomaticEnv() // Read environment variables that match keys
 
+	// Defaults also register keys with viper, so they can be set from the environment alone
+	viper.SetDefault("ROUTE_PREFIX", "")
+	viper.SetDefault("ALLOW_DEBUG_OUTPUT", false)
+	viper.SetDefault("LOG_REQUEST_BODIES", false)
+	viper.SetDefault("SHUTDOWN_DRAIN_PERIOD", "5s")
+	viper.SetDefault("SHUTDOWN_TIMEOUT", "30s")
+	viper.SetDefault("HEADER_NOSNIFF", true)
+	viper.SetDefault("HEADER_FRAME_OPTIONS", "DENY")
+	viper.SetDefault("HEADER_CSP", "default-src 'none'; frame-ancestors 'none'")
+	viper.SetDefault("HEADER_REFERRER_POLICY", "no-referrer")
+	viper.SetDefault("OPENAI_ORG_ID", "")
+	viper.SetDefault("OPENAI_PROJECT_ID", "")
+	viper.SetDefault("OPENAI_EXTRA_HEADERS", "")
+	viper.SetDefault("OPENAI_PROXY_URL", "")
+	viper.SetDefault("OPENAI_CA_BUNDLE", "")
+	viper.SetDefault("OPENAI_MODEL_FALLBACKS", "")
+	viper.SetDefault("EMBEDDING_DIMENSIONS", 0)
+	viper.SetDefault("EMBED_CHUNK_CHARS", 8000)
+	viper.SetDefault("EMBED_CHUNK_OVERLAP", 400)
+	viper.SetDefault("RAG_CONTEXT_TOKENS", 12000)
+	viper.SetDefault("EMBEDDING_CONCURRENCY", 4)
+	viper.SetDefault("RAG_EXCLUDE", "")
+	viper.SetDefault("RAG_MAX_CACHED_INDEXES", 64)
+	viper.SetDefault("CONTEXT_ANSWER_TOKENS", 1500)
+	viper.SetDefault("GEN_RATE_PER_WALLET", 5)
+	viper.SetDefault("SECRET_SCAN_MODE", "redact")
+	viper.SetDefault("FILE_MAX_BYTES", "")
+	viper.SetDefault("SEAL_PING_PATH", "/v1/health")
+	viper.SetDefault("SITES_CONFIG_PATH", "sites-config.yaml")
+	viper.SetDefault("BUNDLE_WARN_BYTES", 5<<20)
+	viper.SetDefault("GET_WAL_ATTEMPTS", 3)
+	viper.SetDefault("GET_WAL_BACKOFF", "2s")
+	viper.SetDefault("DEPLOY_CONCURRENCY", 2)
+	viper.SetDefault("GENERATE_TIMEOUT", "120s")
+	viper.SetDefault("MONTHLY_BUDGET_USD", 0)
+	viper.SetDefault("MAX_RESPONSE_BYTES", 8<<20)
+	viper.SetDefault("MAX_OUTPUT_TOKENS", 16384)
+	viper.SetDefault("SITE_MAX_TOKENS", 8192)
+	viper.SetDefault("LOG_PROMPTS", false)
+	viper.SetDefault("LOG_LLM_OUTPUT", false)
+	viper.SetDefault("TAILWIND_VERSION", 3)
+	viper.SetDefault("TAILWIND_PLUGINS", "")
+	viper.SetDefault("GENERATION_PROFILES", map[string]any{})
+	viper.SetDefault("ALLOWED_PROJECT_TYPES", "")
+	viper.SetDefault("ALLOW_SYSTEM_PROMPT_OVERRIDE", false)
+	viper.SetDefault("POST_GENERATION_HOOKS", []any{})
+	viper.SetDefault("DEPLOY_TIMEOUT", "10m")
+	viper.SetDefault("BATCH_CONCURRENCY", 20)
+	viper.SetDefault("REFINE_CONCURRENCY", 4)
+	viper.SetDefault("DEPLOY_REQUIRED_NFT_TYPE", "")
+	viper.SetDefault("NPM_BIN_PATH", "")
+	viper.SetDefault("NODE_BIN_PATH", "")
+	viper.SetDefault("DEPLOY_BACKEND", "walrus")
+	viper.SetDefault("IPFS_API_URL", "")
+	viper.SetDefault("IPFS_PIN_TOKEN", "")
+	viper.SetDefault("IPFS_GATEWAY_URL", "https://ipfs.io")
+	viper.SetDefault("FILE_STORE", "local")
+	viper.SetDefault("S3_ENDPOINT", "")
+	viper.SetDefault("S3_BUCKET", "")
+	viper.SetDefault("S3_ACCESS_KEY_ID", "")
+	viper.SetDefault("S3_SECRET_ACCESS_KEY", "")
+	viper.SetDefault("S3_REGION", "")
+	viper.SetDefault("S3_USE_SSL", true)
+	viper.SetDefault("S3_PREFIX", "projects/")
+	viper.SetDefault("JANITOR_ENABLED", false)
+	viper.SetDefault("JANITOR_INTERVAL", "1h")
+	viper.SetDefault("JANITOR_TTL", "72h")
+	viper.SetDefault("SUINS_OBJECT_ID", "")
+	viper.SetDefault("SUINS_NFT_TYPES", "")
+	viper.SetDefault("SUINS_NFT_TYPE", "")               // Single-type form from before SUINS_NFT_TYPES
+	viper.SetDefault("SUI_SITE_DEPLOYED_EVENT_TYPE", "") // Empty keeps the event listener off
+	viper.SetDefault("SUI_EVENT_POLL_INTERVAL", "10s")
+
 	// Attempt to read the config file
 	err = viper.ReadInConfig()
 	if err != nil {
@@ -68,6 +238,10 @@ func LoadConfig(path string) (config Config, err error) {
 		return Config{}, fmt.Errorf("unable to decode config into struct: %w", err)
 	}
 
+	if len(config.SuinsNftTypes) == 0 && viper.GetString("SUINS_NFT_TYPE") != "" {
+		config.SuinsNftTypes = []string{viper.GetString("SUINS_NFT_TYPE")}
+	}
+
 	// Optional: Add validation logic here for required fields
 	if config.SuiRPC == "" {
 		log.Println("WARN: SUI_RPC_ENDPOINT is not set.")
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"strings"
 	"sui_ai_server/internal/ai/prompts"
@@ -17,9 +18,102 @@ import (
 
 // GenerateCodeChanges - Specific function for RAG refinement prompt to get code edits.
 func (g *Generator) GenerateCodeChanges(ctx context.Context, userQuery string, contextFiles string) ([]types.GeneratedFile, error) {
+	req := codeChangeRequest(userQuery, contextFiles)
+	log.Printf("Code change request: %s", g.promptForLog(userQuery))
+
+	resp, _, err := g.createCompleteChatCompletion(ctx, req)
+
+	if reason, retry := utils.ClassifyRetry(err); retry {
+		utils.CountRetry(reason)
+		delay := utils.RetryDelay(resp.Header(), 2*time.Second)
+		log.Printf("OpenAI call for code changes failed (%s), retrying after %s... Error: %v", reason, delay, err)
+		if sleepErr := utils.SleepContext(ctx, delay); sleepErr != nil {
+			return nil, fmt.Errorf("openai code changes retry aborted: %w", sleepErr)
+		}
+		resp, _, err = g.createCompleteChatCompletion(ctx, req)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("openai chat completion for code changes failed: %w", utils.WrapRateLimit(err, resp.Header(), 2*time.Second))
+	}
+	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
+		log.Printf("OpenAI usage for failed code change request: %+v", resp.Usage)
+		return nil, errors.New("openai returned empty response for code changes")
+	}
+
+	return g.parseCodeChanges(resp.Choices[0].Message.Content)
+}
+
+// GenerateCodeChangesStream is GenerateCodeChanges over the streaming API. onToken receives each chunk of raw
+// model output as it arrives (for a live view); the changes are only parsed once the stream completes, since
+// the output is a single JSON document.
+func (g *Generator) GenerateCodeChangesStream(ctx context.Context, userQuery string, contextFiles string, onToken func(string)) ([]types.GeneratedFile, error) {
+	req := codeChangeRequest(userQuery, contextFiles)
+	log.Printf("Code change request: %s", g.promptForLog(userQuery))
+	req.User = endUserFrom(ctx)
+	req.Stream = true
+	req.StreamOptions = &openai.StreamOptions{IncludeUsage: true} // The final chunk reports usage for spend tracking
+
+	stream, err := g.client.CreateChatCompletionStream(ctx, req)
+	if reason, retry := utils.ClassifyRetry(err); retry {
+		utils.CountRetry(reason)
+		delay := utils.RetryDelay(nil, 2*time.Second)
+		log.Printf("OpenAI stream for code changes failed to start (%s), retrying after %s... Error: %v", reason, delay, err)
+		if sleepErr := utils.SleepContext(ctx, delay); sleepErr != nil {
+			return nil, fmt.Errorf("openai code changes retry aborted: %w", sleepErr)
+		}
+		stream, err = g.client.CreateChatCompletionStream(ctx, req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("openai chat completion stream for code changes failed: %w", utils.WrapRateLimit(err, nil, 2*time.Second))
+	}
+	defer stream.Close()
+
+	var output strings.Builder
+	var finish openai.FinishReason
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("openai code changes stream interrupted: %w", err)
+		}
+		if chunk.Usage != nil {
+			g.recordUsage(req.Model, *chunk.Usage, 1)
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].FinishReason != "" {
+			finish = chunk.Choices[0].FinishReason
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+		token := chunk.Choices[0].Delta.Content
+		output.WriteString(token)
+		if err := g.checkStreamSize(output.Len()); err != nil {
+			return nil, err
+		}
+		if onToken != nil {
+			onToken(token)
+		}
+	}
+	log.Printf("Code changes stream finished (finish reason %s)", finish)
+	if finish == openai.FinishReasonLength {
+		// The stream has already been shown to the client, so it isn't silently retried with a larger limit.
+		return nil, fmt.Errorf("%w (max_tokens %d)", ErrTruncatedResponse, req.MaxTokens)
+	}
+	if output.Len() == 0 {
+		return nil, errors.New("openai returned empty response for code changes")
+	}
+
+	return g.parseCodeChanges(output.String())
+}
+
+// codeChangeRequest builds the chat request asking for code edits to contextFiles that satisfy userQuery.
+func codeChangeRequest(userQuery string, contextFiles string) openai.ChatCompletionRequest {
 	fullPrompt, ragSystemPrompt := prompts.GetSiteCodeChangePrompt(userQuery, contextFiles)
 
-	req := openai.ChatCompletionRequest{
+	return openai.ChatCompletionRequest{
 		Model: openai.GPT4o, // Or Claude 3 Opus, etc.
 		Messages: []openai.ChatCompletionMessage{
 			{Role: openai.ChatMessageRoleSystem, Content: ragSystemPrompt},
@@ -31,26 +125,11 @@ func (g *Generator) GenerateCodeChanges(ctx context.Context, userQuery string, c
 		MaxTokens:   4096, // Allow ample space for code changes
 		Temperature: 0.3,  // Keep temperature low for focused edits
 	}
+}
 
-	resp, err := g.client.CreateChatCompletion(ctx, req)
-
-	if err != nil && utils.ShouldRetry(err) {
-		log.Printf("OpenAI call for code changes failed, retrying... Error: %v", err)
-		time.Sleep(2 * time.Second)
-		resp, err = g.client.CreateChatCompletion(ctx, req)
-	}
-
-	if err != nil {
-		return nil, fmt.Errorf("openai chat completion for code changes failed: %w", err)
-	}
-	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
-		log.Printf("OpenAI usage for failed code change request: %+v", resp.Usage)
-		return nil, errors.New("openai returned empty response for code changes")
-	}
-
-	// Parse the response (expecting JSON array, possibly wrapped)
-	llmOutput := resp.Choices[0].Message.Content
-	log.Printf("LLM raw output for code changes: %s", llmOutput)
+// parseCodeChanges parses the model's code change output (expecting JSON array, possibly wrapped).
+func (g *Generator) parseCodeChanges(llmOutput string) ([]types.GeneratedFile, error) {
+	log.Printf("LLM raw output for code changes: %s", g.outputForLog(llmOutput))
 
 	var changedFiles []types.GeneratedFile
 	cleanedOutput := strings.TrimSpace(llmOutput)
@@ -58,7 +137,7 @@ func (g *Generator) GenerateCodeChanges(ctx context.Context, userQuery string, c
 	cleanedOutput = strings.TrimSuffix(cleanedOutput, "```")
 	cleanedOutput = strings.TrimSpace(cleanedOutput)
 
-	err = json.Unmarshal([]byte(cleanedOutput), &changedFiles)
+	err := json.Unmarshal([]byte(cleanedOutput), &changedFiles)
 	if err != nil {
 		keysToTry := []string{"files", "changes", "result", "code", "output"}
 		parsed := false
@@ -78,7 +157,7 @@ func (g *Generator) GenerateCodeChanges(ctx context.Context, userQuery string, c
 			}
 		}
 		if !parsed {
-			log.Printf("Failed to parse LLM JSON output for code changes. Original array error: %v. Cleaned output: %s", err, cleanedOutput)
+			log.Printf("Failed to parse LLM JSON output for code changes. Original array error: %v. Cleaned output: %s", err, g.outputForLog(cleanedOutput))
 			return nil, fmt.Errorf("failed to parse LLM JSON output for code changes: %w", err)
 		}
 	}
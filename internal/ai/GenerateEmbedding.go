@@ -11,6 +11,45 @@ import (
 	openai "github.com/sashabaranov/go-openai"
 )
 
+// reducibleEmbeddingModels are the models that accept a dimensions parameter to return shorter vectors.
+var reducibleEmbeddingModels = map[string]bool{
+	string(openai.SmallEmbedding3): true,
+	string(openai.LargeEmbedding3): true,
+}
+
+// ValidateEmbeddingDimensions checks that model can return dims-long embeddings. Zero (the model's own size)
+// is always valid; otherwise the model must support shortening and dims must not exceed its native size.
+func ValidateEmbeddingDimensions(model string, dims int) error {
+	switch {
+	case dims == 0:
+		return nil
+	case dims < 0:
+		return fmt.Errorf("embedding dimensions must be positive, got %d", dims)
+	case !reducibleEmbeddingModels[model]:
+		return fmt.Errorf("embedding model %q does not support custom dimensions", model)
+	case dims > embeddingDimensions[model]:
+		return fmt.Errorf("embedding model %s returns at most %d dimensions, got %d", model, embeddingDimensions[model], dims)
+	}
+	return nil
+}
+
+// WithEmbeddingDimensions requests dims-long embeddings, trading some retrieval quality for smaller, cheaper
+// vectors (see ValidateEmbeddingDimensions). Non-positive values keep the model's own size.
+func WithEmbeddingDimensions(dims int) Option {
+	return func(g *Generator) {
+		if dims > 0 {
+			g.embeddingDims = dims
+			g.expectedDim = dims
+		}
+	}
+}
+
+// EmbeddingSpace identifies the vectors this generator produces: the embedding model and their length
+// (0 when the model is unknown). Vectors from different spaces can't be compared.
+func (g *Generator) EmbeddingSpace() (model string, dims int) {
+	return g.embeddingModelID, g.expectedDim
+}
+
 // GenerateEmbedding creates a vector embedding for the given text.
 func (g *Generator) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
 	if g.embeddingModelID == "" {
@@ -21,27 +60,52 @@ func (g *Generator) GenerateEmbedding(ctx context.Context, text string) ([]float
 		return []float32{}, nil
 	}
 
+	vectors, err := g.createEmbeddings(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// createEmbeddings embeds inputs in one request, returning a vector per input in the same order.
+func (g *Generator) createEmbeddings(ctx context.Context, inputs []string) ([][]float32, error) {
 	model := openai.EmbeddingModel(g.embeddingModelID)
 	req := openai.EmbeddingRequest{
-		Input: []string{text},
-		Model: model,
+		Input:      inputs,
+		Model:      model,
+		User:       endUserFrom(ctx),
+		Dimensions: g.embeddingDims, // Omitted when 0
 	}
 
 	resp, err := g.client.CreateEmbeddings(ctx, req)
 	// Add retry logic here too if needed
-	if err != nil && utils.ShouldRetry(err) {
-		log.Printf("OpenAI embedding failed, retrying... Error: %v", err)
-		time.Sleep(1 * time.Second)
+	if reason, retry := utils.ClassifyRetry(err); retry {
+		utils.CountRetry(reason)
+		delay := utils.RetryDelay(resp.Header(), 1*time.Second)
+		log.Printf("OpenAI embedding failed (%s), retrying after %s... Error: %v", reason, delay, err)
+		if sleepErr := utils.SleepContext(ctx, delay); sleepErr != nil {
+			return nil, fmt.Errorf("openai embedding retry aborted: %w", sleepErr)
+		}
 		resp, err = g.client.CreateEmbeddings(ctx, req)
 	}
 
 	if err != nil {
-		return nil, fmt.Errorf("openai embedding failed: %w", err)
+		return nil, fmt.Errorf("openai embedding failed: %w", utils.WrapRateLimit(err, resp.Header(), 1*time.Second))
 	}
 
-	if len(resp.Data) == 0 || len(resp.Data[0].Embedding) == 0 {
-		return nil, errors.New("openai returned empty embedding")
+	if len(resp.Data) != len(inputs) {
+		return nil, fmt.Errorf("openai returned %d embeddings for %d inputs", len(resp.Data), len(inputs))
 	}
-
-	return resp.Data[0].Embedding, nil
+	vectors := make([][]float32, len(inputs))
+	for _, item := range resp.Data {
+		if item.Index < 0 || item.Index >= len(inputs) || len(item.Embedding) == 0 {
+			return nil, errors.New("openai returned empty embedding")
+		}
+		// A dimension change means the model (or its config) drifted; stored vectors would no longer be comparable.
+		if g.expectedDim > 0 && len(item.Embedding) != g.expectedDim {
+			return nil, fmt.Errorf("embedding dimension mismatch for model %s: got %d, expected %d", g.embeddingModelID, len(item.Embedding), g.expectedDim)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+	return vectors, nil
 }
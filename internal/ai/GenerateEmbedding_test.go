@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// embeddingsOf answers an embedding request with one vector of length dims per input.
+func embeddingsOf(dims int) func(req openai.EmbeddingRequest) (int, any) {
+	return func(req openai.EmbeddingRequest) (int, any) {
+		inputs, _ := req.Input.([]any)
+		resp := openai.EmbeddingResponse{Object: "list", Model: req.Model}
+		for i := range inputs {
+			resp.Data = append(resp.Data, openai.Embedding{Object: "embedding", Index: i, Embedding: make([]float32, dims)})
+		}
+		return http.StatusOK, resp
+	}
+}
+
+func TestGenerateEmbeddingDimensionMismatch(t *testing.T) {
+	g := newTestGenerator(t, &fakeOpenAI{embeddings: embeddingsOf(768)})
+
+	_, err := g.GenerateEmbedding(context.Background(), "hello")
+	if err == nil || !strings.Contains(err.Error(), "dimension mismatch") {
+		t.Fatalf("GenerateEmbedding error = %v, want a dimension mismatch", err)
+	}
+}
+
+func TestGenerateEmbeddingExpectedDimension(t *testing.T) {
+	g := newTestGenerator(t, &fakeOpenAI{embeddings: embeddingsOf(1536)})
+
+	vector, err := g.GenerateEmbedding(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("GenerateEmbedding: %v", err)
+	}
+	if len(vector) != 1536 {
+		t.Errorf("got %d dimensions, want 1536", len(vector))
+	}
+}
+
+func TestGenerateEmbeddingDimensionsParam(t *testing.T) {
+	fake := &fakeOpenAI{embeddings: embeddingsOf(256)}
+	g := newTestGenerator(t, fake, WithEmbeddingDimensions(256))
+
+	vector, err := g.GenerateEmbedding(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("GenerateEmbedding: %v", err)
+	}
+	if len(vector) != 256 {
+		t.Errorf("got %d dimensions, want 256", len(vector))
+	}
+	if dims := fake.embeddingCalls()[0].Dimensions; dims != 256 {
+		t.Errorf("request dimensions = %d, want 256", dims)
+	}
+	if model, dims := g.EmbeddingSpace(); model != string(openai.SmallEmbedding3) || dims != 256 {
+		t.Errorf("EmbeddingSpace = %s/%d, want %s/256", model, dims, openai.SmallEmbedding3)
+	}
+
+	// The model ignoring the parameter is caught like any other size drift.
+	fake.embeddings = embeddingsOf(1536)
+	if _, err := g.GenerateEmbedding(context.Background(), "hello"); err == nil || !strings.Contains(err.Error(), "dimension mismatch") {
+		t.Errorf("full-size vector: error = %v, want a dimension mismatch", err)
+	}
+}
+
+func TestGenerateEmbeddingOmitsDefaultDimensions(t *testing.T) {
+	fake := &fakeOpenAI{embeddings: embeddingsOf(1536)}
+	g := newTestGenerator(t, fake, WithEmbeddingDimensions(0))
+
+	if _, err := g.GenerateEmbedding(context.Background(), "hello"); err != nil {
+		t.Fatalf("GenerateEmbedding: %v", err)
+	}
+	if dims := fake.embeddingCalls()[0].Dimensions; dims != 0 {
+		t.Errorf("request dimensions = %d, want it omitted", dims)
+	}
+}
+
+func TestValidateEmbeddingDimensions(t *testing.T) {
+	tests := []struct {
+		model   string
+		dims    int
+		wantErr bool
+	}{
+		{string(openai.SmallEmbedding3), 0, false},
+		{string(openai.SmallEmbedding3), 512, false},
+		{string(openai.SmallEmbedding3), 1536, false},
+		{string(openai.SmallEmbedding3), 1537, true},
+		{string(openai.LargeEmbedding3), 3072, false},
+		{string(openai.SmallEmbedding3), -1, true},
+		{string(openai.AdaEmbeddingV2), 0, false},
+		{string(openai.AdaEmbeddingV2), 512, true},
+	}
+	for _, tt := range tests {
+		if err := ValidateEmbeddingDimensions(tt.model, tt.dims); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateEmbeddingDimensions(%s, %d) = %v, want error: %v", tt.model, tt.dims, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"sui_ai_server/internal/ai/prompts"
+	"sui_ai_server/internal/secrets"
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+)
+
+// ScopedResult describes the files of a scoped generation. Nothing is written to disk; the caller merges
+// Files into a project if it wants them.
+type ScopedResult struct {
+	Model          string
+	ParseStrategy  ParseStrategy         // Shape the model's output was parsed from
+	TotalTokens    int                   // Prompt and completion tokens of the call
+	Files          []types.GeneratedFile // Files inside the scope (after any secret redaction)
+	Dropped        []string              // Files the model generated outside the scope, which were discarded
+	SecretFindings []secrets.Finding
+}
+
+// buildScopedPrompt renders the scoped generation prompt for userPrompt, with Tailwind defaults applied as in
+// buildSitePrompt.
+func (g *Generator) buildScopedPrompt(userPrompt, scope string, opts types.SiteOptions) string {
+	tailwind := prompts.Tailwind{Version: opts.TailwindVersion, Plugins: opts.TailwindPlugins}
+	if tailwind.Version == 0 {
+		tailwind.Version = g.tailwind.Version
+	}
+	template := prompts.GetScopedGenerationPrompt(scope, opts.IsStatic(), tailwind, opts.Locale)
+	prompt := fmt.Sprintf(template, userPrompt)
+	if opts.ReferenceImage != "" {
+		prompt += prompts.ReferenceImageSection
+	}
+	return prompt + prompts.DesignSystemSection(opts.DesignSystem) + prompts.ProfileSection(opts.Theme, opts.Instructions)
+}
+
+// GenerateScopedFiles generates only the files userPrompt asks for under scope (a normalized project-relative
+// directory or file, see prompts.NormalizeScope), skipping the full-project scaffold. Files outside the scope
+// are dropped.
+func (g *Generator) GenerateScopedFiles(ctx context.Context, userPrompt, walletAddress, scope string, opts types.SiteOptions) (*ScopedResult, error) {
+	log.Printf("Generating files under %s for wallet %s", scope, walletAddress)
+	ctx = WithEndUser(ctx, walletAddress)
+	if err := g.checkReferenceImage(opts); err != nil {
+		return nil, err
+	}
+	req := g.siteCompletionRequest(g.buildScopedPrompt(userPrompt, scope, opts), opts)
+	req.Seed = opts.Seed
+
+	resp, model, err := g.createCompleteChatCompletion(ctx, req)
+	if reason, retry := utils.ClassifyRetry(err); retry {
+		utils.CountRetry(reason)
+		delay := utils.RetryDelay(resp.Header(), 2*time.Second)
+		log.Printf("OpenAI call failed (%s), retrying once after %s... Error: %v", reason, delay, err)
+		if sleepErr := utils.SleepContext(ctx, delay); sleepErr != nil {
+			return nil, fmt.Errorf("openai chat completion retry aborted: %w", sleepErr)
+		}
+		resp, model, err = g.createCompleteChatCompletion(ctx, req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("openai chat completion failed: %w", utils.WrapRateLimit(err, resp.Header(), 2*time.Second))
+	}
+	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
+		return nil, errors.New("openai returned empty response")
+	}
+
+	generated, strategy, err := g.parseSiteFiles("scope "+scope, resp.Choices[0].Message.Content)
+	if err != nil {
+		return nil, err
+	}
+	result := &ScopedResult{Model: model, ParseStrategy: strategy, TotalTokens: resp.Usage.TotalTokens}
+	var files []types.GeneratedFile
+	for _, f := range generated {
+		if !prompts.InScope(scope, f.Filename) {
+			log.Printf("WARN: Dropping %s, generated outside scope %s", f.Filename, scope)
+			result.Dropped = append(result.Dropped, f.Filename)
+			continue
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return nil, ErrNoFilesGenerated
+	}
+
+	result.Files, result.SecretFindings = g.secretScanner.Scan(files)
+	for _, f := range result.SecretFindings {
+		log.Printf("WARN: Possible secret (%s) in %s line %d of scope %s (redacted: %v)", f.Pattern, f.File, f.Line, scope, f.Redacted)
+	}
+	return result, nil
+}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"strings"
 	"sui_ai_server/internal/ai/prompts"
+	"sui_ai_server/internal/secrets"
 	"sui_ai_server/internal/types"
 	"sui_ai_server/internal/utils"
 	"time"
@@ -18,69 +19,227 @@ import (
 	openai "github.com/sashabaranov/go-openai"
 )
 
-// GenerateSiteAndStore generates the site, stores it in Neo4j, and returns the project ID.
-func (g *Generator) GenerateSiteAndStore(ctx context.Context, userPrompt, walletAddress string) (string, error) {
+// siteGenerationModel is the primary model for full site generation; fallbacks may serve the request instead.
+const siteGenerationModel = openai.GPT4oLatest // Or another suitable model like Claude 3 Opus
+
+// ErrNoFilesGenerated means the model's output parsed but contained no files, which usually calls for a
+// clearer prompt rather than a retry.
+var ErrNoFilesGenerated = errors.New("LLM did not generate any files")
+
+// ErrUnparseableOutput means the model's output could not be parsed as a list of files.
+var ErrUnparseableOutput = errors.New("failed to parse LLM JSON output")
+
+const siteSystemPrompt = "You are a helpful AI assistant that generates code based on user prompts and specific formatting instructions."
+
+// siteSystemMessage is the system message for a generation: siteSystemPrompt, or a validated override (see
+// prompts.NormalizeSystemPrompt) followed by the output format rules the parser depends on.
+func siteSystemMessage(override string) string {
+	if override == "" {
+		return siteSystemPrompt
+	}
+	return override + prompts.OutputContract
+}
+
+// buildSitePrompt renders the generation prompt for userPrompt. EstimateSite uses it too, so estimates
+// are counted on exactly what would be sent. Unset Tailwind options fall back to the generator's defaults,
+// and non-empty baseFiles (see loadSiteTemplate) are included for the model to adapt.
+func (g *Generator) buildSitePrompt(userPrompt string, opts types.SiteOptions, baseFiles []types.GeneratedFile) string {
+	pages := prompts.SanitizePageNames(opts.Pages)
+	tailwind := prompts.Tailwind{Version: opts.TailwindVersion, Plugins: opts.TailwindPlugins}
+	if tailwind.Version == 0 {
+		tailwind.Version = g.tailwind.Version
+	}
+	if tailwind.Plugins == nil {
+		tailwind.Plugins = g.tailwind.Plugins
+	}
+	template := prompts.GetSiteGenerationPrompt(pages, tailwind, opts.Locale)
+	if opts.IsStatic() {
+		template = prompts.GetStaticSiteGenerationPrompt(pages, opts.Locale)
+	}
+	prompt := fmt.Sprintf(template, userPrompt)
+	if len(baseFiles) > 0 {
+		prompt += prompts.BaseTemplateSection(baseFiles)
+	}
+	if opts.ReferenceImage != "" {
+		prompt += prompts.ReferenceImageSection
+	}
+	return prompt + prompts.DesignSystemSection(opts.DesignSystem) + prompts.ProfileSection(opts.Theme, opts.Instructions)
+}
+
+// PreviewSitePrompt returns the system and user prompts a generation of userPrompt with opts would send,
+// without calling the model.
+func (g *Generator) PreviewSitePrompt(userPrompt string, opts types.SiteOptions) (system, user string, err error) {
+	baseFiles, err := loadSiteTemplate(opts)
+	if err != nil {
+		return "", "", err
+	}
+	return siteSystemMessage(opts.SystemPrompt), g.buildSitePrompt(userPrompt, opts, baseFiles), nil
+}
+
+// siteCompletionRequest is the chat request for a full site generation from a rendered prompt, with opts'
+// system prompt override and reference image, if any. It starts at siteMaxTokens, so an answer cut off there
+// can be retried with a higher limit (see createCompleteChatCompletion).
+func (g *Generator) siteCompletionRequest(fullPrompt string, opts types.SiteOptions) openai.ChatCompletionRequest {
+	return openai.ChatCompletionRequest{
+		Model: siteGenerationModel,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: siteSystemMessage(opts.SystemPrompt)},
+			siteUserMessage(fullPrompt, opts.ReferenceImage),
+		},
+		// ResponseFormat: &openai.ChatCompletionResponseFormat{
+		// 	Type: openai.ChatCompletionResponseFormatTypeJSONObject, // Expect LLM to wrap array in JSON object
+		// },
+		MaxTokens:   min(g.siteMaxTokens, g.maxOutputTokens),
+		Temperature: 0.3, // Lower temperature for more predictable code generation
+	}
+}
+
+// SiteResult describes a completed site generation.
+type SiteResult struct {
+	ProjectID      string
+	Model          string                // Model that actually produced the files (may be a fallback)
+	TotalTokens    int                   // Prompt and completion tokens of the call that produced the files
+	ParseStrategy  ParseStrategy         // Shape the model's output was parsed from
+	Files          []types.GeneratedFile // Files as written to disk (after any secret redaction)
+	SecretFindings []secrets.Finding     // Secrets detected in the LLM output, if any
+	// Relative imports that point at files the model never generated; such projects usually fail to build
+	UnresolvedImports []UnresolvedImport
+}
+
+// GenerateSiteAndStore generates the site under a fresh project ID and stores it on disk.
+func (g *Generator) GenerateSiteAndStore(ctx context.Context, userPrompt, walletAddress string, opts types.SiteOptions) (*SiteResult, error) {
 	projectID := uuid.New().String()
+	return g.GenerateSiteInto(ctx, projectID, userPrompt, walletAddress, opts)
+}
+
+// GenerateSiteInto runs a full generation for userPrompt and writes the files into an existing project ID.
+// Files with the same name are overwritten and other files are kept; see RegenerateSite to replace them.
+func (g *Generator) GenerateSiteInto(ctx context.Context, projectID, userPrompt, walletAddress string, opts types.SiteOptions) (*SiteResult, error) {
+	return g.generateSiteInto(ctx, projectID, userPrompt, walletAddress, opts, false)
+}
+
+// RegenerateSite is GenerateSiteInto for a project whose previous files are replaced: they are cleared (see
+// ai_utils.ClearProjectFiles) only once the new files have been generated and parsed, so a failed generation
+// leaves the project as it was.
+func (g *Generator) RegenerateSite(ctx context.Context, projectID, userPrompt, walletAddress string, opts types.SiteOptions) (*SiteResult, error) {
+	return g.generateSiteInto(ctx, projectID, userPrompt, walletAddress, opts, true)
+}
+
+// generateSiteInto implements GenerateSiteInto and, with replace, RegenerateSite.
+func (g *Generator) generateSiteInto(ctx context.Context, projectID, userPrompt, walletAddress string, opts types.SiteOptions, replace bool) (*SiteResult, error) {
 	log.Printf("Generating site for project %s, wallet %s", projectID, walletAddress)
+	ctx = WithEndUser(ctx, walletAddress)
+	if err := g.checkReferenceImage(opts); err != nil {
+		return nil, err
+	}
 
-	initialGenerationPromptTemplate := prompts.GetSiteGenerationPrompt()
-
-	// 1. Construct the prompt using the template
-	fullPrompt := fmt.Sprintf(initialGenerationPromptTemplate, userPrompt)
-
-	// log.Println("Full prompt for LLM:", fullPrompt) // Log the full prompt for debugging
-
-	// 2. Call the LLM (e.g., OpenAI GPT-4o)
-	resp, err := g.client.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model: openai.GPT4oLatest, // Or another suitable model like Claude 3 Opus
-			Messages: []openai.ChatCompletionMessage{
-				{Role: openai.ChatMessageRoleSystem, Content: "You are a helpful AI assistant that generates code based on user prompts and specific formatting instructions."},
-				{Role: openai.ChatMessageRoleUser, Content: fullPrompt},
-			},
-			// ResponseFormat: &openai.ChatCompletionResponseFormat{
-			// 	Type: openai.ChatCompletionResponseFormatTypeJSONObject, // Expect LLM to wrap array in JSON object
-			// },
-			// MaxTokens:   4096, // Increased max tokens for potentially large codebases
-			Temperature: 0.3, // Lower temperature for more predictable code generation
-		},
-	)
-
-	// Basic retry logic example
-	if err != nil && utils.ShouldRetry(err) {
-		log.Printf("OpenAI call failed, retrying once after delay... Error: %v", err)
-		time.Sleep(2 * time.Second)
-		// Recreate the request struct for clarity in retry
-		retryReq := openai.ChatCompletionRequest{
-			Model: openai.GPT4o,
-			Messages: []openai.ChatCompletionMessage{
-				{Role: openai.ChatMessageRoleSystem, Content: "You are a helpful AI assistant that generates code based on user prompts and specific formatting instructions."},
-				{Role: openai.ChatMessageRoleUser, Content: fullPrompt},
-			},
-			ResponseFormat: &openai.ChatCompletionResponseFormat{
-				Type: openai.ChatCompletionResponseFormatTypeJSONObject,
-			},
-			MaxTokens:   4096,
-			Temperature: 0.3,
-		}
-		resp, err = g.client.CreateChatCompletion(ctx, retryReq)
+	// 1. Construct the prompt using the template (and the base template files, if one was chosen)
+	baseFiles, err := loadSiteTemplate(opts)
+	if err != nil {
+		return nil, err
 	}
+	fullPrompt := g.buildSitePrompt(userPrompt, opts, baseFiles)
+
+	log.Printf("Prompt for project %s: %s", projectID, g.promptForLog(fullPrompt))
 
+	// 2. Call the LLM (e.g., OpenAI GPT-4o), sharing the call with identical concurrent generations
+	resp, model, err := g.sharedSiteCompletion(ctx, fullPrompt, opts)
+
+	if errors.Is(err, ErrTruncatedResponse) {
+		return nil, keepRawOutput(projectID, resp.Choices[0].Message.Content, err) // Resuming picks up where it stopped
+	}
 	if err != nil {
-		return "", fmt.Errorf("openai chat completion failed: %w", err)
+		return nil, fmt.Errorf("openai chat completion failed: %w", utils.WrapRateLimit(err, resp.Header(), 2*time.Second))
 	}
 
 	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
 		log.Printf("OpenAI usage for failed request: %+v", resp.Usage)
-		return "", errors.New("openai returned empty response")
+		return nil, errors.New("openai returned empty response")
 	}
 
+	result, err := g.storeSiteOutput(ctx, projectID, model, resp.Choices[0].Message.Content, baseFiles, replace)
+	if err != nil {
+		return nil, err
+	}
+	result.TotalTokens = resp.Usage.TotalTokens
+	return result, nil
+}
+
+// completeSite runs the chat completion for a rendered site prompt, retrying once on transient errors.
+func (g *Generator) completeSite(ctx context.Context, fullPrompt string, opts types.SiteOptions) (openai.ChatCompletionResponse, string, error) {
+	req := g.siteCompletionRequest(fullPrompt, opts)
+	req.Seed = opts.Seed
+	resp, model, err := g.createCompleteChatCompletion(ctx, req)
+
+	// Retry once with the same request: same model (and fallback chain) and token limit
+	if reason, retry := utils.ClassifyRetry(err); retry {
+		utils.CountRetry(reason)
+		delay := utils.RetryDelay(resp.Header(), 2*time.Second)
+		log.Printf("OpenAI call failed (%s), retrying once after %s... Error: %v", reason, delay, err)
+		if sleepErr := utils.SleepContext(ctx, delay); sleepErr != nil {
+			return resp, "", fmt.Errorf("openai chat completion retry aborted: %w", sleepErr)
+		}
+		resp, model, err = g.createCompleteChatCompletion(ctx, req)
+	}
+	return resp, model, err
+}
+
+// storeSiteOutput parses the model's raw output into files and stores them (see storeSiteFiles). The
+// synchronous and batch generation paths both finish here. Output that can't be used is kept for ResumeSite.
+func (g *Generator) storeSiteOutput(ctx context.Context, projectID, model, llmOutput string, baseFiles []types.GeneratedFile, replace bool) (*SiteResult, error) {
 	// 3. Parse the LLM response (expecting JSON array, possibly wrapped)
-	llmOutput := resp.Choices[0].Message.Content
-	log.Printf("LLM raw output for project %s: %s", projectID, llmOutput) // Log raw output for debugging
+	generatedFiles, strategy, err := g.parseSiteFiles("project "+projectID, llmOutput)
+	if err != nil {
+		if errors.Is(err, ErrUnparseableOutput) || errors.Is(err, ErrNoFilesGenerated) {
+			return nil, keepRawOutput(projectID, llmOutput, err)
+		}
+		return nil, err
+	}
+	return g.storeSiteFiles(ctx, projectID, model, strategy, generatedFiles, baseFiles, replace)
+}
+
+// storeSiteFiles merges in any base template files the model left unchanged, scans the files and writes them
+// to the project's directory. With replace the project's previous files are cleared first.
+func (g *Generator) storeSiteFiles(ctx context.Context, projectID, model string, strategy ParseStrategy, generatedFiles, baseFiles []types.GeneratedFile, replace bool) (*SiteResult, error) {
+	log.Printf("Successfully parsed %d files from LLM for project %s", len(generatedFiles), projectID)
+	if len(baseFiles) > 0 {
+		generatedFiles = mergeTemplateFiles(baseFiles, generatedFiles)
+		log.Printf("Project %s has %d files after merging its base template", projectID, len(generatedFiles))
+	}
+
+	// log.Println(generatedFiles)
+
+	// Scan before anything touches disk so redacted secrets never get written or deployed.
+	generatedFiles, findings := g.secretScanner.Scan(generatedFiles)
+	for _, f := range findings {
+		log.Printf("WARN: Possible secret (%s) in %s line %d of project %s (redacted: %v)", f.Pattern, f.File, f.Line, projectID, f.Redacted)
+	}
+
+	unresolved := findUnresolvedImports(generatedFiles)
+	for _, u := range unresolved {
+		log.Printf("WARN: %s imports %q but no such file was generated for project %s", u.File, u.Import, projectID)
+	}
+
+	if replace {
+		if err := ai_utils.ClearProjectFiles(projectID); err != nil {
+			return nil, fmt.Errorf("failed to clear previous files of project %s: %w", projectID, err)
+		}
+	}
+	if err := ai_utils.SaveFilesDisk(ctx, projectID, generatedFiles); err != nil {
+		return nil, fmt.Errorf("failed to store files for project %s: %w", projectID, err)
+	}
+
+	return &SiteResult{ProjectID: projectID, Model: model, ParseStrategy: strategy, Files: generatedFiles, SecretFindings: findings, UnresolvedImports: unresolved}, nil
+}
+
+// parseSiteFiles parses the model's output as a list of files: a JSON array, a single file object or an array
+// wrapped under a common key, optionally inside a ```json fence. It reports which of those shapes parsed (see
+// ParseStrategy). label (e.g. "project <id>") names the output in logs.
+func (g *Generator) parseSiteFiles(label, llmOutput string) ([]types.GeneratedFile, ParseStrategy, error) {
+	log.Printf("LLM raw output for %s: %s", label, g.outputForLog(llmOutput))
 
 	var generatedFiles []types.GeneratedFile
+	strategy := ParseArray
 
 	cleanedOutput := strings.TrimSpace(llmOutput)
 	cleanedOutput = strings.TrimPrefix(cleanedOutput, "```json")
@@ -88,27 +247,33 @@ func (g *Generator) GenerateSiteAndStore(ctx context.Context, userPrompt, wallet
 	cleanedOutput = strings.TrimSpace(cleanedOutput)
 
 	// Attempt 1: Try parsing as an array (standard case if LLM returns multiple files)
-	err = json.Unmarshal([]byte(cleanedOutput), &generatedFiles)
+	err := json.Unmarshal([]byte(cleanedOutput), &generatedFiles)
 	if err == nil {
-		log.Printf("Parsed LLM output as a JSON array for project %s.", projectID)
+		log.Printf("Parsed LLM output as a JSON array for %s.", label)
 		// Successfully parsed as an array, proceed.
 	} else {
 		// If array parsing failed, it might be a single object or a wrapped array.
-		log.Printf("Info: Failed to parse as array (%v), trying single object for project %s.", err, projectID)
+		log.Printf("Info: Failed to parse as array (%v), trying single object for %s.", err, label)
 
-		// Attempt 2: Try parsing as a single object
+		// Attempt 2: Try parsing as a single object. Unknown keys are ignored, so a wrapper such as
+		// {"files":[...]} also decodes, as a file without a name; only an object naming its file counts.
 		var singleFile types.GeneratedFile
 		errSingle := json.Unmarshal([]byte(cleanedOutput), &singleFile)
+		if errSingle == nil && singleFile.Filename == "" {
+			errSingle = errors.New("object has no filename")
+		}
 		if errSingle == nil {
-			log.Printf("Parsed LLM output as a single JSON object for project %s.", projectID)
+			log.Printf("Parsed LLM output as a single JSON object for %s.", label)
 			// Success! Wrap the single object in a slice.
 			generatedFiles = []types.GeneratedFile{singleFile}
+			strategy = ParseSingleObject
 			err = nil // Clear the error from the failed array parse attempt
 		} else {
 			// If single object parsing also failed, try the wrapped array logic (your original fallback)
-			log.Printf("Info: Failed to parse as single object (%v), trying wrapped keys for project %s.", errSingle, projectID)
+			log.Printf("Info: Failed to parse as single object (%v), trying wrapped keys for %s.", errSingle, label)
 
-			// Attempt 3: Try parsing as an object containing the array
+			// Attempt 3: Try parsing as an object containing the array. "code" is also a content alias of a
+			// single file, but such an object names its file and was accepted above.
 			keysToTry := []string{"files", "result", "code", "data", "output"}
 			parsedWrapped := false
 			for _, key := range keysToTry {
@@ -119,52 +284,38 @@ func (g *Generator) GenerateSiteAndStore(ctx context.Context, userPrompt, wallet
 						// Attempt to unmarshal the inner value (which should be an array)
 						errInner := json.Unmarshal(rawFiles, &generatedFiles)
 						if errInner == nil && len(generatedFiles) > 0 {
-							log.Printf("Parsed LLM output assuming wrapped array structure with key '%s' for project %s.", key, projectID)
+							log.Printf("Parsed LLM output assuming wrapped array structure with key '%s' for %s.", key, label)
 							err = nil // Clear previous errors
 							parsedWrapped = true
+							strategy = ParseWrapped
 							break
 						} else if errInner != nil {
-							log.Printf("Debug: Wrapped key '%s' found for project %s, but inner unmarshal failed: %v. Raw inner JSON: %s", key, projectID, errInner, string(rawFiles))
+							log.Printf("Debug: Wrapped key '%s' found for %s, but inner unmarshal failed: %v. Raw inner JSON: %s", key, label, errInner, g.outputForLog(string(rawFiles)))
 						}
 					}
 				} else {
-					log.Printf("Debug: Failed to unmarshal into wrapper map for project %s: %v", projectID, errWrapper)
+					log.Printf("Debug: Failed to unmarshal into wrapper map for %s: %v", label, errWrapper)
 				}
 			}
 
 			// If none of the attempts (array, single object, wrapped array) worked
 			if !parsedWrapped && err != nil { // Keep err from original array attempt or errSingle if that's more relevant
-				log.Printf("Failed to parse LLM JSON output for project %s. Array error: %v. Single object error: %v. Cleaned output: %s", projectID, err, errSingle, cleanedOutput)
-				// Return or handle the final error - using the original array error 'err' for consistency with old code
-				fmt.Printf("Error generating site: %v\n", fmt.Errorf("failed to parse LLM JSON output (tried array, single object, and common wrapped keys): %w", err))
-				// return // Exit or return error
+				log.Printf("Failed to parse LLM JSON output for %s. Array error: %v. Single object error: %v. Cleaned output: %s", label, err, errSingle, g.outputForLog(cleanedOutput))
+				// Report the original array error 'err' for consistency with old code
+				return nil, "", fmt.Errorf("%w (tried array, single object, and common wrapped keys): %v", ErrUnparseableOutput, err)
 			}
 		}
 	}
 
-	// If we reach here without returning an error, 'generatedFiles' should be populated.
-	if err == nil {
-		log.Printf("Successfully parsed LLM output for project %s. Number of files: %d", projectID, len(generatedFiles))
-		if len(generatedFiles) > 0 {
-			fmt.Printf("First file filename: %s\n", generatedFiles[0].Filename)
-		}
-	} else {
-		// This case should ideally be covered by the error handling above, but as a fallback:
-		fmt.Printf("An unexpected error occurred during parsing: %v\n", err)
-	}
+	// Reaching here means one of the attempts parsed, so 'generatedFiles' is populated.
+	log.Printf("Successfully parsed LLM output for %s (%s). Number of files: %d", label, strategy, len(generatedFiles))
+	countParseStrategy(strategy)
 
 	// ---------------
 
 	if len(generatedFiles) == 0 {
-		log.Printf("LLM output parsed, but resulted in zero files for project %s.", projectID)
-		return "", errors.New("LLM did not generate any files or parsing failed silently")
+		log.Printf("LLM output parsed, but resulted in zero files for %s.", label)
+		return nil, "", ErrNoFilesGenerated
 	}
-
-	log.Printf("Successfully parsed %d files from LLM for project %s", len(generatedFiles), projectID)
-
-	// log.Println(generatedFiles)
-
-	ai_utils.SaveFilesDisk(projectID, generatedFiles)
-
-	return projectID, nil
+	return generatedFiles, strategy, nil
 }
@@ -0,0 +1,57 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestRegenerateSiteReplacesFilesOnlyOnSuccess(t *testing.T) {
+	tests := []struct {
+		name      string
+		output    string
+		wantErr   bool
+		wantFiles map[string]bool // File to whether it should exist afterwards
+	}{
+		{"unusable output", "not JSON", true,
+			map[string]bool{"old.html": true, "index.html": false, "node_modules/pkg/index.js": true}},
+		{"usable output", `[{"filename":"index.html","content":"<h1>New</h1>"}]`, false,
+			map[string]bool{"old.html": false, "index.html": true, "node_modules/pkg/index.js": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inTempWorkDir(t)
+			for _, name := range []string{"old.html", "node_modules/pkg/index.js"} {
+				path := filepath.Join(utils.ProjectDir("p1"), filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			fake := &fakeOpenAI{chat: func(req openai.ChatCompletionRequest) (int, any) {
+				return http.StatusOK, chatAnswer(req.Model, tt.output, openai.FinishReasonStop)
+			}}
+			g := newTestGenerator(t, fake)
+
+			_, err := g.RegenerateSite(context.Background(), "p1", "A new site", "0xabc", types.SiteOptions{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RegenerateSite error = %v, want error %v", err, tt.wantErr)
+			}
+			for name, want := range tt.wantFiles {
+				_, err := os.Stat(filepath.Join(utils.ProjectDir("p1"), filepath.FromSlash(name)))
+				if exists := err == nil; exists != want {
+					t.Errorf("%s exists = %v, want %v", name, exists, want)
+				}
+			}
+		})
+	}
+}
@@ -11,31 +11,42 @@ import (
 	openai "github.com/sashabaranov/go-openai"
 )
 
+const contextPromptTemplate = "User Query: %s\n\nRelevant Context from Project Files:\n%s"
+
+// contextTruncatedNote is appended when the context had to be cut to fit the model window.
+const contextTruncatedNote = "\n[Context truncated to fit the model's context window]"
+
 // GenerateWithContext is useful for pure Q&A RAG where the answer is text.
+// The context is truncated if the prompt would not leave room for the reserved answer tokens.
 func (g *Generator) GenerateWithContext(ctx context.Context, systemPrompt string, userPrompt string, contextText string) (string, error) {
-	fullUserPrompt := fmt.Sprintf("User Query: %s\n\nRelevant Context from Project Files:\n%s", userPrompt, contextText)
-
-	resp, err := g.client.CreateChatCompletion(
-		ctx,
-		openai.ChatCompletionRequest{
-			Model: openai.GPT4o, // Or preferred model
-			Messages: []openai.ChatCompletionMessage{
-				{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
-				{Role: openai.ChatMessageRoleUser, Content: fullUserPrompt},
-			},
-			MaxTokens:   1500,
-			Temperature: 0.7,
+	model := openai.GPT4o // Or preferred model
+	contextText = g.fitContext(model, systemPrompt, userPrompt, contextText)
+	fullUserPrompt := fmt.Sprintf(contextPromptTemplate, userPrompt, contextText)
+
+	req := openai.ChatCompletionRequest{
+		Model: model,
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: systemPrompt},
+			{Role: openai.ChatMessageRoleUser, Content: fullUserPrompt},
 		},
-	)
+		MaxTokens:   g.answerTokens,
+		Temperature: 0.7,
+	}
 
-	if err != nil && utils.ShouldRetry(err) {
-		log.Printf("OpenAI text generation with context failed, retrying... Error: %v", err)
-		time.Sleep(1 * time.Second)
-		// resp, err = g.client.CreateChatCompletion(ctx)
+	resp, _, err := g.createChatCompletion(ctx, req)
+
+	if reason, retry := utils.ClassifyRetry(err); retry {
+		utils.CountRetry(reason)
+		delay := utils.RetryDelay(resp.Header(), 1*time.Second)
+		log.Printf("OpenAI text generation with context failed (%s), retrying after %s... Error: %v", reason, delay, err)
+		if sleepErr := utils.SleepContext(ctx, delay); sleepErr != nil {
+			return "", fmt.Errorf("openai chat completion with context retry aborted: %w", sleepErr)
+		}
+		resp, _, err = g.createChatCompletion(ctx, req)
 	}
 
 	if err != nil {
-		return "", fmt.Errorf("openai chat completion with context failed: %w", err)
+		return "", fmt.Errorf("openai chat completion with context failed: %w", utils.WrapRateLimit(err, resp.Header(), 1*time.Second))
 	}
 
 	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
@@ -45,3 +56,25 @@ func (g *Generator) GenerateWithContext(ctx context.Context, systemPrompt string
 
 	return resp.Choices[0].Message.Content, nil
 }
+
+// fitContext trims contextText so that the system prompt, user prompt and context, plus the reserved
+// answer tokens, fit inside the model's context window.
+func (g *Generator) fitContext(model, systemPrompt, userPrompt, contextText string) string {
+	fixed, err := utils.CountTokens(model, systemPrompt+fmt.Sprintf(contextPromptTemplate, userPrompt, "")+contextTruncatedNote)
+	if err != nil {
+		log.Printf("WARN: Could not count tokens for model %s, sending context untruncated: %v", model, err)
+		return contextText
+	}
+	budget := contextWindow(model) - g.answerTokens - fixed - 2*messageOverheadTokens
+
+	truncated, wasTruncated, err := utils.TruncateToTokens(model, contextText, budget)
+	if err != nil {
+		log.Printf("WARN: Could not truncate context for model %s, sending it untruncated: %v", model, err)
+		return contextText
+	}
+	if wasTruncated {
+		log.Printf("Context for model %s truncated to %d tokens (window %d, %d reserved for answer).", model, budget, contextWindow(model), g.answerTokens)
+		return truncated + contextTruncatedNote
+	}
+	return contextText
+}
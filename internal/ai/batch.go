@@ -0,0 +1,208 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	// batchCompletionWindow is how long OpenAI may take to run a batch; it is the only window offered.
+	batchCompletionWindow = "24h"
+	// batchPollInterval is how often a submitted batch's status is checked.
+	batchPollInterval = 30 * time.Second
+	// maxBatchLineBytes bounds one line of a batch output file, which holds a whole generated site.
+	maxBatchLineBytes = 16 << 20
+)
+
+// ErrBatchFailed is returned when a batch finishes without a usable response for the generation.
+var ErrBatchFailed = errors.New("batch generation failed")
+
+// BatchProgress is a batch's state as of the latest poll.
+type BatchProgress struct {
+	BatchID   string `json:"batchId"`
+	Status    string `json:"status"` // OpenAI batch status, e.g. "validating", "in_progress", "finalizing", "completed"
+	Total     int    `json:"total"`
+	Completed int    `json:"completed"`
+	Failed    int    `json:"failed"`
+}
+
+// batchOutputLine is one line of a batch's output or error file.
+type batchOutputLine struct {
+	CustomID string `json:"custom_id"`
+	Response *struct {
+		StatusCode int                           `json:"status_code"`
+		Body       openai.ChatCompletionResponse `json:"body"`
+	} `json:"response"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// GenerateSiteBatch generates like GenerateSiteInto but through the OpenAI Batch API, which costs less but may
+// take up to the 24h completion window. progress, if non-nil, is called after every poll. The batch always uses
+// the primary site model; fallbacks don't apply. Cancelling ctx cancels the batch.
+func (g *Generator) GenerateSiteBatch(ctx context.Context, projectID, userPrompt, walletAddress string, opts types.SiteOptions, progress func(BatchProgress)) (*SiteResult, error) {
+	log.Printf("Submitting batch generation for project %s, wallet %s", projectID, walletAddress)
+	if err := g.checkReferenceImage(opts); err != nil {
+		return nil, err
+	}
+
+	baseFiles, err := loadSiteTemplate(opts)
+	if err != nil {
+		return nil, err
+	}
+	req := g.siteCompletionRequest(g.buildSitePrompt(userPrompt, opts, baseFiles), opts)
+	req.Seed = opts.Seed
+	req.User = endUserID(walletAddress)
+	req.MaxTokens = g.maxOutputTokens // A batch answer cut off at its limit can't be retried with more
+	upload := openai.UploadBatchFileRequest{FileName: "site-" + projectID + ".jsonl"}
+	upload.AddChatCompletion(projectID, req)
+	created, err := g.client.CreateBatchWithUploadFile(ctx, openai.CreateBatchWithUploadFileRequest{
+		Endpoint:               openai.BatchEndpointChatCompletions,
+		CompletionWindow:       batchCompletionWindow,
+		Metadata:               map[string]any{"project_id": projectID},
+		UploadBatchFileRequest: upload,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to submit batch: %w", err)
+	}
+	batch := created.Batch
+	log.Printf("Submitted batch %s for project %s", batch.ID, projectID)
+
+	for {
+		if progress != nil {
+			progress(BatchProgress{
+				BatchID:   batch.ID,
+				Status:    batch.Status,
+				Total:     batch.RequestCounts.Total,
+				Completed: batch.RequestCounts.Completed,
+				Failed:    batch.RequestCounts.Failed,
+			})
+		}
+		if batchFinished(batch.Status) {
+			break
+		}
+		if err := utils.SleepContext(ctx, batchPollInterval); err != nil {
+			g.cancelBatch(batch.ID)
+			return nil, err
+		}
+		resp, err := g.client.RetrieveBatch(ctx, batch.ID)
+		if err != nil {
+			// The batch keeps running on OpenAI's side; a failed poll just means trying again later.
+			log.Printf("WARN: Failed to poll batch %s for project %s: %v", batch.ID, projectID, err)
+			continue
+		}
+		batch = resp.Batch
+	}
+	log.Printf("Batch %s for project %s finished: %s", batch.ID, projectID, batch.Status)
+
+	// Expired or cancelled batches may still have completed our request, so check the output regardless of status.
+	if batch.OutputFileID != nil && *batch.OutputFileID != "" {
+		line, err := g.findBatchLine(ctx, *batch.OutputFileID, projectID)
+		if err != nil {
+			return nil, err
+		}
+		if line != nil && line.Response != nil && line.Response.StatusCode == http.StatusOK {
+			body := line.Response.Body
+			g.recordUsage(body.Model, body.Usage, batchDiscount)
+			if len(body.Choices) == 0 || body.Choices[0].Message.Content == "" {
+				log.Printf("OpenAI usage for failed batch request: %+v", body.Usage)
+				return nil, errors.New("openai returned empty response")
+			}
+			log.Printf("Batch output for project %s finished (finish reason %s)", projectID, body.Choices[0].FinishReason)
+			if body.Choices[0].FinishReason == openai.FinishReasonLength {
+				err := fmt.Errorf("%w (%d completion tokens)", ErrTruncatedResponse, body.Usage.CompletionTokens)
+				return nil, keepRawOutput(projectID, body.Choices[0].Message.Content, err)
+			}
+			result, err := g.storeSiteOutput(ctx, projectID, body.Model, body.Choices[0].Message.Content, baseFiles, false)
+			if err != nil {
+				return nil, err
+			}
+			result.TotalTokens = body.Usage.TotalTokens
+			return result, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: batch %s %s: %s", ErrBatchFailed, batch.ID, batch.Status, g.batchErrorDetail(ctx, batch, projectID))
+}
+
+// batchFinished reports whether a batch status is terminal.
+func batchFinished(status string) bool {
+	switch status {
+	case "completed", "failed", "expired", "cancelled":
+		return true
+	}
+	return false
+}
+
+// findBatchLine returns the line for customID in a batch output or error file, or nil if it has none.
+func (g *Generator) findBatchLine(ctx context.Context, fileID, customID string) (*batchOutputLine, error) {
+	content, err := g.client.GetFileContent(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download batch file %s: %w", fileID, err)
+	}
+	defer content.Close()
+
+	scanner := bufio.NewScanner(content)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxBatchLineBytes)
+	for scanner.Scan() {
+		var line batchOutputLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			log.Printf("WARN: Skipping unreadable line in batch file %s: %v", fileID, err)
+			continue
+		}
+		if line.CustomID == customID {
+			return &line, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch file %s: %w", fileID, err)
+	}
+	return nil, nil
+}
+
+// batchErrorDetail explains why a batch produced no usable response, from its own errors or its error file.
+func (g *Generator) batchErrorDetail(ctx context.Context, batch openai.Batch, customID string) string {
+	if batch.Errors != nil && len(batch.Errors.Data) > 0 {
+		messages := make([]string, 0, len(batch.Errors.Data))
+		for _, e := range batch.Errors.Data {
+			messages = append(messages, e.Message)
+		}
+		return strings.Join(messages, "; ")
+	}
+	if batch.ErrorFileID != nil && *batch.ErrorFileID != "" {
+		line, err := g.findBatchLine(ctx, *batch.ErrorFileID, customID)
+		switch {
+		case err != nil:
+			return err.Error()
+		case line != nil && line.Error != nil:
+			return line.Error.Message
+		case line != nil && line.Response != nil:
+			return fmt.Sprintf("request failed with status %d", line.Response.StatusCode)
+		}
+	}
+	return "no response for the generation request"
+}
+
+// cancelBatch stops a batch nobody is waiting for any more, so its output isn't billed.
+func (g *Generator) cancelBatch(batchID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := g.client.CancelBatch(ctx, batchID); err != nil {
+		log.Printf("WARN: Failed to cancel batch %s: %v", batchID, err)
+		return
+	}
+	log.Printf("Cancelled batch %s", batchID)
+}
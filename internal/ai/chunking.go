@@ -0,0 +1,94 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"sui_ai_server/internal/types"
+)
+
+const (
+	// defaultChunkChars keeps each chunk (about 4 characters per token) well under the embedding models'
+	// 8191-token input limit, even for dense code.
+	defaultChunkChars = 8000
+	// defaultChunkOverlap repeats the end of each chunk at the start of the next, so code spanning a
+	// boundary is still seen whole by one of them.
+	defaultChunkOverlap = 400
+	// embeddingBatchSize is how many chunks are sent per embeddings request.
+	embeddingBatchSize = 64
+)
+
+// WithEmbeddingChunks sets the window size and overlap, in characters, used by GenerateChunkedEmbeddings.
+// Non-positive sizes are ignored; the overlap is clamped below half the size.
+func WithEmbeddingChunks(size, overlap int) Option {
+	return func(g *Generator) {
+		if size > 0 {
+			g.chunkChars = size
+		}
+		if overlap >= 0 {
+			g.chunkOverlap = overlap
+		}
+		if g.chunkOverlap >= g.chunkChars/2 {
+			g.chunkOverlap = g.chunkChars / 2
+		}
+	}
+}
+
+// GenerateChunkedEmbeddings splits text into overlapping windows and embeds each one, so files larger than the
+// embedding model's input limit can still be indexed. Text that fits in one window yields a single chunk.
+func (g *Generator) GenerateChunkedEmbeddings(ctx context.Context, text string) ([]types.Chunk, error) {
+	if g.embeddingModelID == "" {
+		return nil, errors.New("embedding model ID is not configured")
+	}
+	chunks := splitChunks(text, g.chunkChars, g.chunkOverlap)
+	for start := 0; start < len(chunks); start += embeddingBatchSize {
+		batch := chunks[start:min(start+embeddingBatchSize, len(chunks))]
+		inputs := make([]string, len(batch))
+		for i, c := range batch {
+			inputs[i] = text[c.Start:c.End]
+		}
+		vectors, err := g.createEmbeddings(ctx, inputs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed chunks %d-%d: %w", start, start+len(batch)-1, err)
+		}
+		for i := range batch {
+			batch[i].Vector = vectors[i]
+		}
+	}
+	return chunks, nil
+}
+
+// splitChunks cuts text into windows of at most size bytes that overlap by about overlap bytes. Windows end
+// after a newline when one falls in their second half, and never split a UTF-8 character.
+func splitChunks(text string, size, overlap int) []types.Chunk {
+	if text == "" {
+		return nil
+	}
+	var chunks []types.Chunk
+	start := 0
+	for {
+		end := start + size
+		if end >= len(text) {
+			return append(chunks, types.Chunk{Index: len(chunks), Start: start, End: len(text)})
+		}
+		if nl := strings.LastIndexByte(text[start+size/2:end], '\n'); nl >= 0 {
+			end = start + size/2 + nl + 1
+		}
+		for end > start+1 && !utf8.RuneStart(text[end]) {
+			end--
+		}
+		chunks = append(chunks, types.Chunk{Index: len(chunks), Start: start, End: end})
+
+		next := end - overlap
+		for next > start && next < len(text) && !utf8.RuneStart(text[next]) {
+			next--
+		}
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+}
@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strconv"
+
+	"sui_ai_server/internal/types"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// siteCompletion is the outcome of one site completion, shared by every generation that waited on it.
+type siteCompletion struct {
+	resp  openai.ChatCompletionResponse
+	model string
+}
+
+// siteRequestKey identifies a site completion by the prompt and options sent to the model, so only requests that
+// would produce the same answer share one. The end user is left out: identical prompts from different wallets
+// share a call (see sharedSiteCompletion).
+func siteRequestKey(fullPrompt string, opts types.SiteOptions) string {
+	seed := ""
+	if opts.Seed != nil {
+		seed = strconv.Itoa(*opts.Seed)
+	}
+	h := sha256.New()
+	for _, part := range []string{siteSystemMessage(opts.SystemPrompt), fullPrompt, opts.ReferenceImage, seed} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// sharedSiteCompletion is completeSite, except that identical generations running at the same time share a
+// single call, whichever wallets asked: each caller gets the same response (or error) and stores it under its
+// own project. The call goes out as the end user that started it; callers that joined it are logged with their
+// own end-user ID, so every generation stays attributable. The call runs with the first caller's deadline but isn't cancelled when that caller goes away, since
+// others may be waiting on it; a caller whose ctx ends stops waiting. Nothing is cached once the call returns.
+func (g *Generator) sharedSiteCompletion(ctx context.Context, fullPrompt string, opts types.SiteOptions) (openai.ChatCompletionResponse, string, error) {
+	results := g.inflight.DoChan(siteRequestKey(fullPrompt, opts), func() (any, error) {
+		callCtx := context.WithoutCancel(ctx)
+		if deadline, ok := ctx.Deadline(); ok {
+			var cancel context.CancelFunc
+			callCtx, cancel = context.WithDeadline(callCtx, deadline)
+			defer cancel()
+		}
+		resp, model, err := g.completeSite(callCtx, fullPrompt, opts)
+		return siteCompletion{resp: resp, model: model}, err
+	})
+	select {
+	case <-ctx.Done():
+		return openai.ChatCompletionResponse{}, "", ctx.Err()
+	case res := <-results:
+		if res.Shared {
+			log.Printf("Shared a site completion with an identical concurrent generation (end user %q)", endUserFrom(ctx))
+		}
+		completion := res.Val.(siteCompletion)
+		return completion.resp, completion.model, res.Err
+	}
+}
@@ -0,0 +1,53 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"sui_ai_server/internal/types"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestSiteRequestKeyIgnoresEndUser(t *testing.T) {
+	if siteRequestKey("prompt", types.SiteOptions{}) == siteRequestKey("other prompt", types.SiteOptions{}) {
+		t.Error("different prompts share a key")
+	}
+	seed := 7
+	if siteRequestKey("prompt", types.SiteOptions{}) == siteRequestKey("prompt", types.SiteOptions{Seed: &seed}) {
+		t.Error("different seeds share a key")
+	}
+}
+
+func TestSharedSiteCompletionAcrossWallets(t *testing.T) {
+	release := make(chan struct{})
+	arrived := make(chan struct{}, 2)
+	fake := &fakeOpenAI{chat: func(req openai.ChatCompletionRequest) (int, any) {
+		arrived <- struct{}{}
+		<-release
+		return http.StatusOK, chatAnswer(req.Model, `[]`, openai.FinishReasonStop)
+	}}
+	g := newTestGenerator(t, fake)
+
+	var wg sync.WaitGroup
+	complete := func(wallet string) {
+		defer wg.Done()
+		if _, _, err := g.sharedSiteCompletion(WithEndUser(context.Background(), wallet), "prompt", types.SiteOptions{}); err != nil {
+			t.Errorf("%s: %v", wallet, err)
+		}
+	}
+	wg.Add(2)
+	go complete("0xa11ce")
+	<-arrived // The first call is in flight
+	go complete("0xb0b")
+	time.Sleep(100 * time.Millisecond) // Give the second wallet time to join it
+	close(release)
+	wg.Wait()
+
+	if n := len(fake.chatCalls()); n != 1 {
+		t.Errorf("%d upstream calls for two wallets, want 1", n)
+	}
+}
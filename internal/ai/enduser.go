@@ -0,0 +1,31 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+type endUserKey struct{}
+
+// WithEndUser attaches the requesting wallet to ctx. Requests made with ctx send a hash of it as OpenAI's
+// `user` field, so abuse is attributed per user rather than per API key without sharing the address itself.
+func WithEndUser(ctx context.Context, wallet string) context.Context {
+	if wallet == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, endUserKey{}, endUserID(wallet))
+}
+
+// endUserFrom returns the hashed end-user ID attached to ctx, or "".
+func endUserFrom(ctx context.Context) string {
+	id, _ := ctx.Value(endUserKey{}).(string)
+	return id
+}
+
+// endUserID is a stable, non-reversible ID for a wallet (addresses are case-insensitive hex).
+func endUserID(wallet string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(wallet))))
+	return hex.EncodeToString(sum[:])
+}
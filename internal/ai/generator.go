@@ -1,32 +1,176 @@
 package ai
 
 import (
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
 
-	// Added for determineFileType
+	"sui_ai_server/internal/ai/prompts"
+	"sui_ai_server/internal/secrets"
 
 	openai "github.com/sashabaranov/go-openai"
+	"golang.org/x/sync/singleflight"
 )
 
+// defaultAnswerTokens is how many tokens GenerateWithContext reserves for the model's answer.
+const defaultAnswerTokens = 1500
+
+// embeddingDimensions are the vector sizes of the known OpenAI embedding models.
+var embeddingDimensions = map[string]int{
+	string(openai.AdaEmbeddingV2):  1536,
+	string(openai.SmallEmbedding3): 1536,
+	string(openai.LargeEmbedding3): 3072,
+}
+
 type Generator struct {
 	client *openai.Client
 	// neo4jService     *neo4j.Service
 	embeddingModelID string
+	expectedDim      int              // Expected embedding length; 0 skips the check (unknown model)
+	embeddingDims    int              // Requested embedding length (the API's dimensions parameter); 0 uses the model's own
+	chunkChars       int              // Window size for GenerateChunkedEmbeddings, in characters
+	chunkOverlap     int              // Characters shared by consecutive chunks
+	answerTokens     int              // Tokens reserved for the answer in GenerateWithContext
+	secretScanner    *secrets.Scanner // Scans generated files for leaked credentials; nil disables scanning
+	orgID            string           // OpenAI organization for billing attribution (optional)
+	projectID        string           // OpenAI project for billing attribution (optional)
+	modelFallbacks   []string         // Models tried in order when a request's model is unavailable
+	pricing          map[string]Price // Per-model prices for cost estimates
+	tailwind         prompts.Tailwind // Tailwind setup for requests that don't choose one
+	spend            *SpendTracker    // Records the cost of completions; nil disables tracking
+	maxResponseBytes int64            // Cap on a provider response (see WithMaxResponseBytes); 0 disables it
+	maxOutputTokens  int              // Highest max_tokens a truncated answer is retried with (see WithMaxOutputTokens)
+	siteMaxTokens    int              // max_tokens site generations start with (see WithSiteMaxTokens)
+	extraHeaders     http.Header      // Sent with every OpenAI request
+	baseURL          string           // OpenAI API base URL; empty uses api.openai.com
+	proxyURL         *url.URL         // Nil uses the HTTPS_PROXY/NO_PROXY environment
+	rootCAs          *x509.CertPool   // Nil uses the system roots
+	logPrompts       bool             // Log full prompts rather than a digest
+	logOutput        bool             // Log raw model output rather than a digest
+
+	modelCheckMu     sync.Mutex
+	modelAvailableAt map[string]time.Time // When each model was last confirmed available (see CheckModels)
+
+	inflight singleflight.Group // Site completions in progress, by siteRequestKey
+}
+
+// Option configures optional Generator settings.
+type Option func(*Generator)
+
+// WithAnswerTokens sets how many tokens GenerateWithContext reserves for the answer.
+// The context is truncated so that prompt + answer fit in the model's window. Non-positive values are ignored.
+func WithAnswerTokens(n int) Option {
+	return func(g *Generator) {
+		if n > 0 {
+			g.answerTokens = n
+		}
+	}
+}
+
+// WithSecretScanner scans (and possibly redacts) generated files for credentials before they are saved.
+func WithSecretScanner(s *secrets.Scanner) Option {
+	return func(g *Generator) {
+		g.secretScanner = s
+	}
+}
+
+// WithTailwind sets the default Tailwind major version and plugins for React sites (see prompts.ValidateTailwind).
+// A zero version keeps the default of v3.
+func WithTailwind(version int, plugins []string) Option {
+	return func(g *Generator) {
+		if version != 0 {
+			g.tailwind.Version = version
+		}
+		g.tailwind.Plugins = plugins
+	}
+}
+
+// WithBaseURL sends OpenAI requests to baseURL (e.g. "http://127.0.0.1:8081/v1", an OpenAI-compatible proxy or
+// a fake in tests) instead of api.openai.com.
+func WithBaseURL(baseURL string) Option {
+	return func(g *Generator) {
+		g.baseURL = baseURL
+	}
+}
+
+// WithOrganization sends the OpenAI-Organization header so usage is billed to orgID.
+func WithOrganization(orgID string) Option {
+	return func(g *Generator) {
+		g.orgID = orgID
+	}
 }
 
-func NewGenerator(apiKey string, embeddingModel string) *Generator {
+// WithProject sends the OpenAI-Project header so usage is billed to projectID.
+func WithProject(projectID string) Option {
+	return func(g *Generator) {
+		g.projectID = projectID
+	}
+}
+
+func NewGenerator(apiKey string, embeddingModel string, opts ...Option) *Generator {
 	// func NewGenerator(apiKey string, neo4jSvc *neo4j.Service, embeddingModel string) *Generator {
-	// Add basic retry logic to the HTTP client used by OpenAI
+	g := &Generator{
+		// neo4jService:     neo4jSvc,
+		embeddingModelID: embeddingModel,
+		expectedDim:      embeddingDimensions[embeddingModel],
+		answerTokens:     defaultAnswerTokens,
+		chunkChars:       defaultChunkChars,
+		chunkOverlap:     defaultChunkOverlap,
+		tailwind:         prompts.Tailwind{Version: prompts.TailwindV3},
+		pricing:          make(map[string]Price, len(defaultPricing)),
+		maxResponseBytes: DefaultMaxResponseBytes,
+		maxOutputTokens:  DefaultMaxOutputTokens,
+		siteMaxTokens:    DefaultSiteMaxTokens,
+		extraHeaders:     make(http.Header),
+	}
+	for model, price := range defaultPricing {
+		g.pricing[model] = price
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+
 	// Note: go-openai doesn't directly expose easy retry config on the default client.
 	// For robust retries, consider using a library like hashicorp/go-retryablehttp
 	// or implementing a custom transport.
-	// config := openai.DefaultConfig(apiKey)
-	// config.HTTPClient = &http.Client{ ... custom transport ... }
-	// client := openai.NewClientWithConfig(config)
+	config := openai.DefaultConfig(apiKey)
+	config.OrgID = g.orgID // Empty leaves the header unset
+	if g.baseURL != "" {
+		config.BaseURL = g.baseURL
+	}
+	transport := g.baseTransport()
+	headers := g.extraHeaders.Clone()
+	if g.projectID != "" {
+		// go-openai has no project setting, so add the header at the transport level.
+		headers.Set("OpenAI-Project", g.projectID)
+	}
+	if len(headers) > 0 {
+		transport = &headerTransport{base: transport, headers: headers}
+	}
+	if g.maxResponseBytes > 0 {
+		transport = &limitTransport{base: transport, max: g.maxResponseBytes}
+	}
+	if transport != http.DefaultTransport {
+		config.HTTPClient = &http.Client{Transport: transport}
+	}
+	g.client = openai.NewClientWithConfig(config)
+	return g
+}
 
-	client := openai.NewClient(apiKey) // Sticking with default for now
-	return &Generator{
-		client: client,
-		// neo4jService:     neo4jSvc,
-		embeddingModelID: embeddingModel,
+// headerTransport adds fixed headers to every outgoing request.
+type headerTransport struct {
+	base    http.RoundTripper
+	headers http.Header
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context()) // RoundTrippers must not modify the caller's request
+	for key, values := range t.headers {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
 	}
+	return t.base.RoundTrip(req)
 }
@@ -0,0 +1,51 @@
+package ai
+
+import (
+	"errors"
+
+	"sui_ai_server/internal/types"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// ErrModelNotMultimodal is returned when a generation carries a reference image but the site model can't read images.
+var ErrModelNotMultimodal = errors.New("the configured site generation model does not accept image input")
+
+// visionModels are the chat models that accept image parts in a user message.
+var visionModels = map[string]bool{
+	openai.GPT4oLatest: true,
+	openai.GPT4o:       true,
+	openai.GPT4oMini:   true,
+	openai.GPT4Turbo:   true,
+}
+
+// SupportsReferenceImages reports whether the site generation model accepts a design reference image.
+func (g *Generator) SupportsReferenceImages() bool {
+	return visionModels[siteGenerationModel]
+}
+
+// checkReferenceImage returns ErrModelNotMultimodal when opts carries a reference image the model can't read.
+func (g *Generator) checkReferenceImage(opts types.SiteOptions) error {
+	if opts.ReferenceImage != "" && !g.SupportsReferenceImages() {
+		return ErrModelNotMultimodal
+	}
+	return nil
+}
+
+// siteUserMessage is the user message of a site generation: the rendered prompt, followed by the reference image
+// (an https or data URL) when there is one.
+func siteUserMessage(fullPrompt, referenceImage string) openai.ChatCompletionMessage {
+	if referenceImage == "" {
+		return openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: fullPrompt}
+	}
+	return openai.ChatCompletionMessage{
+		Role: openai.ChatMessageRoleUser,
+		MultiContent: []openai.ChatMessagePart{
+			{Type: openai.ChatMessagePartTypeText, Text: fullPrompt},
+			{Type: openai.ChatMessagePartTypeImageURL, ImageURL: &openai.ChatMessageImageURL{
+				URL:    referenceImage,
+				Detail: openai.ImageURLDetailHigh, // Layout fidelity needs the full-resolution tiles
+			}},
+		},
+	}
+}
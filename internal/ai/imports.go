@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"sui_ai_server/internal/types"
+)
+
+// UnresolvedImport is a relative import in a generated file that points at no generated file.
+type UnresolvedImport struct {
+	File   string `json:"file"`   // File containing the import
+	Import string `json:"import"` // The import specifier as written, e.g. "./components/Button"
+}
+
+// importPattern matches the specifier of static imports/re-exports, side-effect imports and dynamic imports.
+var importPattern = regexp.MustCompile(`(?:\bfrom\s*|\bimport\s*\(?\s*)['"]([^'"\n]+)['"]`)
+
+// importingExts are the file types whose imports are checked.
+var importingExts = map[string]bool{".ts": true, ".tsx": true, ".js": true, ".jsx": true}
+
+// resolveSuffixes are tried after an import path, the way Vite resolves extensionless imports.
+var resolveSuffixes = []string{"", ".ts", ".tsx", ".js", ".jsx", ".json", "/index.ts", "/index.tsx", "/index.js", "/index.jsx"}
+
+// findUnresolvedImports returns relative imports in TS/JS files that don't resolve to any of files.
+// Package imports are not checked; npm resolves those during the build.
+func findUnresolvedImports(files []types.GeneratedFile) []UnresolvedImport {
+	present := make(map[string]bool, len(files))
+	for _, f := range files {
+		present[path.Clean(strings.TrimPrefix(f.Filename, "/"))] = true
+	}
+
+	var unresolved []UnresolvedImport
+	for _, f := range files {
+		if !importingExts[strings.ToLower(path.Ext(f.Filename))] {
+			continue
+		}
+		dir := path.Dir(path.Clean(strings.TrimPrefix(f.Filename, "/")))
+		seen := make(map[string]bool)
+		for _, m := range importPattern.FindAllStringSubmatch(f.Content, -1) {
+			spec := m[1]
+			if !strings.HasPrefix(spec, "./") && !strings.HasPrefix(spec, "../") || seen[spec] {
+				continue
+			}
+			seen[spec] = true
+			target := path.Join(dir, strings.SplitN(spec, "?", 2)[0]) // Drop Vite query suffixes like ?raw
+			if !resolvesTo(present, target) {
+				unresolved = append(unresolved, UnresolvedImport{File: f.Filename, Import: spec})
+			}
+		}
+	}
+	return unresolved
+}
+
+func resolvesTo(present map[string]bool, target string) bool {
+	for _, suffix := range resolveSuffixes {
+		if present[target+suffix] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxResponseBytes caps a provider response unless WithMaxResponseBytes says otherwise. Real responses,
+// even full sites, are a small fraction of it.
+const DefaultMaxResponseBytes = 8 << 20
+
+// ErrResponseTooLarge is returned when a provider response exceeds the configured size cap. It is never retried.
+var ErrResponseTooLarge = errors.New("model response exceeded the maximum size")
+
+// WithMaxResponseBytes caps how much of a provider response is read: the whole body of ordinary responses and
+// the accumulated content of streamed ones. 0 disables the cap; negative values are ignored.
+func WithMaxResponseBytes(n int64) Option {
+	return func(g *Generator) {
+		if n >= 0 {
+			g.maxResponseBytes = n
+		}
+	}
+}
+
+// checkStreamSize returns ErrResponseTooLarge once streamed content reaches more than the cap.
+func (g *Generator) checkStreamSize(n int) error {
+	if g.maxResponseBytes > 0 && int64(n) > g.maxResponseBytes {
+		return fmt.Errorf("%w (%d bytes streamed, limit %d)", ErrResponseTooLarge, n, g.maxResponseBytes)
+	}
+	return nil
+}
+
+// limitTransport makes reads of a response body fail with ErrResponseTooLarge past max bytes, so a runaway
+// response is abandoned instead of buffered whole. Event streams are left alone: their size is checked on the
+// accumulated content instead (see checkStreamSize), since the SSE framing inflates the byte count.
+type limitTransport struct {
+	base http.RoundTripper
+	max  int64
+}
+
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return resp, err
+	}
+	if resp.ContentLength > t.max {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w (%d bytes, limit %d)", ErrResponseTooLarge, resp.ContentLength, t.max)
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: t.max}
+	return resp, nil
+}
+
+// limitedBody reads at most remaining bytes and then fails rather than reporting a truncated EOF, so the
+// caller never parses a cut-off response as if it were complete.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1] // One extra byte shows whether the body goes past the limit
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return 0, ErrResponseTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,39 @@
+package ai
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// WithContentLogging turns on logging of full prompts sent to the model and of its raw output. Both are off by
+// default: they contain user prompts and whole sites, so logs only get a digest (see redacted) instead.
+func WithContentLogging(prompts, output bool) Option {
+	return func(g *Generator) {
+		g.logPrompts = prompts
+		g.logOutput = output
+	}
+}
+
+// promptForLog returns prompt as it may appear in logs.
+func (g *Generator) promptForLog(prompt string) string {
+	if g.logPrompts {
+		return prompt
+	}
+	return redacted(prompt)
+}
+
+// outputForLog returns model output as it may appear in logs.
+func (g *Generator) outputForLog(output string) string {
+	if g.logOutput {
+		return output
+	}
+	return redacted(output)
+}
+
+// redacted describes text by length and a short SHA-256 prefix, enough to tell whether two log lines saw the
+// same text without revealing it.
+func redacted(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return fmt.Sprintf("[redacted: %d bytes, sha256 %s]", len(text), hex.EncodeToString(sum[:6]))
+}
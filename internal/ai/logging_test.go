@@ -0,0 +1,96 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"sui_ai_server/internal/types"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+const (
+	promptMarker = "PROMPT-MARKER-7f3a"
+	outputMarker = "OUTPUT-MARKER-91bc"
+)
+
+// captureLog sends the standard logger's output to a buffer until the test ends.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+	return &buf
+}
+
+// inTempWorkDir runs the test from a fresh directory, so utils.WorkDir (a relative path) lands in it.
+func inTempWorkDir(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// logContentRun generates a site and code changes, and parses unusable output, all with marked prompts and
+// output, returning everything that was logged.
+func logContentRun(t *testing.T, opts ...Option) string {
+	t.Helper()
+	inTempWorkDir(t)
+	fake := &fakeOpenAI{chat: func(req openai.ChatCompletionRequest) (int, any) {
+		return http.StatusOK, chatAnswer(req.Model, `[{"filename":"index.html","content":"<p>`+outputMarker+`</p>"}]`, openai.FinishReasonStop)
+	}}
+	g := newTestGenerator(t, fake, opts...)
+	logs := captureLog(t)
+
+	ctx := context.Background()
+	if _, err := g.GenerateSiteInto(ctx, "p1", "A landing page about "+promptMarker, "0xabc", types.SiteOptions{}); err != nil {
+		t.Fatalf("GenerateSiteInto: %v", err)
+	}
+	if _, err := g.GenerateCodeChanges(ctx, "Rename "+promptMarker, "// File: index.html\n<p></p>"); err != nil {
+		t.Fatalf("GenerateCodeChanges: %v", err)
+	}
+	if _, _, err := g.parseSiteFiles("project p2", "not JSON "+outputMarker); err == nil {
+		t.Fatal("parseSiteFiles accepted unusable output")
+	}
+	return logs.String()
+}
+
+func TestContentLoggingDisabled(t *testing.T) {
+	logs := logContentRun(t)
+	for _, marker := range []string{promptMarker, outputMarker} {
+		if strings.Contains(logs, marker) {
+			t.Errorf("logs contain %s with content logging off:\n%s", marker, logs)
+		}
+	}
+	if !strings.Contains(logs, "[redacted: ") {
+		t.Errorf("logs have no redacted digest:\n%s", logs)
+	}
+}
+
+func TestContentLoggingEnabled(t *testing.T) {
+	logs := logContentRun(t, WithContentLogging(true, true))
+	for _, marker := range []string{promptMarker, outputMarker} {
+		if !strings.Contains(logs, marker) {
+			t.Errorf("logs don't contain %s with content logging on", marker)
+		}
+	}
+}
+
+func TestRedactedIsStable(t *testing.T) {
+	if redacted("same text") != redacted("same text") || redacted("same text") == redacted("other text") {
+		t.Error("redacted digests don't identify the text")
+	}
+	if got := redacted("secret"); strings.Contains(got, "secret") || !strings.Contains(got, "6 bytes") {
+		t.Errorf("redacted = %q", got)
+	}
+}
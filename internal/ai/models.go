@@ -0,0 +1,137 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// WithModelFallbacks sets the models tried, in order, when a request's own model is unavailable
+// (not found or not permitted for this key). Other errors are returned without falling back.
+func WithModelFallbacks(models ...string) Option {
+	return func(g *Generator) {
+		for _, m := range models {
+			if m = strings.TrimSpace(m); m != "" {
+				g.modelFallbacks = append(g.modelFallbacks, m)
+			}
+		}
+	}
+}
+
+// createChatCompletion sends req with its own model first and then each fallback model, moving on only when
+// the model itself is unavailable. It returns the model that served the request. The end user attached to ctx
+// (see WithEndUser) is sent as the request's user.
+func (g *Generator) createChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, string, error) {
+	if req.User == "" {
+		req.User = endUserFrom(ctx)
+	}
+	chain := append([]string{req.Model}, g.modelFallbacks...)
+	tried := make(map[string]bool, len(chain))
+
+	var resp openai.ChatCompletionResponse
+	var err error
+	var previous string
+	for _, model := range chain {
+		if tried[model] {
+			continue
+		}
+		tried[model] = true
+		if previous != "" {
+			log.Printf("Model %s unavailable (%v), falling back to %s", previous, err, model)
+		}
+		previous = model
+
+		attempt := req
+		attempt.Model = model
+		resp, err = g.client.CreateChatCompletion(ctx, attempt)
+		if err == nil || !isModelUnavailable(err) {
+			if err == nil {
+				// The fingerprint changes with OpenAI's backend configuration; a change explains why a seeded
+				// request stopped reproducing earlier output.
+				if req.Seed != nil {
+					log.Printf("Chat completion served by model %s (finish reason %s, seed %d, system fingerprint %q)", model, finishReason(resp), *req.Seed, resp.SystemFingerprint)
+				} else {
+					log.Printf("Chat completion served by model %s (finish reason %s)", model, finishReason(resp))
+				}
+				g.recordUsage(model, resp.Usage, 1)
+			}
+			return resp, model, err
+		}
+	}
+	return resp, "", err
+}
+
+// finishReason is why the first choice of resp ended, e.g. "stop" or "length".
+func finishReason(resp openai.ChatCompletionResponse) openai.FinishReason {
+	if len(resp.Choices) == 0 {
+		return "none"
+	}
+	return resp.Choices[0].FinishReason
+}
+
+// modelCheckTTL is how long a model confirmed available is trusted before CheckModels asks the API again.
+const modelCheckTTL = 5 * time.Minute
+
+// Model availability values reported by CheckModels.
+const (
+	ModelAvailable   = "available"
+	ModelUnavailable = "unavailable"
+)
+
+// CheckModels confirms that the site generation model and the embedding model (if configured) are available
+// to the API key, returning ModelAvailable or "unavailable: <reason>" per model. Positive results are cached
+// for modelCheckTTL so frequent health probes don't hammer the models API; failures are rechecked every time.
+func (g *Generator) CheckModels(ctx context.Context) map[string]string {
+	models := []string{siteGenerationModel}
+	if g.embeddingModelID != "" {
+		models = append(models, g.embeddingModelID)
+	}
+
+	results := make(map[string]string, len(models))
+	for _, model := range models {
+		g.modelCheckMu.Lock()
+		checkedAt, ok := g.modelAvailableAt[model]
+		g.modelCheckMu.Unlock()
+		if ok && time.Since(checkedAt) < modelCheckTTL {
+			results[model] = ModelAvailable
+			continue
+		}
+
+		if _, err := g.client.GetModel(ctx, model); err != nil {
+			results[model] = ModelUnavailable + ": " + err.Error()
+			continue
+		}
+		g.modelCheckMu.Lock()
+		if g.modelAvailableAt == nil {
+			g.modelAvailableAt = make(map[string]time.Time)
+		}
+		g.modelAvailableAt[model] = time.Now()
+		g.modelCheckMu.Unlock()
+		results[model] = ModelAvailable
+	}
+	return results
+}
+
+// isModelUnavailable reports whether err means the requested model can't be used (missing, deprecated or
+// not enabled for this key), as opposed to a transient or request-level failure.
+func isModelUnavailable(err error) bool {
+	var apiErr *openai.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if code, ok := apiErr.Code.(string); ok && code == "model_not_found" {
+		return true
+	}
+	switch apiErr.HTTPStatusCode {
+	case http.StatusNotFound:
+		return true
+	case http.StatusForbidden:
+		return strings.Contains(strings.ToLower(apiErr.Message), "model")
+	}
+	return false
+}
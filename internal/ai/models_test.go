@@ -0,0 +1,96 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestCreateChatCompletionFallbackChain(t *testing.T) {
+	tests := []struct {
+		name      string
+		errors    map[string]int // Status each model fails with; missing models answer
+		wantModel string         // The model that answered, or that failed for a reason other than availability
+		wantTried []string
+		wantErr   bool
+	}{
+		{"primary answers", nil, "primary", []string{"primary"}, false},
+		{"primary not found", map[string]int{"primary": http.StatusNotFound}, "second", []string{"primary", "second"}, false},
+		{"first fallback forbidden", map[string]int{"primary": http.StatusNotFound, "second": http.StatusForbidden}, "third", []string{"primary", "second", "third"}, false},
+		{"bad request doesn't fall back", map[string]int{"primary": http.StatusBadRequest}, "primary", []string{"primary"}, true},
+		{"all unavailable", map[string]int{"primary": http.StatusNotFound, "second": http.StatusNotFound, "third": http.StatusNotFound}, "", []string{"primary", "second", "third"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeOpenAI{chat: func(req openai.ChatCompletionRequest) (int, any) {
+				if status, ok := tt.errors[req.Model]; ok {
+					return status, apiError("", "The model "+req.Model+" does not exist or you do not have access to it")
+				}
+				return http.StatusOK, chatAnswer(req.Model, "hi", openai.FinishReasonStop)
+			}}
+			g := newTestGenerator(t, fake, WithModelFallbacks("second", "primary", "third"))
+
+			_, model, err := g.createChatCompletion(context.Background(), openai.ChatCompletionRequest{Model: "primary"})
+			if (err != nil) != tt.wantErr || model != tt.wantModel {
+				t.Errorf("createChatCompletion = %q, %v; want %q (error: %v)", model, err, tt.wantModel, tt.wantErr)
+			}
+			var tried []string
+			for _, req := range fake.chatCalls() {
+				tried = append(tried, req.Model)
+			}
+			if len(tried) != len(tt.wantTried) {
+				t.Fatalf("tried %v, want %v", tried, tt.wantTried)
+			}
+			for i := range tried {
+				if tried[i] != tt.wantTried[i] {
+					t.Errorf("tried %v, want %v", tried, tt.wantTried)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestCheckModelsCachesAvailableModels(t *testing.T) {
+	var embeddingUp atomic.Bool
+	fake := &fakeOpenAI{models: func(model string) (int, any) {
+		if model == string(openai.SmallEmbedding3) && !embeddingUp.Load() {
+			return http.StatusNotFound, apiError("model_not_found", "The model does not exist")
+		}
+		return http.StatusOK, openai.Model{ID: model, Object: "model"}
+	}}
+	g := newTestGenerator(t, fake)
+	site, embedding := string(siteGenerationModel), string(openai.SmallEmbedding3)
+
+	results := g.CheckModels(context.Background())
+	if results[site] != ModelAvailable || !strings.HasPrefix(results[embedding], ModelUnavailable+": ") {
+		t.Fatalf("CheckModels = %v, want %s available and %s unavailable", results, site, embedding)
+	}
+
+	embeddingUp.Store(true)
+	results = g.CheckModels(context.Background())
+	if results[site] != ModelAvailable || results[embedding] != ModelAvailable {
+		t.Fatalf("CheckModels = %v, want both available", results)
+	}
+	g.CheckModels(context.Background())
+
+	// The site model was looked up once and then cached; the embedding model was rechecked after failing.
+	counts := map[string]int{}
+	for _, model := range fake.lookups() {
+		counts[model]++
+	}
+	if counts[site] != 1 || counts[embedding] != 2 {
+		t.Errorf("model lookups = %v, want %s once and %s twice", counts, site, embedding)
+	}
+
+	g.modelAvailableAt[site] = time.Now().Add(-modelCheckTTL)
+	g.CheckModels(context.Background())
+	if n := len(fake.lookups()); n != 4 {
+		t.Errorf("%d lookups after the cache expired, want 4", n)
+	}
+}
@@ -0,0 +1,100 @@
+package ai
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// fakeOpenAI is an httptest server standing in for the OpenAI API. Handlers answer chat completions,
+// embeddings and model lookups; each decoded request is recorded.
+type fakeOpenAI struct {
+	chat       func(req openai.ChatCompletionRequest) (int, any) // Status and JSON body per chat request
+	embeddings func(req openai.EmbeddingRequest) (int, any)
+	models     func(model string) (int, any)
+
+	mu           sync.Mutex
+	chatRequests []openai.ChatCompletionRequest
+	embedReqs    []openai.EmbeddingRequest
+	modelLookups []string
+}
+
+// newTestGenerator starts fake and returns a Generator that talks to it, with opts applied.
+func newTestGenerator(t *testing.T, fake *fakeOpenAI, opts ...Option) *Generator {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	return NewGenerator("test-key", string(openai.SmallEmbedding3), append([]Option{WithBaseURL(server.URL + "/v1")}, opts...)...)
+}
+
+func (f *fakeOpenAI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var status int
+	var body any
+	switch {
+	case r.URL.Path == "/v1/chat/completions" && f.chat != nil:
+		var req openai.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		f.mu.Lock()
+		f.chatRequests = append(f.chatRequests, req)
+		f.mu.Unlock()
+		status, body = f.chat(req)
+	case r.URL.Path == "/v1/embeddings" && f.embeddings != nil:
+		var req openai.EmbeddingRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		f.mu.Lock()
+		f.embedReqs = append(f.embedReqs, req)
+		f.mu.Unlock()
+		status, body = f.embeddings(req)
+	case strings.HasPrefix(r.URL.Path, "/v1/models/") && f.models != nil:
+		model := strings.TrimPrefix(r.URL.Path, "/v1/models/")
+		f.mu.Lock()
+		f.modelLookups = append(f.modelLookups, model)
+		f.mu.Unlock()
+		status, body = f.models(model)
+	default:
+		status, body = http.StatusNotFound, apiError("not_found", "unexpected request "+r.URL.Path)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func (f *fakeOpenAI) chatCalls() []openai.ChatCompletionRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]openai.ChatCompletionRequest(nil), f.chatRequests...)
+}
+
+func (f *fakeOpenAI) embeddingCalls() []openai.EmbeddingRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]openai.EmbeddingRequest(nil), f.embedReqs...)
+}
+
+func (f *fakeOpenAI) lookups() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.modelLookups...)
+}
+
+// chatAnswer is a chat completion response from model with content and finish reason.
+func chatAnswer(model, content string, finish openai.FinishReason) openai.ChatCompletionResponse {
+	return openai.ChatCompletionResponse{
+		Model: model,
+		Choices: []openai.ChatCompletionChoice{{
+			Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content},
+			FinishReason: finish,
+		}},
+		Usage: openai.Usage{PromptTokens: 100, CompletionTokens: 50, TotalTokens: 150},
+	}
+}
+
+// apiError is an OpenAI error body with code and message.
+func apiError(code, message string) map[string]any {
+	return map[string]any{"error": map[string]any{"code": code, "message": message, "type": "invalid_request_error"}}
+}
@@ -0,0 +1,40 @@
+package ai
+
+import "sync/atomic"
+
+// ParseStrategy names the shape in which the model returned its files. Anything but ParseArray means the model
+// drifted from the prompt's output format and parseSiteFiles had to fall back.
+type ParseStrategy string
+
+const (
+	ParseArray        ParseStrategy = "array"         // A JSON array of files, as the prompt asks
+	ParseSingleObject ParseStrategy = "single_object" // One file object instead of an array
+	ParseWrapped      ParseStrategy = "wrapped"       // An array under a key such as "files"
+)
+
+var parseStrategies = []ParseStrategy{ParseArray, ParseSingleObject, ParseWrapped}
+
+// parseStrategyCounts counts successful parses per ParseStrategy since startup.
+var parseStrategyCounts = func() map[ParseStrategy]*atomic.Int64 {
+	counts := make(map[ParseStrategy]*atomic.Int64, len(parseStrategies))
+	for _, strategy := range parseStrategies {
+		counts[strategy] = new(atomic.Int64)
+	}
+	return counts
+}()
+
+func countParseStrategy(strategy ParseStrategy) {
+	if counter, ok := parseStrategyCounts[strategy]; ok {
+		counter.Add(1)
+	}
+}
+
+// ParseStrategyCounts returns how many generations were parsed with each strategy since startup, for the
+// metrics endpoint.
+func ParseStrategyCounts() map[ParseStrategy]int64 {
+	counts := make(map[ParseStrategy]int64, len(parseStrategyCounts))
+	for strategy, counter := range parseStrategyCounts {
+		counts[strategy] = counter.Load()
+	}
+	return counts
+}
@@ -0,0 +1,43 @@
+package ai
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSiteFilesStrategy(t *testing.T) {
+	g := newTestGenerator(t, &fakeOpenAI{})
+	tests := []struct {
+		name   string
+		output string
+		want   ParseStrategy
+	}{
+		{"array", `[{"filename":"index.html","content":"<h1>Hi</h1>"}]`, ParseArray},
+		{"fenced array", "```json\n[{\"filename\":\"index.html\",\"content\":\"<h1>Hi</h1>\"}]\n```", ParseArray},
+		{"single object", `{"filename":"index.html","content":"<h1>Hi</h1>"}`, ParseSingleObject},
+		{"single object with code alias", `{"path":"index.html","code":"<h1>Hi</h1>"}`, ParseSingleObject},
+		{"files wrapper", `{"files":[{"filename":"index.html","content":"<h1>Hi</h1>"}]}`, ParseWrapped},
+		{"code wrapper", `{"code":[{"filename":"index.html","content":"<h1>Hi</h1>"}]}`, ParseWrapped},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, strategy, err := g.parseSiteFiles("test", tt.output)
+			if err != nil {
+				t.Fatalf("parseSiteFiles: %v", err)
+			}
+			if strategy != tt.want {
+				t.Errorf("strategy = %q, want %q", strategy, tt.want)
+			}
+			if len(files) != 1 || files[0].Filename != "index.html" || files[0].Content != "<h1>Hi</h1>" {
+				t.Errorf("files = %+v, want index.html", files)
+			}
+		})
+	}
+}
+
+func TestParseSiteFilesNamelessObject(t *testing.T) {
+	g := newTestGenerator(t, &fakeOpenAI{})
+	if _, _, err := g.parseSiteFiles("test", `{"content":"<h1>Hi</h1>"}`); !errors.Is(err, ErrUnparseableOutput) {
+		t.Errorf("error = %v, want %v", err, ErrUnparseableOutput)
+	}
+}
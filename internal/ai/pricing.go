@@ -0,0 +1,100 @@
+package ai
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// Price is a model's cost in USD per million tokens.
+type Price struct {
+	InputPerMillion  float64
+	OutputPerMillion float64
+}
+
+// defaultPricing is OpenAI's list pricing for the chat models we use; WithPricing overrides entries.
+var defaultPricing = map[string]Price{
+	openai.GPT4oLatest:   {InputPerMillion: 5, OutputPerMillion: 15},
+	openai.GPT4o:         {InputPerMillion: 2.5, OutputPerMillion: 10},
+	openai.GPT4oMini:     {InputPerMillion: 0.15, OutputPerMillion: 0.6},
+	openai.GPT4Turbo:     {InputPerMillion: 10, OutputPerMillion: 30},
+	openai.GPT4:          {InputPerMillion: 30, OutputPerMillion: 60},
+	openai.GPT3Dot5Turbo: {InputPerMillion: 0.5, OutputPerMillion: 1.5},
+}
+
+// WithPricing overrides or extends the per-model prices used for cost estimates.
+func WithPricing(prices map[string]Price) Option {
+	return func(g *Generator) {
+		for model, price := range prices {
+			g.pricing[model] = price
+		}
+	}
+}
+
+// ParsePricing parses "model:input:output" entries (USD per million tokens), e.g. "gpt-4o:2.50:10.00".
+func ParsePricing(entries []string) (map[string]Price, error) {
+	prices := make(map[string]Price, len(entries))
+	for _, entry := range entries {
+		parts := strings.Split(strings.TrimSpace(entry), ":")
+		if len(parts) != 3 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid model price %q: expected model:input:output", entry)
+		}
+		input, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input price in %q: %w", entry, err)
+		}
+		output, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output price in %q: %w", entry, err)
+		}
+		prices[parts[0]] = Price{InputPerMillion: input, OutputPerMillion: output}
+	}
+	return prices, nil
+}
+
+// siteOutputTokensEstimate is a typical size of a generated site; output can't be counted before generating.
+const siteOutputTokensEstimate = 4096
+
+// Estimate is the predicted token usage and cost of a site generation.
+type Estimate struct {
+	Model                 string   `json:"model"`
+	InputTokens           int      `json:"inputTokens"`
+	EstimatedOutputTokens int      `json:"estimatedOutputTokens"`
+	InputCostUSD          *float64 `json:"inputCostUsd,omitempty"`     // Nil when the model has no configured price
+	EstimatedCostUSD      *float64 `json:"estimatedCostUsd,omitempty"` // Input plus estimated output cost
+}
+
+// EstimateSite counts the tokens GenerateSiteInto would send for userPrompt and prices them, without calling the model.
+func (g *Generator) EstimateSite(userPrompt string, opts types.SiteOptions) (*Estimate, error) {
+	model := siteGenerationModel
+	systemTokens, err := utils.CountTokens(model, siteSystemMessage(opts.SystemPrompt))
+	if err != nil {
+		return nil, err
+	}
+	baseFiles, err := loadSiteTemplate(opts)
+	if err != nil {
+		return nil, err
+	}
+	userTokens, err := utils.CountTokens(model, g.buildSitePrompt(userPrompt, opts, baseFiles))
+	if err != nil {
+		return nil, err
+	}
+
+	est := &Estimate{
+		Model:                 model,
+		InputTokens:           systemTokens + userTokens + 2*messageOverheadTokens,
+		EstimatedOutputTokens: siteOutputTokensEstimate,
+	}
+	if price, ok := g.pricing[model]; ok {
+		input := float64(est.InputTokens) * price.InputPerMillion / 1e6
+		total := input + float64(est.EstimatedOutputTokens)*price.OutputPerMillion/1e6
+		est.InputCostUSD = &input
+		est.EstimatedCostUSD = &total
+	}
+	return est, nil
+}
@@ -0,0 +1,15 @@
+package prompts
+
+import "strings"
+
+// DesignSystemSection renders a design system spec (components, tokens, usage rules) as a constraint for
+// appending after the rendered generation prompt. It is empty when there is no spec.
+func DesignSystemSection(spec string) string {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return ""
+	}
+	return "\n\n\t\tDesign system: build the UI only from the components and design tokens specified below, following its usage rules. " +
+		"Do not invent new components, colors, fonts or spacing values where the design system provides one; it takes precedence over the styling rules above.\n" +
+		"\t\t--- DESIGN SYSTEM START ---\n" + spec + "\n\t\t--- DESIGN SYSTEM END ---\n"
+}
@@ -0,0 +1,60 @@
+package prompts
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Locales maps the locale codes accepted in requests to the language the site's copy is written in.
+var Locales = map[string]string{
+	"ar":    "Arabic",
+	"de":    "German",
+	"en":    "English",
+	"es":    "Spanish",
+	"fr":    "French",
+	"hi":    "Hindi",
+	"id":    "Indonesian",
+	"it":    "Italian",
+	"ja":    "Japanese",
+	"ko":    "Korean",
+	"nl":    "Dutch",
+	"pl":    "Polish",
+	"pt":    "Portuguese",
+	"pt-BR": "Brazilian Portuguese",
+	"ru":    "Russian",
+	"th":    "Thai",
+	"tr":    "Turkish",
+	"uk":    "Ukrainian",
+	"vi":    "Vietnamese",
+	"zh-CN": "Simplified Chinese",
+	"zh-TW": "Traditional Chinese",
+}
+
+// NormalizeLocale canonicalises a locale code ("ES", "pt_br" become "es", "pt-BR") and checks it is one
+// of Locales. An empty code is valid and means the model's default (English).
+func NormalizeLocale(code string) (string, error) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), "_", "-")
+	if code == "" {
+		return "", nil
+	}
+	lang, region, hasRegion := strings.Cut(code, "-")
+	code = strings.ToLower(lang)
+	if hasRegion {
+		code += "-" + strings.ToUpper(region)
+	}
+	if _, ok := Locales[code]; !ok {
+		return "", fmt.Errorf("unsupported locale %q", code)
+	}
+	return code, nil
+}
+
+// localeInstructions renders the prompt rule asking for user-facing copy in locale's language, or nothing
+// when no locale was chosen.
+func localeInstructions(locale string) string {
+	language, ok := Locales[locale]
+	if !ok {
+		return ""
+	}
+	return "\n\t\tWrite all user-facing text (headings, hero copy, navigation labels, buttons, footer, alt text and the page <title>) in " +
+		language + ". Set the lang attribute of <html> to \"" + locale + "\". Keep code, file names, identifiers and comments in English.\n"
+}
@@ -0,0 +1,54 @@
+package prompts
+
+import (
+	"path"
+	"strings"
+)
+
+// MaxExtraPages caps how many pages a user may request beyond the built-in set.
+const MaxExtraPages = 10
+
+// builtinPages are always generated, so requesting them again adds nothing.
+var builtinPages = map[string]bool{"index": true, "about": true}
+
+// SanitizePageNames turns user-supplied page names ("Pricing", "contact.tsx", "../x") into bare, lowercase
+// filename stems made of letters, digits, '-' and '_'. Empty, duplicate and built-in pages are dropped and
+// the result is capped at MaxExtraPages.
+func SanitizePageNames(pages []string) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, page := range pages {
+		name := path.Base(strings.ReplaceAll(strings.TrimSpace(page), "\\", "/"))
+		name = strings.TrimSuffix(name, path.Ext(name))
+		name = strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+				return r
+			case r >= 'A' && r <= 'Z':
+				return r + ('a' - 'A')
+			case r == ' ':
+				return '-'
+			}
+			return -1
+		}, name)
+		name = strings.Trim(name, "-_")
+		if name == "" || seen[name] || builtinPages[name] {
+			continue
+		}
+		seen[name] = true
+		out = append(out, name)
+		if len(out) == MaxExtraPages {
+			break
+		}
+	}
+	return out
+}
+
+// extraPagesList renders the requested pages as prompt list items with the given file extension.
+func extraPagesList(pages []string, ext string) string {
+	var b strings.Builder
+	for _, page := range pages {
+		b.WriteString("\n\t\t\t*   `" + page + ext + "`: " + page + " page (requested by the user)")
+	}
+	return b.String()
+}
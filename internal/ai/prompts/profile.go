@@ -0,0 +1,20 @@
+package prompts
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ProfileSection renders a generation profile's theme and extra instructions (see types.GenerationProfile) for
+// appending after the rendered generation prompt. It is empty when the profile sets neither.
+func ProfileSection(theme, instructions string) string {
+	theme, instructions = strings.TrimSpace(theme), strings.TrimSpace(instructions)
+	var b strings.Builder
+	if theme != "" {
+		fmt.Fprintf(&b, "\n\n\t\tVisual theme: %s. Apply it consistently to colors, typography and spacing across every page.\n", theme)
+	}
+	if instructions != "" {
+		fmt.Fprintf(&b, "\n\t\tAdditional requirements for this deployment, which take precedence over the defaults above:\n\t\t%s\n", instructions)
+	}
+	return b.String()
+}
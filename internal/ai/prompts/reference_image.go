@@ -0,0 +1,10 @@
+package prompts
+
+// ReferenceImageSection tells the model how to use an attached design reference. It is appended after the
+// rendered generation prompt when the request carries a reference image.
+const ReferenceImageSection = `
+
+		An image of the intended design is attached as a visual reference. Reproduce its layout, section order,
+		spacing, color palette and typography as closely as the setup rules above allow. Use the project description
+		for content and behaviour; where the image shows placeholder or unreadable text, write fitting copy instead.
+`
@@ -0,0 +1,21 @@
+package prompts
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ResumeInstruction asks the model to finish a generation whose previous answer (sent back to it as the
+// assistant turn) was cut off or malformed. complete lists the files that were read from that answer intact;
+// the model returns only the rest, so the work already paid for isn't repeated.
+func ResumeInstruction(complete []string) string {
+	done := "None of the files could be read."
+	if len(complete) > 0 {
+		done = "These files were read intact and must not be repeated: " + strings.Join(complete, ", ") + "."
+	}
+	return fmt.Sprintf(`Your previous answer was cut off or is not valid JSON, so it could not be used in full. %s
+
+Return a JSON array in the same format as before containing only the files that are still missing, incomplete
+or malformed, so that together with the intact files the project is complete and builds. Return every file in
+full, and output only the JSON array.`, done)
+}
@@ -0,0 +1,83 @@
+package prompts
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ErrInvalidScope is returned for scopes that are absolute, leave the project or contain unusual characters.
+var ErrInvalidScope = errors.New("scope must be a relative path inside the project")
+
+// NormalizeScope cleans a generation scope: a project-relative directory ("src/components") or file
+// ("src/components/Hero.tsx"). An empty scope means a full-project generation.
+func NormalizeScope(scope string) (string, error) {
+	scope = strings.TrimSpace(strings.ReplaceAll(scope, "\\", "/"))
+	if scope == "" {
+		return "", nil
+	}
+	if strings.HasPrefix(scope, "/") {
+		return "", ErrInvalidScope
+	}
+	scope = path.Clean(scope)
+	if scope == "." || scope == ".." || strings.HasPrefix(scope, "../") {
+		return "", ErrInvalidScope
+	}
+	// The scope is embedded in the prompt template, so keep it to plain path characters.
+	for _, r := range scope {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./", r)) {
+			return "", ErrInvalidScope
+		}
+	}
+	return scope, nil
+}
+
+// InScope reports whether filename is the scope itself or lies under it. scope must be normalized.
+func InScope(scope, filename string) bool {
+	filename = path.Clean(strings.ReplaceAll(filename, "\\", "/"))
+	return filename == scope || strings.HasPrefix(filename, scope+"/")
+}
+
+// GetScopedGenerationPrompt returns the template for generating only the files under scope (see
+// NormalizeScope) for an existing project, without the full-project page requirements. static selects plain
+// HTML/CSS/JS instead of React; tailwind and locale work as in GetSiteGenerationPrompt.
+func GetScopedGenerationPrompt(scope string, static bool, tailwind Tailwind, locale string) string {
+	stack := fmt.Sprintf("React + TypeScript (Vite) components styled with TailwindCSS v%d utility classes", tailwind.Version)
+	if static {
+		stack = "plain HTML5, CSS and vanilla JavaScript with no build step, frameworks or npm packages"
+	}
+	return `
+		You are a code generator AI adding to an existing project.
+
+		A user has asked for the following:
+
+		---
+		"%s"
+		---
+
+		Generate ONLY the files that request needs, following these rules:
+
+		1.  **Scope**: every file must be ` + "`" + scope + "`" + ` itself or live under ` + "`" + scope + "/`" + `. Do NOT generate
+			pages, routing, App.tsx, main.tsx, package.json, index.html, config files or anything else outside that scope.
+		2.  **Stack**: ` + stack + `.
+		3.  **Self-contained**: only import from other files you generate here or from libraries a typical project of
+			this kind already has; the files will be merged into the project as they are.
+		4.  **Style**: consistent with the project's color theme (Primary #1A73E8, Accent #FF6F61, Background #F9FAFB,
+			Inter font), responsive, cards with soft shadows and rounded corners.
+` + localeInstructions(locale) + `
+		Respond with a structured array of files in the following format:
+
+		` + "```json" + `
+		[
+		{
+			"filename": "` + scope + `/...",
+			"type": "...",
+			"content": "..."
+		}
+		]
+		` + "```" + `
+
+		Only include code — no extra explanation. Your output will be parsed and merged into the project's files.
+	`
+}
@@ -1,7 +1,16 @@
 package prompts
 
-// Constant for the initial generation prompt template
-func GetSiteGenerationPrompt() string {
+import "fmt"
+
+// GetSiteGenerationPrompt returns the initial generation prompt template. extraPages (already sanitized, see
+// SanitizePageNames) are added to the required pages and wired into routing; tailwind picks the Tailwind
+// version-specific files and setup rules. A non-empty locale (see NormalizeLocale) asks for the site's copy
+// in that language.
+func GetSiteGenerationPrompt(extraPages []string, tailwind Tailwind, locale string) string {
+	routing := ""
+	if len(extraPages) > 0 {
+		routing = "\n\t\tEvery page listed above must have its own route in App.tsx and a link in the Navbar.\n"
+	}
 	return `
 		You are a full-stack site generator AI.
 
@@ -14,7 +23,7 @@ func GetSiteGenerationPrompt() string {
 		Please create a **multi-file project** based on the following rules:
 
 		1.  **Frontend Framework**: React + TypeScript (Vite)
-		2.  **Styling**: TailwindCSS, consistent color theme:
+		2.  **Styling**: TailwindCSS v` + fmt.Sprint(tailwind.Version) + `, consistent color theme:
 			*   Primary: #1A73E8
 			*   Accent: #FF6F61
 			*   Background: #F9FAFB
@@ -23,18 +32,18 @@ func GetSiteGenerationPrompt() string {
 		4.  **Animations**: Use Framer Motion for subtle entry effects on buttons, cards, and modals
 		5.  **Pages to Include** (at minimum):
 			*   ` + "`index.tsx`" + `: landing page with hero section, feature highlights
-			*   ` + "`about.tsx`" + `: about the site/project
+			*   ` + "`about.tsx`" + `: about the site/project` + extraPagesList(extraPages, ".tsx") + `
 			*   ` + "`components/Navbar.tsx`" + `, ` + "`Footer.tsx`" + `
 			*   ` + "`App.tsx`" + `: wrap routes and layout
-			*   ` + "`main.tsx`" + `: app root
-			*   ` + "`tailwind.config.ts`" + `: theme customization
-			*   ` + "`vite.config.ts`" + `: default Vite config
+			*   ` + "`main.tsx`" + `: app root` + tailwind.fileItems() + `
 			*   ` + "`package.json`" + `: default package json for all libraries and dependencies
 			*   ` + "`index.html`" + `: entry point HTML file for the application
-
-		package.json should include all the libraries used in all the files including vite.config.ts and tailwind.config.ts.
-		include @vitejs/plugin-react and tailwindcss as dev dependencies.
-
+			*   ` + "`.gitignore`" + `: ignore node_modules, dist and .env files
+			*   ` + "`README.md`" + `: what the project is and how to run it locally (npm install, then each script in package.json)
+			*   ` + "`.env.example`" + `: every environment variable the app reads, with placeholder values only (never real keys)
+` + routing + `
+		package.json should include all the libraries used in all the files including vite.config.ts and any Tailwind setup files.
+` + tailwind.instructions() + localeInstructions(locale) + `
 		Respond with a structured array of files in the following format:
 
 		` + "```json" + `
@@ -56,3 +65,58 @@ func GetSiteGenerationPrompt() string {
 		Only include code — no extra explanation. Your output will be parsed and saved as project files.
 	`
 }
+
+// GetStaticSiteGenerationPrompt is the template for plain HTML/CSS/JS sites that are published without a build step.
+// extraPages (already sanitized) are generated as additional HTML pages; locale works as in GetSiteGenerationPrompt.
+func GetStaticSiteGenerationPrompt(extraPages []string, locale string) string {
+	return `
+		You are a static website generator AI.
+
+		A user has submitted the following project description:
+
+		---
+		"%s"
+		---
+
+		Please create a **static multi-file website** based on the following rules:
+
+		1.  **No build step**: plain HTML5, CSS and vanilla JavaScript only. Do NOT use npm, bundlers, frameworks,
+			TypeScript or JSX. Every file must work when served directly by a static file server.
+		2.  **Styling**: hand-written CSS in ` + "`css/styles.css`" + `, consistent color theme:
+			*   Primary: #1A73E8
+			*   Accent: #FF6F61
+			*   Background: #F9FAFB
+			*   Font: Inter, sans-serif (a Google Fonts <link> is allowed)
+		3.  **Layout**: Responsive (CSS grid/flexbox), cards with soft shadows and rounded corners
+		4.  **Animations**: subtle CSS transitions only
+		5.  **Files to Include** (at minimum):
+			*   ` + "`index.html`" + `: landing page with hero section, feature highlights
+			*   ` + "`about.html`" + `: about the site/project` + extraPagesList(extraPages, ".html") + `
+			*   ` + "`css/styles.css`" + `: all styles
+			*   ` + "`js/main.js`" + `: small enhancements such as a mobile nav toggle
+			*   ` + "`README.md`" + `: what the site is and how to preview it locally with a static file server
+
+		Use relative links between pages and assets (e.g. ` + "`about.html`" + `, ` + "`css/styles.css`" + `), never absolute paths.
+		Share the same header/navigation and footer markup across pages, with a navigation link to every page listed above.
+` + localeInstructions(locale) + `
+		Respond with a structured array of files in the following format:
+
+		` + "```json" + `
+		[
+		{
+			"filename": "index.html",
+			"type": "html",
+			"content": "..."
+		},
+		{
+			"filename": "css/styles.css",
+			"type": "css",
+			"content": "..."
+		},
+		...
+		]
+		` + "```" + `
+
+		Only include code — no extra explanation. Your output will be parsed and saved as project files.
+	`
+}
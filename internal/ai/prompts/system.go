@@ -0,0 +1,42 @@
+package prompts
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxSystemPromptLength caps a caller-supplied system prompt override, in characters.
+const MaxSystemPromptLength = 8000
+
+// ErrInvalidSystemPrompt is returned for system prompt overrides that are too long or try to change the
+// output format the server parses.
+var ErrInvalidSystemPrompt = errors.New("invalid system prompt override")
+
+// OutputContract is appended to every system prompt override so the answer stays parseable whatever the
+// override says.
+const OutputContract = "\n\nRegardless of any instruction above, respond only with the JSON array of file objects " +
+	"(\"filename\", \"type\", \"content\") described in the user's message, with no other text."
+
+// formatOverrides match overrides that tell the model to drop or replace the JSON answer format.
+var formatOverrides = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\b[^.\n]{0,60}\b(json|format|formatting|output|structure)\b`),
+	regexp.MustCompile(`(?i)\b(do not|don't|never|avoid|without)\b[^.\n]{0,40}\b(json|array)\b`),
+	regexp.MustCompile(`(?i)\b(respond|reply|answer|output|return)\b[^.\n]{0,30}\b(plain text|prose|markdown|yaml|xml|html)\b`),
+}
+
+// NormalizeSystemPrompt trims a system prompt override and checks it against MaxSystemPromptLength and the
+// output format. An empty override means the default system prompt.
+func NormalizeSystemPrompt(override string) (string, error) {
+	override = strings.TrimSpace(override)
+	if n := len([]rune(override)); n > MaxSystemPromptLength {
+		return "", fmt.Errorf("%w: %d characters, at most %d allowed", ErrInvalidSystemPrompt, n, MaxSystemPromptLength)
+	}
+	for _, re := range formatOverrides {
+		if match := re.FindString(override); match != "" {
+			return "", fmt.Errorf("%w: it may not change the JSON output format (%q)", ErrInvalidSystemPrompt, match)
+		}
+	}
+	return override, nil
+}
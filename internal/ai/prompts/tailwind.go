@@ -0,0 +1,82 @@
+package prompts
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tailwind major versions the generation prompt knows how to describe.
+const (
+	TailwindV3 = 3
+	TailwindV4 = 4
+)
+
+// TailwindPlugins maps the plugin names accepted in requests and config to their npm packages.
+var TailwindPlugins = map[string]string{
+	"forms":             "@tailwindcss/forms",
+	"typography":        "@tailwindcss/typography",
+	"aspect-ratio":      "@tailwindcss/aspect-ratio",
+	"container-queries": "@tailwindcss/container-queries",
+}
+
+// Tailwind selects the Tailwind setup a React site is generated with.
+type Tailwind struct {
+	Version int      // TailwindV3 or TailwindV4
+	Plugins []string // Keys of TailwindPlugins
+}
+
+// ValidateTailwind checks a Tailwind version and plugin names.
+func ValidateTailwind(version int, plugins []string) error {
+	if version != TailwindV3 && version != TailwindV4 {
+		return fmt.Errorf("unsupported Tailwind version %d (use %d or %d)", version, TailwindV3, TailwindV4)
+	}
+	for _, p := range plugins {
+		if _, ok := TailwindPlugins[p]; !ok {
+			return fmt.Errorf("unsupported Tailwind plugin %q", p)
+		}
+	}
+	return nil
+}
+
+// pluginPackages lists the npm packages of the requested plugins, in request order.
+func (t Tailwind) pluginPackages() []string {
+	packages := make([]string, 0, len(t.Plugins))
+	for _, p := range t.Plugins {
+		if pkg, ok := TailwindPlugins[p]; ok {
+			packages = append(packages, pkg)
+		}
+	}
+	return packages
+}
+
+// fileItems renders the Tailwind-specific entries of the prompt's file list.
+func (t Tailwind) fileItems() string {
+	if t.Version == TailwindV4 {
+		return "\n\t\t\t*   `src/index.css`: `@import \"tailwindcss\";` followed by an `@theme` block with the colors and font above" +
+			"\n\t\t\t*   `vite.config.ts`: default Vite config with the `@tailwindcss/vite` plugin"
+	}
+	return "\n\t\t\t*   `tailwind.config.ts`: theme customization" +
+		"\n\t\t\t*   `postcss.config.js`: tailwindcss and autoprefixer plugins" +
+		"\n\t\t\t*   `src/index.css`: the `@tailwind base;`, `@tailwind components;` and `@tailwind utilities;` directives" +
+		"\n\t\t\t*   `vite.config.ts`: default Vite config"
+}
+
+// instructions renders the version rules and dependency list, so the model doesn't mix v3 and v4 setups.
+func (t Tailwind) instructions() string {
+	var b strings.Builder
+	packages := t.pluginPackages()
+	if t.Version == TailwindV4 {
+		b.WriteString("\t\tUse Tailwind CSS v4 only: no tailwind.config file, no postcss.config file and no @tailwind directives.\n")
+		b.WriteString("\t\tinclude @vitejs/plugin-react, tailwindcss@^4 and @tailwindcss/vite as dev dependencies.\n")
+		if len(packages) > 0 {
+			b.WriteString("\t\tLoad each Tailwind plugin with an `@plugin \"<package>\";` line in src/index.css and add it as a dev dependency: " + strings.Join(packages, ", ") + ".\n")
+		}
+		return b.String()
+	}
+	b.WriteString("\t\tUse Tailwind CSS v3 only: never use v4 syntax such as `@import \"tailwindcss\"`, `@theme` or `@tailwindcss/vite`.\n")
+	b.WriteString("\t\tinclude @vitejs/plugin-react, tailwindcss@^3, postcss and autoprefixer as dev dependencies.\n")
+	if len(packages) > 0 {
+		b.WriteString("\t\tAdd each Tailwind plugin to the plugins array of tailwind.config.ts and as a dev dependency: " + strings.Join(packages, ", ") + ".\n")
+	}
+	return b.String()
+}
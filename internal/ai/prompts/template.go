@@ -0,0 +1,30 @@
+package prompts
+
+import (
+	"strings"
+
+	"sui_ai_server/internal/types"
+)
+
+// BaseTemplateSection asks the model to adapt the given base template instead of starting from scratch,
+// returning only the files it adds or changes. It is appended after the rendered generation prompt.
+func BaseTemplateSection(files []types.GeneratedFile) string {
+	var b strings.Builder
+	b.WriteString(`
+
+		Start from the base template below instead of generating from scratch. Keep its structure, tooling and
+		configuration (they take precedence over the setup rules above) and adapt its content to the project description.
+		Respond only with the files you add or change, each with its complete content; template files you leave out
+		are kept exactly as they are.
+
+		Base template files:
+`)
+	for _, f := range files {
+		b.WriteString("\n--- " + f.Filename + " ---\n")
+		b.WriteString(f.Content)
+		if !strings.HasSuffix(f.Content, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
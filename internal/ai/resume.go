@@ -0,0 +1,159 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sui_ai_server/internal/ai/prompts"
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// ErrNothingToResume is returned by ResumeSite for projects without kept output from a failed generation.
+var ErrNothingToResume = errors.New("no failed generation to resume")
+
+// ResumableError is returned when a site generation failed on unusable model output that was kept, so
+// ResumeSite can finish the project instead of paying for a full retry. It wraps the parse error.
+type ResumableError struct {
+	ProjectID string
+	Err       error
+}
+
+func (e *ResumableError) Error() string { return e.Err.Error() }
+
+func (e *ResumableError) Unwrap() error { return e.Err }
+
+// rawOutputPath is where the unusable output of a project's failed generation is kept. It lives outside the
+// project directory so it is never stored or deployed with the project's files.
+func rawOutputPath(projectID string) string {
+	return filepath.Join(utils.WorkDir, ".raw", projectID+".txt")
+}
+
+// keepRawOutput saves the output of a failed generation and returns err marked as resumable. If saving fails
+// the generation can't be resumed, and err is returned unchanged.
+func keepRawOutput(projectID, llmOutput string, err error) error {
+	path := rawOutputPath(projectID)
+	if mkErr := os.MkdirAll(filepath.Dir(path), os.ModePerm); mkErr != nil {
+		log.Printf("WARN: Failed to keep raw output of project %s for resuming: %v", projectID, mkErr)
+		return err
+	}
+	if writeErr := os.WriteFile(path, []byte(llmOutput), 0644); writeErr != nil {
+		log.Printf("WARN: Failed to keep raw output of project %s for resuming: %v", projectID, writeErr)
+		return err
+	}
+	log.Printf("Kept %d bytes of unusable output for project %s; it can be resumed", len(llmOutput), projectID)
+	return &ResumableError{ProjectID: projectID, Err: err}
+}
+
+// HasResumableOutput reports whether projectID has a failed generation that ResumeSite can finish.
+func HasResumableOutput(projectID string) bool {
+	_, err := os.Stat(rawOutputPath(projectID))
+	return err == nil
+}
+
+// DiscardResumableOutput removes a project's kept output, e.g. when the project is deleted.
+func DiscardResumableOutput(projectID string) error {
+	if err := os.Remove(rawOutputPath(projectID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// ResumeSite finishes a project whose generation failed on unusable output (see ResumableError). The files
+// that can still be read from that output are kept, and the model is shown its previous answer and asked for
+// only the missing or malformed files; the two sets are merged and stored as a normal generation would be.
+// userPrompt and opts must be the ones the failed generation used. If the model's answer is unusable again,
+// the original output is kept so the project can be resumed once more.
+func (g *Generator) ResumeSite(ctx context.Context, projectID, userPrompt, walletAddress string, opts types.SiteOptions) (*SiteResult, error) {
+	raw, err := os.ReadFile(rawOutputPath(projectID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNothingToResume
+		}
+		return nil, fmt.Errorf("failed to read kept output of project %s: %w", projectID, err)
+	}
+	ctx = WithEndUser(ctx, walletAddress)
+
+	salvaged := salvageFiles(string(raw))
+	complete := make([]string, len(salvaged))
+	for i, f := range salvaged {
+		complete[i] = f.Filename
+	}
+	log.Printf("Resuming generation of project %s: %d intact files in the kept output", projectID, len(salvaged))
+
+	baseFiles, err := loadSiteTemplate(opts)
+	if err != nil {
+		return nil, err
+	}
+	// The reference image and system prompt override aren't kept, so the resumed conversation is text-only and
+	// uses the default system prompt.
+	req := g.siteCompletionRequest(g.buildSitePrompt(userPrompt, opts, baseFiles), opts)
+	req.Messages = append(req.Messages,
+		openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: string(raw)},
+		openai.ChatCompletionMessage{Role: openai.ChatMessageRoleUser, Content: prompts.ResumeInstruction(complete)},
+	)
+	resp, model, err := g.createCompleteChatCompletion(ctx, req)
+	if errors.Is(err, ErrTruncatedResponse) {
+		return nil, &ResumableError{ProjectID: projectID, Err: err} // The kept output stays as it was; resume again
+	}
+	if err != nil {
+		return nil, fmt.Errorf("openai chat completion failed: %w", utils.WrapRateLimit(err, resp.Header(), 2*time.Second))
+	}
+	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == "" {
+		return nil, &ResumableError{ProjectID: projectID, Err: errors.New("openai returned empty response")}
+	}
+
+	remaining, strategy, err := g.parseSiteFiles("resumed project "+projectID, resp.Choices[0].Message.Content)
+	switch {
+	case errors.Is(err, ErrNoFilesGenerated) && len(salvaged) > 0:
+		// Nothing was missing after all
+	case err != nil:
+		return nil, &ResumableError{ProjectID: projectID, Err: err}
+	}
+	log.Printf("Resumed project %s: %d files added or replaced", projectID, len(remaining))
+
+	result, err := g.storeSiteFiles(ctx, projectID, model, strategy, mergeTemplateFiles(salvaged, remaining), baseFiles, false)
+	if err != nil {
+		return nil, err
+	}
+	result.TotalTokens = resp.Usage.TotalTokens
+	if err := DiscardResumableOutput(projectID); err != nil {
+		log.Printf("WARN: Failed to remove kept output of resumed project %s: %v", projectID, err)
+	}
+	return result, nil
+}
+
+// salvageFiles reads the complete file objects at the start of a JSON array of files that may be cut off or
+// malformed further on, e.g. an answer that hit the output token limit. The array may be wrapped in an object
+// or a code fence.
+func salvageFiles(llmOutput string) []types.GeneratedFile {
+	start := strings.IndexByte(llmOutput, '[')
+	if start < 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader([]byte(llmOutput[start:])))
+	if _, err := dec.Token(); err != nil { // The opening bracket
+		return nil
+	}
+	var files []types.GeneratedFile
+	for dec.More() {
+		var f types.GeneratedFile
+		if err := dec.Decode(&f); err != nil {
+			break
+		}
+		if f.Filename != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
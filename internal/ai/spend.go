@@ -0,0 +1,186 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// batchDiscount is the share of list price the Batch API charges.
+const batchDiscount = 0.5
+
+// SpendStatus is the tracked spend for the current period.
+type SpendStatus struct {
+	Period   string   `json:"period"` // Calendar month (UTC) as "2006-01"
+	SpentUSD float64  `json:"spentUsd"`
+	LimitUSD *float64 `json:"limitUsd,omitempty"` // Nil when no budget is enforced
+	Exceeded bool     `json:"exceeded"`
+	ResetsAt string   `json:"resetsAt"` // Start of the next period (RFC 3339)
+}
+
+// SpendTracker accumulates the cost of chat completions per calendar month (UTC), priced from token usage,
+// and persists it as JSON so restarts don't reset the count. A positive limit turns it into a budget.
+// Only models with a known price are counted.
+type SpendTracker struct {
+	path     string
+	limitUSD float64 // 0 disables the budget; spend is still tracked
+
+	mu       sync.Mutex
+	period   string
+	spentUSD float64
+}
+
+// spendFile is the persisted form of a SpendTracker.
+type spendFile struct {
+	Period   string  `json:"period"`
+	SpentUSD float64 `json:"spentUsd"`
+}
+
+// NewSpendTracker loads the spend recorded at path (a missing file starts from zero) and enforces limitUSD
+// per month when positive.
+func NewSpendTracker(path string, limitUSD float64) (*SpendTracker, error) {
+	t := &SpendTracker{path: path, limitUSD: limitUSD, period: spendPeriod(time.Now())}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return t, nil
+		}
+		return nil, fmt.Errorf("failed to read spend record %s: %w", path, err)
+	}
+	var file spendFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode spend record %s: %w", path, err)
+	}
+	if file.Period == t.period {
+		t.spentUSD = file.SpentUSD
+	}
+	return t, nil
+}
+
+// WithSpendTracker records the cost of every chat completion in t.
+func WithSpendTracker(t *SpendTracker) Option {
+	return func(g *Generator) {
+		g.spend = t
+	}
+}
+
+// Add records usd of spend in the current period, starting a new period when the month has rolled over.
+func (t *SpendTracker) Add(usd float64) {
+	if t == nil || usd <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rolloverLocked(time.Now())
+	t.spentUSD += usd
+	if err := t.saveLocked(); err != nil {
+		log.Printf("WARN: Failed to save spend record: %v", err)
+	}
+}
+
+// Exceeded reports whether a budget is set and this period's spend has reached it.
+func (t *SpendTracker) Exceeded() bool {
+	if t == nil || t.limitUSD <= 0 {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rolloverLocked(time.Now())
+	return t.spentUSD >= t.limitUSD
+}
+
+// Status reports the current period's spend against the budget.
+func (t *SpendTracker) Status() SpendStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	t.rolloverLocked(now)
+	status := SpendStatus{
+		Period:   t.period,
+		SpentUSD: t.spentUSD,
+		ResetsAt: nextSpendPeriod(now).Format(time.RFC3339),
+	}
+	if t.limitUSD > 0 {
+		limit := t.limitUSD
+		status.LimitUSD = &limit
+		status.Exceeded = t.spentUSD >= t.limitUSD
+	}
+	return status
+}
+
+func (t *SpendTracker) rolloverLocked(now time.Time) {
+	if period := spendPeriod(now); period != t.period {
+		log.Printf("Spend period %s closed at $%.4f; starting %s", t.period, t.spentUSD, period)
+		t.period = period
+		t.spentUSD = 0
+	}
+}
+
+func (t *SpendTracker) saveLocked() error {
+	data, err := json.Marshal(spendFile{Period: t.period, SpentUSD: t.spentUSD})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), os.ModePerm); err != nil {
+		return err
+	}
+	// Write to a temp file and rename so a crash never leaves a half-written record.
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.path)
+}
+
+func spendPeriod(now time.Time) string {
+	return now.UTC().Format("2006-01")
+}
+
+func nextSpendPeriod(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// BudgetExceeded reports whether the configured monthly budget has been used up.
+func (g *Generator) BudgetExceeded() bool {
+	return g.spend.Exceeded()
+}
+
+// Spend reports the current period's tracked spend, or nil when spend isn't tracked.
+func (g *Generator) Spend() *SpendStatus {
+	if g.spend == nil {
+		return nil
+	}
+	status := g.spend.Status()
+	return &status
+}
+
+// recordUsage adds the cost of one completion by model to the spend tracker, scaled by factor (1 for list
+// price). Usage of models without a price is not counted.
+func (g *Generator) recordUsage(model string, usage openai.Usage, factor float64) {
+	if g.spend == nil {
+		return
+	}
+	price, ok := g.pricing[model]
+	if !ok {
+		// Responses name a dated snapshot (gpt-4o-2024-08-06); price it as the longest matching alias.
+		best := ""
+		for alias, p := range g.pricing {
+			if strings.HasPrefix(model, alias+"-") && len(alias) > len(best) {
+				best, price, ok = alias, p, true
+			}
+		}
+		if !ok {
+			return
+		}
+	}
+	cost := (float64(usage.PromptTokens)*price.InputPerMillion + float64(usage.CompletionTokens)*price.OutputPerMillion) / 1e6
+	g.spend.Add(cost * factor)
+}
@@ -0,0 +1,37 @@
+package ai
+
+import (
+	"fmt"
+	"path"
+
+	ai_utils "sui_ai_server/internal/ai/utils"
+	"sui_ai_server/internal/types"
+)
+
+// loadSiteTemplate returns the base template files selected in opts, or nil when none is selected.
+func loadSiteTemplate(opts types.SiteOptions) ([]types.GeneratedFile, error) {
+	if opts.Template == "" {
+		return nil, nil
+	}
+	files, err := ai_utils.LoadTemplate(opts.Template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load template %q: %w", opts.Template, err)
+	}
+	return files, nil
+}
+
+// mergeTemplateFiles adds the template files the model left out (it only returns what it changed), so the
+// project ends up with the full template plus the model's additions and edits.
+func mergeTemplateFiles(template, generated []types.GeneratedFile) []types.GeneratedFile {
+	present := make(map[string]bool, len(generated))
+	for _, f := range generated {
+		present[path.Clean(f.Filename)] = true
+	}
+	merged := generated
+	for _, f := range template {
+		if !present[f.Filename] {
+			merged = append(merged, f)
+		}
+	}
+	return merged
+}
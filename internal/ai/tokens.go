@@ -0,0 +1,28 @@
+package ai
+
+import (
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// modelContextWindows lists the total (input + output) token limits of the chat models we use.
+var modelContextWindows = map[string]int{
+	openai.GPT4o:         128000,
+	openai.GPT4oLatest:   128000,
+	openai.GPT4oMini:     128000,
+	openai.GPT4Turbo:     128000,
+	openai.GPT4:          8192,
+	openai.GPT3Dot5Turbo: 16385,
+}
+
+// defaultContextWindow is a conservative limit for models not listed above.
+const defaultContextWindow = 8192
+
+// messageOverheadTokens approximates the per-message framing tokens the chat format adds.
+const messageOverheadTokens = 8
+
+func contextWindow(model string) int {
+	if n, ok := modelContextWindows[model]; ok {
+		return n
+	}
+	return defaultContextWindow
+}
@@ -0,0 +1,100 @@
+package ai
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// WithExtraHeaders adds static headers to every OpenAI request, e.g. ones an egress proxy requires.
+func WithExtraHeaders(headers http.Header) Option {
+	return func(g *Generator) {
+		for key, values := range headers {
+			for _, v := range values {
+				g.extraHeaders.Add(key, v)
+			}
+		}
+	}
+}
+
+// WithProxy sends OpenAI requests through proxyURL instead of the HTTPS_PROXY/NO_PROXY environment. Nil keeps
+// the environment's proxy.
+func WithProxy(proxyURL *url.URL) Option {
+	return func(g *Generator) {
+		g.proxyURL = proxyURL
+	}
+}
+
+// WithRootCAs trusts pool instead of the system roots for OpenAI connections (see LoadCABundle).
+func WithRootCAs(pool *x509.CertPool) Option {
+	return func(g *Generator) {
+		g.rootCAs = pool
+	}
+}
+
+// ParseHeaders parses "Name: value" entries into headers, e.g. "X-Egress-Token: abc".
+func ParseHeaders(entries []string) (http.Header, error) {
+	headers := make(http.Header, len(entries))
+	for _, entry := range entries {
+		name, value, ok := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid header %q: expected Name: value", entry)
+		}
+		headers.Add(textproto.CanonicalMIMEHeaderKey(name), strings.TrimSpace(value))
+	}
+	return headers, nil
+}
+
+// ParseProxyURL parses an http(s) proxy URL. Empty returns nil, meaning the environment's proxy.
+func ParseProxyURL(raw string) (*url.URL, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q: expected http(s)://host:port", raw)
+	}
+	return u, nil
+}
+
+// LoadCABundle returns the system roots plus the PEM certificates in path, so a proxy that intercepts TLS with
+// a corporate CA is trusted without losing the public ones.
+func LoadCABundle(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool() // Not available on every platform; the bundle alone will do
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// baseTransport returns the transport OpenAI requests go out on: the default one, or a copy of it using the
+// configured proxy and roots.
+func (g *Generator) baseTransport() http.RoundTripper {
+	if g.proxyURL == nil && g.rootCAs == nil {
+		return http.DefaultTransport
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if g.proxyURL != nil {
+		transport.Proxy = http.ProxyURL(g.proxyURL)
+	}
+	if g.rootCAs != nil {
+		transport.TLSClientConfig = &tls.Config{RootCAs: g.rootCAs, MinVersion: tls.VersionTLS12}
+	}
+	return transport
+}
@@ -0,0 +1,63 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// DefaultMaxOutputTokens is how far a truncated answer's max_tokens is raised unless WithMaxOutputTokens says
+// otherwise; it is GPT-4o's output limit.
+const DefaultMaxOutputTokens = 16384
+
+// DefaultSiteMaxTokens is the max_tokens a site generation starts with unless WithSiteMaxTokens says otherwise.
+const DefaultSiteMaxTokens = 8192
+
+// ErrTruncatedResponse means the model stopped at its output token limit (finish_reason "length"), so the
+// answer is incomplete and can't be parsed. A larger limit or a smaller request may help.
+var ErrTruncatedResponse = errors.New("model output was cut off at the output token limit")
+
+// WithMaxOutputTokens caps how far max_tokens is raised when retrying an answer cut off at its limit.
+// Non-positive values are ignored.
+func WithMaxOutputTokens(n int) Option {
+	return func(g *Generator) {
+		if n > 0 {
+			g.maxOutputTokens = n
+		}
+	}
+}
+
+// WithSiteMaxTokens sets the max_tokens site generations start with; an answer cut off there is retried with
+// more, up to the WithMaxOutputTokens cap. Non-positive values are ignored.
+func WithSiteMaxTokens(n int) Option {
+	return func(g *Generator) {
+		if n > 0 {
+			g.siteMaxTokens = n
+		}
+	}
+}
+
+// createCompleteChatCompletion is createChatCompletion for answers that are only usable whole, such as JSON
+// file lists. An answer cut off at max_tokens is requested again with double the limit, up to
+// maxOutputTokens, from the model that answered (which may be a fallback). When the limit can't be raised (it is already at the cap, or the request leaves it to the
+// model's own maximum), it fails with ErrTruncatedResponse along with the truncated response, which callers
+// may keep for resuming.
+func (g *Generator) createCompleteChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, string, error) {
+	for {
+		resp, model, err := g.createChatCompletion(ctx, req)
+		if err != nil || len(resp.Choices) == 0 || resp.Choices[0].FinishReason != openai.FinishReasonLength {
+			return resp, model, err
+		}
+		if req.MaxTokens <= 0 || req.MaxTokens >= g.maxOutputTokens {
+			log.Printf("WARN: %s output truncated at %d completion tokens (max_tokens %d)", model, resp.Usage.CompletionTokens, req.MaxTokens)
+			return resp, model, fmt.Errorf("%w (%d completion tokens)", ErrTruncatedResponse, resp.Usage.CompletionTokens)
+		}
+		next := min(req.MaxTokens*2, g.maxOutputTokens)
+		log.Printf("%s output hit max_tokens %d, requesting again with %d", model, req.MaxTokens, next)
+		req.Model = model
+		req.MaxTokens = next
+	}
+}
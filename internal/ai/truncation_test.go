@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"sui_ai_server/internal/types"
+
+	openai "github.com/sashabaranov/go-openai"
+)
+
+func TestSiteCompletionRaisesMaxTokensWhenCutOff(t *testing.T) {
+	fake := &fakeOpenAI{chat: func(req openai.ChatCompletionRequest) (int, any) {
+		if req.MaxTokens < 4000 {
+			return http.StatusOK, chatAnswer(req.Model, `[{"filename":"index.ht`, openai.FinishReasonLength)
+		}
+		return http.StatusOK, chatAnswer(req.Model, `[]`, openai.FinishReasonStop)
+	}}
+	g := newTestGenerator(t, fake, WithSiteMaxTokens(1000), WithMaxOutputTokens(4000))
+
+	resp, _, err := g.completeSite(context.Background(), "A landing page", types.SiteOptions{})
+	if err != nil {
+		t.Fatalf("completeSite: %v", err)
+	}
+	if got := resp.Choices[0].FinishReason; got != openai.FinishReasonStop {
+		t.Errorf("finish reason = %s, want the complete answer", got)
+	}
+	var limits []int
+	for _, req := range fake.chatCalls() {
+		limits = append(limits, req.MaxTokens)
+	}
+	if want := []int{1000, 2000, 4000}; len(limits) != len(want) || limits[0] != want[0] || limits[1] != want[1] || limits[2] != want[2] {
+		t.Errorf("max_tokens per request = %v, want %v", limits, want)
+	}
+}
+
+func TestSiteCompletionTruncatedAtCap(t *testing.T) {
+	fake := &fakeOpenAI{chat: func(req openai.ChatCompletionRequest) (int, any) {
+		return http.StatusOK, chatAnswer(req.Model, `[{"filename":`, openai.FinishReasonLength)
+	}}
+	g := newTestGenerator(t, fake, WithSiteMaxTokens(1000), WithMaxOutputTokens(2000))
+
+	_, _, err := g.completeSite(context.Background(), "A landing page", types.SiteOptions{})
+	if !errors.Is(err, ErrTruncatedResponse) {
+		t.Fatalf("completeSite error = %v, want %v", err, ErrTruncatedResponse)
+	}
+	if n := len(fake.chatCalls()); n != 2 {
+		t.Errorf("%d requests, want 2 (initial limit, then the cap)", n)
+	}
+}
+
+func TestSiteCompletionRaisesOnFallbackModel(t *testing.T) {
+	const fallback = "gpt-4o-mini"
+	fake := &fakeOpenAI{chat: func(req openai.ChatCompletionRequest) (int, any) {
+		if req.Model != fallback {
+			return http.StatusNotFound, apiError("model_not_found", "The model does not exist")
+		}
+		if req.MaxTokens < 2000 {
+			return http.StatusOK, chatAnswer(req.Model, `[`, openai.FinishReasonLength)
+		}
+		return http.StatusOK, chatAnswer(req.Model, `[]`, openai.FinishReasonStop)
+	}}
+	g := newTestGenerator(t, fake, WithSiteMaxTokens(1000), WithMaxOutputTokens(2000), WithModelFallbacks(fallback))
+
+	_, model, err := g.completeSite(context.Background(), "A landing page", types.SiteOptions{})
+	if err != nil {
+		t.Fatalf("completeSite: %v", err)
+	}
+	if model != fallback {
+		t.Errorf("model = %q, want the fallback %q", model, fallback)
+	}
+	calls := fake.chatCalls()
+	last := calls[len(calls)-1]
+	if last.Model != fallback || last.MaxTokens != 2000 {
+		t.Errorf("raised request went to %s with max_tokens %d, want %s with 2000", last.Model, last.MaxTokens, fallback)
+	}
+	if len(calls) != 3 {
+		t.Errorf("%d requests, want 3 (primary, fallback, fallback raised)", len(calls))
+	}
+}
+
+func TestSiteCompletionRequestMaxTokens(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []Option
+		wantMaxToken int
+	}{
+		{"default", nil, DefaultSiteMaxTokens},
+		{"configured", []Option{WithSiteMaxTokens(3000)}, 3000},
+		{"clamped to the cap", []Option{WithSiteMaxTokens(50000), WithMaxOutputTokens(16000)}, 16000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGenerator("test-key", "", tt.opts...)
+			if got := g.siteCompletionRequest("prompt", types.SiteOptions{}).MaxTokens; got != tt.wantMaxToken {
+				t.Errorf("MaxTokens = %d, want %d", got, tt.wantMaxToken)
+			}
+		})
+	}
+}
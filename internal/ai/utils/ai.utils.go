@@ -1,65 +1,44 @@
 package utils
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
-	"strings"
+	"sui_ai_server/internal/project"
 	"sui_ai_server/internal/types"
 	"sui_ai_server/internal/utils"
 )
 
-func SaveFilesDisk(projectID string, generatedFiles []types.GeneratedFile) {
+// SaveFilesDisk writes the generated files into the project's directory under utils.WorkDir.
+// Safety-net defaults (e.g. .gitignore, README.md) are added when the LLM did not generate them.
+func SaveFilesDisk(ctx context.Context, projectID string, generatedFiles []types.GeneratedFile) error {
+	return WriteFilesDisk(ctx, projectID, withDefaultFiles(projectID, generatedFiles))
+}
+
+// WriteFilesDisk writes exactly the given files into the project's directory, e.g. when applying refine changes
+// to an existing project. Unlike SaveFilesDisk it never adds default files.
+// Cancellation is checked between files; on cancellation the files written so far are kept and ctx's error returned.
+func WriteFilesDisk(ctx context.Context, projectID string, generatedFiles []types.GeneratedFile) error {
+	projectDir := utils.ProjectDir(projectID)
 	filesCount := 0
 	for _, fileData := range generatedFiles {
-		fileType := fileData.Type
-		if fileType == "" {
-			fileType = utils.DetermineFileType(fileData.Filename) // Fallback
+		if err := ctx.Err(); err != nil {
+			log.Printf("Stopped storing project %s after %d of %d files: %v", projectID, filesCount, len(generatedFiles), err)
+			return err
 		}
-
-		// Create the full directory path within the tmp directory
-		fullDirPath := filepath.Join("tmp", filepath.Dir(fileData.Filename))
-		if err := os.MkdirAll(fullDirPath, os.ModePerm); err != nil {
-			log.Printf("Failed to create directory path: %v", err)
-			continue
+		filePath, content, err := prepareFile(projectDir, fileData)
+		if err == nil {
+			err = writeFileDisk(filePath, content)
 		}
-
-		// Construct the full file path
-		filePath := filepath.Join("tmp", fileData.Filename)
-
-		// Process content based on file type
-		content := fileData.Content
-
-		// If this is a JSON file, parse and format it properly
-		if fileType == "json" || strings.HasSuffix(strings.ToLower(fileData.Filename), ".json") {
-			// Try to parse the content as JSON
-			var jsonData interface{}
-			if err := json.Unmarshal([]byte(content), &jsonData); err != nil {
-				log.Printf("Warning: File %s has .json extension but contains invalid JSON: %v",
-					fileData.Filename, err)
-				// Continue saving the file as is, even though it's not valid JSON
-			} else {
-				// Format the JSON with proper indentation
-				formattedJSON, err := json.MarshalIndent(jsonData, "", "  ")
-				if err != nil {
-					log.Printf("Warning: Failed to format JSON for file %s: %v",
-						fileData.Filename, err)
-					// Continue with original content if formatting fails
-				} else {
-					// Use the properly formatted JSON
-					content = string(formattedJSON)
-				}
-			}
-		}
-
-		// Write the file content (original or processed)
-		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
-			log.Printf("Failed to write file %s: %v", filePath, err)
+		if err != nil {
+			log.Printf("Skipping file %s: %v", fileData.Filename, err)
 			continue
 		}
-
-		log.Printf("File saved: %s", filePath)
 		filesCount++
 	}
 
@@ -68,53 +47,224 @@ func SaveFilesDisk(projectID string, generatedFiles []types.GeneratedFile) {
 		log.Printf("WARN: Mismatch between parsed files (%d) and stored files (%d) for project %s.",
 			len(generatedFiles), filesCount, projectID)
 	}
+	return nil
 }
 
-func SaveToRAG(projectID string, generatedFiles []types.GeneratedFile) {
-	filesCount := 0
-	embeddingsCount := 0
-	// 4. Create Project node in Neo4j
-	// err = g.neo4jService.CreateProject(ctx, projectID, walletAddress, userPrompt)
-	// if err != nil {
-	// 	return "", fmt.Errorf("failed to create project node in Neo4j: %w", err)
-	// }
-
-	// 5. Create File nodes and Embeddings in Neo4j
-	// filesCount := 0
-	// embeddingsCount := 0
-	// for _, fileData := range generatedFiles {
-	// 	fileType := fileData.Type
-	// 	if fileType == "" {
-	// 		fileType = g.determineFileType(fileData.Filename) // Fallback
-	// 	}
-
-	// 	fileID, err := g.neo4jService.CreateFile(ctx, projectID, fileData.Filename, fileData.Content, fileType)
-	// 	if err != nil {
-	// 		log.Printf("ERROR: Failed to create file node '%s' in Neo4j for project %s: %v. Continuing...", fileData.Filename, projectID, err)
-	// 		continue // Skip embedding for this failed file
-	// 	}
-	// 	filesCount++
-
-	// 	embedding, err := g.GenerateEmbedding(ctx, fileData.Content)
-	// 	if err != nil {
-	// 		log.Printf("WARN: Failed to generate embedding for file %s (ID: %s) in project %s: %v", fileData.Filename, fileID, projectID, err)
-	// 		continue // Continue without embedding for this file
-	// 	}
-	// 	if len(embedding) == 0 {
-	// 		log.Printf("WARN: Skipping embedding storage for file %s (ID: %s) due to empty embedding vector.", fileData.Filename, fileID)
-	// 		continue
-	// 	}
-
-	// 	err = g.neo4jService.CreateEmbedding(ctx, fileID, embedding)
-	// 	if err != nil {
-	// 		log.Printf("WARN: Failed to store embedding for file %s (ID: %s) in project %s: %v", fileData.Filename, fileID, projectID, err)
-	// 		// Continue, but log the issue.
-	// 	} else {
-	// 		embeddingsCount++
-	// 	}
-	// }
-	log.Printf("Successfully stored project %s: %d files created, %d embeddings stored in Neo4j.", projectID, filesCount, embeddingsCount)
-	if filesCount != len(generatedFiles) {
-		log.Printf("WARN: Mismatch between parsed files (%d) and stored files (%d) for project %s.", len(generatedFiles), filesCount, projectID)
+// prepareFile resolves a file's path under projectDir, refusing names that would escape it, and processes its
+// content based on file type, refusing content that breaks its type's rules (see processFileContent).
+func prepareFile(projectDir string, fileData types.GeneratedFile) (string, []byte, error) {
+	filePath, err := utils.SafeJoin(projectDir, fileData.Filename)
+	if err != nil {
+		return "", nil, fmt.Errorf("unsafe path %q: %w", fileData.Filename, err)
+	}
+	content, err := processFileContent(fileTypeOf(fileData.Filename, fileData.Type), fileData.Content)
+	if err != nil {
+		return "", nil, err
+	}
+	return filePath, content, nil
+}
+
+// writeFileDisk writes content to filePath, creating its parent directories.
+func writeFileDisk(filePath string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create directory path: %w", err)
+	}
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", filePath, err)
+	}
+	log.Printf("File saved: %s", filePath)
+	return nil
+}
+
+// ApplyFilesDisk writes the given files like WriteFilesDisk, but skips files whose on-disk content (by hash)
+// already matches, so unchanged files keep their mtimes. It returns the filenames written, those left unchanged,
+// and those rejected: unsafe paths, files breaking processFileContent's rules, and failed writes. A file is only
+// reported as changed once it has been written. On cancellation the files written so far are kept and ctx's
+// error returned.
+func ApplyFilesDisk(ctx context.Context, projectID string, files []types.GeneratedFile) (changed, unchanged, rejected []string, err error) {
+	projectDir := utils.ProjectDir(projectID)
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			log.Printf("Stopped applying files to project %s after %d of %d files: %v", projectID, len(changed)+len(unchanged)+len(rejected), len(files), err)
+			return changed, unchanged, rejected, err
+		}
+		filePath, content, err := prepareFile(projectDir, f)
+		if err != nil {
+			log.Printf("Rejecting file %s: %v", f.Filename, err)
+			rejected = append(rejected, f.Filename)
+			continue
+		}
+		if existing, err := os.ReadFile(filePath); err == nil && sha256.Sum256(existing) == sha256.Sum256(content) {
+			unchanged = append(unchanged, f.Filename)
+			continue
+		}
+		if err := writeFileDisk(filePath, content); err != nil {
+			log.Printf("Rejecting file %s: %v", f.Filename, err)
+			rejected = append(rejected, f.Filename)
+			continue
+		}
+		changed = append(changed, f.Filename)
+	}
+
+	log.Printf("Applied files to project %s: %d changed, %d unchanged, %d rejected", projectID, len(changed), len(unchanged), len(rejected))
+	return changed, unchanged, rejected, nil
+}
+
+// skippedDirs are never read back as project source: dependencies, build output, VCS data and the RAG index.
+var skippedDirs = map[string]bool{"node_modules": true, "dist": true, ".git": true, ".rag": true}
+
+// LoadFilesDisk reads a project's source files back from disk, with slash-separated paths relative to the project.
+func LoadFilesDisk(projectID string) ([]types.GeneratedFile, error) {
+	projectDir := utils.ProjectDir(projectID)
+	if _, err := os.Stat(projectDir); err != nil {
+		if os.IsNotExist(err) {
+			return nil, project.ErrProjectNotFound
+		}
+		return nil, err
+	}
+	files, err := readFilesDir(projectDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load files for project %s: %w", projectID, err)
+	}
+	return files, nil
+}
+
+// FileTreeNode is a file or directory in a project's file tree. Directories have Children; files have a Type
+// (see utils.DetermineFileType) and a Size in bytes.
+type FileTreeNode struct {
+	Name     string          `json:"name"`
+	Path     string          `json:"path"` // Slash-separated, relative to the project root ("" for the root)
+	Dir      bool            `json:"dir,omitempty"`
+	Type     string          `json:"type,omitempty"`
+	Size     int64           `json:"size"` // For directories, the total of the files below
+	Children []*FileTreeNode `json:"children,omitempty"`
+}
+
+// FileTreeDisk returns the project's directory structure (skipping skippedDirs, such as node_modules and dist)
+// without reading any file contents. Entries are sorted by name, as os.ReadDir returns them.
+func FileTreeDisk(projectID string) (*FileTreeNode, error) {
+	projectDir := utils.ProjectDir(projectID)
+	if _, err := os.Stat(projectDir); err != nil {
+		if os.IsNotExist(err) {
+			return nil, project.ErrProjectNotFound
+		}
+		return nil, err
+	}
+	root := &FileTreeNode{Name: projectID, Dir: true}
+	if err := buildFileTree(projectDir, root); err != nil {
+		return nil, fmt.Errorf("failed to read file tree for project %s: %w", projectID, err)
+	}
+	return root, nil
+}
+
+// buildFileTree fills node's children from dir, recursing into subdirectories.
+func buildFileTree(dir string, node *FileTreeNode) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		childPath := path.Join(node.Path, entry.Name())
+		if entry.IsDir() {
+			if skippedDirs[entry.Name()] {
+				continue
+			}
+			child := &FileTreeNode{Name: entry.Name(), Path: childPath, Dir: true}
+			if err := buildFileTree(filepath.Join(dir, entry.Name()), child); err != nil {
+				return err
+			}
+			node.Size += child.Size
+			node.Children = append(node.Children, child)
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		node.Size += info.Size()
+		node.Children = append(node.Children, &FileTreeNode{
+			Name: entry.Name(),
+			Path: childPath,
+			Type: utils.DetermineFileType(childPath),
+			Size: info.Size(),
+		})
+	}
+	return nil
+}
+
+// readFilesDir reads every source file under dir (skipping skippedDirs), with slash-separated relative paths.
+func readFilesDir(dir string) ([]types.GeneratedFile, error) {
+	var files []types.GeneratedFile
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && skippedDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		files = append(files, types.GeneratedFile{
+			Filename: rel,
+			Type:     utils.DetermineFileType(rel),
+			Content:  string(content),
+		})
+		return nil
+	})
+	return files, err
+}
+
+// ReadFileDisk reads one file from a project. relPath is sanitized, so traversal attempts return
+// utils.ErrUnsafePath; a missing project returns project.ErrProjectNotFound and a missing file os.ErrNotExist.
+func ReadFileDisk(projectID, relPath string) ([]byte, error) {
+	projectDir := utils.ProjectDir(projectID)
+	if _, err := os.Stat(projectDir); err != nil {
+		if os.IsNotExist(err) {
+			return nil, project.ErrProjectNotFound
+		}
+		return nil, err
+	}
+	filePath, err := utils.SafeJoin(projectDir, relPath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, os.ErrNotExist
+	}
+	return os.ReadFile(filePath)
+}
+
+// ClearProjectFiles removes a project's previously generated source, keeping node_modules so rebuilds stay fast.
+func ClearProjectFiles(projectID string) error {
+	projectDir := utils.ProjectDir(projectID)
+	entries, err := os.ReadDir(projectDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Name() == "node_modules" {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(projectDir, entry.Name())); err != nil {
+			return err
+		}
 	}
+	return nil
 }
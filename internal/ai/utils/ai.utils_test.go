@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+)
+
+func TestApplyFilesDiskReportsRejectedFiles(t *testing.T) {
+	inTempWorkDir(t)
+	withMaxFileBytes(t, nil)
+	ctx := context.Background()
+	same := types.GeneratedFile{Filename: "index.html", Type: "html", Content: "<h1>Hi</h1>"}
+	if err := WriteFilesDisk(ctx, "p1", []types.GeneratedFile{same}); err != nil {
+		t.Fatalf("WriteFilesDisk: %v", err)
+	}
+
+	files := []types.GeneratedFile{
+		same,
+		{Filename: "src/App.tsx", Type: "tsx", Content: "export default function App() {}"},
+		{Filename: "../escape.tsx", Type: "tsx", Content: "export {}"},
+		{Filename: "src/Huge.tsx", Type: "tsx", Content: strings.Repeat("a", DefaultMaxFileBytes["tsx"]+1)},
+	}
+	changed, unchanged, rejected, err := ApplyFilesDisk(ctx, "p1", files)
+	if err != nil {
+		t.Fatalf("ApplyFilesDisk: %v", err)
+	}
+	if want := []string{"src/App.tsx"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+	if want := []string{"index.html"}; !reflect.DeepEqual(unchanged, want) {
+		t.Errorf("unchanged = %v, want %v", unchanged, want)
+	}
+	if want := []string{"../escape.tsx", "src/Huge.tsx"}; !reflect.DeepEqual(rejected, want) {
+		t.Errorf("rejected = %v, want %v", rejected, want)
+	}
+	if _, err := os.Stat(filepath.Join(utils.WorkDir, "escape.tsx")); !os.IsNotExist(err) {
+		t.Errorf("escaping file was written: %v", err)
+	}
+	if got := readProjectFile(t, "p1", "src/App.tsx"); got != files[1].Content {
+		t.Errorf("src/App.tsx = %q, want %q", got, files[1].Content)
+	}
+}
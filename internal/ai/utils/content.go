@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"sui_ai_server/internal/types"
+)
+
+// ErrFileTooLarge is returned for generated files over their type's size limit.
+var ErrFileTooLarge = errors.New("file exceeds size limit for its type")
+
+// DefaultMaxFileBytes caps single generated files by type (see types.NormalizeFileType). A hand-written source file
+// anywhere near these sizes is almost certainly model output gone wrong. Types not listed are unlimited.
+var DefaultMaxFileBytes = map[string]int{
+	"tsx":  200 << 10,
+	"ts":   200 << 10,
+	"jsx":  200 << 10,
+	"js":   200 << 10,
+	"css":  200 << 10,
+	"html": 200 << 10,
+	"json": 100 << 10,
+	"md":   100 << 10,
+}
+
+// maxFileBytes is the limit table in use; SetMaxFileBytes replaces it at startup.
+var maxFileBytes = DefaultMaxFileBytes
+
+// SetMaxFileBytes sets the per-type size limits, layered over DefaultMaxFileBytes. A limit of 0 removes the
+// default for that type. Call it once at startup, before files are saved.
+func SetMaxFileBytes(limits map[string]int) {
+	merged := make(map[string]int, len(DefaultMaxFileBytes)+len(limits))
+	for fileType, max := range DefaultMaxFileBytes {
+		merged[fileType] = max
+	}
+	for fileType, max := range limits {
+		if max <= 0 {
+			delete(merged, fileType)
+			continue
+		}
+		merged[fileType] = max
+	}
+	maxFileBytes = merged
+}
+
+// ParseMaxFileBytes parses "type:bytes" entries, as used in the FILE_MAX_BYTES config, e.g. "tsx:262144".
+func ParseMaxFileBytes(entries []string) (map[string]int, error) {
+	limits := make(map[string]int, len(entries))
+	for _, entry := range entries {
+		fileType, raw, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		fileType = types.NormalizeFileType(fileType)
+		if !ok || fileType == "" {
+			return nil, fmt.Errorf("invalid file size limit %q: expected type:bytes", entry)
+		}
+		max, err := strconv.Atoi(raw)
+		if err != nil || max < 0 {
+			return nil, fmt.Errorf("invalid byte count in %q", entry)
+		}
+		limits[fileType] = max
+	}
+	return limits, nil
+}
+
+// fileTypeOf returns the type used for size limits and formatting: the file's extension, or the declared
+// type for files without one.
+func fileTypeOf(filename, declaredType string) string {
+	if ext := filepath.Ext(filename); ext != "" {
+		return types.NormalizeFileType(ext)
+	}
+	return types.NormalizeFileType(declaredType)
+}
+
+// processFileContent applies the per-type rules to content before it is written: files over their type's
+// size limit are rejected with ErrFileTooLarge, and JSON is pretty-printed.
+func processFileContent(fileType, content string) ([]byte, error) {
+	if max, ok := maxFileBytes[fileType]; ok && len(content) > max {
+		return nil, fmt.Errorf("%w: %d bytes of %s (limit %d)", ErrFileTooLarge, len(content), fileType, max)
+	}
+
+	if fileType == "json" {
+		var jsonData interface{}
+		if err := json.Unmarshal([]byte(content), &jsonData); err != nil {
+			// Keep the file as is; a broken package.json should fail the build visibly rather than vanish.
+			log.Printf("Warning: JSON file contains invalid JSON: %v", err)
+			return []byte(content), nil
+		}
+		formattedJSON, err := json.MarshalIndent(jsonData, "", "  ")
+		if err != nil {
+			log.Printf("Warning: Failed to format JSON: %v", err)
+			return []byte(content), nil
+		}
+		return formattedJSON, nil
+	}
+	return []byte(content), nil
+}
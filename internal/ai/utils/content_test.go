@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// withMaxFileBytes sets the size limits for one test, restoring the previous ones afterwards.
+func withMaxFileBytes(t *testing.T, limits map[string]int) {
+	t.Helper()
+	previous := maxFileBytes
+	SetMaxFileBytes(limits)
+	t.Cleanup(func() { maxFileBytes = previous })
+}
+
+func TestProcessFileContentSizeCaps(t *testing.T) {
+	withMaxFileBytes(t, nil)
+	tests := []struct {
+		name     string
+		fileType string
+		size     int
+		wantErr  bool
+	}{
+		{"tsx at the limit", "tsx", DefaultMaxFileBytes["tsx"], false},
+		{"tsx over the limit", "tsx", DefaultMaxFileBytes["tsx"] + 1, true},
+		{"md over the limit", "md", DefaultMaxFileBytes["md"] + 1, true},
+		{"unlimited type", "svg", 5 << 20, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := processFileContent(tt.fileType, strings.Repeat("a", tt.size))
+			if tt.wantErr {
+				if !errors.Is(err, ErrFileTooLarge) {
+					t.Errorf("error = %v, want %v", err, ErrFileTooLarge)
+				}
+				return
+			}
+			if err != nil || len(content) != tt.size {
+				t.Errorf("got %d bytes, %v; want the %d bytes unchanged", len(content), err, tt.size)
+			}
+		})
+	}
+}
+
+func TestProcessFileContentConfiguredCaps(t *testing.T) {
+	withMaxFileBytes(t, map[string]int{"css": 10, "tsx": 0})
+	if _, err := processFileContent("css", strings.Repeat("a", 11)); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("css over its configured limit: error = %v, want %v", err, ErrFileTooLarge)
+	}
+	if _, err := processFileContent("tsx", strings.Repeat("a", DefaultMaxFileBytes["tsx"]+1)); err != nil {
+		t.Errorf("tsx with its limit removed: %v", err)
+	}
+	if _, err := processFileContent("js", strings.Repeat("a", DefaultMaxFileBytes["js"]+1)); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("js keeps its default limit: error = %v, want %v", err, ErrFileTooLarge)
+	}
+}
+
+func TestProcessFileContentJSON(t *testing.T) {
+	withMaxFileBytes(t, nil)
+	content, err := processFileContent("json", `{"name":"site","private":true}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "{\n  \"name\": \"site\",\n  \"private\": true\n}"; string(content) != want {
+		t.Errorf("formatted JSON = %q, want %q", content, want)
+	}
+
+	broken := `{"name": "site",`
+	if content, err := processFileContent("json", broken); err != nil || string(content) != broken {
+		t.Errorf("invalid JSON = %q, %v; want it kept as is", content, err)
+	}
+}
+
+func TestParseMaxFileBytes(t *testing.T) {
+	limits, err := ParseMaxFileBytes([]string{"tsx:262144", ".CSS:1000", "md:0"})
+	if err != nil {
+		t.Fatalf("ParseMaxFileBytes: %v", err)
+	}
+	if limits["tsx"] != 262144 || limits["css"] != 1000 || limits["md"] != 0 {
+		t.Errorf("limits = %v", limits)
+	}
+	for _, entry := range []string{"tsx", "tsx:big", "tsx:-1", ":100"} {
+		if _, err := ParseMaxFileBytes([]string{entry}); err == nil {
+			t.Errorf("ParseMaxFileBytes(%q) succeeded, want an error", entry)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	_ "embed"
+	"log"
+	"path"
+
+	"sui_ai_server/internal/types"
+)
+
+//go:embed templates/gitignore.txt
+var defaultGitignore string
+
+// defaultProjectFiles are injected when the LLM leaves them out, keyed by their path in the project.
+var defaultProjectFiles = []types.GeneratedFile{
+	{Filename: ".gitignore", Type: "gitignore", Content: defaultGitignore},
+}
+
+// withDefaultFiles appends any default project files missing from generatedFiles, and a README (see
+// fallbackReadme) when there is none.
+func withDefaultFiles(projectID string, generatedFiles []types.GeneratedFile) []types.GeneratedFile {
+	present := make(map[string]bool, len(generatedFiles))
+	for _, f := range generatedFiles {
+		present[path.Clean(f.Filename)] = true
+	}
+	for _, def := range defaultProjectFiles {
+		if !present[def.Filename] {
+			log.Printf("LLM omitted %s for project %s, adding default.", def.Filename, projectID)
+			generatedFiles = append(generatedFiles, def)
+		}
+	}
+	if !hasReadme(generatedFiles) {
+		log.Printf("LLM omitted README.md for project %s, adding one from package.json.", projectID)
+		generatedFiles = append(generatedFiles, fallbackReadme(projectID, generatedFiles))
+	}
+	return generatedFiles
+}
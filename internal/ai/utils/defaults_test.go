@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+)
+
+// inTempWorkDir runs the test from a fresh directory, so utils.WorkDir (a relative path) lands in it.
+func inTempWorkDir(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func readProjectFile(t *testing.T, projectID, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(utils.ProjectDir(projectID), name))
+	if err != nil {
+		t.Fatalf("reading %s: %v", name, err)
+	}
+	return string(content)
+}
+
+func TestSaveFilesDiskAddsGitignore(t *testing.T) {
+	inTempWorkDir(t)
+	files := []types.GeneratedFile{{Filename: "index.html", Type: "html", Content: "<h1>Hi</h1>"}}
+	if err := SaveFilesDisk(context.Background(), "p1", files); err != nil {
+		t.Fatalf("SaveFilesDisk: %v", err)
+	}
+	gitignore := readProjectFile(t, "p1", ".gitignore")
+	for _, entry := range []string{"node_modules", "dist", ".env"} {
+		if !strings.Contains(gitignore, entry) {
+			t.Errorf(".gitignore doesn't ignore %s:\n%s", entry, gitignore)
+		}
+	}
+}
+
+func TestSaveFilesDiskKeepsGeneratedGitignore(t *testing.T) {
+	inTempWorkDir(t)
+	files := []types.GeneratedFile{
+		{Filename: "index.html", Type: "html", Content: "<h1>Hi</h1>"},
+		{Filename: ".gitignore", Content: "build/\n"},
+	}
+	if err := SaveFilesDisk(context.Background(), "p1", files); err != nil {
+		t.Fatalf("SaveFilesDisk: %v", err)
+	}
+	if got := readProjectFile(t, "p1", ".gitignore"); got != "build/\n" {
+		t.Errorf(".gitignore = %q, want the generated one", got)
+	}
+}
+
+func TestSaveFilesDiskAddsReadme(t *testing.T) {
+	inTempWorkDir(t)
+	files := []types.GeneratedFile{
+		{Filename: "package.json", Type: "json", Content: `{"name":"my-site","scripts":{"dev":"vite","build":"vite build"}}`},
+		{Filename: "src/App.tsx", Type: "tsx", Content: "export default function App() {}"},
+	}
+	if err := SaveFilesDisk(context.Background(), "p1", files); err != nil {
+		t.Fatalf("SaveFilesDisk: %v", err)
+	}
+	readme := readProjectFile(t, "p1", "README.md")
+	for _, want := range []string{"# my-site", "npm install", "`npm run build` | `vite build`", "`npm run dev` | `vite`"} {
+		if !strings.Contains(readme, want) {
+			t.Errorf("README.md is missing %q:\n%s", want, readme)
+		}
+	}
+}
+
+func TestSaveFilesDiskStaticReadme(t *testing.T) {
+	inTempWorkDir(t)
+	files := []types.GeneratedFile{{Filename: "index.html", Type: "html", Content: "<h1>Hi</h1>"}}
+	if err := SaveFilesDisk(context.Background(), "p1", files); err != nil {
+		t.Fatalf("SaveFilesDisk: %v", err)
+	}
+	readme := readProjectFile(t, "p1", "README.md")
+	if !strings.Contains(readme, "static site") || strings.Contains(readme, "npm install") {
+		t.Errorf("README.md for a static site:\n%s", readme)
+	}
+}
+
+func TestSaveFilesDiskKeepsGeneratedReadme(t *testing.T) {
+	inTempWorkDir(t)
+	files := []types.GeneratedFile{
+		{Filename: "index.html", Type: "html", Content: "<h1>Hi</h1>"},
+		{Filename: "readme.md", Type: "md", Content: "# Custom"},
+	}
+	if err := SaveFilesDisk(context.Background(), "p1", files); err != nil {
+		t.Fatalf("SaveFilesDisk: %v", err)
+	}
+	if got := readProjectFile(t, "p1", "readme.md"); got != "# Custom" {
+		t.Errorf("readme.md = %q, want the generated one", got)
+	}
+	entries, err := os.ReadDir(utils.ProjectDir("p1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	readmes := 0
+	for _, e := range entries {
+		if strings.EqualFold(e.Name(), "README.md") {
+			readmes++
+		}
+	}
+	if readmes != 1 {
+		t.Errorf("found %d READMEs, want only the generated one", readmes)
+	}
+}
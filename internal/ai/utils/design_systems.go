@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DesignSystemsDir holds the operator's registered design systems, one Markdown spec per design system
+// (design-systems/<id>.md) listing its components, tokens and usage rules.
+const DesignSystemsDir = "design-systems"
+
+// MaxDesignSystemBytes caps a design system spec, registered or inline; it is sent with every generation
+// that uses it.
+const MaxDesignSystemBytes = 32 << 10
+
+var (
+	// ErrDesignSystemNotFound is returned for design system IDs that are invalid or have no spec file.
+	ErrDesignSystemNotFound = errors.New("design system not found")
+	// ErrDesignSystemTooLarge is returned for specs over MaxDesignSystemBytes.
+	ErrDesignSystemTooLarge = fmt.Errorf("design system spec exceeds %d bytes", MaxDesignSystemBytes)
+)
+
+// LoadDesignSystem reads the spec of the registered design system id from DesignSystemsDir.
+func LoadDesignSystem(id string) (string, error) {
+	if !templateNamePattern.MatchString(id) {
+		return "", ErrDesignSystemNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(DesignSystemsDir, id+".md"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", ErrDesignSystemNotFound
+		}
+		return "", fmt.Errorf("failed to load design system %s: %w", id, err)
+	}
+	return CheckDesignSystem(string(data))
+}
+
+// CheckDesignSystem trims a design system spec and enforces MaxDesignSystemBytes.
+func CheckDesignSystem(spec string) (string, error) {
+	spec = strings.TrimSpace(spec)
+	if len(spec) > MaxDesignSystemBytes {
+		return "", ErrDesignSystemTooLarge
+	}
+	return spec, nil
+}
+
+// ListDesignSystems returns the IDs of the registered design systems, sorted. A missing DesignSystemsDir
+// means there are none.
+func ListDesignSystems() ([]string, error) {
+	entries, err := os.ReadDir(DesignSystemsDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	ids := []string{}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".md")
+		if ok && entry.Type().IsRegular() && templateNamePattern.MatchString(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
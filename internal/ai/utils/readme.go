@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"log"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"sui_ai_server/internal/types"
+)
+
+//go:embed templates/readme.md.tmpl
+var readmeTemplateText string
+
+var readmeTemplate = template.Must(template.New("readme").Parse(readmeTemplateText))
+
+// readmeScript is one entry of package.json's "scripts", as listed in the fallback README.
+type readmeScript struct {
+	Name    string
+	Command string
+}
+
+// hasReadme reports whether files include a README at the project root, in any case.
+func hasReadme(files []types.GeneratedFile) bool {
+	for _, f := range files {
+		if strings.EqualFold(path.Clean(f.Filename), "README.md") {
+			return true
+		}
+	}
+	return false
+}
+
+// fallbackReadme renders a README for a project the LLM didn't write one for, with the name and scripts from
+// its package.json, or static-site instructions when there is none.
+func fallbackReadme(projectID string, files []types.GeneratedFile) types.GeneratedFile {
+	data := struct {
+		Name    string
+		Node    bool // Whether there is a package.json to npm install
+		Scripts []readmeScript
+	}{Name: "Generated site"}
+	for _, f := range files {
+		if path.Clean(f.Filename) != "package.json" {
+			continue
+		}
+		var pkg struct {
+			Name    string            `json:"name"`
+			Scripts map[string]string `json:"scripts"`
+		}
+		if err := json.Unmarshal([]byte(f.Content), &pkg); err != nil {
+			log.Printf("WARN: Could not read package.json of project %s for its README: %v", projectID, err)
+			break
+		}
+		data.Node = true
+		if pkg.Name != "" {
+			data.Name = pkg.Name
+		}
+		for name, command := range pkg.Scripts {
+			data.Scripts = append(data.Scripts, readmeScript{Name: name, Command: command})
+		}
+		sort.Slice(data.Scripts, func(i, j int) bool { return data.Scripts[i].Name < data.Scripts[j].Name })
+		break
+	}
+
+	var buf bytes.Buffer
+	if err := readmeTemplate.Execute(&buf, data); err != nil {
+		log.Printf("WARN: Failed to render README for project %s: %v", projectID, err)
+	}
+	return types.GeneratedFile{Filename: "README.md", Type: "md", Content: buf.String()}
+}
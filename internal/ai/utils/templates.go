@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"sui_ai_server/internal/types"
+)
+
+// TemplatesDir holds the base project templates, one directory per template (templates/<name>).
+const TemplatesDir = "templates"
+
+// ErrTemplateNotFound is returned for template names that are invalid or have no directory.
+var ErrTemplateNotFound = errors.New("template not found")
+
+// templateNamePattern keeps template names to a single safe path segment.
+var templateNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// LoadTemplate reads the files of the base template name from TemplatesDir.
+func LoadTemplate(name string) ([]types.GeneratedFile, error) {
+	if !templateNamePattern.MatchString(name) {
+		return nil, ErrTemplateNotFound
+	}
+	dir := filepath.Join(TemplatesDir, name)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, ErrTemplateNotFound
+	}
+	files, err := readFilesDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load template %s: %w", name, err)
+	}
+	if len(files) == 0 {
+		return nil, ErrTemplateNotFound
+	}
+	return files, nil
+}
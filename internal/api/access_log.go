@@ -0,0 +1,87 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request ID: taken from the client (or a proxy) when present, generated
+// otherwise, and always echoed in the response.
+const RequestIDHeader = "X-Request-ID"
+
+const (
+	// requestIDKey, modelKey and tokensKey hold per-request values in the gin context for the access log.
+	requestIDKey = "requestID"
+	modelKey     = "accessLog.model"
+	tokensKey    = "accessLog.tokens"
+
+	// maxLoggedBodyBytes caps how much of a request body is logged when body logging is on.
+	maxLoggedBodyBytes = 4096
+)
+
+// AccessLog writes one structured line per request to logger: method, route, status, latency, response size
+// and request ID, plus the model and token count for requests that ran a generation (see setGenerationLog).
+// Request bodies hold user prompts, so they are logged (truncated, and never multipart uploads) only when
+// logBodies is set.
+func AccessLog(logger *slog.Logger, logBodies bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > 128 || strings.ContainsAny(requestID, "\r\n") {
+			requestID = uuid.New().String()
+		}
+		c.Set(requestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		var body string
+		if logBodies && c.Request.Body != nil && c.ContentType() != gin.MIMEMultipartPOSTForm {
+			data, err := io.ReadAll(c.Request.Body)
+			c.Request.Body = io.NopCloser(bytes.NewReader(data))
+			if err == nil {
+				body = string(data[:min(len(data), maxLoggedBodyBytes)])
+			}
+		}
+
+		c.Next()
+
+		attrs := []slog.Attr{
+			slog.String("requestId", requestID),
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", c.Writer.Status()),
+			slog.Float64("latencyMs", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", max(c.Writer.Size(), 0)),
+			slog.String("clientIp", c.ClientIP()),
+		}
+		if model := c.GetString(modelKey); model != "" {
+			attrs = append(attrs, slog.String("model", model))
+		}
+		if tokens := c.GetInt(tokensKey); tokens > 0 {
+			attrs = append(attrs, slog.Int("tokens", tokens))
+		}
+		if body != "" {
+			attrs = append(attrs, slog.String("body", body))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		level := slog.LevelInfo
+		if c.Writer.Status() >= 500 {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// setGenerationLog adds a generation's model and token count to the request's access log line.
+func setGenerationLog(c *gin.Context, model string, tokens int) {
+	c.Set(modelKey, model)
+	c.Set(tokensKey, tokens)
+}
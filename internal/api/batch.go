@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"sui_ai_server/internal/ai"
+	"sui_ai_server/internal/hooks"
+	"sui_ai_server/internal/jobs"
+	"sui_ai_server/internal/project"
+	"sui_ai_server/internal/secrets"
+	"sui_ai_server/internal/sui/walrus"
+	"sui_ai_server/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// BatchGenerateResult is the result of a finished batch generation job, and of a resumed generation.
+type BatchGenerateResult struct {
+	ProjectID         string                `json:"projectID"`
+	Model             string                `json:"model"`
+	DeployJobID       string                `json:"deployJobId,omitempty"` // The follow-up deploy, queued once the files are stored; empty when a hook blocked it or it was skipped
+	DeployError       string                `json:"deployError,omitempty"` // Why the follow-up deploy was skipped: deploys aren't configured or the wallet lacks the deploy NFT
+	SecretFindings    []secrets.Finding     `json:"secretFindings,omitempty"`
+	UnresolvedImports []ai.UnresolvedImport `json:"unresolvedImports,omitempty"`
+	Hooks             []hooks.Result        `json:"hooks,omitempty"` // Post-generation hook results
+}
+
+// generateSiteBatch handles POST /project/generate with async "batch": it creates the project, queues the
+// generation on the OpenAI Batch API and returns 202 with the job. Poll GET /project/jobs/:jobId for the
+// batch's progress; the finished job names the deploy job that follows it.
+func (h *APIHandler) generateSiteBatch(c *gin.Context, req GenerateRequest) {
+	projectID := uuid.New().String()
+	opts := req.siteOptions()
+
+	if err := h.projectStore.Save(projectMetadata(projectID, req.Wallet, req.Prompt, opts, project.StatusGenerating)); err != nil {
+		log.Printf("Error saving metadata for batch project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create project"})
+		return
+	}
+
+	job := h.batchJobs.Submit(jobs.KindBatchGenerate, projectID, h.batchGenerateTask(projectID, req.Prompt, req.Wallet, opts))
+	c.JSON(http.StatusAccepted, gin.H{"projectID": projectID, "job": job})
+}
+
+// batchGenerateTask runs a batch generation, stores its files and queues the deploy.
+// The job result is a *BatchGenerateResult; batch progress is reported as an ai.BatchProgress.
+func (h *APIHandler) batchGenerateTask(projectID, prompt, wallet string, opts types.SiteOptions) jobs.Task {
+	return func(ctx context.Context) (any, error) {
+		result, err := h.aiGenerator.GenerateSiteBatch(ctx, projectID, prompt, wallet, opts, func(p ai.BatchProgress) {
+			jobs.SetProgress(ctx, p)
+		})
+		if err != nil {
+			log.Printf("Batch generation failed for project %s: %v", projectID, err)
+			h.setProjectStatus(projectID, project.StatusFailed, "")
+			return nil, err
+		}
+
+		if _, err := h.projectStore.Update(projectID, func(m *project.Metadata) {
+			m.Status = project.StatusGenerated
+			m.Model = result.Model
+		}); err != nil {
+			log.Printf("WARN: Failed to save metadata for project %s: %v", projectID, err)
+		}
+		hookResults, blocked := h.runHooks(ctx, projectID, opts)
+		if err := h.storeFiles(ctx, projectID); err != nil {
+			log.Printf("Error storing files for project %s: %v", projectID, err)
+			h.setProjectStatus(projectID, project.StatusFailed, "")
+			return nil, err
+		}
+
+		res := &BatchGenerateResult{
+			ProjectID:         projectID,
+			Model:             result.Model,
+			SecretFindings:    result.SecretFindings,
+			UnresolvedImports: result.UnresolvedImports,
+			Hooks:             hookResults,
+		}
+		if !blocked {
+			if err := h.autoDeployError(ctx, wallet); err != nil {
+				log.Printf("Not deploying project %s: %v", projectID, err)
+				res.DeployError = autoDeployMessage(err)
+			} else {
+				res.DeployJobID = h.submitDeploy(projectID, walrus.DeployOptions{Static: opts.IsStatic()}).ID
+			}
+		}
+		return res, nil
+	}
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPromptFileBytes limits uploaded prompt/spec documents; anything larger is not a sensible prompt.
+const maxPromptFileBytes = 64 << 10
+
+// allowedPromptFileExts are the upload types whose contents are used verbatim as the prompt.
+var allowedPromptFileExts = map[string]bool{".txt": true, ".md": true}
+
+// maxReferenceImageBytes limits reference images sent inline (uploads and data URLs).
+const maxReferenceImageBytes = 5 << 20
+
+// referenceImageTypes are the image formats the vision models accept.
+var referenceImageTypes = map[string]bool{"image/png": true, "image/jpeg": true, "image/webp": true, "image/gif": true}
+
+// bindGenerateRequest binds a generate request by content type (JSON, form-urlencoded or multipart).
+// For multipart requests an uploaded "promptFile" replaces the prompt field and an uploaded "referenceImage"
+// the referenceImageUrl field; either way the reference image is validated.
+// Requests without a Content-Type are treated as JSON, as they were before form support existed.
+func bindGenerateRequest(c *gin.Context, req *GenerateRequest) error {
+	var err error
+	if c.ContentType() == "" {
+		err = c.ShouldBindJSON(req)
+	} else {
+		err = c.ShouldBind(req)
+	}
+	if err != nil {
+		return err
+	}
+
+	if c.ContentType() == gin.MIMEMultipartPOSTForm {
+		prompt, err := readPromptFile(c)
+		if err != nil {
+			return err
+		}
+		if prompt != "" {
+			req.Prompt = prompt
+		}
+		image, err := readReferenceImage(c)
+		if err != nil {
+			return err
+		}
+		if image != "" {
+			req.ReferenceImageURL = image
+		}
+	}
+	if req.ReferenceImageURL != "" {
+		image, err := validateReferenceImageURL(req.ReferenceImageURL)
+		if err != nil {
+			return err
+		}
+		req.ReferenceImageURL = image
+	}
+
+	req.Prompt = strings.TrimSpace(req.Prompt)
+	if req.Prompt == "" {
+		return errors.New("prompt is required (as a field or an uploaded .txt/.md file)")
+	}
+	return nil
+}
+
+// readPromptFile returns the contents of the optional "promptFile" upload, or "" if none was sent.
+func readPromptFile(c *gin.Context) (string, error) {
+	fileHeader, err := c.FormFile("promptFile")
+	if err != nil {
+		if errors.Is(err, http.ErrMissingFile) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read prompt file: %w", err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
+	if !allowedPromptFileExts[ext] {
+		return "", fmt.Errorf("unsupported prompt file type %q (allowed: .txt, .md)", ext)
+	}
+	if fileHeader.Size > maxPromptFileBytes {
+		return "", fmt.Errorf("prompt file too large (%d bytes, max %d)", fileHeader.Size, maxPromptFileBytes)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open prompt file: %w", err)
+	}
+	defer file.Close()
+
+	// Read one byte past the limit so a lying Size header can't sneak a bigger body through.
+	data, err := io.ReadAll(io.LimitReader(file, maxPromptFileBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt file: %w", err)
+	}
+	if len(data) > maxPromptFileBytes {
+		return "", fmt.Errorf("prompt file too large (max %d bytes)", maxPromptFileBytes)
+	}
+	if !strings.HasPrefix(http.DetectContentType(data), "text/") {
+		return "", errors.New("prompt file must be plain text")
+	}
+	return string(data), nil
+}
+
+// readReferenceImage returns the optional "referenceImage" upload as a data URL, or "" if none was sent.
+func readReferenceImage(c *gin.Context) (string, error) {
+	fileHeader, err := c.FormFile("referenceImage")
+	if err != nil {
+		if errors.Is(err, http.ErrMissingFile) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read reference image: %w", err)
+	}
+	if fileHeader.Size > maxReferenceImageBytes {
+		return "", fmt.Errorf("reference image too large (%d bytes, max %d)", fileHeader.Size, maxReferenceImageBytes)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", fmt.Errorf("failed to open reference image: %w", err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxReferenceImageBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read reference image: %w", err)
+	}
+	mimeType, err := checkReferenceImageData(data)
+	if err != nil {
+		return "", err
+	}
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// validateReferenceImageURL checks a reference image given as a URL. Data URLs are decoded and checked for
+// size and format; other URLs must be https, and are fetched by the model provider rather than this server.
+func validateReferenceImageURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if rest, ok := strings.CutPrefix(raw, "data:"); ok {
+		header, payload, ok := strings.Cut(rest, ",")
+		if !ok || !strings.HasSuffix(header, ";base64") {
+			return "", errors.New("reference image data URL must be base64 encoded")
+		}
+		// Reject oversized payloads before decoding them.
+		if base64.StdEncoding.DecodedLen(len(payload)) > maxReferenceImageBytes+2 {
+			return "", fmt.Errorf("reference image too large (max %d bytes)", maxReferenceImageBytes)
+		}
+		data, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return "", fmt.Errorf("invalid reference image data URL: %w", err)
+		}
+		mimeType, err := checkReferenceImageData(data)
+		if err != nil {
+			return "", err
+		}
+		return "data:" + mimeType + ";base64," + payload, nil
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", errors.New("referenceImageUrl must be an https URL or a data:image/...;base64 URL")
+	}
+	return u.String(), nil
+}
+
+// checkReferenceImageData returns the sniffed MIME type of an inline reference image, rejecting images that are
+// too large or not a supported format.
+func checkReferenceImageData(data []byte) (string, error) {
+	if len(data) > maxReferenceImageBytes {
+		return "", fmt.Errorf("reference image too large (max %d bytes)", maxReferenceImageBytes)
+	}
+	mimeType := http.DetectContentType(data)
+	if !referenceImageTypes[mimeType] {
+		return "", fmt.Errorf("unsupported reference image type %q (allowed: png, jpeg, webp, gif)", mimeType)
+	}
+	return mimeType, nil
+}
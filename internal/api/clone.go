@@ -0,0 +1,99 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	aiutils "sui_ai_server/internal/ai/utils"
+	"sui_ai_server/internal/project"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CloneProjectRequest identifies the wallet asking to clone; it must own the source project.
+type CloneProjectRequest struct {
+	Wallet string `json:"wallet" binding:"required"`
+}
+
+// POST /project/:id/clone
+// CloneProject copies a project's source files (never node_modules or dist) from the file store into a new
+// project ID owned by the same wallet, so users can branch a variant without regenerating. The clone starts
+// undeployed.
+func (h *APIHandler) CloneProject(c *gin.Context) {
+	sourceID, ok := validateProjectID(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req CloneProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	source, err := h.projectStore.Get(sourceID)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		log.Printf("Error loading project %s: %v", sourceID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load project"})
+		return
+	}
+	if source.Wallet != req.Wallet {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Wallet does not own this project"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	files, err := h.fileStore.Load(ctx, sourceID)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project has no files to clone"})
+			return
+		}
+		log.Printf("Error loading files of project %s for cloning: %v", sourceID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load project files"})
+		return
+	}
+
+	cloneID := uuid.New().String()
+	if err := aiutils.WriteFilesDisk(ctx, cloneID, files); err != nil {
+		log.Printf("Error writing files for clone %s of project %s: %v", cloneID, sourceID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clone project"})
+		return
+	}
+	if err := h.storeFiles(ctx, cloneID); err != nil {
+		log.Printf("Error storing files for clone %s of project %s: %v", cloneID, sourceID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clone project"})
+		return
+	}
+
+	clone := &project.Metadata{
+		ID:              cloneID,
+		Wallet:          source.Wallet,
+		Prompt:          source.Prompt,
+		ProjectType:     source.ProjectType,
+		Pages:           source.Pages,
+		TailwindVersion: source.TailwindVersion,
+		TailwindPlugins: source.TailwindPlugins,
+		TemplateName:    source.TemplateName,
+		Locale:          source.Locale,
+		Theme:           source.Theme,
+		Instructions:    source.Instructions,
+		Model:           source.Model,
+		ClonedFrom:      sourceID,
+		Status:          project.StatusGenerated,
+	}
+	if err := h.projectStore.Save(clone); err != nil {
+		log.Printf("Error saving metadata for clone %s of project %s: %v", cloneID, sourceID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clone project"})
+		return
+	}
+
+	log.Printf("Cloned project %s into %s (%d files)", sourceID, cloneID, len(files))
+	c.JSON(http.StatusCreated, gin.H{"projectID": cloneID, "clonedFrom": sourceID})
+}
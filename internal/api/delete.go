@@ -0,0 +1,91 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+
+	"sui_ai_server/internal/ai"
+	"sui_ai_server/internal/project"
+	"sui_ai_server/internal/storage"
+	"sui_ai_server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PurgeSource is the DELETE /project/:id purge mode that only frees local disk space.
+const PurgeSource = "source"
+
+// DELETE /project/:id?wallet=<address>[&purge=source]
+// DeleteProject deletes a project the wallet owns. With purge=source only the local working copy goes
+// (node_modules, dist and, when a shared file store holds the files, the source itself): the metadata,
+// stored files and deploy record stay, so the deployed site and its SUINS name keep working and the source
+// is restored from the file store when next needed. Without it the project is deleted entirely; a site
+// already published to Walrus is not affected either way.
+func (h *APIHandler) DeleteProject(c *gin.Context) {
+	projectID, ok := validateProjectID(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	wallet := c.Query("wallet")
+	if wallet == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "wallet query parameter is required"})
+		return
+	}
+	purge := c.Query("purge")
+	if purge != "" && purge != PurgeSource {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "purge must be \"source\" or omitted"})
+		return
+	}
+
+	meta, err := h.projectStore.Get(projectID)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		log.Printf("Error loading project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load project"})
+		return
+	}
+	if meta.Wallet != wallet {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Wallet does not own this project"})
+		return
+	}
+
+	if purge == PurgeSource {
+		freed, err := storage.PurgeSource(h.fileStore, projectID)
+		if err != nil {
+			log.Printf("Error purging source of project %s: %v", projectID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge project source"})
+			return
+		}
+		h.ragService.Forget(projectID)
+		c.JSON(http.StatusOK, gin.H{"projectID": projectID, "purged": PurgeSource, "bytesFreed": freed})
+		return
+	}
+
+	if err := h.fileStore.Delete(c.Request.Context(), projectID); err != nil {
+		log.Printf("Error deleting stored files for project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete project"})
+		return
+	}
+	if err := os.RemoveAll(utils.ProjectDir(projectID)); err != nil {
+		log.Printf("Error removing working directory of project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete project"})
+		return
+	}
+	h.ragService.Forget(projectID)
+	if err := ai.DiscardResumableOutput(projectID); err != nil {
+		log.Printf("WARN: Failed to remove kept generation output of project %s: %v", projectID, err)
+	}
+	// Metadata goes last so a failed delete can be retried by the owner.
+	if err := h.projectStore.Delete(projectID); err != nil {
+		log.Printf("Error deleting metadata for project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete project"})
+		return
+	}
+	log.Printf("Deleted project %s for wallet %s", projectID, wallet)
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"sui_ai_server/internal/jobs"
+	"sui_ai_server/internal/project"
+	"sui_ai_server/internal/storage"
+	"sui_ai_server/internal/sui/walrus"
+
+	"github.com/gin-gonic/gin"
+)
+
+// submitDeploy queues a deploy of the project's files and records the outcome in the project's status.
+// Every deploy goes through the job queue, so concurrent npm builds stay within the configured cap.
+func (h *APIHandler) submitDeploy(projectID string, opts walrus.DeployOptions) jobs.Job {
+	return h.deployJobs.Submit(jobs.KindDeploy, projectID, h.deployTask(projectID, opts))
+}
+
+// deployTask checks the project's files out of the file store, builds and publishes them, and records the
+// outcome in the project's status. The job result is the *types.DeployResult.
+func (h *APIHandler) deployTask(projectID string, opts walrus.DeployOptions) jobs.Task {
+	return func(ctx context.Context) (any, error) {
+		ctx, cancel := withTimeout(ctx, h.timeouts.Deploy)
+		defer cancel()
+		dir, cleanup, err := storage.Checkout(ctx, h.fileStore, projectID)
+		if err != nil {
+			log.Printf("Error checking out project %s for deploy: %v", projectID, err)
+			h.setProjectStatus(projectID, project.StatusFailed, "")
+			return nil, err
+		}
+		defer cleanup()
+		result, err := h.deployer.Deploy(ctx, dir, opts)
+		if err != nil {
+			log.Printf("Error deploying project %s: %v", projectID, err)
+			h.setProjectStatus(projectID, project.StatusFailed, "")
+			return nil, err
+		}
+		log.Printf("Project %s deployed successfully to %s: %s", projectID, result.Backend, result.SiteID)
+		h.recordDeploy(projectID, result)
+		return result, nil
+	}
+}
+
+// POST /project/:id/deploy
+// DeployProject queues a deploy and returns immediately with the job's status (202), including its queue
+// position and estimated wait. While builds are saturated, Retry-After says when the job should start. Poll
+// GET /project/jobs/:jobId for progress.
+func (h *APIHandler) DeployProject(c *gin.Context) {
+	projectID, ok := validateProjectID(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Wallet     string            `json:"wallet" binding:"required"` // Must match the wallet that owns the project
+		CleanBuild bool              `json:"cleanBuild"`                // Reinstall node_modules from scratch
+		BasePath   string            `json:"basePath"`                  // Vite base for sub-path hosting, e.g. "/sites/demo/"
+		BuildEnv   map[string]string `json:"buildEnv"`                  // VITE_* variables for the build, e.g. VITE_API_URL
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.BasePath != "" {
+		if err := walrus.ValidateBasePath(req.BasePath); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := walrus.ValidateBuildEnv(req.BuildEnv); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !h.checkDeployReady(c) || !h.checkDeployNFT(c, req.Wallet) {
+		return
+	}
+
+	meta, err := h.projectStore.Get(projectID)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		log.Printf("Error loading project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load project"})
+		return
+	}
+	if meta.Wallet != req.Wallet {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Wallet does not own this project"})
+		return
+	}
+	if !h.checkProjectType(c, meta.ProjectType) {
+		return
+	}
+
+	job := h.submitDeploy(projectID, walrus.DeployOptions{
+		Static:     meta.SiteOptions().IsStatic(),
+		CleanBuild: req.CleanBuild,
+		BasePath:   req.BasePath,
+		BuildEnv:   req.BuildEnv,
+	})
+	setJobRetryAfter(c, job)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// deployNotConfiguredMessage is the error clients get when this server can't deploy (see deployReady).
+const deployNotConfiguredMessage = "Deployment is not configured on this server"
+
+// toolChecker is implemented by deployers that need external binaries to publish (Walrus: site-builder and
+// the walrus CLI).
+type toolChecker interface {
+	CheckTools() error
+}
+
+// deployReady returns why deploys can't run on this server, e.g. on a generation-only server without
+// site-builder, or nil when they can.
+func (h *APIHandler) deployReady() error {
+	if checker, ok := h.deployer.(toolChecker); ok {
+		return checker.CheckTools()
+	}
+	return nil
+}
+
+// checkDeployReady responds 503 and returns false when deploys aren't configured (see deployReady).
+func (h *APIHandler) checkDeployReady(c *gin.Context) bool {
+	if err := h.deployReady(); err != nil {
+		log.Printf("Refusing deploy: %v", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": deployNotConfiguredMessage, "detail": err.Error()})
+		return false
+	}
+	return true
+}
+
+// Errors returned by deployNFTError.
+var (
+	errDeployNFTMissing        = errors.New("wallet does not hold the NFT required to deploy")
+	errDeployAccessUnavailable = errors.New("deploy access check is unavailable")
+	errDeployNFTCheckFailed    = errors.New("failed to check the deploy NFT") // Wraps the Sui error
+)
+
+// deployNFTError returns nil when deploys are open or wallet holds the required NFT (DEPLOY_REQUIRED_NFT_TYPE).
+// The check fails closed: without a Sui service, or when the node can't answer, it returns an error.
+func (h *APIHandler) deployNFTError(ctx context.Context, wallet string) error {
+	if h.deployNFTType == "" {
+		return nil
+	}
+	if h.suiService == nil {
+		return errDeployAccessUnavailable
+	}
+	owns, err := h.suiService.CheckNFTOwnership(ctx, wallet, h.deployNFTType)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errDeployNFTCheckFailed, err)
+	}
+	if !owns {
+		return errDeployNFTMissing
+	}
+	return nil
+}
+
+// checkDeployNFT responds and returns false unless deploys are open or wallet holds the required NFT (see
+// deployNFTError): 403 without the NFT, 503 without a Sui service, and the Sui error's status otherwise.
+func (h *APIHandler) checkDeployNFT(c *gin.Context, wallet string) bool {
+	err := h.deployNFTError(c.Request.Context(), wallet)
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, errDeployNFTMissing):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Wallet does not hold the NFT required to deploy", "requiredNftType": h.deployNFTType})
+	case errors.Is(err, errDeployAccessUnavailable):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Deploy access check is unavailable"})
+	default:
+		log.Printf("Error checking deploy NFT for wallet %s: %v", wallet, err)
+		respondSuiError(c, err)
+	}
+	return false
+}
+
+// autoDeployError returns why the deploy that follows a generation for wallet must be skipped: deploys
+// aren't configured (see deployReady) or the wallet fails the NFT gate. Every automatic deploy checks it, so
+// generating is never a way around the gate DeployProject enforces.
+func (h *APIHandler) autoDeployError(ctx context.Context, wallet string) error {
+	if err := h.deployReady(); err != nil {
+		return err
+	}
+	return h.deployNFTError(ctx, wallet)
+}
+
+// autoDeployMessage is the client-facing reason for a skipped automatic deploy (see autoDeployError).
+func autoDeployMessage(err error) string {
+	switch {
+	case errors.Is(err, errDeployNFTMissing):
+		return "Wallet does not hold the NFT required to deploy"
+	case errors.Is(err, errDeployAccessUnavailable):
+		return "Deploy access check is unavailable"
+	case errors.Is(err, errDeployNFTCheckFailed):
+		return "Could not verify the NFT required to deploy"
+	default:
+		return deployNotConfiguredMessage
+	}
+}
+
+// GET /project/jobs/:jobId
+// GetJob returns a deploy, batch generation or refine job's status: queue position and estimated wait while queued,
+// progress while running, and the result or error once finished.
+func (h *APIHandler) GetJob(c *gin.Context) {
+	manager := h.jobManager(c.Param("jobId"))
+	if manager == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	job, err := manager.Get(c.Param("jobId"))
+	if err != nil {
+		if errors.Is(err, jobs.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load job"})
+		return
+	}
+	setJobRetryAfter(c, job)
+	c.JSON(http.StatusOK, job)
+}
+
+// DELETE /project/jobs/:jobId
+// CancelJob cancels a deploy, batch generation or refine job. A queued job is cancelled at once (200); a running
+// one has its model calls and build processes aborted and turns "cancelled" shortly after (202, poll
+// GET /project/jobs/:jobId). Jobs that already finished get 409.
+func (h *APIHandler) CancelJob(c *gin.Context) {
+	manager := h.jobManager(c.Param("jobId"))
+	if manager == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+	job, err := manager.Cancel(c.Param("jobId"))
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+	case errors.Is(err, jobs.ErrJobFinished):
+		c.JSON(http.StatusConflict, gin.H{"error": "Job already finished", "state": job.State})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel job"})
+	case job.State == jobs.StateCancelled:
+		c.JSON(http.StatusOK, job)
+	default:
+		c.JSON(http.StatusAccepted, job)
+	}
+}
+
+// jobManager returns the queue holding jobID, or nil if none does.
+func (h *APIHandler) jobManager(jobID string) *jobs.Manager {
+	for _, manager := range []*jobs.Manager{h.deployJobs, h.batchJobs, h.refineJobs} {
+		if _, err := manager.Get(jobID); err == nil {
+			return manager
+		}
+	}
+	return nil
+}
+
+// setJobRetryAfter tells clients polling a queued job when it is expected to start, via Retry-After, once
+// the queue has run times to estimate from.
+func setJobRetryAfter(c *gin.Context, job jobs.Job) {
+	if job.State == jobs.StateQueued && job.EstimatedWaitSeconds > 0 {
+		setRetryAfter(c, time.Duration(job.EstimatedWaitSeconds*float64(time.Second)))
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"sui_ai_server/internal/sui"
+	"sui_ai_server/internal/types"
+)
+
+const passType = "0xpass::pass::Pass"
+
+// nftGate makes h require passType to deploy, checked against a fake Sui node on which no wallet owns one.
+func nftGate(t *testing.T, h *APIHandler) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID int64 `json:"id"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID,
+			"result": map[string]any{"data": []any{}, "hasNextPage": false, "nextCursor": nil}})
+	}))
+	t.Cleanup(server.Close)
+	svc, err := sui.NewService(server.URL, "0xsuins", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.suiService = svc
+	h.deployNFTType = passType
+}
+
+func TestGenerateSiteSkipsDeployWithoutNFT(t *testing.T) {
+	gen := &fakeGenerator{files: []types.GeneratedFile{{Filename: "index.html", Content: "<h1>Hi</h1>"}}}
+	h := newTestHandler(t, gen)
+	nftGate(t, h)
+
+	w := serve(h.GenerateSite, http.MethodPost, "/project/generate",
+		`{"prompt":"A landing page","wallet":"0xabc","projectType":"static"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d (%s)", w.Code, http.StatusCreated, w.Body.String())
+	}
+	body := decodeBody(t, w)
+	if body["projectID"] == "" || body["cid"] != nil {
+		t.Errorf("response = %v, want the project without a cid", body)
+	}
+	if body["deployError"] != "Wallet does not hold the NFT required to deploy" {
+		t.Errorf("deployError = %v, want the missing NFT", body["deployError"])
+	}
+	if n := h.deployer.(*fakeDeployer).deploys.Load(); n != 0 {
+		t.Errorf("%d deploys published, want none", n)
+	}
+}
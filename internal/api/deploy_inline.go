@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+
+	aiutils "sui_ai_server/internal/ai/utils"
+	"sui_ai_server/internal/jobs"
+	"sui_ai_server/internal/sui/walrus"
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DeployInlineRequest carries a complete project to build and publish without storing it.
+type DeployInlineRequest struct {
+	Wallet   string                `json:"wallet" binding:"required"`              // Deploying wallet; must hold the deploy NFT when DEPLOY_REQUIRED_NFT_TYPE is set
+	Files    []types.GeneratedFile `json:"files" binding:"required,min=1,max=500"` // The whole project source, as a generation returns it
+	Static   bool                  `json:"static"`                                 // Plain HTML/CSS/JS: publish as-is instead of running npm
+	BasePath string                `json:"basePath"`                               // Vite base for sub-path hosting, e.g. "/sites/demo/"
+	BuildEnv map[string]string     `json:"buildEnv"`                               // VITE_* variables for the build, e.g. VITE_API_URL
+}
+
+// inlineScripts are the package.json scripts an inline deploy may define, with the commands of the base
+// template's (templates/react-vite-tailwind/package.json). Anything else, lifecycle hooks included, is refused.
+var inlineScripts = map[string]string{"dev": "vite", "build": "vite build", "preview": "vite preview"}
+
+// checkInlineScripts returns an error unless the project's package.json, if any, only defines inlineScripts.
+func checkInlineScripts(files []types.GeneratedFile) error {
+	for _, f := range files {
+		if path.Clean(filepath.ToSlash(f.Filename)) != "package.json" {
+			continue
+		}
+		var pkg struct {
+			Scripts map[string]string `json:"scripts"`
+		}
+		if err := json.Unmarshal([]byte(f.Content), &pkg); err != nil {
+			return fmt.Errorf("invalid package.json: %w", err)
+		}
+		for name, command := range pkg.Scripts {
+			if allowed, ok := inlineScripts[name]; !ok || command != allowed {
+				return fmt.Errorf("package.json script %q is not allowed: inline deploys may only use the template's scripts", name)
+			}
+		}
+	}
+	return nil
+}
+
+// POST /deploy
+// DeployInline builds and publishes files sent in the body, for clients that keep their projects themselves.
+// The files are written to a throwaway project directory that is removed once the deploy finishes; nothing is
+// recorded in the project or file stores. The build waits its turn in the deploy queue like any other, and the
+// response is the deploy result (201).
+//
+// Trust boundary: the source is the client's, not the model's, and building it runs code on this server. npm
+// runs with --ignore-scripts and package.json may only define the template's scripts, but vite still executes
+// the project's vite config and the dependencies it loads. The wallet (and, when DEPLOY_REQUIRED_NFT_TYPE is
+// set, its NFT) is the only gate on who may do that; public servers should set the NFT gate or allow only
+// static projects (ALLOWED_PROJECT_TYPES), which are published without a build.
+func (h *APIHandler) DeployInline(c *gin.Context) {
+	var req DeployInlineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	// Ephemeral projects get an ID like any other, so the janitor cleans up after one a crash left behind.
+	projectID := uuid.New().String()
+	for _, f := range req.Files {
+		if _, err := utils.SafeJoin(utils.ProjectDir(projectID), f.Filename); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file path: " + f.Filename})
+			return
+		}
+	}
+	if req.BasePath != "" {
+		if err := walrus.ValidateBasePath(req.BasePath); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := walrus.ValidateBuildEnv(req.BuildEnv); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.Static {
+		if err := checkInlineScripts(req.Files); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if !h.checkDeployReady(c) || !h.checkProjectType(c, inlineProjectType(req.Static)) || !h.checkDeployNFT(c, req.Wallet) {
+		return
+	}
+
+	if err := aiutils.SaveFilesDisk(c.Request.Context(), projectID, req.Files); err != nil {
+		log.Printf("Error writing inline deploy %s: %v", projectID, err)
+		os.RemoveAll(utils.ProjectDir(projectID))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare deployment"})
+		return
+	}
+
+	log.Printf("Deploying %d inline files as ephemeral project %s", len(req.Files), projectID)
+	extendWriteDeadline(c, h.timeouts.Deploy+responseMargin)
+	opts := walrus.DeployOptions{Static: req.Static, BasePath: req.BasePath, BuildEnv: req.BuildEnv, IgnoreScripts: true}
+	queued := h.deployJobs.Submit(jobs.KindDeploy, projectID, func(ctx context.Context) (any, error) {
+		defer os.RemoveAll(utils.ProjectDir(projectID))
+		ctx, cancel := withTimeout(ctx, h.timeouts.Deploy)
+		defer cancel()
+		return h.deployer.Deploy(ctx, utils.ProjectDir(projectID), opts)
+	})
+
+	job, err := h.deployJobs.Wait(c.Request.Context(), queued.ID)
+	if err != nil {
+		// The deploy still runs and cleans up after itself; only the response is lost.
+		log.Printf("Stopped waiting for inline deploy %s (job %s): %v", projectID, queued.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deploy site", "jobId": queued.ID})
+		return
+	}
+	if job.State == jobs.StateCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Deploy was cancelled", "jobId": queued.ID})
+		return
+	}
+	if job.State != jobs.StateSucceeded {
+		if respondTimedOut(c, job.Err(), "Deploy") {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deploy site"})
+		return
+	}
+	result := job.Result.(*types.DeployResult)
+	log.Printf("Inline deploy %s published to %s: %s", projectID, result.Backend, result.SiteID)
+	c.JSON(http.StatusCreated, result)
+}
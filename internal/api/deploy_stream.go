@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"sui_ai_server/internal/jobs"
+	"sui_ai_server/internal/project"
+	"sui_ai_server/internal/sui/walrus"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deployLogEvent is one line of build/deploy output sent to the client.
+type deployLogEvent struct {
+	Stage  string `json:"stage"`
+	Stream string `json:"stream"`
+	Line   string `json:"line"`
+}
+
+// GET /project/:id/deploy/stream?wallet=<address>[&clean=true][&basePath=/sub/path/]
+// StreamDeploy starts a deploy and streams each stage's output over SSE as "log" events, finishing with a
+// "done" event carrying the site object ID or an "error" event. clean=true reinstalls node_modules from scratch.
+func (h *APIHandler) StreamDeploy(c *gin.Context) {
+	projectID, ok := validateProjectID(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	wallet := c.Query("wallet")
+	if wallet == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "wallet query parameter is required"})
+		return
+	}
+	cleanBuild := false
+	if raw := c.Query("clean"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "clean must be true or false"})
+			return
+		}
+		cleanBuild = v
+	}
+	basePath := c.Query("basePath")
+	if basePath != "" {
+		if err := walrus.ValidateBasePath(basePath); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if !h.checkDeployReady(c) || !h.checkDeployNFT(c, wallet) {
+		return
+	}
+
+	meta, err := h.projectStore.Get(projectID)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		log.Printf("Error loading project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load project"})
+		return
+	}
+	if meta.Wallet != wallet {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Wallet does not own this project"})
+		return
+	}
+	if !h.checkProjectType(c, meta.ProjectType) {
+		return
+	}
+
+	// The deploy runs as a queued job; output lines are buffered so a slow client doesn't stall the build.
+	logs := make(chan deployLogEvent, 256)
+	ctx := c.Request.Context()
+	opts := walrus.DeployOptions{
+		Static:     meta.SiteOptions().IsStatic(), // Deploy mode follows how the project was generated
+		CleanBuild: cleanBuild,
+		BasePath:   basePath,
+		Progress: func(stage, stream, line string) {
+			select {
+			case logs <- deployLogEvent{Stage: stage, Stream: stream, Line: line}:
+			case <-ctx.Done():
+			}
+		},
+	}
+	job := h.deployJobs.Submit(jobs.KindDeploy, projectID, func(jobCtx context.Context) (any, error) {
+		defer close(logs)
+		return h.deployTask(projectID, opts)(jobCtx)
+	})
+
+	// Builds routinely outlast the server's WriteTimeout, so lift the deadline for this response only.
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("WARN: Could not clear write deadline for deploy stream: %v", err)
+	}
+
+	log.Printf("Streaming deploy for project %s (job %s)", projectID, job.ID)
+	c.SSEvent("queued", job) // Position and estimated wait, before any build output
+	c.Stream(func(w io.Writer) bool {
+		var event deployLogEvent
+		var ok bool
+		select {
+		case event, ok = <-logs:
+		case <-ctx.Done():
+			return false
+		}
+		if ok {
+			c.SSEvent("log", event)
+			return true
+		}
+
+		final, err := h.deployJobs.Wait(ctx, job.ID)
+		if err != nil {
+			return false
+		}
+		if final.State != jobs.StateSucceeded {
+			c.SSEvent("error", gin.H{"error": final.Error})
+			return false
+		}
+		c.SSEvent("done", final.Result)
+		return false
+	})
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	aiutils "sui_ai_server/internal/ai/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkDesignSystem resolves a request's design system, either a registered one by id or an inline spec, into
+// spec. It responds 400 and returns false when both are given, the id is unknown or the spec is too large.
+// Giving neither uses no design system.
+func checkDesignSystem(c *gin.Context, id, inline string, spec *string) bool {
+	if id != "" && inline != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "designSystemId and designSystem can't be combined"})
+		return false
+	}
+	var err error
+	switch {
+	case id != "":
+		*spec, err = aiutils.LoadDesignSystem(id)
+	case inline != "":
+		*spec, err = aiutils.CheckDesignSystem(inline)
+	}
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, aiutils.ErrDesignSystemNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown design system: " + id})
+	case errors.Is(err, aiutils.ErrDesignSystemTooLarge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Error loading design system %s: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load design system"})
+	}
+	return false
+}
+
+// GET /project/design-systems
+// ListDesignSystems returns the IDs of the registered design systems (design-systems/<id>.md) that
+// generations can select with designSystemId.
+func (h *APIHandler) ListDesignSystems(c *gin.Context) {
+	ids, err := aiutils.ListDesignSystems()
+	if err != nil {
+		log.Printf("Error listing design systems: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list design systems"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"designSystems": ids})
+}
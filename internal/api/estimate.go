@@ -0,0 +1,107 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"sui_ai_server/internal/ai/prompts"
+	"sui_ai_server/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EstimateRequest takes the same generation settings as GenerateRequest, minus the wallet. The prompt preview
+// uses it too.
+type EstimateRequest struct {
+	Prompt      string   `json:"prompt" binding:"required"`
+	ProjectType string   `json:"projectType" binding:"omitempty,oneof=react static"`
+	Pages       []string `json:"pages" binding:"omitempty,max=10"`
+
+	TailwindVersion int      `json:"tailwindVersion" binding:"omitempty,oneof=3 4"`
+	TailwindPlugins []string `json:"tailwindPlugins" binding:"omitempty,max=4,dive,oneof=forms typography aspect-ratio container-queries"`
+	TemplateName    string   `json:"templateName"`
+	Locale          string   `json:"locale"`
+	Profile         string   `json:"profile"`
+
+	SystemPromptOverride string `json:"systemPromptOverride"` // See GenerateRequest
+	DesignSystemID       string `json:"designSystemId"`
+	DesignSystem         string `json:"designSystem"`
+
+	profile    types.GenerationProfile // Resolved from Profile by checkProfile
+	designSpec string                  // Resolved from DesignSystemID or DesignSystem by checkDesignSystem
+}
+
+// siteOptions converts the request's generation settings, on top of its profile's, into generator options.
+func (r EstimateRequest) siteOptions() types.SiteOptions {
+	return mergeProfile(types.SiteOptions{
+		ProjectType:     r.ProjectType,
+		Pages:           prompts.SanitizePageNames(r.Pages),
+		TailwindVersion: r.TailwindVersion,
+		TailwindPlugins: r.TailwindPlugins,
+		Template:        r.TemplateName,
+		Locale:          r.Locale,
+		SystemPrompt:    r.SystemPromptOverride,
+		DesignSystem:    r.designSpec,
+	}, r.profile)
+}
+
+// POST /project/estimate
+// EstimateGeneration counts the tokens of the prompt that /project/generate would send and prices them,
+// without calling the model, so the frontend can show a cost preview.
+func (h *APIHandler) EstimateGeneration(c *gin.Context) {
+	var req EstimateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	prompt := strings.TrimSpace(req.Prompt)
+	if prompt == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "prompt is required"})
+		return
+	}
+	if !h.checkProfile(c, req.Profile, &req.profile) || !checkTemplate(c, req.TemplateName) || !checkLocale(c, &req.Locale) || !h.checkSystemPrompt(c, &req.SystemPromptOverride) || !checkDesignSystem(c, req.DesignSystemID, req.DesignSystem, &req.designSpec) {
+		return
+	}
+
+	estimate, err := h.aiGenerator.EstimateSite(prompt, req.siteOptions())
+	if err != nil {
+		log.Printf("Error estimating generation cost: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to estimate generation"})
+		return
+	}
+	c.JSON(http.StatusOK, estimate)
+}
+
+// PromptPreviewResponse is the exact pair of messages a generation would send.
+type PromptPreviewResponse struct {
+	SystemPrompt string `json:"systemPrompt"`
+	UserPrompt   string `json:"userPrompt"`
+}
+
+// POST /project/prompt-preview (only when ALLOW_DEBUG_OUTPUT is set)
+// PreviewPrompt returns the fully rendered system and user prompts /project/generate would send for the same
+// settings (pages, Tailwind, template, locale), without calling the model, for tuning the prompt templates.
+func (h *APIHandler) PreviewPrompt(c *gin.Context) {
+	var req EstimateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	prompt := strings.TrimSpace(req.Prompt)
+	if prompt == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "prompt is required"})
+		return
+	}
+	if !h.checkProfile(c, req.Profile, &req.profile) || !checkTemplate(c, req.TemplateName) || !checkLocale(c, &req.Locale) || !h.checkSystemPrompt(c, &req.SystemPromptOverride) || !checkDesignSystem(c, req.DesignSystemID, req.DesignSystem, &req.designSpec) {
+		return
+	}
+
+	system, user, err := h.aiGenerator.PreviewSitePrompt(prompt, req.siteOptions())
+	if err != nil {
+		log.Printf("Error building prompt preview: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build prompt"})
+		return
+	}
+	c.JSON(http.StatusOK, PromptPreviewResponse{SystemPrompt: system, UserPrompt: user})
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"sui_ai_server/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contentETag is a strong ETag for body.
+func contentETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// filesETag is a strong ETag over a manifest of files: each name with the hash of its content, so it changes
+// whenever any file is added, removed, renamed or edited.
+func filesETag(files []types.GeneratedFile) string {
+	manifest := sha256.New()
+	for _, f := range files {
+		sum := sha256.Sum256([]byte(f.Content))
+		manifest.Write([]byte(f.Filename))
+		manifest.Write([]byte{0})
+		manifest.Write(sum[:])
+	}
+	return `"` + hex.EncodeToString(manifest.Sum(nil)[:16]) + `"`
+}
+
+// notModified sets the ETag header and, when the request's If-None-Match already names etag, responds 304 and
+// returns true. Clients are asked to revalidate on every use, so an edit is never served stale.
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+	for _, candidate := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/") // If-None-Match compares weakly
+		if candidate == etag || candidate == "*" {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// jsonWithETag responds 200 with v as JSON tagged with the hash of the encoded body, or 304 when the client
+// already has it.
+func jsonWithETag(c *gin.Context, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response"})
+		return
+	}
+	if notModified(c, contentETag(body)) {
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"sui_ai_server/internal/jobs"
+	"sui_ai_server/internal/sui/seal"
+	"sui_ai_server/internal/sui/walrus"
+	"sui_ai_server/internal/types"
+)
+
+// DeployForEvent queues a deploy of projectID in response to an on-chain SiteDeployed event from deployer,
+// and registers the published site's Seal access policy once the deploy succeeds. The deployer must own
+// the project, since anyone can emit the event, and the deploy must pass the same checks DeployProject makes:
+// deploys are configured, the project type is allowed and the deployer holds the deploy NFT. Otherwise the
+// event is skipped and the returned error logged by the listener.
+func (h *APIHandler) DeployForEvent(ctx context.Context, projectID, deployer string) error {
+	projectID, err := canonicalProjectID(projectID)
+	if err != nil {
+		return fmt.Errorf("invalid project ID in event: %w", err)
+	}
+	meta, err := h.projectStore.Get(projectID)
+	if err != nil {
+		return fmt.Errorf("failed to load project %s: %w", projectID, err)
+	}
+	if !strings.EqualFold(meta.Wallet, deployer) {
+		return fmt.Errorf("deployer %s does not own project %s", deployer, projectID)
+	}
+	if err := h.deployReady(); err != nil {
+		return fmt.Errorf("not deploying project %s: %w", projectID, err)
+	}
+	if !h.projectTypeAllowed(meta.ProjectType) {
+		return fmt.Errorf("not deploying project %s: project type %q is not enabled on this server", projectID, meta.ProjectType)
+	}
+	if err := h.deployNFTError(ctx, deployer); err != nil {
+		return fmt.Errorf("deployer %s may not deploy project %s: %w", deployer, projectID, err)
+	}
+
+	deploy := h.deployTask(projectID, walrus.DeployOptions{Static: meta.SiteOptions().IsStatic()})
+	job := h.deployJobs.Submit(jobs.KindDeploy, projectID, func(ctx context.Context) (any, error) {
+		result, err := deploy(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if published := result.(*types.DeployResult); published.Backend == types.DeployBackendWalrus {
+			h.registerAccessPolicy(ctx, projectID, deployer, published.SiteObjectID) // Seal policies guard Walrus sites
+		}
+		return result, nil
+	})
+	log.Printf("Queued event-triggered deploy of project %s (job %s)", projectID, job.ID)
+	return nil
+}
+
+// registerAccessPolicy registers a Seal policy for a published site: holders of the deploy NFT type when
+// deploys are gated, otherwise the deployer's wallet. Failures are logged; the site is already published.
+func (h *APIHandler) registerAccessPolicy(ctx context.Context, projectID, deployer, siteObjectID string) {
+	if h.sealClient == nil || !h.sealClient.Configured() {
+		return
+	}
+	nftCriteria := map[string]interface{}{
+		"network":    "sui-" + h.suiNetwork,
+		"groupLogic": "owner_of",
+	}
+	if h.deployNFTType != "" {
+		nftCriteria["nftType"] = h.deployNFTType
+	} else {
+		nftCriteria["wallet"] = deployer
+	}
+	policyName := fmt.Sprintf("project-%s-access", projectID)
+	if err := h.sealClient.RegisterPolicy(ctx, policyName, siteObjectID, nftCriteria); err != nil {
+		if errors.Is(err, seal.ErrSealUnauthorized) {
+			log.Printf("ERROR: Seal rejected the policy for project %s (site %s); check SEAL_API_KEY: %v", projectID, siteObjectID, err)
+			return
+		}
+		log.Printf("ERROR: Failed to register Seal policy for project %s (site %s): %v", projectID, siteObjectID, err)
+	}
+}
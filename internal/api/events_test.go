@@ -0,0 +1,42 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"sui_ai_server/internal/project"
+	"sui_ai_server/internal/types"
+
+	"github.com/google/uuid"
+)
+
+func TestDeployForEventChecks(t *testing.T) {
+	tests := []struct {
+		name        string
+		projectType string
+		gated       bool
+	}{
+		{"project type not allowed", types.ProjectTypeReact, false},
+		{"deployer without the NFT", types.ProjectTypeStatic, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, &fakeGenerator{})
+			h.allowedProjectTypes = []string{types.ProjectTypeStatic}
+			if tt.gated {
+				nftGate(t, h)
+			}
+			projectID := uuid.New().String()
+			if err := h.projectStore.Save(&project.Metadata{ID: projectID, Wallet: "0xabc", ProjectType: tt.projectType, Status: project.StatusGenerated}); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := h.DeployForEvent(context.Background(), projectID, "0xabc"); err == nil {
+				t.Fatal("DeployForEvent queued a deploy that should be skipped")
+			}
+			if h.deployJobs.Busy(projectID) || h.deployer.(*fakeDeployer).deploys.Load() != 0 {
+				t.Error("a deploy was queued for the skipped event")
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"os"
+
+	aiutils "sui_ai_server/internal/ai/utils"
+	"sui_ai_server/internal/project"
+	"sui_ai_server/internal/storage"
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProjectFilesResponse lists every source file of a project.
+type ProjectFilesResponse struct {
+	ProjectID string                `json:"projectId"`
+	Files     []types.GeneratedFile `json:"files"`
+}
+
+// GET /project/:id/files
+// GetProjectFiles returns all source files (excluding node_modules and dist) with their content. The ETag
+// covers every file's name and content, so polling editors can send If-None-Match and get 304 until one changes.
+func (h *APIHandler) GetProjectFiles(c *gin.Context) {
+	projectID, ok := validateProjectID(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	files, err := storage.LoadOrRestore(c.Request.Context(), h.fileStore, projectID, aiutils.LoadFilesDisk)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		log.Printf("Error loading files for project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load project files"})
+		return
+	}
+
+	if notModified(c, filesETag(files)) {
+		return
+	}
+	c.JSON(http.StatusOK, ProjectFilesResponse{ProjectID: projectID, Files: files})
+}
+
+// GET /project/:id/tree
+// GetProjectTree returns the project's directory tree with each file's type and size but no content, for
+// rendering a file explorer. Like the other file endpoints it has an ETag and honours If-None-Match.
+func (h *APIHandler) GetProjectTree(c *gin.Context) {
+	projectID, ok := validateProjectID(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	tree, err := aiutils.FileTreeDisk(projectID)
+	if errors.Is(err, project.ErrProjectNotFound) {
+		if _, err = storage.Restore(c.Request.Context(), h.fileStore, projectID); err == nil {
+			tree, err = aiutils.FileTreeDisk(projectID)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		log.Printf("Error reading file tree of project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read project files"})
+		return
+	}
+
+	jsonWithETag(c, tree)
+}
+
+// GET /project/:id/file?path=src/App.tsx
+// GetProjectFile returns a single file's raw content with a Content-Type matching its file type, and an ETag
+// of the content (304 for a matching If-None-Match).
+func (h *APIHandler) GetProjectFile(c *gin.Context) {
+	projectID, ok := validateProjectID(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	relPath := c.Query("path")
+	if relPath == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "path query parameter is required"})
+		return
+	}
+
+	content, err := aiutils.ReadFileDisk(projectID, relPath)
+	if errors.Is(err, project.ErrProjectNotFound) {
+		if _, err = storage.Restore(c.Request.Context(), h.fileStore, projectID); err == nil {
+			content, err = aiutils.ReadFileDisk(projectID, relPath)
+		}
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrUnsafePath):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file path"})
+		case errors.Is(err, project.ErrProjectNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		case errors.Is(err, os.ErrNotExist):
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		default:
+			log.Printf("Error reading %s from project %s: %v", relPath, projectID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		}
+		return
+	}
+
+	if notModified(c, contentETag(content)) {
+		return
+	}
+	c.Data(http.StatusOK, utils.ContentTypeForFile(relPath), content)
+}
+
+// PutProjectFilesRequest carries files to merge into a project, e.g. the output of a scoped generation.
+type PutProjectFilesRequest struct {
+	Wallet string                `json:"wallet" binding:"required"` // Must match the wallet that owns the project
+	Files  []types.GeneratedFile `json:"files" binding:"required,min=1,max=50"`
+}
+
+// PUT /project/:id/files
+// PutProjectFiles writes the given files into the project, adding new ones and overwriting same-named ones;
+// other files are left alone. The result lists which files changed, like a refine.
+func (h *APIHandler) PutProjectFiles(c *gin.Context) {
+	projectID, ok := validateProjectID(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req PutProjectFilesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	for _, f := range req.Files {
+		if _, err := utils.SafeJoin(utils.ProjectDir(projectID), f.Filename); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file path: " + f.Filename})
+			return
+		}
+	}
+
+	meta, err := h.projectStore.Get(projectID)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		log.Printf("Error loading project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load project"})
+		return
+	}
+	if meta.Wallet != req.Wallet {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Wallet does not own this project"})
+		return
+	}
+
+	// The store is synced from the working copy, so a purged one must be complete again before merging.
+	_, err = storage.LoadOrRestore(c.Request.Context(), h.fileStore, projectID, aiutils.LoadFilesDisk)
+	if err != nil && !errors.Is(err, project.ErrProjectNotFound) {
+		log.Printf("Error loading files for project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load project files"})
+		return
+	}
+
+	resp, err := h.applyRefinement(c.Request.Context(), projectID, req.Files)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
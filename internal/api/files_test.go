@@ -0,0 +1,164 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"sui_ai_server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// writeProject creates a project directory holding files (name to content) and returns its ID.
+func writeProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+	projectID := uuid.New().String()
+	for name, content := range files {
+		path := filepath.Join(utils.ProjectDir(projectID), filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return projectID
+}
+
+// getFile requests path from the project through GetProjectFile, with If-None-Match when etag is set.
+func getFile(h *APIHandler, projectID, path, etag string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/project/"+projectID+"/file?path="+path, nil)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	return serveRequest(h.GetProjectFile, req, gin.Param{Key: "id", Value: projectID})
+}
+
+func TestGetProjectFileContentTypes(t *testing.T) {
+	h := newTestHandler(t, &fakeGenerator{})
+	files := map[string]string{
+		"index.html":   "<h1>Hi</h1>",
+		"src/app.css":  "h1 { color: red; }",
+		"src/main.js":  "run()",
+		"package.json": `{"name":"site"}`,
+		"src/App.tsx":  "export default function App() {}",
+		"logo.svg":     "<svg></svg>",
+		"README.md":    "# Site",
+	}
+	projectID := writeProject(t, files)
+
+	want := map[string]string{
+		"index.html":   "text/html; charset=utf-8",
+		"src/app.css":  "text/css; charset=utf-8",
+		"src/main.js":  "text/javascript; charset=utf-8",
+		"package.json": "application/json; charset=utf-8",
+		"src/App.tsx":  "text/plain; charset=utf-8",
+		"logo.svg":     "image/svg+xml",
+		"README.md":    "text/markdown; charset=utf-8",
+	}
+	for path, contentType := range want {
+		w := getFile(h, projectID, path, "")
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want %d", path, w.Code, http.StatusOK)
+			continue
+		}
+		if got := w.Header().Get("Content-Type"); got != contentType {
+			t.Errorf("%s: Content-Type = %q, want %q", path, got, contentType)
+		}
+		if w.Body.String() != files[path] {
+			t.Errorf("%s: body = %q, want the file content", path, w.Body.String())
+		}
+	}
+}
+
+func TestGetProjectFileErrors(t *testing.T) {
+	h := newTestHandler(t, &fakeGenerator{})
+	projectID := writeProject(t, map[string]string{"index.html": "<h1>Hi</h1>"})
+
+	tests := []struct {
+		name      string
+		projectID string
+		path      string
+		want      int
+	}{
+		{"missing file", projectID, "src/missing.js", http.StatusNotFound},
+		{"directory", projectID, "src", http.StatusNotFound},
+		{"missing project", uuid.New().String(), "index.html", http.StatusNotFound},
+		{"traversal", projectID, "../../etc/passwd", http.StatusBadRequest},
+		{"absolute path", projectID, "/etc/passwd", http.StatusBadRequest},
+		{"no path", projectID, "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := getFile(h, tt.projectID, tt.path, ""); w.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGetProjectFileETag(t *testing.T) {
+	h := newTestHandler(t, &fakeGenerator{})
+	projectID := writeProject(t, map[string]string{"index.html": "<h1>Hi</h1>"})
+
+	first := getFile(h, projectID, "index.html", "")
+	etag := first.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag header")
+	}
+
+	if w := getFile(h, projectID, "index.html", etag); w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("matching If-None-Match: status = %d with %d bytes, want an empty 304", w.Code, w.Body.Len())
+	}
+	if w := getFile(h, projectID, "index.html", `"other", W/`+etag); w.Code != http.StatusNotModified {
+		t.Errorf("weak match in a list: status = %d, want 304", w.Code)
+	}
+	if w := getFile(h, projectID, "index.html", `"stale"`); w.Code != http.StatusOK {
+		t.Errorf("stale If-None-Match: status = %d, want 200", w.Code)
+	}
+
+	os.WriteFile(filepath.Join(utils.ProjectDir(projectID), "index.html"), []byte("<h1>Changed</h1>"), 0644)
+	if w := getFile(h, projectID, "index.html", etag); w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("after an edit: status = %d, ETag %s; want 200 with a new ETag", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestProjectFilesAndTreeETag(t *testing.T) {
+	h := newTestHandler(t, &fakeGenerator{})
+	projectID := writeProject(t, map[string]string{"index.html": "<h1>Hi</h1>", "src/main.js": "run()"})
+
+	for name, handler := range map[string]gin.HandlerFunc{"files": h.GetProjectFiles, "tree": h.GetProjectTree} {
+		t.Run(name, func(t *testing.T) {
+			get := func(etag string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodGet, "/project/"+projectID+"/"+name, nil)
+				if etag != "" {
+					req.Header.Set("If-None-Match", etag)
+				}
+				return serveRequest(handler, req, gin.Param{Key: "id", Value: projectID})
+			}
+
+			first := get("")
+			etag := first.Header().Get("ETag")
+			if first.Code != http.StatusOK || etag == "" {
+				t.Fatalf("status = %d, ETag %q; want 200 with an ETag", first.Code, etag)
+			}
+			if w := get(etag); w.Code != http.StatusNotModified {
+				t.Errorf("matching If-None-Match: status = %d, want 304", w.Code)
+			}
+		})
+	}
+
+	// The bulk ETag covers every file, so adding one changes it.
+	req := httptest.NewRequest(http.MethodGet, "/project/"+projectID+"/files", nil)
+	before := serveRequest(h.GetProjectFiles, req, gin.Param{Key: "id", Value: projectID}).Header().Get("ETag")
+	os.WriteFile(filepath.Join(utils.ProjectDir(projectID), "about.html"), []byte("<h1>About</h1>"), 0644)
+	req = httptest.NewRequest(http.MethodGet, "/project/"+projectID+"/files", nil)
+	req.Header.Set("If-None-Match", before)
+	if w := serveRequest(h.GetProjectFiles, req, gin.Param{Key: "id", Value: projectID}); w.Code != http.StatusOK {
+		t.Errorf("after adding a file: status = %d, want 200", w.Code)
+	}
+}
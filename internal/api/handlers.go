@@ -1,71 +1,201 @@
 package api
 
 import (
-	// "errors" // Import errors
-	// "fmt"
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	// "strings"          // Import strings
 	"sui_ai_server/internal/ai" // Import ai package
+	"sui_ai_server/internal/ai/prompts"
+	aiutils "sui_ai_server/internal/ai/utils"
+	"sui_ai_server/internal/hooks"
+	"sui_ai_server/internal/jobs"
+	"sui_ai_server/internal/project"
+	"sui_ai_server/internal/ratelimit"
+	"sui_ai_server/internal/storage"
 	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
 
 	// "sui_ai_server/db/neo4j"
-	// "sui_ai_server/rag"
-	// "sui_ai_server/sui" // NEW: Import sui interaction package
-	// "sui_ai_server/sui/seal"
+	"sui_ai_server/internal/rag"
+	"sui_ai_server/internal/sui"
+	"sui_ai_server/internal/sui/seal"
 	"sui_ai_server/internal/sui/walrus" // Make sure context is imported
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // APIHandler holds dependencies for API endpoints.
 type APIHandler struct {
-	aiGenerator *ai.Generator
+	aiGenerator     Generator
+	projectStore    *project.Store    // Project metadata (wallet, prompt, status)
+	generateLimiter ratelimit.Limiter // Per-wallet generation limit; nil disables it
 	// neo4jService   *neo4j.Service
-	walrusDeployer *walrus.Deployer
-	// sealClient     *seal.Client
-	// ragService     *rag.RAGService
-	// suiService     *sui.Service // Service for Sui interactions
-	suiNetwork string // Network name (e.g., devnet) for context
+	deployer      SiteDeployer      // Walrus or IPFS, per DEPLOY_BACKEND
+	deployJobs    *jobs.Manager     // Queue that runs every deploy within the build concurrency cap
+	batchJobs     *jobs.Manager     // Batch API generations, which mostly wait on OpenAI
+	refineJobs    *jobs.Manager     // Streamed refinements, whether followed over SSE or polled
+	fileStore     storage.FileStore // Shared copy of project files that deploys build from
+	sealClient    *seal.Client      // Optional; nil or unconfigured means Seal is not in use
+	ragService    *rag.RAGService
+	suiService    *sui.Service // Service for Sui interactions; nil when the RPC endpoint isn't configured
+	deployNFTType string       // NFT type a wallet must hold to deploy; empty allows anyone
+	suiNetwork    string       // Network name (e.g., devnet) for context
+	timeouts      Timeouts     // Server-side limits for generation and deploys
+	allowDebug    bool         // Serve debugging endpoints (ALLOW_DEBUG_OUTPUT)
+
+	profiles map[string]types.GenerationProfile // Generation presets by name (GENERATION_PROFILES)
+	hooks    *hooks.Runner                      // Post-generation checks (POST_GENERATION_HOOKS); nil runs none
+
+	allowedProjectTypes []string // Project types that may be generated and deployed (ALLOWED_PROJECT_TYPES); empty allows all
+	allowSystemPrompt   bool     // Accept systemPromptOverride on generations (ALLOW_SYSTEM_PROMPT_OVERRIDE)
+}
+
+// SiteDeployer builds a project directory and publishes it. walrus.Deployer and ipfs.Deployer implement it.
+type SiteDeployer interface {
+	Deploy(ctx context.Context, projectDir string, opts walrus.DeployOptions) (*types.DeployResult, error)
+}
+
+// Generator produces sites with the LLM. ai.Generator implements it; handler tests can substitute a fake
+// that needs no OpenAI access.
+type Generator interface {
+	GenerateSiteAndStore(ctx context.Context, userPrompt, walletAddress string, opts types.SiteOptions) (*ai.SiteResult, error)
+	GenerateSiteInto(ctx context.Context, projectID, userPrompt, walletAddress string, opts types.SiteOptions) (*ai.SiteResult, error)
+	RegenerateSite(ctx context.Context, projectID, userPrompt, walletAddress string, opts types.SiteOptions) (*ai.SiteResult, error)
+	GenerateScopedFiles(ctx context.Context, userPrompt, walletAddress, scope string, opts types.SiteOptions) (*ai.ScopedResult, error)
+	GenerateSiteBatch(ctx context.Context, projectID, userPrompt, walletAddress string, opts types.SiteOptions, progress func(ai.BatchProgress)) (*ai.SiteResult, error)
+	ResumeSite(ctx context.Context, projectID, userPrompt, walletAddress string, opts types.SiteOptions) (*ai.SiteResult, error)
+	EstimateSite(userPrompt string, opts types.SiteOptions) (*ai.Estimate, error)
+	PreviewSitePrompt(userPrompt string, opts types.SiteOptions) (system, user string, err error)
+	SupportsReferenceImages() bool
+	BudgetExceeded() bool
+	Spend() *ai.SpendStatus
+	CheckModels(ctx context.Context) map[string]string
 }
 
+// Timeouts bound long-running work server-side, independent of the client. Zero means no limit.
+type Timeouts struct {
+	Generate time.Duration // One LLM generation, including parsing and saving files
+	Deploy   time.Duration // One build and publish
+}
+
+// responseMargin is extra write time after a handler's timeouts, so the timeout error itself can still be sent.
+const responseMargin = 10 * time.Second
+
 // NewAPIHandler initializes a new API handler with its dependencies.
 func NewAPIHandler(
-	aiGen *ai.Generator,
+	aiGen Generator,
+	projectStore *project.Store,
+	generateLimiter ratelimit.Limiter, // Optional; nil disables per-wallet generation limits
 	// neo4jSvc *neo4j.Service,
-	walrusDep *walrus.Deployer,
-	// sealCli *seal.Client,
-	// ragSvc *rag.RAGService,
+	deployer SiteDeployer,
+	deployJobs *jobs.Manager,
+	batchJobs *jobs.Manager,
+	refineJobs *jobs.Manager,
+	fileStore storage.FileStore,
+	sealCli *seal.Client,
+	ragSvc *rag.RAGService,
+	timeouts Timeouts,
 	suiNet string, // Network name (e.g., devnet)
 	suiRpcUrl string, // RPC endpoint needed by SuiService
 	suinsContractAddr string, // SUINS contract address needed by SuiService
-	suinsNftType string, // SUINS NFT type needed by SuiService
+	suinsNftTypes []string, // SUINS NFT types needed by SuiService
+	suinsObjectID string, // Shared SuiNS registry object needed for subdomain transactions
+	deployNFTType string, // NFT type required to deploy; empty for open deployments
+	allowDebug bool, // Expose debugging endpoints such as the prompt preview
+	profiles map[string]types.GenerationProfile, // Validated generation presets; nil for none
+	postHooks *hooks.Runner, // Commands run over each generated project; nil for none
+	allowedProjectTypes []string, // Validated project type allowlist; empty allows every type
+	allowSystemPrompt bool, // Let generations replace the default system prompt
 ) *APIHandler {
 	// Initialize the Sui Service here
-	// suiSvc, err := sui.NewService(suiRpcUrl, suinsContractAddr, suinsNftType)
-	// if err != nil {
-	// 	// Log warning and continue - some endpoints might fail if SuiService is nil
-	// 	log.Printf("WARN: Failed to initialize Sui Service: %v. SUINS verification and potentially other Sui interactions might fail.", err)
-	// 	suiSvc = nil // Explicitly set to nil on error
-	// }
+	suiSvc, err := sui.NewService(suiRpcUrl, suinsContractAddr, suinsNftTypes, sui.WithSuinsObject(suinsObjectID))
+	if err != nil {
+		// Log warning and continue - some endpoints might fail if SuiService is nil
+		log.Printf("WARN: Failed to initialize Sui Service: %v. SUINS verification and potentially other Sui interactions might fail.", err)
+		suiSvc = nil // Explicitly set to nil on error
+	}
+	if deployNFTType != "" && suiSvc == nil {
+		log.Printf("WARN: DEPLOY_REQUIRED_NFT_TYPE is set but the Sui Service is unavailable; deploys will be refused.")
+	}
 
 	return &APIHandler{
-		aiGenerator: aiGen,
+		aiGenerator:     aiGen,
+		projectStore:    projectStore,
+		generateLimiter: generateLimiter,
 		// neo4jService:   neo4jSvc,
-		walrusDeployer: walrusDep,
-		// sealClient:     sealCli,
-		// ragService:     ragSvc,
-		// suiService:     suiSvc, // Assign the initialized (or nil) Sui Service
-		suiNetwork: suiNet,
+		deployer:      deployer,
+		deployJobs:    deployJobs,
+		batchJobs:     batchJobs,
+		refineJobs:    refineJobs,
+		fileStore:     fileStore,
+		sealClient:    sealCli,
+		ragService:    ragSvc,
+		suiService:    suiSvc, // Assign the initialized (or nil) Sui Service
+		deployNFTType: deployNFTType,
+		suiNetwork:    suiNet,
+		timeouts:      timeouts,
+		allowDebug:    allowDebug,
+		profiles:      profiles,
+		hooks:         postHooks,
+
+		allowedProjectTypes: allowedProjectTypes,
+		allowSystemPrompt:   allowSystemPrompt,
 	}
 }
 
 // --- Structs for API Requests/Responses ---
 
+// GenerateRequest can be sent as JSON, form-urlencoded, or multipart. For multipart requests the prompt may
+// instead come from an uploaded .txt/.md file in the "promptFile" field.
 type GenerateRequest struct {
-	Prompt string `json:"prompt" binding:"required"`
-	Wallet string `json:"wallet" binding:"required"` // Wallet address of the user
+	Prompt      string   `json:"prompt" form:"prompt"`
+	Wallet      string   `json:"wallet" form:"wallet" binding:"required"`                               // Wallet address of the user
+	ProjectType string   `json:"projectType" form:"projectType" binding:"omitempty,oneof=react static"` // "static" skips the npm build entirely
+	Pages       []string `json:"pages" form:"pages" binding:"omitempty,max=10"`                         // Extra pages, e.g. ["pricing", "contact"]
+	Async       string   `json:"async" form:"async" binding:"omitempty,oneof=batch"`                    // "batch" uses the cheaper, slower Batch API and returns a job
+
+	TailwindVersion int      `json:"tailwindVersion" form:"tailwindVersion" binding:"omitempty,oneof=3 4"`                                                        // Defaults to TAILWIND_VERSION
+	TailwindPlugins []string `json:"tailwindPlugins" form:"tailwindPlugins" binding:"omitempty,max=4,dive,oneof=forms typography aspect-ratio container-queries"` // Defaults to TAILWIND_PLUGINS
+	TemplateName    string   `json:"templateName" form:"templateName"`                                                                                            // Base template (templates/<name>) to adapt instead of starting from scratch
+	Locale          string   `json:"locale" form:"locale"`                                                                                                        // Language of the site's copy, e.g. "es" or "ja"; defaults to English
+	Scope           string   `json:"scope" form:"scope"`                                                                                                          // Generate only files under this path (e.g. "src/components") instead of a full project
+	// Design mockup the site should match, as an https URL or a data:image/...;base64 URL. Multipart requests may
+	// upload it as "referenceImage" instead. Requires a multimodal site model.
+	ReferenceImageURL string `json:"referenceImageUrl" form:"referenceImageUrl"`
+	Seed              *int   `json:"seed" form:"seed"`       // OpenAI sampling seed for reproducible output; requires ALLOW_DEBUG_OUTPUT
+	Profile           string `json:"profile" form:"profile"` // Operator-defined preset (GENERATION_PROFILES) filling in unset options
+	// Replaces the default system prompt (the JSON output rules are still appended); requires
+	// ALLOW_SYSTEM_PROMPT_OVERRIDE
+	SystemPromptOverride string `json:"systemPromptOverride" form:"systemPromptOverride"`
+	DesignSystemID       string `json:"designSystemId" form:"designSystemId"` // Registered design system (design-systems/<id>.md) the site must use
+	DesignSystem         string `json:"designSystem" form:"designSystem"`     // Inline design system spec, instead of designSystemId
+
+	profile    types.GenerationProfile // Resolved from Profile by checkProfile
+	designSpec string                  // Resolved from DesignSystemID or DesignSystem by checkDesignSystem
+}
+
+// siteOptions converts the request's generation settings, on top of its profile's, into generator options.
+func (r GenerateRequest) siteOptions() types.SiteOptions {
+	return mergeProfile(types.SiteOptions{
+		ProjectType:     r.ProjectType,
+		Pages:           prompts.SanitizePageNames(r.Pages),
+		TailwindVersion: r.TailwindVersion,
+		TailwindPlugins: r.TailwindPlugins,
+		Template:        r.TemplateName,
+		Locale:          r.Locale,
+		ReferenceImage:  r.ReferenceImageURL,
+		Seed:            r.Seed,
+		SystemPrompt:    r.SystemPromptOverride,
+		DesignSystem:    r.designSpec,
+	}, r.profile)
 }
 
 type GenerateResponse struct {
@@ -82,7 +212,9 @@ type DeployResponse struct {
 }
 
 type RAGQueryRequest struct {
-	Query string `json:"query" binding:"required"`
+	Query        string   `json:"query" binding:"required"`
+	Exclude      []string `json:"exclude"`                                          // Glob patterns of files to leave out of context; replaces the configured list when present
+	OutputFormat string   `json:"outputFormat" binding:"omitempty,oneof=text json"` // "json" adds the cited source files (query only); default "text"
 }
 
 type RAGQueryResponse struct { // For text answers
@@ -90,7 +222,10 @@ type RAGQueryResponse struct { // For text answers
 }
 
 type RefineCodeResponse struct { // For code change suggestions
-	Files []types.GeneratedFile `json:"files"` // Return the array of file objects
+	Files     []types.GeneratedFile `json:"files"`     // Return the array of file objects
+	Changed   []string              `json:"changed"`   // Files whose content was written
+	Unchanged []string              `json:"unchanged"` // Files identical to what was already on disk
+	Rejected  []string              `json:"rejected"`  // Files not written: unsafe paths, over their size limit, or failed writes
 }
 
 type RegisterSuinsRequest struct {
@@ -104,42 +239,588 @@ type RegisterSuinsResponse struct {
 	Message string `json:"message"`
 }
 
+type UpdatePromptRequest struct {
+	Prompt string `json:"prompt" binding:"required"`
+	Wallet string `json:"wallet" binding:"required"`                     // Must match the wallet that owns the project
+	Mode   string `json:"mode" binding:"omitempty,oneof=replace append"` // replace (default) clears old files; append overlays new files
+}
+
 // --- API Handlers ---
 
-// POST /project/generate
+// POST /project/generate[?includeFiles=true]
+// includeFiles=true adds the generated files to the response, saving a follow-up GET /project/:id/files.
+// With async "batch" the generation is queued instead and 202 is returned (see generateSiteBatch). With a
+// scope only the requested files are generated and returned, without creating a project (see generateScoped).
 func (h *APIHandler) GenerateSite(c *gin.Context) {
 	var req GenerateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindGenerateRequest(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
 		return
 	}
+	includeFiles := false
+	if raw := c.Query("includeFiles"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "includeFiles must be true or false"})
+			return
+		}
+		includeFiles = v
+	}
+	if !h.checkProfile(c, req.Profile, &req.profile) || !h.checkProjectType(c, req.siteOptions().ProjectType) || !checkTemplate(c, req.TemplateName) || !checkLocale(c, &req.Locale) || !h.checkReferenceImage(c, req.ReferenceImageURL) || !h.checkSystemPrompt(c, &req.SystemPromptOverride) || !checkDesignSystem(c, req.DesignSystemID, req.DesignSystem, &req.designSpec) || !h.checkBudget(c) {
+		return
+	}
+	if req.Seed != nil && !h.allowDebug {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "seed is only accepted when ALLOW_DEBUG_OUTPUT is enabled"})
+		return
+	}
+	scope, err := prompts.NormalizeScope(req.Scope)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if scope != "" {
+		if req.Async != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "scope can't be combined with async generation"})
+			return
+		}
+		req.Scope = scope
+		h.generateScoped(c, req)
+		return
+	}
+	if req.Async == "batch" {
+		h.generateSiteBatch(c, req)
+		return
+	}
 
 	// Optional: Basic validation for wallet address format?
 	// if !isValidSuiAddress(req.Wallet) { ... }
 
 	log.Printf("Received generation request for wallet %s", req.Wallet)
 
-	projectID, err := h.aiGenerator.GenerateSiteAndStore(c.Request.Context(), req.Prompt, req.Wallet)
+	extendWriteDeadline(c, h.timeouts.Generate+h.timeouts.Deploy+responseMargin)
+	genCtx, cancel := withTimeout(c.Request.Context(), h.timeouts.Generate)
+	defer cancel()
+
+	opts := req.siteOptions()
+	result, err := h.aiGenerator.GenerateSiteAndStore(genCtx, req.Prompt, req.Wallet, opts)
 	if err != nil {
 		log.Printf("Error generating site for wallet %s: %v", req.Wallet, err)
+		var resumable *ai.ResumableError
+		if errors.As(err, &resumable) {
+			// Record the project so its owner can resume it rather than generate from scratch.
+			if err := h.projectStore.Save(projectMetadata(resumable.ProjectID, req.Wallet, req.Prompt, opts, project.StatusFailed)); err != nil {
+				log.Printf("WARN: Failed to save metadata for resumable project %s: %v", resumable.ProjectID, err)
+			}
+		}
+		if respondRateLimited(c, err) || respondTimedOut(c, err, "Site generation") || respondUnusableOutput(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate site"})
 		return
 	}
 
+	projectID := result.ProjectID
+	setGenerationLog(c, result.Model, result.TotalTokens)
 	log.Printf("Site generation successful for wallet %s. Project ID: %s", req.Wallet, projectID)
 
-	// Move the response after we have both projectID and cid
-	cid, err := h.walrusDeployer.DeployFiles(c.Request.Context())
-	if err != nil {
-		log.Printf("Error deploying project %s to Walrus: %v", projectID, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deploy project to Walrus"})
+	meta := projectMetadata(projectID, req.Wallet, req.Prompt, opts, project.StatusGenerated)
+	meta.Model = result.Model
+	if err := h.projectStore.Save(meta); err != nil {
+		// Files are on disk; losing metadata only affects later prompt updates, so keep going.
+		log.Printf("WARN: Failed to save metadata for project %s: %v", projectID, err)
+	}
+	hookResults, blocked := h.runHooks(c.Request.Context(), projectID, opts)
+	if err := h.storeFiles(c.Request.Context(), projectID); err != nil {
+		log.Printf("Error storing files for project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store generated files"})
+		return
+	}
+	if blocked {
+		respondHooksBlocked(c, projectID, hookResults)
 		return
 	}
-	log.Printf("Project %s deployed successfully. CID: %s", projectID, cid)
 
-	// Return both projectID and cid in the response
-	c.JSON(http.StatusCreated, gin.H{
+	resp := gin.H{
 		"projectID": projectID,
-		"cid":       cid,
+		"model":     result.Model,
+	}
+	if err := h.autoDeployError(c.Request.Context(), req.Wallet); err != nil {
+		// Generation-only servers, and wallets without the deploy NFT, still get the project; it can be
+		// deployed once deploys are configured or the wallet qualifies.
+		log.Printf("Not deploying project %s: %v", projectID, err)
+		resp["deployError"] = autoDeployMessage(err)
+	} else {
+		// The deploy waits its turn in the build queue.
+		queued := h.submitDeploy(projectID, walrus.DeployOptions{Static: opts.IsStatic()})
+		job, err := h.deployJobs.Wait(c.Request.Context(), queued.ID)
+		if err != nil {
+			log.Printf("Stopped waiting for deploy of project %s (job %s): %v", projectID, queued.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deploy project to Walrus"})
+			return
+		}
+		if job.State == jobs.StateCancelled {
+			c.JSON(http.StatusConflict, gin.H{"error": "Deploy was cancelled", "projectID": projectID, "jobId": queued.ID})
+			return
+		}
+		if job.State != jobs.StateSucceeded {
+			if respondTimedOut(c, job.Err(), "Deploy") {
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to deploy project to Walrus"})
+			return
+		}
+		deployed := job.Result.(*types.DeployResult)
+		resp["cid"] = deployed.SiteID
+		if deployed.Artifacts != nil {
+			resp["artifacts"] = deployed.Artifacts
+		}
+	}
+	if includeFiles {
+		resp["files"] = hookedFiles(projectID, result.Files, hookResults)
+	}
+	if len(hookResults) > 0 {
+		resp["hooks"] = hookResults
+	}
+	if len(result.SecretFindings) > 0 {
+		resp["secretFindings"] = result.SecretFindings
+		resp["warning"] = "Possible secrets were detected in the generated files; review the listed files before sharing the project."
+	}
+	if len(result.UnresolvedImports) > 0 {
+		resp["unresolvedImports"] = result.UnresolvedImports
+	}
+	if h.allowDebug {
+		resp["parseStrategy"] = result.ParseStrategy
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+// PUT /project/:id/prompt
+// UpdateProjectPrompt stores a revised prompt and re-scaffolds the project from it under the same project ID.
+// Unlike refine, this is a full regeneration; the project's identity and SUINS mapping are kept.
+func (h *APIHandler) UpdateProjectPrompt(c *gin.Context) {
+	projectID, ok := validateProjectID(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req UpdatePromptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if req.Mode == "" {
+		req.Mode = "replace"
+	}
+
+	meta, err := h.projectStore.Get(projectID)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		log.Printf("Error loading project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load project"})
+		return
+	}
+	if meta.Wallet != req.Wallet {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Wallet does not own this project"})
+		return
+	}
+	if !h.checkProjectType(c, meta.ProjectType) || !h.checkBudget(c) {
+		return
+	}
+
+	meta, err = h.projectStore.Update(projectID, func(m *project.Metadata) {
+		m.Prompt = req.Prompt
+		m.Status = project.StatusGenerating
+	})
+	if err != nil {
+		log.Printf("Error updating prompt for project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
+		return
+	}
+
+	// Replace mode clears the previous files only once the new ones have been generated (see RegenerateSite).
+	regenerate := h.aiGenerator.RegenerateSite
+	if req.Mode != "replace" {
+		regenerate = h.aiGenerator.GenerateSiteInto
+		// A purged working copy must be restored first, or files the model leaves out would drop from the store.
+		if _, err := storage.LoadOrRestore(c.Request.Context(), h.fileStore, projectID, aiutils.LoadFilesDisk); err != nil && !errors.Is(err, project.ErrProjectNotFound) {
+			log.Printf("Error restoring files for project %s: %v", projectID, err)
+			h.setProjectStatus(projectID, project.StatusFailed, "")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load previous project files"})
+			return
+		}
+	}
+
+	log.Printf("Regenerating project %s (%s mode) for wallet %s", projectID, req.Mode, req.Wallet)
+	extendWriteDeadline(c, h.timeouts.Generate+responseMargin)
+	genCtx, cancel := withTimeout(c.Request.Context(), h.timeouts.Generate)
+	defer cancel()
+	result, err := regenerate(genCtx, projectID, req.Prompt, req.Wallet, meta.SiteOptions())
+	if err != nil {
+		log.Printf("Error regenerating project %s: %v", projectID, err)
+		h.setProjectStatus(projectID, project.StatusFailed, "")
+		if respondRateLimited(c, err) || respondTimedOut(c, err, "Site generation") || respondUnusableOutput(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to regenerate site"})
+		return
+	}
+	if err := h.storeFiles(c.Request.Context(), projectID); err != nil {
+		log.Printf("Error storing files for project %s: %v", projectID, err)
+		h.setProjectStatus(projectID, project.StatusFailed, "")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store generated files"})
+		return
+	}
+
+	meta, err = h.projectStore.Update(projectID, func(m *project.Metadata) {
+		m.Status = project.StatusGenerated
+		m.Model = result.Model
+	})
+	if err != nil {
+		log.Printf("Error saving status for project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Project regenerated but status could not be saved"})
+		return
+	}
+
+	if len(result.SecretFindings) > 0 || len(result.UnresolvedImports) > 0 {
+		resp := gin.H{"project": meta}
+		if len(result.SecretFindings) > 0 {
+			resp["secretFindings"] = result.SecretFindings
+		}
+		if len(result.UnresolvedImports) > 0 {
+			resp["unresolvedImports"] = result.UnresolvedImports
+		}
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+	c.JSON(http.StatusOK, meta)
+}
+
+// POST /rag/:projectId/query
+// QueryProjectRAG answers a question about the project's code using its most relevant files as context. With
+// outputFormat "json" the response is a rag.CitedAnswer, adding the files the answer cites.
+func (h *APIHandler) QueryProjectRAG(c *gin.Context) {
+	projectID, ok := validateProjectID(c, c.Param("projectId"))
+	if !ok {
+		return
+	}
+
+	var req RAGQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if err := rag.ValidateExcludePatterns(req.Exclude); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var resp any
+	var err error
+	if req.OutputFormat == "json" {
+		resp, err = h.ragService.QueryProjectCited(h.ownerContext(c, projectID), projectID, req.Query, req.Exclude)
+	} else {
+		var answer string
+		answer, err = h.ragService.QueryProject(h.ownerContext(c, projectID), projectID, req.Query, req.Exclude)
+		resp = RAGQueryResponse{Answer: answer}
+	}
+	if err != nil {
+		log.Printf("Error querying project %s: %v", projectID, err)
+		if errors.Is(err, project.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		if respondRateLimited(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to answer query"})
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// POST /rag/:projectId/refine
+// RefineProjectCode asks the LLM for targeted code changes and applies them to the project's files.
+func (h *APIHandler) RefineProjectCode(c *gin.Context) {
+	projectID, ok := validateProjectID(c, c.Param("projectId"))
+	if !ok {
+		return
+	}
+
+	var req RAGQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if err := rag.ValidateExcludePatterns(req.Exclude); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	changedFiles, err := h.ragService.RefineProjectCode(h.ownerContext(c, projectID), projectID, req.Query, req.Exclude)
+	if err != nil {
+		log.Printf("Error refining project %s: %v", projectID, err)
+		if errors.Is(err, project.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		if respondRateLimited(c, err) {
+			return
+		}
+		if errors.Is(err, ai.ErrTruncatedResponse) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": truncatedChangesMessage})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate code changes"})
+		return
+	}
+
+	resp, err := h.applyRefinement(c.Request.Context(), projectID, changedFiles)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// applyRefinement writes the suggested files to the project and syncs them to the file store. Its errors are
+// client-facing messages; the underlying cause is logged.
+func (h *APIHandler) applyRefinement(ctx context.Context, projectID string, changedFiles []types.GeneratedFile) (RefineCodeResponse, error) {
+	resp := RefineCodeResponse{Files: changedFiles, Changed: []string{}, Unchanged: []string{}, Rejected: []string{}}
+	if len(changedFiles) > 0 {
+		written, unchanged, rejected, err := aiutils.ApplyFilesDisk(ctx, projectID, changedFiles)
+		if err != nil {
+			log.Printf("Error applying changes to project %s: %v", projectID, err)
+			return resp, errors.New("Failed to apply code changes")
+		}
+		resp.Changed = append(resp.Changed, written...)
+		resp.Unchanged = append(resp.Unchanged, unchanged...)
+		resp.Rejected = append(resp.Rejected, rejected...)
+	}
+	if len(resp.Changed) > 0 {
+		if err := h.storeFiles(ctx, projectID); err != nil {
+			log.Printf("Error storing files for project %s: %v", projectID, err)
+			return resp, errors.New("Failed to store code changes")
+		}
+	}
+	return resp, nil
+}
+
+// ownerContext returns the request context tagged with the project owner's wallet as the OpenAI end user
+// (see ai.WithEndUser). Projects without metadata are left untagged.
+func (h *APIHandler) ownerContext(c *gin.Context, projectID string) context.Context {
+	return h.withOwner(c.Request.Context(), projectID)
+}
+
+// withOwner is ownerContext for any ctx, e.g. a job's.
+func (h *APIHandler) withOwner(ctx context.Context, projectID string) context.Context {
+	meta, err := h.projectStore.Get(projectID)
+	if err != nil {
+		return ctx
+	}
+	return ai.WithEndUser(ctx, meta.Wallet)
+}
+
+// storeFiles copies the project's files from its local working directory into the file store, so any
+// instance can deploy them.
+func (h *APIHandler) storeFiles(ctx context.Context, projectID string) error {
+	files, err := aiutils.LoadFilesDisk(projectID)
+	if err != nil {
+		return err
+	}
+	return h.fileStore.Save(ctx, projectID, files)
+}
+
+// setProjectStatus records a lifecycle change, logging rather than failing the request if the store is unavailable.
+// An empty siteObjectID leaves the previously recorded deployment untouched.
+func (h *APIHandler) setProjectStatus(projectID string, status project.Status, siteObjectID string) {
+	_, err := h.projectStore.Update(projectID, func(m *project.Metadata) {
+		m.Status = status
+		if siteObjectID != "" {
+			m.SiteObjectID = siteObjectID
+		}
 	})
+	if err != nil {
+		log.Printf("WARN: Failed to record status %s for project %s: %v", status, projectID, err)
+	}
+}
+
+// recordDeploy marks the project deployed and remembers where: the Walrus site object, or the IPFS CID.
+func (h *APIHandler) recordDeploy(projectID string, result *types.DeployResult) {
+	_, err := h.projectStore.Update(projectID, func(m *project.Metadata) {
+		m.Status = project.StatusDeployed
+		switch result.Backend {
+		case types.DeployBackendIPFS:
+			m.SiteCID = result.SiteID
+		default:
+			m.SiteObjectID = result.SiteID
+		}
+	})
+	if err != nil {
+		log.Printf("WARN: Failed to record deploy of project %s: %v", projectID, err)
+	}
+}
+
+// respondRateLimited writes a 429 with a Retry-After header if err came from an upstream rate limit.
+// It returns false (writing nothing) for any other error.
+func respondRateLimited(c *gin.Context, err error) bool {
+	var rateLimitErr *utils.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		return false
+	}
+	retryAfter := setRetryAfter(c, rateLimitErr.RetryAfter)
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": "AI provider is rate limiting requests, please retry later", "retryAfter": retryAfter})
+	return true
+}
+
+// checkTemplate responds 400 and returns false when a base template was requested that doesn't exist.
+func checkTemplate(c *gin.Context, name string) bool {
+	if name == "" {
+		return true
+	}
+	if _, err := aiutils.LoadTemplate(name); err != nil {
+		if errors.Is(err, aiutils.ErrTemplateNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown template: " + name})
+		} else {
+			log.Printf("Error loading template %s: %v", name, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load template"})
+		}
+		return false
+	}
+	return true
+}
+
+// validateProjectID canonicalises a project ID taken from the request, responding 400 and returning false unless
+// it is a UUID (the only IDs the server creates). IDs end up in filesystem paths, so this keeps separators and
+// traversal out before any file operation sees them.
+func validateProjectID(c *gin.Context, raw string) (string, bool) {
+	id, err := canonicalProjectID(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return "", false
+	}
+	return id, true
+}
+
+// canonicalProjectID returns raw in the lowercase form project IDs are stored under, or an error if it isn't a
+// UUID in the standard 36-character form.
+func canonicalProjectID(raw string) (string, error) {
+	id, err := uuid.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if len(raw) != 36 {
+		return "", fmt.Errorf("project ID %q is not in canonical UUID form", raw)
+	}
+	return id.String(), nil
+}
+
+// checkLocale canonicalises *locale in place, responding 400 and returning false when it isn't supported.
+func checkLocale(c *gin.Context, locale *string) bool {
+	normalized, err := prompts.NormalizeLocale(*locale)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown locale: " + *locale})
+		return false
+	}
+	*locale = normalized
+	return true
+}
+
+// checkReferenceImage responds 400 and returns false when a reference image was sent but the site model can't
+// read images.
+func (h *APIHandler) checkReferenceImage(c *gin.Context, image string) bool {
+	if image == "" || h.aiGenerator.SupportsReferenceImages() {
+		return true
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Reference images are not supported: " + ai.ErrModelNotMultimodal.Error()})
+	return false
+}
+
+// checkBudget responds 402 and returns false while MONTHLY_BUDGET_USD is used up for the current month.
+func (h *APIHandler) checkBudget(c *gin.Context) bool {
+	if !h.aiGenerator.BudgetExceeded() {
+		return true
+	}
+	spend := h.aiGenerator.Spend()
+	c.JSON(http.StatusPaymentRequired, gin.H{
+		"error":    "Monthly AI budget exceeded; generation is unavailable until the budget resets",
+		"resetsAt": spend.ResetsAt,
+	})
+	return false
+}
+
+// respondUnusableOutput sends 422 when generation succeeded at the provider but produced nothing usable,
+// so clients change the prompt instead of retrying a server error. When the output was kept for resuming,
+// the response names the project to pass to POST /project/:id/resume. It reports whether it responded.
+func respondUnusableOutput(c *gin.Context, err error) bool {
+	var message string
+	switch {
+	case errors.Is(err, ai.ErrNoFilesGenerated):
+		message = "No files were generated for this prompt; try describing the site in more detail"
+	case errors.Is(err, ai.ErrTruncatedResponse):
+		message = "The model's answer was cut off at its output limit; resume the project or ask for a smaller site"
+	case errors.Is(err, ai.ErrResponseTooLarge):
+		message = "The model's response was too large to process; try a narrower prompt"
+	case errors.Is(err, ai.ErrUnparseableOutput):
+		message = "The generated output could not be read as site files; try again or rephrase the prompt"
+	default:
+		return false
+	}
+	body := gin.H{"error": message}
+	var resumable *ai.ResumableError
+	if errors.As(err, &resumable) {
+		body["projectID"] = resumable.ProjectID
+		body["resumable"] = true
+	}
+	c.JSON(http.StatusUnprocessableEntity, body)
+	return true
+}
+
+// projectMetadata is the initial record of a project generated from prompt with opts.
+func projectMetadata(projectID, wallet, prompt string, opts types.SiteOptions, status project.Status) *project.Metadata {
+	return &project.Metadata{ID: projectID, Wallet: wallet, Prompt: prompt, ProjectType: opts.ProjectType, Pages: opts.Pages,
+		TailwindVersion: opts.TailwindVersion, TailwindPlugins: opts.TailwindPlugins, TemplateName: opts.Template, Locale: opts.Locale,
+		Theme: opts.Theme, Instructions: opts.Instructions, Status: status}
+}
+
+// setRetryAfter sets the Retry-After header (in whole seconds, at least 1) and returns the value used.
+func setRetryAfter(c *gin.Context, d time.Duration) int {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	return seconds
+}
+
+// withTimeout bounds ctx by d; a non-positive d leaves ctx unbounded.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// respondTimedOut writes a 504 if err is (or was caused by) a server-side deadline.
+func respondTimedOut(c *gin.Context, err error, what string) bool {
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	c.JSON(http.StatusGatewayTimeout, gin.H{"error": what + " timed out"})
+	return true
+}
+
+// extendWriteDeadline lets this response be written up to d from now, past the server's default WriteTimeout,
+// so the handler's own timeouts are the ones that apply.
+func extendWriteDeadline(c *gin.Context, d time.Duration) {
+	deadline := time.Time{} // No deadline when the handler itself is unbounded
+	if d > 0 {
+		deadline = time.Now().Add(d)
+	}
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(deadline); err != nil {
+		log.Printf("WARN: Could not extend write deadline: %v", err)
+	}
 }
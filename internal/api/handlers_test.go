@@ -0,0 +1,393 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"sui_ai_server/internal/ai"
+	aiutils "sui_ai_server/internal/ai/utils"
+	"sui_ai_server/internal/jobs"
+	"sui_ai_server/internal/project"
+	"sui_ai_server/internal/storage"
+	"sui_ai_server/internal/sui/walrus"
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// fakeGenerator is a Generator that returns canned results instead of calling OpenAI. Site generations write
+// files into the project directory like the real generator.
+type fakeGenerator struct {
+	mu    sync.Mutex
+	calls int
+	files []types.GeneratedFile
+	err   error // Returned by every generation when set
+}
+
+func (g *fakeGenerator) generate(projectID, model string) (*ai.SiteResult, error) {
+	g.mu.Lock()
+	g.calls++
+	g.mu.Unlock()
+	if g.err != nil {
+		return nil, g.err
+	}
+	dir := utils.ProjectDir(projectID)
+	for _, f := range g.files {
+		path := filepath.Join(dir, f.Filename)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(f.Content), 0644); err != nil {
+			return nil, err
+		}
+	}
+	return &ai.SiteResult{ProjectID: projectID, Model: model, Files: g.files}, nil
+}
+
+func (g *fakeGenerator) callCount() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.calls
+}
+
+func (g *fakeGenerator) GenerateSiteAndStore(ctx context.Context, userPrompt, walletAddress string, opts types.SiteOptions) (*ai.SiteResult, error) {
+	return g.generate(uuid.New().String(), "fake-model")
+}
+
+func (g *fakeGenerator) GenerateSiteInto(ctx context.Context, projectID, userPrompt, walletAddress string, opts types.SiteOptions) (*ai.SiteResult, error) {
+	return g.generate(projectID, "fake-model")
+}
+
+func (g *fakeGenerator) RegenerateSite(ctx context.Context, projectID, userPrompt, walletAddress string, opts types.SiteOptions) (*ai.SiteResult, error) {
+	if g.err == nil {
+		if err := aiutils.ClearProjectFiles(projectID); err != nil {
+			return nil, err
+		}
+	}
+	return g.generate(projectID, "fake-model")
+}
+
+func (g *fakeGenerator) GenerateScopedFiles(ctx context.Context, userPrompt, walletAddress, scope string, opts types.SiteOptions) (*ai.ScopedResult, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (g *fakeGenerator) GenerateSiteBatch(ctx context.Context, projectID, userPrompt, walletAddress string, opts types.SiteOptions, progress func(ai.BatchProgress)) (*ai.SiteResult, error) {
+	return g.generate(projectID, "fake-model")
+}
+
+func (g *fakeGenerator) ResumeSite(ctx context.Context, projectID, userPrompt, walletAddress string, opts types.SiteOptions) (*ai.SiteResult, error) {
+	return g.generate(projectID, "fake-model")
+}
+
+func (g *fakeGenerator) EstimateSite(userPrompt string, opts types.SiteOptions) (*ai.Estimate, error) {
+	return &ai.Estimate{}, nil
+}
+
+func (g *fakeGenerator) PreviewSitePrompt(userPrompt string, opts types.SiteOptions) (string, string, error) {
+	return "system", userPrompt, nil
+}
+
+func (g *fakeGenerator) SupportsReferenceImages() bool                 { return false }
+func (g *fakeGenerator) BudgetExceeded() bool                          { return false }
+func (g *fakeGenerator) Spend() *ai.SpendStatus                        { return nil }
+func (g *fakeGenerator) CheckModels(context.Context) map[string]string { return nil }
+
+// fakeDeployer publishes nothing and reports a fixed site ID, recording the deploys it was asked for.
+type fakeDeployer struct {
+	siteID  string
+	deploys atomic.Int32
+	mu      sync.Mutex
+	opts    walrus.DeployOptions // Options of the latest deploy
+}
+
+func (d *fakeDeployer) Deploy(ctx context.Context, projectDir string, opts walrus.DeployOptions) (*types.DeployResult, error) {
+	d.deploys.Add(1)
+	d.mu.Lock()
+	d.opts = opts
+	d.mu.Unlock()
+	return &types.DeployResult{Backend: types.DeployBackendWalrus, SiteID: d.siteID}, nil
+}
+
+// inTempWorkDir runs the test from a fresh directory, so utils.WorkDir (a relative path) lands in it.
+func inTempWorkDir(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// newTestHandler returns a handler backed by gen, a local file store and a fake deployer, with its job
+// managers running until the test ends.
+func newTestHandler(t *testing.T, gen Generator) *APIHandler {
+	t.Helper()
+	inTempWorkDir(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	deployJobs, batchJobs, refineJobs := jobs.NewManager(1), jobs.NewManager(1), jobs.NewManager(1)
+	for _, m := range []*jobs.Manager{deployJobs, batchJobs, refineJobs} {
+		m.Run(ctx)
+	}
+	return &APIHandler{
+		aiGenerator:  gen,
+		projectStore: project.NewStore(utils.WorkDir),
+		deployer:     &fakeDeployer{siteID: "0xsite"},
+		deployJobs:   deployJobs,
+		batchJobs:    batchJobs,
+		refineJobs:   refineJobs,
+		fileStore:    storage.NewLocalStore(),
+	}
+}
+
+// serve sends a JSON request to handler and returns the recorded response.
+func serve(handler gin.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+	return serveRequest(handler, httptest.NewRequest(method, target, strings.NewReader(body)))
+}
+
+// serveRequest sends req to handler with the given path parameters and returns the recorded response. JSON
+// is assumed unless req has a Content-Type.
+func serveRequest(handler gin.HandlerFunc, req *http.Request, params ...gin.Param) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.Params = params
+	handler(c)
+	c.Writer.WriteHeaderNow() // As the engine does after the handlers, for responses without a body (304)
+	return w
+}
+
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response is not JSON: %v (%s)", err, w.Body.String())
+	}
+	return body
+}
+
+func TestGenerateSiteSuccess(t *testing.T) {
+	gen := &fakeGenerator{files: []types.GeneratedFile{
+		{Filename: "index.html", Type: "html", Content: "<h1>Hi</h1>"},
+	}}
+	h := newTestHandler(t, gen)
+
+	w := serve(h.GenerateSite, http.MethodPost, "/project/generate?includeFiles=true",
+		`{"prompt":"A landing page","wallet":"0xabc","projectType":"static"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d (%s)", w.Code, http.StatusCreated, w.Body.String())
+	}
+	body := decodeBody(t, w)
+	projectID, _ := body["projectID"].(string)
+	if uuid.Validate(projectID) != nil {
+		t.Fatalf("projectID = %q, want a UUID", projectID)
+	}
+	if body["cid"] != "0xsite" {
+		t.Errorf("cid = %v, want 0xsite", body["cid"])
+	}
+	if files, _ := body["files"].([]any); len(files) != 1 {
+		t.Errorf("files = %v, want the one generated file", body["files"])
+	}
+	meta, err := h.projectStore.Get(projectID)
+	if err != nil {
+		t.Fatalf("metadata not saved: %v", err)
+	}
+	if meta.Wallet != "0xabc" || meta.Status != project.StatusDeployed {
+		t.Errorf("metadata = wallet %q status %q, want 0xabc deployed", meta.Wallet, meta.Status)
+	}
+}
+
+func TestGenerateSiteValidationError(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		body   string
+	}{
+		{"missing wallet", "/project/generate", `{"prompt":"A landing page"}`},
+		{"missing prompt", "/project/generate", `{"wallet":"0xabc"}`},
+		{"unknown project type", "/project/generate", `{"prompt":"A landing page","wallet":"0xabc","projectType":"php"}`},
+		{"bad includeFiles", "/project/generate?includeFiles=maybe", `{"prompt":"A landing page","wallet":"0xabc"}`},
+		{"malformed JSON", "/project/generate", `{"prompt":`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := &fakeGenerator{}
+			h := newTestHandler(t, gen)
+			w := serve(h.GenerateSite, http.MethodPost, tt.target, tt.body)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d (%s)", w.Code, http.StatusBadRequest, w.Body.String())
+			}
+			if n := gen.callCount(); n != 0 {
+				t.Errorf("generator called %d times, want 0", n)
+			}
+		})
+	}
+}
+
+func TestGenerateSiteGenerationError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"provider failure", errors.New("connection reset"), http.StatusInternalServerError},
+		{"rate limited", &utils.RateLimitError{RetryAfter: 0, Err: errors.New("429")}, http.StatusTooManyRequests},
+		{"timed out", context.DeadlineExceeded, http.StatusGatewayTimeout},
+		{"no files", ai.ErrNoFilesGenerated, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := &fakeGenerator{err: tt.err}
+			h := newTestHandler(t, gen)
+			w := serve(h.GenerateSite, http.MethodPost, "/project/generate", `{"prompt":"A landing page","wallet":"0xabc"}`)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+			}
+			if n := gen.callCount(); n != 1 {
+				t.Errorf("generator called %d times, want 1", n)
+			}
+			if _, ok := decodeBody(t, w)["error"]; !ok {
+				t.Error("response has no error message")
+			}
+		})
+	}
+}
+
+func TestGenerateSiteUnusableOutput(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"no files", fmt.Errorf("generation: %w", ai.ErrNoFilesGenerated), http.StatusUnprocessableEntity},
+		{"parse failure", fmt.Errorf("generation: %w", ai.ErrUnparseableOutput), http.StatusUnprocessableEntity},
+		{"truncated", &ai.ResumableError{ProjectID: "p1", Err: ai.ErrTruncatedResponse}, http.StatusUnprocessableEntity},
+		{"provider error", &openai.APIError{HTTPStatusCode: http.StatusBadGateway, Message: "upstream"}, http.StatusInternalServerError},
+	}
+	messages := make(map[string]string)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, &fakeGenerator{err: tt.err})
+			w := serve(h.GenerateSite, http.MethodPost, "/project/generate", `{"prompt":"A landing page","wallet":"0xabc"}`)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			body := decodeBody(t, w)
+			message, _ := body["error"].(string)
+			for other, seen := range messages {
+				if seen == message {
+					t.Errorf("%s has the same message as %s: %q", tt.name, other, message)
+				}
+			}
+			messages[tt.name] = message
+			if _, resumable := tt.err.(*ai.ResumableError); resumable != (body["resumable"] == true) {
+				t.Errorf("resumable = %v, want %v", body["resumable"], resumable)
+			}
+		})
+	}
+}
+
+func TestValidateProjectID(t *testing.T) {
+	id := uuid.New().String()
+	tests := []struct {
+		name string
+		raw  string
+		want string // Empty when the ID must be rejected
+	}{
+		{"uuid", id, id},
+		{"uppercase uuid", strings.ToUpper(id), id},
+		{"traversal", "../../etc/passwd", ""},
+		{"encoded traversal", "..%2F..%2Fetc", ""},
+		{"uuid with a path", id + "/../other", ""},
+		{"braced uuid", "{" + id + "}", ""},
+		{"urn uuid", "urn:uuid:" + id, ""},
+		{"junk", "not-a-project", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			var ok bool
+			w := serveRequest(func(c *gin.Context) { got, ok = validateProjectID(c, tt.raw) }, httptest.NewRequest(http.MethodGet, "/", nil))
+			if tt.want == "" {
+				if ok || w.Code != http.StatusBadRequest {
+					t.Errorf("validateProjectID(%q) = %q, %v with status %d; want rejected with 400", tt.raw, got, ok, w.Code)
+				}
+				return
+			}
+			if !ok || got != tt.want {
+				t.Errorf("validateProjectID(%q) = %q, %v; want %q", tt.raw, got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectHandlersRejectInvalidIDs(t *testing.T) {
+	h := newTestHandler(t, &fakeGenerator{})
+	for _, id := range []string{"..", "../../etc/passwd", "junk"} {
+		req := httptest.NewRequest(http.MethodGet, "/project/x/file?path=index.html", nil)
+		if w := serveRequest(h.GetProjectFile, req, gin.Param{Key: "id", Value: id}); w.Code != http.StatusBadRequest {
+			t.Errorf("GetProjectFile with ID %q: status = %d, want %d", id, w.Code, http.StatusBadRequest)
+		}
+		req = httptest.NewRequest(http.MethodPost, "/rag/x/query", strings.NewReader(`{"query":"what is this?"}`))
+		if w := serveRequest(h.QueryProjectRAG, req, gin.Param{Key: "projectId", Value: id}); w.Code != http.StatusBadRequest {
+			t.Errorf("QueryProjectRAG with ID %q: status = %d, want %d", id, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestUpdatePromptReplace(t *testing.T) {
+	tests := []struct {
+		name      string
+		genErr    error
+		want      int
+		wantFiles map[string]bool // File to whether it should exist afterwards
+	}{
+		{"generation fails", errors.New("model unavailable"), http.StatusInternalServerError,
+			map[string]bool{"old.html": true, "index.html": false}},
+		{"generation succeeds", nil, http.StatusOK,
+			map[string]bool{"old.html": false, "index.html": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := &fakeGenerator{files: []types.GeneratedFile{{Filename: "index.html", Content: "<h1>New</h1>"}}, err: tt.genErr}
+			h := newTestHandler(t, gen)
+			projectID := writeProject(t, map[string]string{"old.html": "<h1>Old</h1>"})
+			if err := h.projectStore.Save(&project.Metadata{ID: projectID, Wallet: "0xabc", Status: project.StatusGenerated}); err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodPut, "/project/"+projectID+"/prompt", strings.NewReader(`{"prompt":"A new site","wallet":"0xabc"}`))
+			w := serveRequest(h.UpdateProjectPrompt, req, gin.Param{Key: "id", Value: projectID})
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+			}
+			for name, want := range tt.wantFiles {
+				_, err := os.Stat(filepath.Join(utils.ProjectDir(projectID), name))
+				if exists := err == nil; exists != want {
+					t.Errorf("%s exists = %v, want %v", name, exists, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"sui_ai_server/internal/ai"
+	"sui_ai_server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// healthCheckTimeout bounds each dependency check so /health stays fast when a dependency hangs.
+const healthCheckTimeout = 3 * time.Second
+
+// GET /health
+// Health reports that the server is up, along with the state of optional dependencies and whether the
+// configured OpenAI models are available to the key. The server keeps serving when they are down, so the
+// response stays 200 with status "degraded" rather than failing.
+func (h *APIHandler) Health(c *gin.Context) {
+	// TODO: Implement deeper health checks:
+	// - Neo4j connectivity (e.g., ping or simple query)
+	// - Sui RPC connectivity
+	status := "ok"
+	checks := gin.H{}
+
+	modelsCtx, cancelModels := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+	defer cancelModels()
+	models := h.aiGenerator.CheckModels(modelsCtx)
+	for _, availability := range models {
+		if availability != ai.ModelAvailable {
+			status = "degraded"
+		}
+	}
+	checks["models"] = models
+
+	if h.sealClient == nil || !h.sealClient.Configured() {
+		checks["seal"] = "not configured"
+	} else {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), healthCheckTimeout)
+		defer cancel()
+		if err := h.sealClient.Ping(ctx); err != nil {
+			checks["seal"] = "down: " + err.Error()
+			status = "degraded"
+		} else {
+			checks["seal"] = "up"
+		}
+	}
+
+	// Only deployers that need external tools or configuration of their own (Walrus: site-builder, the walrus
+	// CLI and sites-config.yaml) report them
+	if checker, ok := h.deployer.(toolChecker); ok {
+		if err := checker.CheckTools(); err != nil {
+			checks["deployTools"] = "not configured: " + err.Error()
+			status = "degraded"
+		} else {
+			checks["deployTools"] = "ok"
+		}
+	}
+	if checker, ok := h.deployer.(configChecker); ok {
+		if err := checker.CheckConfig(); err != nil {
+			checks["deployConfig"] = "invalid: " + err.Error()
+			status = "degraded"
+		} else {
+			checks["deployConfig"] = "ok"
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": status, "checks": checks})
+}
+
+// configChecker is implemented by deployers that can validate their configuration ahead of a deploy.
+type configChecker interface {
+	CheckConfig() error
+}
+
+// GET /metrics
+// Metrics reports operational counters as JSON: deploy queue length, running builds and average build time,
+// the same for Batch API generations and refinements, provider call retries by reason (rate_limit,
+// server_error, ...), parsed generations by output shape (array, single_object, wrapped; see
+// ai.ParseStrategy), and this month's OpenAI spend against MONTHLY_BUDGET_USD (null when spend isn't tracked).
+func (h *APIHandler) Metrics(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"deployQueue": h.deployJobs.Stats(),
+		"batchQueue":  h.batchJobs.Stats(),
+		"refineQueue": h.refineJobs.Stats(),
+		"retries":     utils.RetryCounts(),
+		"parses":      ai.ParseStrategyCounts(),
+		"spend":       h.aiGenerator.Spend(),
+	})
+}
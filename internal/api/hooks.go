@@ -0,0 +1,48 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	aiutils "sui_ai_server/internal/ai/utils"
+	"sui_ai_server/internal/hooks"
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// runHooks runs the post-generation hooks (POST_GENERATION_HOOKS) over a project's working copy. Call it before
+// storing the files, so changes made by fixers such as "eslint --fix" are kept. blocked means an error-severity
+// hook failed and the automatic deploy must not be queued; the owner can fix the files and deploy by hand.
+func (h *APIHandler) runHooks(ctx context.Context, projectID string, opts types.SiteOptions) (results []hooks.Result, blocked bool) {
+	results, blocked = h.hooks.Run(ctx, utils.ProjectDir(projectID), opts.ProjectType)
+	if blocked {
+		log.Printf("Post-generation hooks blocked the deploy of project %s", projectID)
+	}
+	return results, blocked
+}
+
+// respondHooksBlocked writes the 422 for a generation whose files were stored but failed a required hook.
+func respondHooksBlocked(c *gin.Context, projectID string, results []hooks.Result) {
+	c.JSON(http.StatusUnprocessableEntity, gin.H{
+		"error":     "Generated project failed a required quality check; it was saved but not deployed",
+		"projectID": projectID,
+		"hooks":     results,
+	})
+}
+
+// hookedFiles returns the files to report after hooks ran over them, re-read from disk when a hook may have
+// rewritten them.
+func hookedFiles(projectID string, generated []types.GeneratedFile, results []hooks.Result) []types.GeneratedFile {
+	if len(results) == 0 {
+		return generated
+	}
+	files, err := aiutils.LoadFilesDisk(projectID)
+	if err != nil {
+		log.Printf("WARN: Failed to reload files of project %s after hooks: %v", projectID, err)
+		return generated
+	}
+	return files
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"sui_ai_server/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WalletHeader lets clients identify the wallet without it being in the body.
+const WalletHeader = "X-Wallet-Address"
+
+// maxGenerateBodyBytes bounds generate request bodies, which may carry an inline reference image
+// (maxReferenceImageBytes, base64-encoded) and an uploaded prompt file.
+const maxGenerateBodyBytes = 8 << 20
+
+// WalletRateLimit rejects requests with 429 once a wallet exceeds its allowance, and with 413 when the body is
+// larger than maxGenerateBodyBytes. Requests without an identifiable wallet pass through and fail the handler's
+// own validation.
+func WalletRateLimit(limiter ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		wallet, err := requestWallet(c)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Request body too large (max %d bytes)", tooLarge.Limit)})
+				return
+			}
+		}
+		if wallet == "" {
+			c.Next()
+			return
+		}
+
+		allowed, retryAfter := limiter.Allow(strings.ToLower(wallet))
+		if !allowed {
+			seconds := setRetryAfter(c, retryAfter)
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many generation requests for this wallet", "retryAfter": seconds})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RejectWhileShuttingDown answers new requests with 503 and Connection: close once shuttingDown is set, so load
+// balancers drain the instance while in-flight requests finish. Health checks get a 503 as well, reporting
+// status "shutting down", so the instance is taken out of rotation during the drain period.
+func RejectWhileShuttingDown(shuttingDown *atomic.Bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !shuttingDown.Load() {
+			c.Next()
+			return
+		}
+		c.Header("Connection", "close")
+		if strings.HasSuffix(c.FullPath(), "/health") {
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"status": "shutting down"})
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Server is shutting down, please retry"})
+	}
+}
+
+// requestWallet finds the wallet the handler will act for: the "wallet" field of the form or JSON body, or the
+// header only when the body has none, so a client can't dodge its limit by sending a different header wallet.
+// The body is capped at maxGenerateBodyBytes and restored for the handler; err is only set when reading it
+// failed.
+func requestWallet(c *gin.Context) (string, error) {
+	if c.Request.Body != nil {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxGenerateBodyBytes)
+	}
+	wallet, err := bodyWallet(c)
+	if wallet == "" && err == nil {
+		wallet = c.GetHeader(WalletHeader)
+	}
+	return wallet, err
+}
+
+// bodyWallet returns the "wallet" field of a form or JSON body.
+func bodyWallet(c *gin.Context) (string, error) {
+	switch c.ContentType() {
+	case gin.MIMEPOSTForm:
+		if err := c.Request.ParseForm(); err != nil {
+			return "", err
+		}
+		return c.Request.PostForm.Get("wallet"), nil
+	case gin.MIMEMultipartPOSTForm:
+		if _, err := c.MultipartForm(); err != nil {
+			return "", err
+		}
+		return c.PostForm("wallet"), nil
+	}
+
+	if c.Request.Body == nil {
+		return "", nil
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	var payload struct {
+		Wallet string `json:"wallet"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return "", nil // Malformed JSON is the handler's to report
+	}
+	return payload.Wallet, nil
+}
+
+// generateRateLimit returns the per-wallet limiter middleware for generation, or a pass-through when disabled.
+func (h *APIHandler) generateRateLimit() gin.HandlerFunc {
+	if h.generateLimiter == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return WalletRateLimit(h.generateLimiter)
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRejectWhileShuttingDown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var shuttingDown atomic.Bool
+	router := gin.New()
+	router.Use(RejectWhileShuttingDown(&shuttingDown))
+	router.GET("/health", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "ok"}) })
+	router.POST("/project/generate", func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	tests := []struct {
+		name         string
+		shuttingDown bool
+		method, path string
+		want         int
+	}{
+		{"health while serving", false, http.MethodGet, "/health", http.StatusOK},
+		{"request while serving", false, http.MethodPost, "/project/generate", http.StatusCreated},
+		{"health while shutting down", true, http.MethodGet, "/health", http.StatusServiceUnavailable},
+		{"request while shutting down", true, http.MethodPost, "/project/generate", http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shuttingDown.Store(tt.shuttingDown)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+			if tt.shuttingDown && w.Header().Get("Connection") != "close" {
+				t.Error("Connection: close not set while shutting down")
+			}
+		})
+	}
+}
+
+// countingLimiter allows everything and records which wallets were charged.
+type countingLimiter struct {
+	charged []string
+}
+
+func (l *countingLimiter) Allow(key string) (bool, time.Duration) {
+	l.charged = append(l.charged, key)
+	return true, 0
+}
+
+func TestWalletRateLimitKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		header      string
+		want        string // Charged wallet; empty for none
+		wantStatus  int
+	}{
+		{"json body", "application/json", `{"wallet":"0xBody","prompt":"x"}`, "", "0xbody", http.StatusOK},
+		{"body wins over header", "application/json", `{"wallet":"0xBody"}`, "0xOther", "0xbody", http.StatusOK},
+		{"header when body has none", "application/json", `{"prompt":"x"}`, "0xHeader", "0xheader", http.StatusOK},
+		{"form body wins over header", "application/x-www-form-urlencoded", "wallet=0xForm&prompt=x", "0xOther", "0xform", http.StatusOK},
+		{"no wallet", "application/json", `{"prompt":"x"}`, "", "", http.StatusOK},
+		{"oversized body", "application/json", `{"wallet":"0xBody","prompt":"` + strings.Repeat("a", maxGenerateBodyBytes) + `"}`, "", "", http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := &countingLimiter{}
+			router := gin.New()
+			var handlerBody string
+			router.POST("/project/generate", WalletRateLimit(limiter), func(c *gin.Context) {
+				data, _ := io.ReadAll(c.Request.Body)
+				handlerBody = string(data)
+				c.Status(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodPost, "/project/generate", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.header != "" {
+				req.Header.Set(WalletHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			var want []string
+			if tt.want != "" {
+				want = []string{tt.want}
+			}
+			if !slices.Equal(limiter.charged, want) {
+				t.Errorf("charged %v, want %v", limiter.charged, want)
+			}
+			if tt.wantStatus == http.StatusOK && tt.contentType == "application/json" && handlerBody != tt.body {
+				t.Errorf("handler read body %q, want it restored as %q", handlerBody, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"sui_ai_server/internal/jobs"
+	"sui_ai_server/internal/sui/walrus"
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxPrebuiltArchiveBytes limits the uploaded archive itself.
+	maxPrebuiltArchiveBytes = 50 << 20
+	// maxPrebuiltExtractedBytes limits what the archive may expand to, guarding against zip bombs.
+	maxPrebuiltExtractedBytes = 200 << 20
+)
+
+// POST /deploy/prebuilt (multipart, fields "wallet" and "archive": .zip, .tar, .tar.gz or .tgz)
+// DeployPrebuilt publishes an already-built dist directory uploaded by the client, skipping npm install/build.
+// The archive may contain the built files at its root or inside a single top-level folder such as dist/.
+// The wallet must pass the deploy NFT gate. The publish waits its turn in the deploy queue like any other:
+// the response is the queued job (202); poll GET /project/jobs/:jobId for the deploy result.
+func (h *APIHandler) DeployPrebuilt(c *gin.Context) {
+	wallet := c.PostForm("wallet")
+	if wallet == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "wallet is required to deploy"})
+		return
+	}
+	// Prebuilt sites are published as-is, like static projects, so they need that type enabled.
+	if !h.checkDeployReady(c) || !h.checkProjectType(c, types.ProjectTypeStatic) || !h.checkDeployNFT(c, wallet) {
+		return
+	}
+	fileHeader, err := c.FormFile("archive")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "archive file is required"})
+		return
+	}
+	if fileHeader.Size > maxPrebuiltArchiveBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Archive too large"})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		log.Printf("Error opening uploaded archive: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read archive"})
+		return
+	}
+	defer file.Close()
+
+	// Like inline deploys, the upload gets an ephemeral project ID, so the janitor cleans up after one a crash
+	// left behind. The deploy job removes it once it has run.
+	projectID := uuid.New().String()
+	workDir := utils.ProjectDir(projectID)
+	if err := os.MkdirAll(workDir, os.ModePerm); err != nil {
+		log.Printf("Error creating directory for prebuilt deploy %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prepare deployment"})
+		return
+	}
+	queued := false
+	defer func() {
+		if !queued {
+			os.RemoveAll(workDir)
+		}
+	}()
+
+	if err := utils.ExtractArchive(file, fileHeader.Size, fileHeader.Filename, workDir, maxPrebuiltExtractedBytes); err != nil {
+		switch {
+		case errors.Is(err, utils.ErrUnsupportedArchive):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported archive type (use .zip, .tar, .tar.gz or .tgz)"})
+		case errors.Is(err, utils.ErrUnsafePath):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Archive contains unsafe paths"})
+		case errors.Is(err, utils.ErrArchiveTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Archive contents too large"})
+		default:
+			log.Printf("Error extracting prebuilt archive %s: %v", fileHeader.Filename, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to extract archive"})
+		}
+		return
+	}
+
+	siteDir, ok := findSiteRoot(workDir)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Archive must contain an index.html"})
+		return
+	}
+
+	log.Printf("Queueing prebuilt site %s from %s for wallet %s", projectID, siteDir, wallet)
+	job := h.deployJobs.Submit(jobs.KindDeploy, projectID, func(ctx context.Context) (any, error) {
+		defer os.RemoveAll(workDir)
+		ctx, cancel := withTimeout(ctx, h.timeouts.Deploy)
+		defer cancel()
+		result, err := h.deployer.Deploy(ctx, siteDir, walrus.DeployOptions{Static: true})
+		if err != nil {
+			log.Printf("Error deploying prebuilt site %s: %v", projectID, err)
+			return nil, err
+		}
+		log.Printf("Prebuilt site %s deployed successfully to %s: %s", projectID, result.Backend, result.SiteID)
+		return result, nil
+	})
+	queued = true
+	setJobRetryAfter(c, job)
+	c.JSON(http.StatusAccepted, job)
+}
+
+// findSiteRoot returns the directory holding index.html: dir itself, dir/dist, or the single top-level
+// folder of dir (and its dist), which covers archives made by zipping the dist folder itself.
+func findSiteRoot(dir string) (string, bool) {
+	candidates := []string{dir, filepath.Join(dir, "dist")}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) == 1 && entries[0].IsDir() {
+		top := filepath.Join(dir, entries[0].Name())
+		candidates = append(candidates, top, filepath.Join(top, "dist"))
+	}
+	for _, candidate := range candidates {
+		if info, err := os.Stat(filepath.Join(candidate, "index.html")); err == nil && info.Mode().IsRegular() {
+			return candidate, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"sui_ai_server/internal/jobs"
+	"sui_ai_server/internal/utils"
+)
+
+// prebuiltRequest is a POST /deploy/prebuilt upload of a zip holding index.html, with wallet unless empty.
+func prebuiltRequest(t *testing.T, wallet string) *http.Request {
+	t.Helper()
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	f, err := zw.Create("dist/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("<h1>Hi</h1>"))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if wallet != "" {
+		mw.WriteField("wallet", wallet)
+	}
+	part, err := mw.CreateFormFile("archive", "site.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(archive.Bytes())
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/deploy/prebuilt", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestDeployPrebuiltQueuesDeploy(t *testing.T) {
+	h := newTestHandler(t, &fakeGenerator{})
+	w := serveRequest(h.DeployPrebuilt, prebuiltRequest(t, "0xabc"))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status = %d, want %d (%s)", w.Code, http.StatusAccepted, w.Body.String())
+	}
+	var queued jobs.Job
+	if err := json.Unmarshal(w.Body.Bytes(), &queued); err != nil {
+		t.Fatal(err)
+	}
+	job, err := h.deployJobs.Wait(context.Background(), queued.ID)
+	if err != nil || job.State != jobs.StateSucceeded {
+		t.Fatalf("deploy job = %+v, %v; want succeeded", job, err)
+	}
+	if n := h.deployer.(*fakeDeployer).deploys.Load(); n != 1 {
+		t.Errorf("%d deploys published, want 1", n)
+	}
+	if _, err := os.Stat(utils.ProjectDir(queued.ProjectID)); !os.IsNotExist(err) {
+		t.Errorf("upload directory left behind: %v", err)
+	}
+}
+
+func TestDeployPrebuiltRequiresWalletAndNFT(t *testing.T) {
+	tests := []struct {
+		name   string
+		wallet string
+		gated  bool
+		want   int
+	}{
+		{"no wallet", "", false, http.StatusBadRequest},
+		{"wallet without the NFT", "0xabc", true, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, &fakeGenerator{})
+			if tt.gated {
+				nftGate(t, h)
+			}
+			w := serveRequest(h.DeployPrebuilt, prebuiltRequest(t, tt.wallet))
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+			}
+			if n := h.deployer.(*fakeDeployer).deploys.Load(); n != 0 {
+				t.Errorf("%d deploys published, want none", n)
+			}
+		})
+	}
+}
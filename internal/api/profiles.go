@@ -0,0 +1,86 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"sui_ai_server/internal/ai/prompts"
+	"sui_ai_server/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limits on operator-defined generation profiles, which end up in every prompt that selects them.
+const (
+	maxProfileThemeLen        = 200
+	maxProfileInstructionsLen = 4000
+)
+
+// profileNamePattern is what a profile name may look like. Config keys are case-insensitive, so names are lowercase.
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// ValidateProfiles checks the GENERATION_PROFILES loaded from config.
+func ValidateProfiles(profiles map[string]types.GenerationProfile) error {
+	for name, p := range profiles {
+		if !profileNamePattern.MatchString(name) {
+			return fmt.Errorf("profile name %q must be lowercase letters, digits, '-' or '_'", name)
+		}
+		switch p.Framework {
+		case "", types.ProjectTypeReact, types.ProjectTypeStatic:
+		default:
+			return fmt.Errorf("profile %q: framework must be %q or %q, got %q", name, types.ProjectTypeReact, types.ProjectTypeStatic, p.Framework)
+		}
+		if len(p.Pages) > prompts.MaxExtraPages {
+			return fmt.Errorf("profile %q: at most %d pages, got %d", name, prompts.MaxExtraPages, len(p.Pages))
+		}
+		if len(p.Pages) > 0 && len(prompts.SanitizePageNames(p.Pages)) == 0 {
+			return fmt.Errorf("profile %q: none of its pages is a usable page name", name)
+		}
+		if len(p.Theme) > maxProfileThemeLen {
+			return fmt.Errorf("profile %q: theme is longer than %d bytes", name, maxProfileThemeLen)
+		}
+		if len(p.Instructions) > maxProfileInstructionsLen {
+			return fmt.Errorf("profile %q: instructions are longer than %d bytes", name, maxProfileInstructionsLen)
+		}
+	}
+	return nil
+}
+
+// checkProfile looks up the requested generation profile into profile, responding 400 and returning false
+// when no profile of that name is configured. An empty name selects no profile.
+func (h *APIHandler) checkProfile(c *gin.Context, name string, profile *types.GenerationProfile) bool {
+	if name == "" {
+		return true
+	}
+	p, ok := h.profiles[name]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown profile %q", name)})
+		return false
+	}
+	*profile = p
+	return true
+}
+
+// mergeProfile fills the options a request left unset from profile; the request's own settings win.
+func mergeProfile(opts types.SiteOptions, profile types.GenerationProfile) types.SiteOptions {
+	if opts.ProjectType == "" {
+		opts.ProjectType = profile.Framework
+	}
+	if opts.Pages == nil {
+		opts.Pages = prompts.SanitizePageNames(profile.Pages)
+	}
+	opts.Theme = profile.Theme
+	opts.Instructions = profile.Instructions
+	return opts
+}
+
+// GET /project/profiles
+// ListProfiles returns the configured generation profiles by name, for clients to offer as presets.
+func (h *APIHandler) ListProfiles(c *gin.Context) {
+	profiles := h.profiles
+	if profiles == nil {
+		profiles = map[string]types.GenerationProfile{}
+	}
+	c.JSON(http.StatusOK, gin.H{"profiles": profiles})
+}
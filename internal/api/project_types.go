@@ -0,0 +1,61 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"sui_ai_server/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ValidateProjectTypes checks ALLOWED_PROJECT_TYPES: every entry must be a known project type.
+func ValidateProjectTypes(allowed []string) error {
+	for _, t := range allowed {
+		if t != types.ProjectTypeReact && t != types.ProjectTypeStatic {
+			return fmt.Errorf("unknown project type %q (must be %q or %q)", t, types.ProjectTypeReact, types.ProjectTypeStatic)
+		}
+	}
+	return nil
+}
+
+// checkProjectType responds 403 and returns false when projectType ("" means React) isn't one of
+// ALLOWED_PROJECT_TYPES. An empty allowlist permits every type.
+func (h *APIHandler) checkProjectType(c *gin.Context, projectType string) bool {
+	if h.projectTypeAllowed(projectType) {
+		return true
+	}
+	if projectType == "" {
+		projectType = types.ProjectTypeReact
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"error":               fmt.Sprintf("Project type %q is not enabled on this server", projectType),
+		"allowedProjectTypes": h.allowedProjectTypes,
+	})
+	return false
+}
+
+// projectTypeAllowed reports whether projectType ("" means React) is one of ALLOWED_PROJECT_TYPES. An empty
+// allowlist permits every type.
+func (h *APIHandler) projectTypeAllowed(projectType string) bool {
+	if len(h.allowedProjectTypes) == 0 {
+		return true
+	}
+	if projectType == "" {
+		projectType = types.ProjectTypeReact
+	}
+	for _, t := range h.allowedProjectTypes {
+		if t == projectType {
+			return true
+		}
+	}
+	return false
+}
+
+// inlineProjectType is the project type of an inline deploy: static when it skips the build.
+func inlineProjectType(static bool) string {
+	if static {
+		return types.ProjectTypeStatic
+	}
+	return types.ProjectTypeReact
+}
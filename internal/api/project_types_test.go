@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"sui_ai_server/internal/jobs"
+	"sui_ai_server/internal/project"
+	"sui_ai_server/internal/types"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestGenerateSiteProjectTypeAllowlist(t *testing.T) {
+	tests := []struct {
+		name        string
+		projectType string
+		want        int
+	}{
+		{"allowed", `"static"`, http.StatusCreated},
+		{"disallowed", `"react"`, http.StatusForbidden},
+		{"default is react", `""`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := &fakeGenerator{files: []types.GeneratedFile{{Filename: "index.html", Content: "<h1>Hi</h1>"}}}
+			h := newTestHandler(t, gen)
+			h.allowedProjectTypes = []string{types.ProjectTypeStatic}
+
+			w := serve(h.GenerateSite, http.MethodPost, "/project/generate",
+				`{"prompt":"A landing page","wallet":"0xabc","projectType":`+tt.projectType+`}`)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want == http.StatusForbidden && gen.callCount() != 0 {
+				t.Error("a disallowed project type was generated")
+			}
+		})
+	}
+}
+
+func TestDeployProjectTypeAllowlist(t *testing.T) {
+	tests := []struct {
+		name        string
+		projectType string
+		want        int
+	}{
+		{"allowed", types.ProjectTypeStatic, http.StatusAccepted},
+		{"disallowed", types.ProjectTypeReact, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, &fakeGenerator{})
+			h.allowedProjectTypes = []string{types.ProjectTypeStatic}
+			projectID := uuid.New().String()
+			if err := h.projectStore.Save(&project.Metadata{ID: projectID, Wallet: "0xabc", ProjectType: tt.projectType, Status: project.StatusGenerated}); err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/project/"+projectID+"/deploy", strings.NewReader(`{"wallet":"0xabc"}`))
+			w := serveRequest(h.DeployProject, req, gin.Param{Key: "id", Value: projectID})
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want == http.StatusForbidden && h.deployJobs.Busy(projectID) {
+				t.Error("a disallowed project type was queued for deploy")
+			}
+			if jobID, ok := decodeBody(t, w)["id"].(string); ok {
+				h.deployJobs.Wait(context.Background(), jobID) // Finish before the work dir is removed
+			}
+		})
+	}
+}
+
+func TestValidateProjectTypes(t *testing.T) {
+	if err := ValidateProjectTypes([]string{types.ProjectTypeStatic, types.ProjectTypeReact}); err != nil {
+		t.Errorf("known types rejected: %v", err)
+	}
+	if err := ValidateProjectTypes([]string{"php"}); err == nil {
+		t.Error("an unknown type was accepted")
+	}
+}
+
+func TestDeployPrebuiltProjectTypeAllowlist(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		want    int
+	}{
+		{"static allowed", []string{types.ProjectTypeStatic}, http.StatusAccepted},
+		{"static not allowed", []string{types.ProjectTypeReact}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, &fakeGenerator{})
+			h.allowedProjectTypes = tt.allowed
+			w := serveRequest(h.DeployPrebuilt, prebuiltRequest(t, "0xabc"))
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+			}
+			if w.Code == http.StatusAccepted {
+				var queued jobs.Job
+				if err := json.Unmarshal(w.Body.Bytes(), &queued); err != nil {
+					t.Fatal(err)
+				}
+				h.deployJobs.Wait(context.Background(), queued.ID) // Don't leave the deploy running past the test
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+
+	"sui_ai_server/internal/ai"
+	"sui_ai_server/internal/jobs"
+	"sui_ai_server/internal/project"
+	"sui_ai_server/internal/rag"
+	"sui_ai_server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// POST /project/:id/refine/stream[?fallback=poll]
+// StreamRefine asks the LLM for code changes like RefineProjectCode, streaming the raw model output over SSE as
+// "token" events for a live view. Changes are applied only once the complete output parses, finishing with a
+// "done" event carrying the RefineCodeResponse, or an "error" event (in which case nothing is written).
+//
+// The refinement runs as a job either way, so it finishes even if the client goes away and its result stays
+// available from GET /project/jobs/:jobId (the "queued" event carries the job). Clients that can't consume SSE,
+// because they pass fallback=poll or don't accept text/event-stream, get 202 with the job to poll instead.
+func (h *APIHandler) StreamRefine(c *gin.Context) {
+	projectID, ok := validateProjectID(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req RAGQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if err := rag.ValidateExcludePatterns(req.Exclude); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if c.Query("fallback") == "poll" || !acceptsEventStream(c.GetHeader("Accept")) {
+		job := h.refineJobs.Submit(jobs.KindRefine, projectID, h.refineTask(projectID, req, nil))
+		log.Printf("Queued refinement for project %s as job %s (polling)", projectID, job.ID)
+		setJobRetryAfter(c, job)
+		c.JSON(http.StatusAccepted, job)
+		return
+	}
+
+	// Tokens are buffered so a slow client doesn't stall the model, and dropped once the client is gone.
+	ctx := c.Request.Context()
+	tokens := make(chan string, 256)
+	var runErr error // Set by the task before tokens is closed
+	task := h.refineTask(projectID, req, func(token string) {
+		select {
+		case tokens <- token:
+		case <-ctx.Done():
+		}
+	})
+	job := h.refineJobs.Submit(jobs.KindRefine, projectID, func(jobCtx context.Context) (any, error) {
+		defer close(tokens)
+		result, err := task(jobCtx)
+		runErr = err
+		return result, err
+	})
+
+	extendWriteDeadline(c, h.timeouts.Generate+responseMargin)
+	log.Printf("Streaming refinement for project %s (job %s)", projectID, job.ID)
+	c.SSEvent("queued", job)
+	c.Stream(func(w io.Writer) bool {
+		var token string
+		var ok bool
+		select {
+		case token, ok = <-tokens:
+		case <-ctx.Done():
+			return false
+		}
+		if ok {
+			c.SSEvent("token", gin.H{"text": token})
+			return true
+		}
+
+		final, err := h.refineJobs.Wait(ctx, job.ID)
+		if err != nil {
+			return false
+		}
+		if final.State == jobs.StateCancelled {
+			c.SSEvent("error", gin.H{"error": "Refinement was cancelled"})
+			return false
+		}
+		if final.State != jobs.StateSucceeded {
+			c.SSEvent("error", refineStreamError(runErr))
+			return false
+		}
+		c.SSEvent("done", final.Result)
+		return false
+	})
+}
+
+// refineTask generates and applies code changes for a refinement query, passing each streamed token to onToken
+// when it isn't nil. It is the single execution path behind both the SSE and the polled forms of StreamRefine.
+func (h *APIHandler) refineTask(projectID string, req RAGQueryRequest, onToken func(string)) jobs.Task {
+	return func(jobCtx context.Context) (any, error) {
+		ctx, cancel := withTimeout(h.withOwner(jobCtx, projectID), h.timeouts.Generate)
+		defer cancel()
+
+		jobs.SetProgress(jobCtx, "generating")
+		files, err := h.ragService.RefineProjectCodeStream(ctx, projectID, req.Query, req.Exclude, func(token string) {
+			if onToken != nil {
+				onToken(token)
+			}
+		})
+		if err != nil {
+			log.Printf("Error refining project %s: %v", projectID, err)
+			return nil, err
+		}
+		jobs.SetProgress(jobCtx, "applying")
+		return h.applyRefinement(ctx, projectID, files)
+	}
+}
+
+// acceptsEventStream reports whether an Accept header admits an SSE response. A missing header accepts anything.
+func acceptsEventStream(accept string) bool {
+	if accept == "" {
+		return true
+	}
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, _ := strings.Cut(part, ";")
+		switch strings.TrimSpace(mediaType) {
+		case "text/event-stream", "text/*", "*/*":
+			return true
+		}
+	}
+	return false
+}
+
+// truncatedChangesMessage explains a refinement whose answer hit the model's output limit.
+const truncatedChangesMessage = "The requested changes were too large for one answer; split the request into smaller changes"
+
+// refineStreamError is the "error" event payload for a failed streamed refinement, mirroring the status
+// responses of the non-streaming endpoint.
+func refineStreamError(err error) gin.H {
+	var rateLimitErr *utils.RateLimitError
+	switch {
+	case err == nil:
+		return gin.H{"error": "Refinement did not complete"}
+	case errors.Is(err, project.ErrProjectNotFound):
+		return gin.H{"error": "Project not found"}
+	case errors.Is(err, ai.ErrTruncatedResponse):
+		return gin.H{"error": truncatedChangesMessage}
+	case errors.As(err, &rateLimitErr):
+		return gin.H{"error": "AI provider is rate limiting requests, please retry later", "retryAfter": int(math.Ceil(rateLimitErr.RetryAfter.Seconds()))}
+	default:
+		return gin.H{"error": "Failed to generate code changes"}
+	}
+}
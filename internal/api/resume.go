@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"sui_ai_server/internal/ai"
+	"sui_ai_server/internal/project"
+	"sui_ai_server/internal/sui/walrus"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ResumeProjectRequest identifies the wallet asking to resume; it must own the project.
+type ResumeProjectRequest struct {
+	Wallet string `json:"wallet" binding:"required"`
+}
+
+// POST /project/:id/resume
+// ResumeProject finishes a generation that failed on unusable model output (a 422 with "resumable": true).
+// The files that can be read from the failed output are kept and the model only generates the rest, which
+// costs much less than generating again. The finished project is stored and a deploy queued, as after a
+// batch generation; the response names the deploy job.
+func (h *APIHandler) ResumeProject(c *gin.Context) {
+	projectID, ok := validateProjectID(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	var req ResumeProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	meta, err := h.projectStore.Get(projectID)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		log.Printf("Error loading project %s: %v", projectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load project"})
+		return
+	}
+	if meta.Wallet != req.Wallet {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Wallet does not own this project"})
+		return
+	}
+	if !h.checkProjectType(c, meta.ProjectType) {
+		return
+	}
+	if !ai.HasResumableOutput(projectID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Project has no failed generation to resume"})
+		return
+	}
+	if !h.checkBudget(c) {
+		return
+	}
+
+	extendWriteDeadline(c, h.timeouts.Generate+responseMargin)
+	genCtx, cancel := withTimeout(c.Request.Context(), h.timeouts.Generate)
+	defer cancel()
+
+	opts := meta.SiteOptions()
+	result, err := h.aiGenerator.ResumeSite(genCtx, projectID, meta.Prompt, meta.Wallet, opts)
+	if err != nil {
+		log.Printf("Error resuming generation of project %s: %v", projectID, err)
+		if errors.Is(err, ai.ErrNothingToResume) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Project has no failed generation to resume"})
+			return
+		}
+		if respondRateLimited(c, err) || respondTimedOut(c, err, "Resumed generation") || respondUnusableOutput(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resume generation"})
+		return
+	}
+
+	setGenerationLog(c, result.Model, result.TotalTokens)
+	if _, err := h.projectStore.Update(projectID, func(m *project.Metadata) {
+		m.Status = project.StatusGenerated
+		m.Model = result.Model
+	}); err != nil {
+		log.Printf("WARN: Failed to save metadata for project %s: %v", projectID, err)
+	}
+	hookResults, blocked := h.runHooks(c.Request.Context(), projectID, opts)
+	if err := h.storeFiles(c.Request.Context(), projectID); err != nil {
+		log.Printf("Error storing files for project %s: %v", projectID, err)
+		h.setProjectStatus(projectID, project.StatusFailed, "")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store generated files"})
+		return
+	}
+	if blocked {
+		respondHooksBlocked(c, projectID, hookResults)
+		return
+	}
+
+	res := BatchGenerateResult{
+		ProjectID:         projectID,
+		Model:             result.Model,
+		SecretFindings:    result.SecretFindings,
+		UnresolvedImports: result.UnresolvedImports,
+		Hooks:             hookResults,
+	}
+	if err := h.autoDeployError(c.Request.Context(), meta.Wallet); err != nil {
+		log.Printf("Not deploying project %s: %v", projectID, err)
+		res.DeployError = autoDeployMessage(err)
+	} else {
+		res.DeployJobID = h.submitDeploy(projectID, walrus.DeployOptions{Static: opts.IsStatic()}).ID
+	}
+	c.JSON(http.StatusOK, res)
+}
@@ -1,53 +1,89 @@
 package api
 
 import (
-	"net/http" // Import net/http
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 // RegisterRoutes sets up the API endpoints and groups them logically.
-func RegisterRoutes(router *gin.Engine, h *APIHandler) {
+// All routes are registered under prefix (e.g. "/api"; empty means the root). The health check and metrics are
+// the exception: they are always served at the root so probes and scrapers can reach the server directly, and
+// are additionally served under the prefix so they can also be checked through the proxy.
+func RegisterRoutes(router *gin.Engine, h *APIHandler, prefix string) {
+	prefix = normalizePrefix(prefix)
+	apiGroup := router.Group(prefix)
 
 	// --- Project Lifecycle ---
 	// Group related project actions under /project
-	projectGroup := router.Group("/project")
+	projectGroup := apiGroup.Group("/project")
 	{
-		projectGroup.POST("/generate", h.GenerateSite) // Generate a new project from a prompt
-		// projectGroup.GET("/:id/files", h.GetProjectFiles) // Get the files for a specific project
-		// projectGroup.POST("/:id/deploy", h.DeployProject) // Trigger deployment for a specific project
+		projectGroup.POST("/generate", h.generateRateLimit(), h.GenerateSite) // Generate a new project from a prompt
+		projectGroup.POST("/estimate", h.EstimateGeneration)                  // Preview token count and cost of a generation
+		projectGroup.GET("/profiles", h.ListProfiles)                         // List the configured generation profiles
+		projectGroup.GET("/design-systems", h.ListDesignSystems)              // List the registered design systems
+		if h.allowDebug {
+			projectGroup.POST("/prompt-preview", h.PreviewPrompt) // Show the exact prompts a generation would send
+		}
+		projectGroup.PUT("/:id/prompt", h.UpdateProjectPrompt)                   // Revise the prompt and re-scaffold the project
+		projectGroup.GET("/:id/deploy/stream", h.StreamDeploy)                   // Deploy and stream build output over SSE
+		projectGroup.GET("/:id/files", h.GetProjectFiles)                        // Get the files for a specific project
+		projectGroup.GET("/:id/tree", h.GetProjectTree)                          // Get the directory tree (paths, types, sizes) without content
+		projectGroup.PUT("/:id/files", h.PutProjectFiles)                        // Merge files (e.g. from a scoped generation) into the project
+		projectGroup.GET("/:id/file", h.GetProjectFile)                          // Get one file's raw content (?path=src/App.tsx)
+		projectGroup.POST("/:id/deploy", h.DeployProject)                        // Queue a deploy; returns the job with its queue position
+		projectGroup.DELETE("/:id", h.DeleteProject)                             // Delete a project, or free its local disk with ?purge=source
+		projectGroup.POST("/:id/clone", h.CloneProject)                          // Copy the project's files into a new project ID
+		projectGroup.POST("/:id/resume", h.generateRateLimit(), h.ResumeProject) // Finish a generation that failed on unusable output
+		projectGroup.POST("/:id/refine/stream", h.StreamRefine)                  // Refine code, streaming model output over SSE (or ?fallback=poll for a job)
+		projectGroup.GET("/jobs/:jobId", h.GetJob)                               // Poll a queued/running job's status
+		projectGroup.DELETE("/jobs/:jobId", h.CancelJob)                         // Cancel a queued/running job
+	}
+
+	// --- Deployment of client-built sites ---
+	deployGroup := apiGroup.Group("/deploy")
+	{
+		deployGroup.POST("", h.DeployInline)            // Build and publish files sent in the body, without storing them
+		deployGroup.POST("/prebuilt", h.DeployPrebuilt) // Publish an uploaded dist archive without building
 	}
 
 	// --- RAG (Retrieval-Augmented Generation) Endpoints ---
 	// Group RAG actions under /rag/:projectId
-	// ragGroup := router.Group("/rag/:projectId")
-	// {
-	// 	ragGroup.POST("/query", h.QueryProjectRAG)    // Get a text-based answer about the project code
-	// 	ragGroup.POST("/refine", h.RefineProjectCode) // Get code modification suggestions for the project
-	// }
+	ragGroup := apiGroup.Group("/rag/:projectId")
+	{
+		ragGroup.POST("/query", h.QueryProjectRAG)    // Get a text-based answer about the project code
+		ragGroup.POST("/refine", h.RefineProjectCode) // Apply LLM-suggested code modifications to the project
+	}
 
 	// --- SUINS (Sui Name Service) Integration ---
 	// Group SUINS actions under /suins
-	// suinsGroup := router.Group("/suins")
-	// {
-	// 	suinsGroup.POST("/register", h.RegisterSuins) // Register (map) a SUINS name to a project
-	// 	// Optional future endpoint:
-	// 	// GET /suins/{name} -> Find project details by SUINS name
-	// 	// suinsGroup.GET("/:name", h.GetProjectBySuins) // Needs handler implementation in api/handlers.go and likely neo4j/graph.go
-	// }
+	suinsGroup := apiGroup.Group("/suins")
+	{
+		suinsGroup.POST("/register", h.RegisterSuins)         // Register (map) a SUINS name to a project
+		suinsGroup.POST("/subdomain", h.CreateSuinsSubdomain) // Build the transaction pointing a subdomain at a project's site
+		suinsGroup.GET("/:name", h.GetProjectBySuins)         // Find the project a name is mapped to (?verify=true checks on chain)
+	}
 
 	// --- Access Control & Utilities ---
 	// Endpoint for backend-based access check using Seal (less common than client-side check)
-	// router.GET("/access/:cid", h.CheckAccess) // Requires ?wallet=<address> query parameter
-
-	// --- Simple Health Check ---
-	// Basic health endpoint to check if the service is running
-	router.GET("/health", func(c *gin.Context) {
-		// TODO: Implement deeper health checks:
-		// - Neo4j connectivity (e.g., ping or simple query)
-		// - AI client status (if possible)
-		// - Sui RPC connectivity
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
-	})
+	// apiGroup.GET("/access/:cid", h.CheckAccess) // Requires ?wallet=<address> query parameter
+
+	// --- Health Check & Metrics ---
+	// Reports liveness plus the state of optional dependencies (see APIHandler.Health)
+	router.GET("/health", h.Health)
+	router.GET("/metrics", h.Metrics)
+	if prefix != "" {
+		apiGroup.GET("/health", h.Health)
+		apiGroup.GET("/metrics", h.Metrics)
+	}
 
 }
+
+// normalizePrefix turns "api", "/api/" etc. into "/api", and "" or "/" into "".
+func normalizePrefix(prefix string) string {
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if prefix == "" {
+		return ""
+	}
+	return "/" + prefix
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"sui_ai_server/internal/ai"
+	"sui_ai_server/internal/secrets"
+	"sui_ai_server/internal/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScopedGenerateResponse carries the files of a scoped generation. Merge them into a project with
+// PUT /project/:id/files.
+type ScopedGenerateResponse struct {
+	Scope          string                `json:"scope"`
+	Model          string                `json:"model"`
+	Files          []types.GeneratedFile `json:"files"`
+	Dropped        []string              `json:"dropped,omitempty"` // Files the model generated outside the scope
+	SecretFindings []secrets.Finding     `json:"secretFindings,omitempty"`
+	ParseStrategy  ai.ParseStrategy      `json:"parseStrategy,omitempty"` // Only with ALLOW_DEBUG_OUTPUT
+}
+
+// generateScoped handles POST /project/generate with a scope: it generates only the files under req.Scope
+// (already normalized) and returns them without creating or changing any project.
+func (h *APIHandler) generateScoped(c *gin.Context, req GenerateRequest) {
+	log.Printf("Received scoped generation request (%s) for wallet %s", req.Scope, req.Wallet)
+
+	extendWriteDeadline(c, h.timeouts.Generate+responseMargin)
+	genCtx, cancel := withTimeout(c.Request.Context(), h.timeouts.Generate)
+	defer cancel()
+
+	result, err := h.aiGenerator.GenerateScopedFiles(genCtx, req.Prompt, req.Wallet, req.Scope, req.siteOptions())
+	if err != nil {
+		log.Printf("Error generating files under %s for wallet %s: %v", req.Scope, req.Wallet, err)
+		if respondRateLimited(c, err) || respondTimedOut(c, err, "Generation") || respondUnusableOutput(c, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate files"})
+		return
+	}
+	setGenerationLog(c, result.Model, result.TotalTokens)
+
+	resp := ScopedGenerateResponse{
+		Scope:          req.Scope,
+		Model:          result.Model,
+		Files:          result.Files,
+		Dropped:        result.Dropped,
+		SecretFindings: result.SecretFindings,
+	}
+	if h.allowDebug {
+		resp.ParseStrategy = result.ParseStrategy
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"sui_ai_server/internal/project"
+	"sui_ai_server/internal/sui"
+
+	"github.com/gin-gonic/gin"
+)
+
+// POST /suins/register
+// RegisterSuins maps a SUINS name to a project after checking on chain that the wallet owns the name.
+// The wallet must also own the project.
+func (h *APIHandler) RegisterSuins(c *gin.Context) {
+	var req RegisterSuinsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	var ok bool
+	if req.ProjectID, ok = validateProjectID(c, req.ProjectID); !ok {
+		return
+	}
+	if h.suiService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Sui integration is not configured"})
+		return
+	}
+
+	meta, err := h.projectStore.Get(req.ProjectID)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		log.Printf("Error loading project %s: %v", req.ProjectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load project"})
+		return
+	}
+	if meta.Wallet != req.Wallet {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Wallet does not own this project"})
+		return
+	}
+
+	name := sui.NormalizeName(req.SuinsName)
+	owned, err := h.suiService.VerifySuinsOwnership(c.Request.Context(), req.Wallet, name)
+	if err != nil {
+		log.Printf("Error verifying SUINS name %s for wallet %s: %v", name, req.Wallet, err)
+		respondSuiError(c, err)
+		return
+	}
+	if !owned {
+		c.JSON(http.StatusForbidden, RegisterSuinsResponse{Success: false, Message: "Wallet does not own " + name})
+		return
+	}
+
+	if _, err := h.projectStore.Update(req.ProjectID, func(m *project.Metadata) {
+		m.SuinsName = name
+	}); err != nil {
+		log.Printf("Error saving SUINS name for project %s: %v", req.ProjectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save SUINS mapping"})
+		return
+	}
+	log.Printf("Mapped SUINS name %s to project %s", name, req.ProjectID)
+	c.JSON(http.StatusOK, RegisterSuinsResponse{Success: true, Message: name + " now points to project " + req.ProjectID})
+}
+
+// GET /suins/:name[?verify=true]
+// GetProjectBySuins returns the project a SUINS name was registered to with POST /suins/register. With
+// verify=true the name is also resolved on chain and the response reports whether its target still matches the
+// project's deployed site object, since the on-chain record can be changed without us knowing.
+func (h *APIHandler) GetProjectBySuins(c *gin.Context) {
+	name := sui.NormalizeName(c.Param("name"))
+	verify := false
+	if raw := c.Query("verify"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "verify must be true or false"})
+			return
+		}
+		verify = v
+	}
+
+	meta, err := h.projectStore.FindBySuinsName(name)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No project is registered to " + name})
+			return
+		}
+		log.Printf("Error looking up SUINS name %s: %v", name, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to look up SUINS name"})
+		return
+	}
+	resp := gin.H{
+		"suinsName":    name,
+		"projectID":    meta.ID,
+		"status":       meta.Status,
+		"siteObjectId": meta.SiteObjectID,
+	}
+	if !verify {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	if h.suiService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Sui integration is not configured"})
+		return
+	}
+	target, err := h.suiService.ResolveSuinsToTarget(c.Request.Context(), name)
+	switch {
+	case errors.Is(err, sui.ErrNameNotFound):
+		// Our record outlived the on-chain one (expired or never set); report it rather than fail.
+		resp["registered"] = false
+		resp["targetMatches"] = false
+	case err != nil:
+		log.Printf("Error resolving SUINS name %s: %v", name, err)
+		respondSuiError(c, err)
+		return
+	default:
+		resp["registered"] = true
+		resp["onChainTarget"] = target
+		resp["targetMatches"] = meta.SiteObjectID != "" && strings.EqualFold(target, meta.SiteObjectID)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// CreateSubdomainRequest asks for a subdomain of a name the wallet owns to point at a project's deployed site.
+type CreateSubdomainRequest struct {
+	ProjectID string `json:"projectId" binding:"required"`
+	Subdomain string `json:"subdomain" binding:"required"` // e.g. "app.mysite.sui"; the wallet must own "mysite.sui"
+	Wallet    string `json:"wallet" binding:"required"`
+}
+
+// POST /suins/subdomain
+// CreateSuinsSubdomain verifies the wallet owns both the project and the subdomain's parent name, then returns
+// the subdomain record along with the unsigned transaction (txBytes) that creates or updates it, pointing at
+// the project's site object. The wallet signs and executes the transaction itself.
+func (h *APIHandler) CreateSuinsSubdomain(c *gin.Context) {
+	var req CreateSubdomainRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	var ok bool
+	if req.ProjectID, ok = validateProjectID(c, req.ProjectID); !ok {
+		return
+	}
+	if h.suiService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Sui integration is not configured"})
+		return
+	}
+	if _, _, err := sui.SplitSubdomain(req.Subdomain); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	meta, err := h.projectStore.Get(req.ProjectID)
+	if err != nil {
+		if errors.Is(err, project.ErrProjectNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		log.Printf("Error loading project %s: %v", req.ProjectID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load project"})
+		return
+	}
+	if meta.Wallet != req.Wallet {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Wallet does not own this project"})
+		return
+	}
+	if meta.SiteObjectID == "" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Project has not been deployed yet"})
+		return
+	}
+
+	record, err := h.suiService.CreateSubdomain(c.Request.Context(), req.Wallet, req.Subdomain, meta.SiteObjectID)
+	if err != nil {
+		log.Printf("Error creating SUINS subdomain %s for project %s: %v", req.Subdomain, req.ProjectID, err)
+		if errors.Is(err, sui.ErrNameNotOwned) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Wallet does not own the parent SUINS name"})
+			return
+		}
+		respondSuiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, record)
+}
+
+// respondSuiError maps a Sui service failure to a response: bad input 400, unknown names 404, ownership
+// scans over the page cap 422, missing configuration 503, and node errors or outages 502.
+func respondSuiError(c *gin.Context, err error) {
+	var rpcErr *sui.RPCError
+	switch {
+	case errors.Is(err, sui.ErrInvalidSubdomain):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, sui.ErrNameNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "SUINS name not found"})
+	case errors.Is(err, sui.ErrNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "SUINS is not configured"})
+	case errors.Is(err, sui.ErrOwnedObjectsLimit):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Wallet holds too many objects to verify ownership"})
+	case errors.As(err, &rpcErr):
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Sui node rejected the request: " + rpcErr.Message})
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to reach the Sui node"})
+	}
+}
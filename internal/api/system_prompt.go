@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"sui_ai_server/internal/ai/prompts"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkSystemPrompt normalizes a request's system prompt override (see prompts.NormalizeSystemPrompt). It
+// responds 403 when one is sent but ALLOW_SYSTEM_PROMPT_OVERRIDE is off, 400 when it is invalid, and returns
+// false in both cases.
+func (h *APIHandler) checkSystemPrompt(c *gin.Context, override *string) bool {
+	if strings.TrimSpace(*override) == "" {
+		*override = ""
+		return true
+	}
+	if !h.allowSystemPrompt {
+		c.JSON(http.StatusForbidden, gin.H{"error": "systemPromptOverride is only accepted when ALLOW_SYSTEM_PROMPT_OVERRIDE is enabled"})
+		return false
+	}
+	normalized, err := prompts.NormalizeSystemPrompt(*override)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	*override = normalized
+	return true
+}
@@ -0,0 +1,151 @@
+// Package events watches the Sui chain for the app's Move events and hands them to the backend.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"sui_ai_server/internal/sui"
+	"sui_ai_server/internal/utils"
+)
+
+// SiteDeployedEvent is the payload of the contract's SiteDeployed event. Field names match the Move struct.
+type SiteDeployedEvent struct {
+	Deployer  string `json:"deployer"`
+	ProjectID string `json:"project_id"` // The backend project ID the deployer asked to publish
+}
+
+// SiteDeployedHandler reacts to one SiteDeployed event. Errors are logged; the listener moves on either way.
+type SiteDeployedHandler func(ctx context.Context, event SiteDeployedEvent) error
+
+const (
+	// DefaultPollInterval is how often the listener asks the node for new events.
+	DefaultPollInterval = 10 * time.Second
+	// maxBackoff caps the wait between retries while the node keeps failing.
+	maxBackoff = 2 * time.Minute
+	// eventsPageSize is how many events are fetched per query.
+	eventsPageSize = 50
+)
+
+// SuiEventListener polls the node for events of one Move type and passes each SiteDeployed event to its
+// handler, in chain order. It starts after the newest event at startup, so events emitted while the server
+// was down are not replayed.
+type SuiEventListener struct {
+	suiService   *sui.Service
+	eventType    string // Full event type, e.g. "0xPACKAGE::module::SiteDeployed"
+	handle       SiteDeployedHandler
+	pollInterval time.Duration
+}
+
+// Option configures optional listener settings.
+type Option func(*SuiEventListener)
+
+// WithPollInterval sets how often the node is polled for new events. Non-positive values are ignored.
+func WithPollInterval(d time.Duration) Option {
+	return func(l *SuiEventListener) {
+		if d > 0 {
+			l.pollInterval = d
+		}
+	}
+}
+
+// NewSuiEventListener creates a listener for eventType that calls handle for each event.
+func NewSuiEventListener(suiService *sui.Service, eventType string, handle SiteDeployedHandler, opts ...Option) *SuiEventListener {
+	l := &SuiEventListener{
+		suiService:   suiService,
+		eventType:    eventType,
+		handle:       handle,
+		pollInterval: DefaultPollInterval,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// StartListening polls for events until ctx is cancelled, then returns ctx's error. Failed polls (the node
+// dropping out, rate limits) are retried with exponential backoff up to maxBackoff; the position in the
+// event stream is kept, so nothing is skipped once the node is back.
+func (l *SuiEventListener) StartListening(ctx context.Context) error {
+	log.Printf("Starting Sui event listener for type: %s (polling every %s)", l.eventType, l.pollInterval)
+
+	var cursor *sui.EventID
+	started := false
+	backoff := l.pollInterval
+	for {
+		var err error
+		if !started {
+			cursor, err = l.latestCursor(ctx)
+			started = err == nil
+		} else {
+			cursor, err = l.poll(ctx, cursor)
+		}
+
+		wait := l.pollInterval
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("WARN: Sui event poll failed, retrying in %s: %v", backoff, err)
+			wait = backoff
+			backoff = min(backoff*2, maxBackoff)
+		} else {
+			backoff = l.pollInterval
+		}
+		if err := utils.SleepContext(ctx, wait); err != nil {
+			log.Println("Context cancelled, stopping event listener.")
+			return err
+		}
+	}
+}
+
+// latestCursor returns the ID of the newest existing event (nil when there are none), so polling starts after it.
+func (l *SuiEventListener) latestCursor(ctx context.Context) (*sui.EventID, error) {
+	page, err := l.suiService.QueryEvents(ctx, l.eventType, nil, 1, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(page.Data) == 0 {
+		return nil, nil
+	}
+	return &page.Data[0].ID, nil
+}
+
+// poll handles every event after cursor and returns the cursor to continue from, which has advanced past
+// the events handled even when a later page fails.
+func (l *SuiEventListener) poll(ctx context.Context, cursor *sui.EventID) (*sui.EventID, error) {
+	for {
+		page, err := l.suiService.QueryEvents(ctx, l.eventType, cursor, eventsPageSize, false)
+		if err != nil {
+			return cursor, err
+		}
+		for _, event := range page.Data {
+			l.processEvent(ctx, event)
+			id := event.ID
+			cursor = &id
+		}
+		if !page.HasNextPage || len(page.Data) == 0 {
+			return cursor, nil
+		}
+	}
+}
+
+func (l *SuiEventListener) processEvent(ctx context.Context, event sui.Event) {
+	log.Printf("Received event: Type=%s, Tx=%s, Seq=%s", event.Type, event.ID.TxDigest, event.ID.EventSeq)
+	if event.Type != l.eventType {
+		log.Printf("Skipping event with unexpected type: %s", event.Type)
+		return
+	}
+
+	var siteDeployed SiteDeployedEvent
+	if err := json.Unmarshal(event.ParsedJSON, &siteDeployed); err != nil || siteDeployed.ProjectID == "" {
+		log.Printf("ERROR: Unusable SiteDeployed event data in tx %s (%v). Raw JSON: %s", event.ID.TxDigest, err, event.ParsedJSON)
+		return
+	}
+	log.Printf("Processing SiteDeployed event: Deployer=%s, ProjectID=%s", siteDeployed.Deployer, siteDeployed.ProjectID)
+	if err := l.handle(ctx, siteDeployed); err != nil {
+		log.Printf("ERROR: Failed to handle SiteDeployed event for project %s (tx %s): %v", siteDeployed.ProjectID, event.ID.TxDigest, err)
+	}
+}
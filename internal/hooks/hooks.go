@@ -0,0 +1,175 @@
+// Package hooks runs operator-configured commands (formatters, linters, type checks) over a freshly generated
+// project and reports their output, so quality gates can be enforced without hand-editing generated files.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+
+	"sui_ai_server/internal/types"
+)
+
+// Severity decides what a failing hook means for the project.
+type Severity string
+
+const (
+	SeverityWarning Severity = "warning" // Failure is reported; the project is deployed anyway
+	SeverityError   Severity = "error"   // Failure is reported and blocks the deploy
+)
+
+const (
+	// DefaultTimeout bounds a hook that doesn't set its own timeout.
+	DefaultTimeout = 2 * time.Minute
+	// maxOutputBytes caps the output kept from one hook; linters can be very chatty.
+	maxOutputBytes = 16 << 10
+	// dirPlaceholder in a command is replaced with the project directory (hooks also run inside it).
+	dirPlaceholder = "{dir}"
+)
+
+// Hook is one command run after generation (POST_GENERATION_HOOKS), e.g. "npx --no-install eslint --fix .".
+// The command is split on whitespace and run without a shell, in the project directory.
+type Hook struct {
+	Name         string        `mapstructure:"name"`
+	Command      string        `mapstructure:"command"`
+	ProjectTypes []string      `mapstructure:"projectTypes"` // types.ProjectType* it runs for; empty means every type
+	Severity     Severity      `mapstructure:"severity"`     // SeverityWarning (default) or SeverityError
+	Timeout      time.Duration `mapstructure:"timeout"`      // 0 uses DefaultTimeout
+}
+
+// Result is the outcome of one hook on one project.
+type Result struct {
+	Name     string   `json:"name"`
+	Severity Severity `json:"severity"`
+	Passed   bool     `json:"passed"`
+	Skipped  bool     `json:"skipped,omitempty"` // The hook's tool isn't installed; not counted as a failure
+	Output   string   `json:"output,omitempty"`  // Combined stdout and stderr, truncated
+}
+
+// Validate checks hooks as loaded from config.
+func Validate(hooks []Hook) error {
+	seen := make(map[string]bool)
+	for i, h := range hooks {
+		if h.Name == "" {
+			return fmt.Errorf("hook %d has no name", i+1)
+		}
+		if seen[h.Name] {
+			return fmt.Errorf("duplicate hook name %q", h.Name)
+		}
+		seen[h.Name] = true
+		if len(strings.Fields(h.Command)) == 0 {
+			return fmt.Errorf("hook %q has no command", h.Name)
+		}
+		switch h.Severity {
+		case "", SeverityWarning, SeverityError:
+		default:
+			return fmt.Errorf("hook %q: severity must be %q or %q, got %q", h.Name, SeverityWarning, SeverityError, h.Severity)
+		}
+		for _, t := range h.ProjectTypes {
+			if t != types.ProjectTypeReact && t != types.ProjectTypeStatic {
+				return fmt.Errorf("hook %q: unknown project type %q", h.Name, t)
+			}
+		}
+		if h.Timeout < 0 {
+			return fmt.Errorf("hook %q: timeout must not be negative", h.Name)
+		}
+	}
+	return nil
+}
+
+// Runner runs the configured hooks in order. A nil Runner runs none.
+type Runner struct {
+	hooks []Hook
+}
+
+// NewRunner creates a runner for hooks, which should have passed Validate. It returns nil when there are none.
+func NewRunner(hooks []Hook) *Runner {
+	if len(hooks) == 0 {
+		return nil
+	}
+	return &Runner{hooks: hooks}
+}
+
+// Run runs every hook that applies to projectType ("" means React) in dir and returns their results, and
+// whether any error-severity hook failed. A hook whose tool isn't installed is skipped rather than failed.
+func (r *Runner) Run(ctx context.Context, dir, projectType string) (results []Result, blocked bool) {
+	if r == nil {
+		return nil, false
+	}
+	if projectType == "" {
+		projectType = types.ProjectTypeReact
+	}
+	for _, h := range r.hooks {
+		if len(h.ProjectTypes) > 0 && !contains(h.ProjectTypes, projectType) {
+			continue
+		}
+		result := runHook(ctx, dir, h)
+		if !result.Passed && !result.Skipped {
+			log.Printf("Post-generation hook %q failed in %s (%s)", h.Name, dir, result.Severity)
+			blocked = blocked || result.Severity == SeverityError
+		}
+		results = append(results, result)
+	}
+	return results, blocked
+}
+
+func runHook(ctx context.Context, dir string, h Hook) Result {
+	result := Result{Name: h.Name, Severity: h.Severity}
+	if result.Severity == "" {
+		result.Severity = SeverityWarning
+	}
+	timeout := h.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	args := strings.Fields(h.Command)
+	for i, arg := range args {
+		args[i] = strings.ReplaceAll(arg, dirPlaceholder, dir)
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.WaitDelay = 5 * time.Second
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	result.Output = truncate(output.String())
+	switch {
+	case err == nil:
+		result.Passed = true
+	case errors.Is(err, exec.ErrNotFound), errors.Is(err, fs.ErrNotExist):
+		log.Printf("Skipping post-generation hook %q: %v", h.Name, err)
+		result.Skipped = true
+		result.Output = fmt.Sprintf("%s is not installed; hook skipped", args[0])
+	case ctx.Err() == context.DeadlineExceeded:
+		result.Output = strings.TrimSpace(result.Output + fmt.Sprintf("\n(timed out after %s)", timeout))
+	}
+	return result
+}
+
+func truncate(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) <= maxOutputBytes {
+		return s
+	}
+	return s[:maxOutputBytes] + "\n... (truncated)"
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
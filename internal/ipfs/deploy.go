@@ -0,0 +1,210 @@
+// Package ipfs publishes built sites to IPFS through a Kubo-compatible HTTP API (a local node or a pinning
+// service that exposes /api/v0/add).
+package ipfs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"sui_ai_server/internal/sui/walrus"
+	"sui_ai_server/internal/types"
+)
+
+// DefaultGatewayURL is the public gateway used for site URLs unless WithGateway says otherwise.
+const DefaultGatewayURL = "https://ipfs.io"
+
+// ErrAddFailed is returned when the IPFS API rejects or fails an upload.
+var ErrAddFailed = errors.New("ipfs add failed")
+
+// Builder turns a project directory into the directory to publish and lists what it contains (see
+// walrus.Deployer.Build and Manifest).
+type Builder interface {
+	Build(ctx context.Context, projectDir string, opts walrus.DeployOptions) (string, error)
+	Manifest(publishDir string) (*types.BuildManifest, error)
+}
+
+// Deployer builds a project and pins its output to IPFS as one directory.
+type Deployer struct {
+	apiURL     string // Base URL of the IPFS HTTP API, e.g. "http://127.0.0.1:5001"
+	pinToken   string // Bearer token for pinning services; empty sends no Authorization header
+	gatewayURL string
+	builder    Builder
+	httpClient *http.Client
+}
+
+// Option configures optional Deployer settings.
+type Option func(*Deployer)
+
+// WithGateway sets the gateway used to build site URLs. Empty keeps DefaultGatewayURL.
+func WithGateway(gatewayURL string) Option {
+	return func(d *Deployer) {
+		if gatewayURL != "" {
+			d.gatewayURL = strings.TrimRight(gatewayURL, "/")
+		}
+	}
+}
+
+// WithHTTPClient replaces the client used to call the IPFS API.
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Deployer) {
+		d.httpClient = client
+	}
+}
+
+// NewDeployer creates a deployer that builds with builder and adds the result through the API at apiURL.
+func NewDeployer(apiURL, pinToken string, builder Builder, opts ...Option) *Deployer {
+	d := &Deployer{
+		apiURL:     strings.TrimRight(apiURL, "/"),
+		pinToken:   pinToken,
+		gatewayURL: DefaultGatewayURL,
+		builder:    builder,
+		httpClient: &http.Client{Timeout: 10 * time.Minute}, // Large sites upload slowly; ctx bounds it further
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Deploy builds the project in projectDir and pins the output directory, returning its root CID. Vite's default
+// base of "/" works on subdomain gateways; for path gateways (/ipfs/<cid>/) deploy with a relative base.
+func (d *Deployer) Deploy(ctx context.Context, projectDir string, opts walrus.DeployOptions) (*types.DeployResult, error) {
+	publishDir, err := d.builder.Build(ctx, projectDir, opts)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := d.builder.Manifest(publishDir)
+	if err != nil {
+		log.Printf("WARN: %v", err) // Only informational; publish anyway
+	}
+	if opts.Progress != nil {
+		opts.Progress("ipfs add", "stdout", "Adding "+publishDir+" to IPFS")
+	}
+	cid, err := d.addDir(ctx, publishDir)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Pinned %s to IPFS as %s", publishDir, cid)
+	if opts.Progress != nil {
+		opts.Progress("ipfs add", "stdout", "Pinned as "+cid)
+	}
+	return &types.DeployResult{
+		Backend:   types.DeployBackendIPFS,
+		SiteID:    cid,
+		URL:       d.gatewayURL + "/ipfs/" + cid + "/",
+		Artifacts: manifest,
+	}, nil
+}
+
+// addEntry is one line of the API's newline-delimited add response.
+type addEntry struct {
+	Name string `json:"Name"`
+	Hash string `json:"Hash"`
+}
+
+// addDir uploads every file under dir, wrapped in a directory and pinned, and returns the wrapper's CID.
+func (d *Deployer) addDir(ctx context.Context, dir string) (string, error) {
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		writer.CloseWithError(writeDirForm(form, dir))
+	}()
+
+	query := url.Values{"pin": {"true"}, "wrap-with-directory": {"true"}, "cid-version": {"1"}, "progress": {"false"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.apiURL+"/api/v0/add?"+query.Encode(), body)
+	if err != nil {
+		body.Close()
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if d.pinToken != "" {
+		req.Header.Set("Authorization", "Bearer "+d.pinToken)
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		body.Close()
+		return "", fmt.Errorf("%w: %w", ErrAddFailed, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("%w: status %d: %s", ErrAddFailed, resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	// The wrapping directory is the entry with an empty name, reported last.
+	var root string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var entry addEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue // Progress or other lines we don't need
+		}
+		if entry.Name == "" && entry.Hash != "" {
+			root = entry.Hash
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("%w: reading response: %w", ErrAddFailed, err)
+	}
+	if root == "" {
+		return "", fmt.Errorf("%w: no root CID in response", ErrAddFailed)
+	}
+	return root, nil
+}
+
+// writeDirForm writes dir's subdirectories and files as the multipart parts the add endpoint expects, parents
+// before their contents, and closes the form.
+func writeDirForm(form *multipart.Writer, dir string) error {
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		name := url.PathEscape(filepath.ToSlash(rel))
+		header := make(textproto.MIMEHeader)
+		if entry.IsDir() {
+			header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, name))
+			header.Set("Content-Type", "application/x-directory")
+			_, err := form.CreatePart(header)
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil // Symlinks and the like aren't part of a built site
+		}
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, name))
+		header.Set("Content-Type", "application/octet-stream")
+		part, err := form.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(part, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return form.Close()
+}
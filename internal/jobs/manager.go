@@ -0,0 +1,421 @@
+// Package jobs runs long operations (builds and deploys) in the background through a FIFO queue with a
+// concurrency cap, and keeps their status so clients can poll it.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrJobNotFound is returned for unknown (or expired) job IDs.
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobFinished is returned when cancelling a job that has already finished.
+	ErrJobFinished = errors.New("job already finished")
+	// ErrJobCancelled is the error of a cancelled job.
+	ErrJobCancelled = errors.New("job cancelled")
+)
+
+// State is where a job is in its lifecycle.
+type State string
+
+const (
+	StateQueued    State = "queued"
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+// Job kinds.
+const (
+	KindDeploy        = "deploy"
+	KindBatchGenerate = "batch-generate"
+	KindRefine        = "refine"
+)
+
+// Task is the work a job performs. ctx is cancelled when the server shuts down or the job is cancelled; tasks
+// may report intermediate status through it with SetProgress. A task whose job is cancelled before it starts
+// is still called, with ctx already cancelled, so that it can release anything set aside for it; it should
+// return as soon as it sees ctx is done.
+type Task func(ctx context.Context) (result any, err error)
+
+// Job is a snapshot of a job's status.
+type Job struct {
+	ID                   string     `json:"id"`
+	Kind                 string     `json:"kind"`
+	ProjectID            string     `json:"projectId,omitempty"`
+	State                State      `json:"state"`
+	Position             int        `json:"position,omitempty"`             // 1-based place in the queue while queued
+	EstimatedWaitSeconds float64    `json:"estimatedWaitSeconds,omitempty"` // Until the job starts, from running jobs' start times and recent run times
+	Progress             any        `json:"progress,omitempty"`             // Latest SetProgress value from a running task
+	Result               any        `json:"result,omitempty"`
+	Error                string     `json:"error,omitempty"`
+	CreatedAt            time.Time  `json:"createdAt"`
+	StartedAt            *time.Time `json:"startedAt,omitempty"`
+	FinishedAt           *time.Time `json:"finishedAt,omitempty"`
+
+	err error // The task's error, kept for errors.Is checks (Error only has its text)
+}
+
+// Err returns the error a failed job's task returned, or nil.
+func (j Job) Err() error {
+	return j.err
+}
+
+// Stats summarises the queue for metrics.
+type Stats struct {
+	Queued             int     `json:"queued"`
+	Running            int     `json:"running"`
+	Concurrency        int     `json:"concurrency"`
+	AvgDurationSeconds float64 `json:"avgDurationSeconds"` // Over recently finished jobs; 0 until one finishes
+}
+
+const (
+	// recentDurations is how many finished jobs the average run time is computed over.
+	recentDurations = 20
+	// finishedJobTTL is how long finished jobs stay queryable.
+	finishedJobTTL = time.Hour
+)
+
+type entry struct {
+	job       Job
+	task      Task
+	done      chan struct{}      // Closed when the job finishes
+	cancel    context.CancelFunc // Cancels the running task's context; nil until the job starts
+	cancelled bool               // Cancel was called while the job was running
+}
+
+// Manager queues jobs and runs at most concurrency of them at a time, in submission order.
+type Manager struct {
+	concurrency int
+
+	mu        sync.Mutex
+	cond      *sync.Cond
+	jobs      map[string]*entry
+	queue     []*entry // Waiting jobs, oldest first
+	running   int
+	durations []time.Duration // Run times of the most recent finished jobs
+	draining  bool            // Set by Drain; workers start no more jobs
+	active    sync.WaitGroup  // Running tasks, for Drain
+}
+
+// NewManager creates a manager that runs up to concurrency jobs at once (at least one). Call Run to start it.
+func NewManager(concurrency int) *Manager {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	m := &Manager{concurrency: concurrency, jobs: make(map[string]*entry)}
+	m.cond = sync.NewCond(&m.mu)
+	return m
+}
+
+// Run starts the workers. They stop taking new jobs once ctx is cancelled; ctx is also passed to running tasks.
+func (m *Manager) Run(ctx context.Context) {
+	for i := 0; i < m.concurrency; i++ {
+		go m.worker(ctx)
+	}
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		m.cond.Broadcast()
+		m.mu.Unlock()
+	}()
+}
+
+// Drain stops the workers from starting queued jobs and waits until the running ones finish or ctx is done,
+// returning ctx's error in that case. Jobs still queued are left unstarted. Cancel Run's context afterwards to
+// stop whatever is still running.
+func (m *Manager) Drain(ctx context.Context) error {
+	m.mu.Lock()
+	m.draining = true
+	m.cond.Broadcast()
+	queued, running := len(m.queue), m.running
+	m.mu.Unlock()
+	if running > 0 || queued > 0 {
+		log.Printf("Draining job queue: waiting for %d running jobs; %d queued jobs will not start", running, queued)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		m.active.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Submit queues task and returns the new job's status, including its queue position and estimated wait.
+func (m *Manager) Submit(kind, projectID string, task Task) Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pruneLocked(time.Now())
+
+	e := &entry{
+		job: Job{
+			ID:        uuid.New().String(),
+			Kind:      kind,
+			ProjectID: projectID,
+			State:     StateQueued,
+			CreatedAt: time.Now(),
+		},
+		task: task,
+		done: make(chan struct{}),
+	}
+	m.jobs[e.job.ID] = e
+	m.queue = append(m.queue, e)
+	m.cond.Signal()
+
+	log.Printf("Queued %s job %s for project %s (position %d)", kind, e.job.ID, projectID, len(m.queue))
+	return m.snapshotLocked(e)
+}
+
+// Get returns the current status of a job.
+func (m *Manager) Get(jobID string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[jobID]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	return m.snapshotLocked(e), nil
+}
+
+// Wait blocks until the job finishes or ctx is done, and returns its final status.
+// Giving up on waiting does not cancel the job.
+func (m *Manager) Wait(ctx context.Context, jobID string) (Job, error) {
+	m.mu.Lock()
+	e, ok := m.jobs[jobID]
+	m.mu.Unlock()
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	select {
+	case <-e.done:
+		return m.Get(jobID)
+	case <-ctx.Done():
+		return Job{}, ctx.Err()
+	}
+}
+
+// Cancel cancels a job. A queued job leaves the queue and is cancelled at once. A running job's context is
+// cancelled, aborting its model calls and commands, and the job is marked cancelled once its task returns;
+// Wait for it to see that. The returned status is the job's right after the request.
+func (m *Manager) Cancel(jobID string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[jobID]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	switch e.job.State {
+	case StateQueued:
+		for i, queued := range m.queue {
+			if queued == e {
+				m.queue = append(m.queue[:i], m.queue[i+1:]...)
+				break
+			}
+		}
+		m.finishCancelledLocked(e)
+		go func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			e.task(ctx) // Only to let it clean up; see Task
+		}()
+	case StateRunning:
+		if !e.cancelled {
+			e.cancelled = true
+			e.cancel()
+			log.Printf("Cancelling running %s job %s for project %s", e.job.Kind, e.job.ID, e.job.ProjectID)
+		}
+	default:
+		return m.snapshotLocked(e), ErrJobFinished
+	}
+	return m.snapshotLocked(e), nil
+}
+
+func (m *Manager) finishCancelledLocked(e *entry) {
+	finished := time.Now()
+	e.job.State = StateCancelled
+	e.job.FinishedAt = &finished
+	e.job.Error = ErrJobCancelled.Error()
+	e.job.err = ErrJobCancelled
+	close(e.done)
+	log.Printf("%s job %s for project %s cancelled", e.job.Kind, e.job.ID, e.job.ProjectID)
+}
+
+// Busy reports whether projectID has a queued or running job.
+func (m *Manager) Busy(projectID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.jobs {
+		if e.job.ProjectID == projectID && (e.job.State == StateQueued || e.job.State == StateRunning) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats returns the queue length, running count and average recent run time.
+func (m *Manager) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return Stats{
+		Queued:             len(m.queue),
+		Running:            m.running,
+		Concurrency:        m.concurrency,
+		AvgDurationSeconds: m.avgDurationLocked().Seconds(),
+	}
+}
+
+func (m *Manager) worker(ctx context.Context) {
+	for {
+		m.mu.Lock()
+		for len(m.queue) == 0 && ctx.Err() == nil && !m.draining {
+			m.cond.Wait()
+		}
+		if ctx.Err() != nil || m.draining {
+			m.mu.Unlock()
+			return
+		}
+		e := m.queue[0]
+		m.queue[0] = nil
+		m.queue = m.queue[1:]
+		started := time.Now()
+		e.job.State = StateRunning
+		e.job.StartedAt = &started
+		taskCtx, cancel := context.WithCancel(context.WithValue(ctx, progressKey{}, progressTarget{m: m, e: e}))
+		e.cancel = cancel
+		m.running++
+		m.active.Add(1)
+		m.mu.Unlock()
+
+		result, err := e.task(taskCtx)
+		cancel()
+		m.active.Done()
+
+		m.mu.Lock()
+		m.running--
+		if e.cancelled {
+			m.finishCancelledLocked(e) // Its partial run time would skew the average
+			m.mu.Unlock()
+			continue
+		}
+		finished := time.Now()
+		e.job.FinishedAt = &finished
+		if err != nil {
+			e.job.State = StateFailed
+			e.job.Error = err.Error()
+			e.job.err = err
+			log.Printf("%s job %s for project %s failed: %v", e.job.Kind, e.job.ID, e.job.ProjectID, err)
+		} else {
+			e.job.State = StateSucceeded
+			e.job.Result = result
+		}
+		m.durations = append(m.durations, finished.Sub(started))
+		if len(m.durations) > recentDurations {
+			m.durations = m.durations[len(m.durations)-recentDurations:]
+		}
+		close(e.done)
+		m.mu.Unlock()
+	}
+}
+
+type progressKey struct{}
+
+type progressTarget struct {
+	m *Manager
+	e *entry
+}
+
+// SetProgress records progress for the job whose task was given ctx, shown in the job's status until it
+// finishes. It does nothing for contexts that don't belong to a job.
+func SetProgress(ctx context.Context, progress any) {
+	target, ok := ctx.Value(progressKey{}).(progressTarget)
+	if !ok {
+		return
+	}
+	target.m.mu.Lock()
+	target.e.job.Progress = progress
+	target.m.mu.Unlock()
+}
+
+// snapshotLocked copies a job's status, filling in its current queue position and estimated wait.
+func (m *Manager) snapshotLocked(e *entry) Job {
+	job := e.job
+	if job.State != StateQueued {
+		return job
+	}
+	for i, queued := range m.queue {
+		if queued == e {
+			job.Position = i + 1
+			break
+		}
+	}
+	if job.Position > 0 {
+		job.EstimatedWaitSeconds = m.waitLocked(job.Position, time.Now()).Seconds()
+	}
+	return job
+}
+
+// waitLocked estimates how long the job at a 1-based queue position waits to start. Each running job is
+// expected to take the average run time from when it started, and each job ahead in the queue takes the
+// first slot to free up for the average run time. It is 0 until a job has finished, since there is nothing
+// to estimate from.
+func (m *Manager) waitLocked(position int, now time.Time) time.Duration {
+	avg := m.avgDurationLocked()
+	if avg == 0 {
+		return 0
+	}
+	slots := make([]time.Duration, m.concurrency) // When each slot frees up, from now; idle slots are free at 0
+	i := 0
+	for _, e := range m.jobs {
+		if e.job.State == StateRunning && i < len(slots) {
+			slots[i] = max(avg-now.Sub(*e.job.StartedAt), 0)
+			i++
+		}
+	}
+	for ; position > 1; position-- {
+		slots[earliestSlot(slots)] += avg
+	}
+	return slots[earliestSlot(slots)]
+}
+
+func earliestSlot(slots []time.Duration) int {
+	earliest := 0
+	for i, free := range slots {
+		if free < slots[earliest] {
+			earliest = i
+		}
+	}
+	return earliest
+}
+
+func (m *Manager) avgDurationLocked() time.Duration {
+	if len(m.durations) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, d := range m.durations {
+		total += d
+	}
+	return total / time.Duration(len(m.durations))
+}
+
+// pruneLocked forgets jobs that finished more than finishedJobTTL ago.
+func (m *Manager) pruneLocked(now time.Time) {
+	for id, e := range m.jobs {
+		if e.job.FinishedAt != nil && now.Sub(*e.job.FinishedAt) > finishedJobTTL {
+			delete(m.jobs, id)
+		}
+	}
+}
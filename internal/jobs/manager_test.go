@@ -0,0 +1,167 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitState polls until the job reaches state or the test times out.
+func waitState(t *testing.T, m *Manager, jobID string, state State) Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := m.Get(jobID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.State == state {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s is %s, want %s", jobID, job.State, state)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestManagerRunsJobsInSubmissionOrder(t *testing.T) {
+	m := NewManager(1)
+	var mu sync.Mutex
+	var order []string
+	var ids []string
+	for i := range 5 {
+		name := fmt.Sprintf("p%d", i)
+		job := m.Submit(KindDeploy, name, func(ctx context.Context) (any, error) {
+			mu.Lock()
+			order = append(order, name)
+			mu.Unlock()
+			return nil, nil
+		})
+		if job.Position != i+1 {
+			t.Errorf("job %d queued at position %d, want %d", i, job.Position, i+1)
+		}
+		ids = append(ids, job.ID)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.Run(ctx)
+	for _, id := range ids {
+		if _, err := m.Wait(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := fmt.Sprint(order); got != "[p0 p1 p2 p3 p4]" {
+		t.Errorf("ran in order %s, want submission order", got)
+	}
+}
+
+func TestManagerConcurrencyCap(t *testing.T) {
+	const concurrency = 2
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewManager(concurrency)
+	m.Run(ctx)
+
+	release := make(chan struct{})
+	var mu sync.Mutex
+	current, peak := 0, 0
+	var ids []string
+	for i := range 5 {
+		job := m.Submit(KindDeploy, fmt.Sprintf("p%d", i), func(ctx context.Context) (any, error) {
+			mu.Lock()
+			current++
+			peak = max(peak, current)
+			mu.Unlock()
+			<-release
+			mu.Lock()
+			current--
+			mu.Unlock()
+			return nil, nil
+		})
+		ids = append(ids, job.ID)
+	}
+
+	waitState(t, m, ids[0], StateRunning)
+	waitState(t, m, ids[1], StateRunning)
+	if stats := m.Stats(); stats.Running != concurrency || stats.Queued != 3 {
+		t.Errorf("stats = %+v, want %d running and 3 queued", stats, concurrency)
+	}
+	if job, _ := m.Get(ids[4]); job.State != StateQueued || job.Position != 3 {
+		t.Errorf("last job is %s at position %d, want queued at 3", job.State, job.Position)
+	}
+
+	close(release)
+	for _, id := range ids {
+		if job, err := m.Wait(ctx, id); err != nil || job.State != StateSucceeded {
+			t.Fatalf("job %s = %s, %v; want succeeded", id, job.State, err)
+		}
+	}
+	if peak != concurrency {
+		t.Errorf("up to %d jobs ran at once, want %d", peak, concurrency)
+	}
+}
+
+func TestDrainWaitsForRunningJobs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewManager(1)
+	m.Run(ctx)
+
+	release := make(chan struct{})
+	running := m.Submit(KindDeploy, "p1", func(ctx context.Context) (any, error) {
+		select {
+		case <-release:
+			return "done", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	})
+	waitState(t, m, running.ID, StateRunning)
+	queued := m.Submit(KindDeploy, "p2", func(ctx context.Context) (any, error) { return "done", nil })
+
+	drained := make(chan error, 1)
+	go func() { drained <- m.Drain(context.Background()) }()
+	select {
+	case err := <-drained:
+		t.Fatalf("Drain returned %v while a job was running", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-drained; err != nil {
+		t.Fatalf("Drain = %v, want nil", err)
+	}
+	if job := waitState(t, m, running.ID, StateSucceeded); job.Result != "done" {
+		t.Errorf("running job result = %v, want done", job.Result)
+	}
+	if job, _ := m.Get(queued.ID); job.State != StateQueued {
+		t.Errorf("queued job is %s after Drain, want it left queued", job.State)
+	}
+}
+
+func TestDrainTimesOut(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewManager(1)
+	m.Run(ctx)
+
+	job := m.Submit(KindRefine, "p1", func(ctx context.Context) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	waitState(t, m, job.ID, StateRunning)
+
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer drainCancel()
+	if err := m.Drain(drainCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Drain = %v, want %v", err, context.DeadlineExceeded)
+	}
+	// Cancelling Run's context afterwards stops the job.
+	cancel()
+	waitState(t, m, job.ID, StateFailed)
+}
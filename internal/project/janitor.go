@@ -0,0 +1,139 @@
+package project
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"sui_ai_server/internal/utils"
+
+	"github.com/google/uuid"
+)
+
+// DefaultJanitorInterval is how often the janitor sweeps unless WithSweepInterval says otherwise.
+const DefaultJanitorInterval = time.Hour
+
+// Janitor removes the working directories of projects that were never deployed and haven't been touched
+// for a TTL, so abandoned generations don't fill the disk. Metadata is kept.
+type Janitor struct {
+	store    *Store
+	ttl      time.Duration
+	interval time.Duration
+	busy     func(projectID string) bool // Reports projects with a queued or running job; nil means none
+}
+
+// JanitorOption configures optional Janitor settings.
+type JanitorOption func(*Janitor)
+
+// WithSweepInterval sets how often the work directory is scanned. Non-positive values are ignored.
+func WithSweepInterval(d time.Duration) JanitorOption {
+	return func(j *Janitor) {
+		if d > 0 {
+			j.interval = d
+		}
+	}
+}
+
+// WithBusyCheck skips projects for which busy returns true, e.g. ones with a deploy in the queue.
+func WithBusyCheck(busy func(projectID string) bool) JanitorOption {
+	return func(j *Janitor) {
+		j.busy = busy
+	}
+}
+
+// NewJanitor creates a janitor for the project directories under store's work directory that removes those
+// idle for longer than ttl.
+func NewJanitor(store *Store, ttl time.Duration, opts ...JanitorOption) *Janitor {
+	j := &Janitor{store: store, ttl: ttl, interval: DefaultJanitorInterval}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+// Run sweeps once immediately and then every interval until ctx is cancelled.
+func (j *Janitor) Run(ctx context.Context) {
+	log.Printf("Project janitor started (removing undeployed projects idle for %s, every %s)", j.ttl, j.interval)
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		j.Sweep(ctx)
+		select {
+		case <-ctx.Done():
+			log.Println("Context cancelled, stopping project janitor.")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep removes every stale, never-deployed project directory and returns how many it removed. Directories
+// that aren't named like a project ID (metadata, checkouts) are left alone.
+func (j *Janitor) Sweep(ctx context.Context) int {
+	entries, err := os.ReadDir(j.store.baseDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("WARN: Janitor failed to read %s: %v", j.store.baseDir, err)
+		}
+		return 0
+	}
+
+	now := time.Now()
+	removed := 0
+	var freed int64
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			break
+		}
+		projectID := entry.Name()
+		if !entry.IsDir() || uuid.Validate(projectID) != nil {
+			continue
+		}
+		dir := filepath.Join(j.store.baseDir, projectID)
+		if !j.stale(projectID, dir, now) {
+			continue
+		}
+		size := utils.DirSize(dir)
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("WARN: Janitor failed to remove %s: %v", dir, err)
+			continue
+		}
+		log.Printf("Janitor removed stale project %s (%d bytes)", projectID, size)
+		removed++
+		freed += size
+	}
+	if removed > 0 {
+		log.Printf("Janitor reclaimed %d bytes from %d stale projects", freed, removed)
+	}
+	return removed
+}
+
+// stale reports whether a project directory may be removed: never deployed, not busy, and idle (by its
+// metadata, or the directory itself when there is none) for longer than the TTL.
+func (j *Janitor) stale(projectID, dir string, now time.Time) bool {
+	var lastUsed time.Time
+	meta, err := j.store.Get(projectID)
+	switch {
+	case err == nil:
+		if meta.SiteObjectID != "" || meta.SiteCID != "" || meta.Status == StatusDeployed {
+			return false
+		}
+		lastUsed = meta.UpdatedAt
+	case errors.Is(err, ErrProjectNotFound):
+		info, err := os.Stat(dir)
+		if err != nil {
+			return false
+		}
+		lastUsed = info.ModTime()
+	default:
+		log.Printf("WARN: Janitor skipping project %s: %v", projectID, err)
+		return false
+	}
+	if now.Sub(lastUsed) <= j.ttl {
+		return false
+	}
+	return j.busy == nil || !j.busy(projectID)
+}
@@ -0,0 +1,181 @@
+package project
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"sui_ai_server/internal/types"
+)
+
+// ErrProjectNotFound is returned when no metadata exists for a project ID.
+var ErrProjectNotFound = errors.New("project not found")
+
+// Status describes where a project is in its lifecycle.
+type Status string
+
+const (
+	StatusGenerating Status = "generating"
+	StatusGenerated  Status = "generated"
+	StatusDeployed   Status = "deployed"
+	StatusFailed     Status = "failed"
+)
+
+// Metadata is the persisted record for a generated project.
+// File contents live in the project directory; this only tracks identity and state.
+type Metadata struct {
+	ID              string    `json:"id"`
+	Wallet          string    `json:"wallet"`
+	Prompt          string    `json:"prompt"`
+	ProjectType     string    `json:"projectType,omitempty"`     // types.ProjectType*; empty means a React project
+	Pages           []string  `json:"pages,omitempty"`           // Extra pages requested on top of the built-in set
+	TailwindVersion int       `json:"tailwindVersion,omitempty"` // Requested Tailwind major version; 0 means the server default
+	TailwindPlugins []string  `json:"tailwindPlugins,omitempty"` // Requested Tailwind plugins
+	TemplateName    string    `json:"templateName,omitempty"`    // Base template the project was generated from
+	Locale          string    `json:"locale,omitempty"`          // Language of the site's copy; empty means English
+	Theme           string    `json:"theme,omitempty"`           // Visual theme from the generation profile, if one was used
+	Instructions    string    `json:"instructions,omitempty"`    // Extra prompt requirements from the generation profile
+	Model           string    `json:"model,omitempty"`           // Model that generated the current files
+	ClonedFrom      string    `json:"clonedFrom,omitempty"`      // Source project ID when this project is a clone
+	Status          Status    `json:"status"`
+	SiteObjectID    string    `json:"siteObjectId,omitempty"` // Walrus site object, once deployed there
+	SiteCID         string    `json:"siteCid,omitempty"`      // IPFS root CID, once deployed with DEPLOY_BACKEND=ipfs
+	SuinsName       string    `json:"suinsName,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+// SiteOptions returns the generation options recorded for this project.
+func (m *Metadata) SiteOptions() types.SiteOptions {
+	return types.SiteOptions{ProjectType: m.ProjectType, Pages: m.Pages, TailwindVersion: m.TailwindVersion, TailwindPlugins: m.TailwindPlugins, Template: m.TemplateName, Locale: m.Locale,
+		Theme: m.Theme, Instructions: m.Instructions}
+}
+
+// Store persists project metadata as JSON files under <baseDir>/.meta.
+type Store struct {
+	baseDir string
+	mu      sync.Mutex
+}
+
+// NewStore creates a metadata store rooted at baseDir (the same work directory that holds project files).
+func NewStore(baseDir string) *Store {
+	return &Store{baseDir: baseDir}
+}
+
+func (s *Store) metaPath(projectID string) string {
+	return filepath.Join(s.baseDir, ".meta", projectID+".json")
+}
+
+// Get loads the metadata for a project.
+func (s *Store) Get(projectID string) (*Metadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read(projectID)
+}
+
+func (s *Store) read(projectID string) (*Metadata, error) {
+	data, err := os.ReadFile(s.metaPath(projectID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, fmt.Errorf("failed to read metadata for project %s: %w", projectID, err)
+	}
+	var meta Metadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata for project %s: %w", projectID, err)
+	}
+	return &meta, nil
+}
+
+// Save writes the metadata, stamping UpdatedAt (and CreatedAt for new records).
+func (s *Store) Save(meta *Metadata) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(meta)
+}
+
+func (s *Store) write(meta *Metadata) error {
+	now := time.Now().UTC()
+	if meta.CreatedAt.IsZero() {
+		meta.CreatedAt = now
+	}
+	meta.UpdatedAt = now
+
+	path := s.metaPath(meta.ID)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create metadata directory: %w", err)
+	}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata for project %s: %w", meta.ID, err)
+	}
+	// Write to a temp file and rename so a crash never leaves a half-written record.
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write metadata for project %s: %w", meta.ID, err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// Update applies fn to the stored metadata and saves the result atomically with respect to other Store calls.
+func (s *Store) Update(projectID string, fn func(meta *Metadata)) (*Metadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	meta, err := s.read(projectID)
+	if err != nil {
+		return nil, err
+	}
+	fn(meta)
+	if err := s.write(meta); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+// FindBySuinsName returns the project a SUINS name (as normalized when registered) is mapped to. Should
+// several projects carry the name, the most recently updated wins. ErrProjectNotFound means none does.
+func (s *Store) FindBySuinsName(name string) (*Metadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entries, err := os.ReadDir(filepath.Join(s.baseDir, ".meta"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, fmt.Errorf("failed to list project metadata: %w", err)
+	}
+	var found *Metadata
+	for _, entry := range entries {
+		projectID, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		meta, err := s.read(projectID)
+		if err != nil {
+			continue // A record being rewritten or damaged; it can't be the one we're after
+		}
+		if meta.SuinsName == name && (found == nil || meta.UpdatedAt.After(found.UpdatedAt)) {
+			found = meta
+		}
+	}
+	if found == nil {
+		return nil, ErrProjectNotFound
+	}
+	return found, nil
+}
+
+// Delete removes the project's metadata. Deleting an unknown project is not an error.
+func (s *Store) Delete(projectID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.Remove(s.metaPath(projectID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete metadata for project %s: %w", projectID, err)
+	}
+	return nil
+}
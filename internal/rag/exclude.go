@@ -0,0 +1,91 @@
+package rag
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+)
+
+// DefaultExcludePatterns keep lockfiles, dependencies, build output and minified assets out of RAG context.
+// They only waste tokens and crowd out the source a query is actually about.
+var DefaultExcludePatterns = []string{
+	"package-lock.json",
+	"yarn.lock",
+	"pnpm-lock.yaml",
+	"bun.lockb",
+	"node_modules/**",
+	"dist/**",
+	"*.min.js",
+	"*.min.css",
+	"*.map",
+}
+
+// ValidateExcludePatterns reports the first malformed glob in patterns.
+func ValidateExcludePatterns(patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(strings.TrimSuffix(p, "/**"), ""); err != nil {
+			return fmt.Errorf("invalid exclude pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// ExcludeFiles drops files matching any of patterns, plus binary files (those DetermineFileType reports as
+// "image" or "unknown"), which never belong in a text prompt. A pattern without a slash matches the file's base
+// name in any directory; one with a slash matches the whole path. A trailing "/**" matches everything under
+// that directory.
+func ExcludeFiles(files []types.GeneratedFile, patterns []string) []types.GeneratedFile {
+	kept := make([]types.GeneratedFile, 0, len(files))
+	for _, f := range files {
+		if isBinaryFile(f.Filename) || matchesAny(f.Filename, patterns) {
+			continue
+		}
+		kept = append(kept, f)
+	}
+	return kept
+}
+
+func isBinaryFile(filename string) bool {
+	switch utils.DetermineFileType(filename) {
+	case "image", "unknown":
+		return true
+	}
+	return false
+}
+
+func matchesAny(filename string, patterns []string) bool {
+	for _, p := range patterns {
+		if matchExclude(p, filename) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchExclude reports whether the slash-separated filename matches pattern (see ExcludeFiles).
+func matchExclude(pattern, filename string) bool {
+	if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+		segments := strings.Split(filename, "/")
+		for i := range segments[:len(segments)-1] {
+			prefix := strings.Join(segments[:i+1], "/")
+			if ok, _ := path.Match(dir, prefix); ok {
+				return true
+			}
+			if !strings.Contains(dir, "/") {
+				if ok, _ := path.Match(dir, segments[i]); ok {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(filename))
+		return ok
+	}
+	ok, _ := path.Match(pattern, filename)
+	return ok
+}
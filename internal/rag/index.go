@@ -0,0 +1,239 @@
+package rag
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// IndexDir is the directory inside a project that holds its embedding index. It is not project source.
+const IndexDir = ".rag"
+
+// IndexPath returns where a project's embedding index is stored.
+func IndexPath(projectDir string) string {
+	return filepath.Join(projectDir, IndexDir, "index.json")
+}
+
+// ContentHash identifies a file version; an entry is only valid while its file still has this hash.
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// IndexEntry is one file's embedding. Files too large to embed whole have one vector per chunk instead.
+type IndexEntry struct {
+	Hash   string      `json:"hash"`
+	Vector []float32   `json:"vector,omitempty"`
+	Chunks [][]float32 `json:"chunks,omitempty"`
+}
+
+// vectors returns every vector stored for the file.
+func (e IndexEntry) vectors() [][]float32 {
+	if len(e.Chunks) > 0 {
+		return e.Chunks
+	}
+	return [][]float32{e.Vector}
+}
+
+// SearchResult is a file ranked by similarity to a query.
+type SearchResult struct {
+	Filename string
+	Score    float64 // Cosine similarity, -1..1
+}
+
+// indexFile is the on-disk form of an Index. Model and Dimensions record the embedding space the entries
+// belong to, so an index built with another model or size is discarded rather than compared against.
+type indexFile struct {
+	Model      string                `json:"model"`
+	Dimensions int                   `json:"dimensions,omitempty"`
+	Entries    map[string]IndexEntry `json:"entries"`
+}
+
+// Index is a per-project store of file embeddings keyed by filename and content hash, persisted as JSON.
+// It is loaded lazily on first use and safe for concurrent use.
+type Index struct {
+	path       string
+	model      string // Embedding model the vectors come from
+	dimensions int    // Length every vector must have; 0 skips the check
+
+	mu      sync.Mutex
+	loaded  bool
+	dirty   bool
+	entries map[string]IndexEntry
+}
+
+// NewIndex returns an index backed by the file at path (see IndexPath) for vectors of the given embedding
+// model and length (0 when unknown). Nothing is read until first use.
+func NewIndex(path, model string, dimensions int) *Index {
+	return &Index{path: path, model: model, dimensions: dimensions}
+}
+
+// Load reads the index from disk if it hasn't been loaded yet. A missing file is an empty index.
+func (ix *Index) Load() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.loadLocked()
+}
+
+func (ix *Index) loadLocked() error {
+	if ix.loaded {
+		return nil
+	}
+	ix.entries = make(map[string]IndexEntry)
+	data, err := os.ReadFile(ix.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			ix.loaded = true
+			return nil
+		}
+		return fmt.Errorf("failed to read embedding index %s: %w", ix.path, err)
+	}
+	var file indexFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Model != ix.model || file.Dimensions != ix.dimensions {
+		// A corrupt or stale index (older format, or another model or size) only costs re-embedding, so start
+		// over rather than failing every query.
+		log.Printf("Discarding embedding index %s built for %s/%d (now %s/%d)", ix.path, file.Model, file.Dimensions, ix.model, ix.dimensions)
+		ix.dirty = true
+	} else if file.Entries != nil {
+		ix.entries = file.Entries
+	}
+	ix.loaded = true
+	return nil
+}
+
+// Has reports whether filename has an embedding for exactly this content hash.
+func (ix *Index) Has(filename, hash string) bool {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if err := ix.loadLocked(); err != nil {
+		return false
+	}
+	entry, ok := ix.entries[filename]
+	return ok && entry.Hash == hash
+}
+
+// Upsert stores the embeddings of filename's content (identified by hash), one per chunk, replacing any
+// older version.
+func (ix *Index) Upsert(filename, hash string, vectors [][]float32) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if err := ix.loadLocked(); err != nil {
+		return err
+	}
+	for _, vector := range vectors {
+		if ix.dimensions > 0 && len(vector) != ix.dimensions {
+			return fmt.Errorf("embedding for %s has %d dimensions, index expects %d", filename, len(vector), ix.dimensions)
+		}
+	}
+	entry := IndexEntry{Hash: hash}
+	if len(vectors) == 1 {
+		entry.Vector = vectors[0]
+	} else {
+		entry.Chunks = vectors
+	}
+	ix.entries[filename] = entry
+	ix.dirty = true
+	return nil
+}
+
+// Invalidate drops entries for files that no longer exist or whose content hash changed.
+// current maps each existing filename to its content hash. It returns how many entries were dropped.
+func (ix *Index) Invalidate(current map[string]string) (int, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if err := ix.loadLocked(); err != nil {
+		return 0, err
+	}
+	dropped := 0
+	for filename, entry := range ix.entries {
+		if hash, ok := current[filename]; !ok || hash != entry.Hash {
+			delete(ix.entries, filename)
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		ix.dirty = true
+	}
+	return dropped, nil
+}
+
+// Search returns up to topK files ranked by cosine similarity to queryVec, most similar first. A chunked
+// file scores as its best-matching chunk. Vectors whose length differs from queryVec (e.g. from another
+// embedding model) are ignored.
+func (ix *Index) Search(queryVec []float32, topK int) ([]SearchResult, error) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if err := ix.loadLocked(); err != nil {
+		return nil, err
+	}
+	results := make([]SearchResult, 0, len(ix.entries))
+	for filename, entry := range ix.entries {
+		best, found := 0.0, false
+		for _, vector := range entry.vectors() {
+			if len(vector) != len(queryVec) {
+				continue
+			}
+			if score := cosineSimilarity(queryVec, vector); !found || score > best {
+				best, found = score, true
+			}
+		}
+		if found {
+			results = append(results, SearchResult{Filename: filename, Score: best})
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Filename < results[j].Filename
+	})
+	if topK > 0 && len(results) > topK {
+		results = results[:topK]
+	}
+	return results, nil
+}
+
+// Save writes the index to disk if it changed since it was loaded or last saved.
+func (ix *Index) Save() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if !ix.dirty {
+		return nil
+	}
+	data, err := json.Marshal(indexFile{Model: ix.model, Dimensions: ix.dimensions, Entries: ix.entries})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(ix.path), os.ModePerm); err != nil {
+		return err
+	}
+	tmp := ix.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, ix.path); err != nil {
+		return err
+	}
+	ix.dirty = false
+	return nil
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
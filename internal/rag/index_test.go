@@ -0,0 +1,128 @@
+package rag
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestIndex(t *testing.T) (*Index, string) {
+	t.Helper()
+	path := IndexPath(t.TempDir())
+	return NewIndex(path, "text-embedding-3-small", 3), path
+}
+
+func TestIndexUpsertAndSearch(t *testing.T) {
+	ix, _ := newTestIndex(t)
+	for name, vector := range map[string][]float32{
+		"a.js": {1, 0, 0},
+		"b.js": {0.9, 0.1, 0},
+		"c.js": {0, 0, 1},
+	} {
+		if err := ix.Upsert(name, ContentHash(name), [][]float32{vector}); err != nil {
+			t.Fatalf("Upsert %s: %v", name, err)
+		}
+	}
+
+	results, err := ix.Search([]float32{1, 0, 0}, 2)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) != 2 || results[0].Filename != "a.js" || results[1].Filename != "b.js" {
+		t.Fatalf("Search = %+v, want a.js then b.js", results)
+	}
+	if results[0].Score < 0.999 {
+		t.Errorf("identical vector scored %f, want 1", results[0].Score)
+	}
+	if !ix.Has("a.js", ContentHash("a.js")) || ix.Has("a.js", ContentHash("changed")) {
+		t.Error("Has doesn't match on the content hash")
+	}
+}
+
+func TestIndexChunkScoresAsBestChunk(t *testing.T) {
+	ix, _ := newTestIndex(t)
+	ix.Upsert("big.js", "h1", [][]float32{{0, 1, 0}, {0, 0, 1}})
+	ix.Upsert("small.js", "h2", [][]float32{{0, 0.5, 0.5}})
+
+	results, _ := ix.Search([]float32{0, 0, 1}, 0)
+	if len(results) != 2 || results[0].Filename != "big.js" {
+		t.Errorf("Search = %+v, want big.js first by its second chunk", results)
+	}
+}
+
+func TestIndexUpsertRejectsWrongDimensions(t *testing.T) {
+	ix, _ := newTestIndex(t)
+	if err := ix.Upsert("a.js", "h", [][]float32{{1, 0}}); err == nil {
+		t.Error("Upsert accepted a 2-dimensional vector into a 3-dimensional index")
+	}
+}
+
+func TestIndexInvalidate(t *testing.T) {
+	ix, _ := newTestIndex(t)
+	ix.Upsert("kept.js", "h1", [][]float32{{1, 0, 0}})
+	ix.Upsert("changed.js", "h2", [][]float32{{0, 1, 0}})
+	ix.Upsert("deleted.js", "h3", [][]float32{{0, 0, 1}})
+
+	dropped, err := ix.Invalidate(map[string]string{"kept.js": "h1", "changed.js": "h2-new"})
+	if err != nil || dropped != 2 {
+		t.Fatalf("Invalidate = %d, %v; want 2 dropped", dropped, err)
+	}
+	results, _ := ix.Search([]float32{1, 1, 1}, 0)
+	if len(results) != 1 || results[0].Filename != "kept.js" {
+		t.Errorf("after Invalidate, Search = %+v, want only kept.js", results)
+	}
+}
+
+func TestIndexPersists(t *testing.T) {
+	ix, path := newTestIndex(t)
+	ix.Upsert("a.js", "h1", [][]float32{{1, 0, 0}})
+	if err := ix.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	reloaded := NewIndex(path, "text-embedding-3-small", 3)
+	if !reloaded.Has("a.js", "h1") {
+		t.Error("a saved entry is missing after reloading")
+	}
+}
+
+func TestIndexDiscardedForAnotherEmbeddingSpace(t *testing.T) {
+	tests := []struct {
+		name       string
+		model      string
+		dimensions int
+	}{
+		{"other model", "text-embedding-ada-002", 3},
+		{"other dimensions", "text-embedding-3-small", 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ix, path := newTestIndex(t)
+			ix.Upsert("a.js", "h1", [][]float32{{1, 0, 0}})
+			if err := ix.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			other := NewIndex(path, tt.model, tt.dimensions)
+			if other.Has("a.js", "h1") {
+				t.Error("entries from another embedding space were kept")
+			}
+			if results, _ := other.Search([]float32{1, 0, 0}, 0); len(results) != 0 {
+				t.Errorf("Search = %+v, want nothing", results)
+			}
+		})
+	}
+}
+
+func TestIndexDiscardsCorruptFile(t *testing.T) {
+	ix, path := newTestIndex(t)
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ix.Load(); err != nil {
+		t.Errorf("Load of a corrupt index = %v, want it discarded", err)
+	}
+}
@@ -0,0 +1,98 @@
+package rag
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+)
+
+// contextModel is the model query/refine prompts are sent to, whose tokenizer measures the context budget.
+const contextModel = "gpt-4o"
+
+// fileHeader is the path annotation placed before each packed file.
+func fileHeader(filename string) string {
+	return fmt.Sprintf("// File: %s\n", filename)
+}
+
+// PackFilesForContext concatenates files into a single context string of at most tokenBudget tokens.
+// Files named in order go first, in that order, followed by the rest in their original order. Each file is
+// prefixed with a "// File: <path>" header. Files that don't fit in the remaining budget are skipped whole,
+// so a smaller, less relevant file can still be included after a large one is dropped.
+func PackFilesForContext(files []types.GeneratedFile, order []string, tokenBudget int) string {
+	text, _ := packFiles(files, order, tokenBudget)
+	return text
+}
+
+// packFiles is PackFilesForContext that also returns the names of the files it packed, in context order.
+func packFiles(files []types.GeneratedFile, order []string, tokenBudget int) (string, []string) {
+	byName := make(map[string]types.GeneratedFile, len(files))
+	for _, f := range files {
+		byName[f.Filename] = f
+	}
+
+	ordered := make([]types.GeneratedFile, 0, len(files))
+	seen := make(map[string]bool, len(files))
+	for _, name := range order {
+		if f, ok := byName[name]; ok && !seen[name] {
+			ordered = append(ordered, f)
+			seen[name] = true
+		}
+	}
+	for _, f := range files {
+		if !seen[f.Filename] {
+			ordered = append(ordered, f)
+			seen[f.Filename] = true
+		}
+	}
+
+	var b strings.Builder
+	var packed []string
+	used := 0
+	for _, f := range ordered {
+		chunk := fileHeader(f.Filename) + f.Content + "\n\n"
+		cost, _ := utils.CountTokens(contextModel, chunk)
+		if used+cost > tokenBudget {
+			continue
+		}
+		b.WriteString(chunk)
+		packed = append(packed, f.Filename)
+		used += cost
+	}
+	return b.String(), packed
+}
+
+// RankFilesByQuery orders filenames by a simple lexical relevance score against query: matches in the path
+// count more than matches in the content. Ties keep the original file order.
+func RankFilesByQuery(files []types.GeneratedFile, query string) []string {
+	terms := strings.Fields(strings.ToLower(query))
+	type scored struct {
+		name  string
+		score int
+	}
+	ranked := make([]scored, len(files))
+	for i, f := range files {
+		name := strings.ToLower(f.Filename)
+		content := strings.ToLower(f.Content)
+		score := 0
+		for _, term := range terms {
+			if len(term) < 3 {
+				continue // Skip noise words like "a", "to", "of"
+			}
+			if strings.Contains(name, term) {
+				score += 10
+			}
+			score += strings.Count(content, term)
+		}
+		ranked[i] = scored{name: f.Filename, score: score}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+
+	order := make([]string, len(ranked))
+	for i, r := range ranked {
+		order[i] = r.name
+	}
+	return order
+}
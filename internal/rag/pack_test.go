@@ -0,0 +1,65 @@
+package rag
+
+import (
+	"strings"
+	"testing"
+
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+)
+
+func packTestFiles() []types.GeneratedFile {
+	return []types.GeneratedFile{
+		{Filename: "index.html", Content: "<div id=\"root\"></div>"},
+		{Filename: "src/App.tsx", Content: "export default function App() { return <h1>Hello</h1>; }"},
+		{Filename: "src/big.ts", Content: strings.Repeat("const value = 42;\n", 200)},
+		{Filename: "src/main.tsx", Content: "createRoot(root).render(<App />);"},
+	}
+}
+
+// chunkTokens is what packing one file costs.
+func chunkTokens(f types.GeneratedFile) int {
+	n, _ := utils.CountTokens(contextModel, fileHeader(f.Filename)+f.Content+"\n\n")
+	return n
+}
+
+func TestPackFilesForContextHeaders(t *testing.T) {
+	files := packTestFiles()
+	text := PackFilesForContext(files, nil, 1_000_000)
+	for _, f := range files {
+		if !strings.Contains(text, "// File: "+f.Filename+"\n"+f.Content) {
+			t.Errorf("packed context has no header before %s", f.Filename)
+		}
+	}
+}
+
+func TestPackFilesForContextOrder(t *testing.T) {
+	_, packed := packFiles(packTestFiles(), []string{"src/main.tsx", "missing.js", "src/App.tsx"}, 1_000_000)
+	want := []string{"src/main.tsx", "src/App.tsx", "index.html", "src/big.ts"}
+	if strings.Join(packed, ",") != strings.Join(want, ",") {
+		t.Errorf("packed %v, want %v", packed, want)
+	}
+}
+
+func TestPackFilesForContextBudget(t *testing.T) {
+	files := packTestFiles()
+	// Room for App.tsx and main.tsx but not big.ts, which comes between them in order
+	budget := chunkTokens(files[1]) + chunkTokens(files[3]) + 1
+	text, packed := packFiles(files, []string{"src/App.tsx", "src/big.ts", "src/main.tsx"}, budget)
+
+	if want := []string{"src/App.tsx", "src/main.tsx"}; strings.Join(packed, ",") != strings.Join(want, ",") {
+		t.Errorf("packed %v, want %v", packed, want)
+	}
+	if used, _ := utils.CountTokens(contextModel, text); used > budget {
+		t.Errorf("packed %d tokens, over the %d budget", used, budget)
+	}
+	if strings.Contains(text, "src/big.ts") {
+		t.Error("a file over the remaining budget was packed (or partly packed)")
+	}
+}
+
+func TestPackFilesForContextNothingFits(t *testing.T) {
+	if text := PackFilesForContext(packTestFiles(), nil, 1); text != "" {
+		t.Errorf("packed %q into a 1-token budget", text)
+	}
+}
@@ -0,0 +1,284 @@
+package rag
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+)
+
+// Generator is the subset of the AI generator the RAG service needs.
+type Generator interface {
+	GenerateWithContext(ctx context.Context, systemPrompt string, userPrompt string, contextText string) (string, error)
+	GenerateCodeChanges(ctx context.Context, userQuery string, contextFiles string) ([]types.GeneratedFile, error)
+	GenerateCodeChangesStream(ctx context.Context, userQuery string, contextFiles string, onToken func(string)) ([]types.GeneratedFile, error)
+	GenerateEmbedding(ctx context.Context, text string) ([]float32, error)
+	GenerateChunkedEmbeddings(ctx context.Context, text string) ([]types.Chunk, error)
+	EmbeddingSpace() (model string, dims int)
+}
+
+// FileLoader returns a project's current source files.
+type FileLoader func(projectID string) ([]types.GeneratedFile, error)
+
+type RAGService struct {
+	aiGenerator Generator
+	loadFiles   FileLoader
+	tokenBudget int      // Maximum tokens of project files packed into a prompt
+	exclude     []string // Glob patterns of files kept out of context by default (see ExcludeFiles)
+
+	embedConcurrency int // Files SaveToRAG embeds at once
+
+	indexesMu  sync.Mutex
+	indexes    map[string]*list.Element // Per-project embedding indexes, loaded lazily; values are in indexLRU
+	indexLRU   *list.List               // *cachedIndex, most recently used first
+	maxIndexes int                      // Indexes kept in memory before the least recently used is dropped
+}
+
+// cachedIndex is a project's embedding index in the RAGService cache.
+type cachedIndex struct {
+	projectID string
+	index     *Index
+}
+
+// DefaultEmbeddingConcurrency is how many files SaveToRAG embeds at once unless WithEmbeddingConcurrency says otherwise.
+const DefaultEmbeddingConcurrency = 4
+
+// DefaultMaxCachedIndexes is how many project indexes stay in memory unless WithMaxCachedIndexes says otherwise.
+const DefaultMaxCachedIndexes = 64
+
+// Option configures optional RAGService settings.
+type Option func(*RAGService)
+
+// WithEmbeddingConcurrency sets how many files SaveToRAG embeds at once. Values below 1 are ignored.
+func WithEmbeddingConcurrency(n int) Option {
+	return func(r *RAGService) {
+		if n >= 1 {
+			r.embedConcurrency = n
+		}
+	}
+}
+
+// WithMaxCachedIndexes sets how many project indexes stay in memory; beyond that the least recently used is
+// dropped and reloaded from disk when next needed. Values below 1 are ignored.
+func WithMaxCachedIndexes(n int) Option {
+	return func(r *RAGService) {
+		if n >= 1 {
+			r.maxIndexes = n
+		}
+	}
+}
+
+// NewRAGService creates the service. exclude lists the glob patterns of files left out of context unless a
+// request overrides them; empty means DefaultExcludePatterns.
+func NewRAGService(aiGen Generator, loadFiles FileLoader, tokenBudget int, exclude []string, opts ...Option) *RAGService {
+	if len(exclude) == 0 {
+		exclude = DefaultExcludePatterns
+	}
+	r := &RAGService{
+		aiGenerator:      aiGen,
+		loadFiles:        loadFiles,
+		tokenBudget:      tokenBudget,
+		exclude:          exclude,
+		embedConcurrency: DefaultEmbeddingConcurrency,
+		indexes:          make(map[string]*list.Element),
+		indexLRU:         list.New(),
+		maxIndexes:       DefaultMaxCachedIndexes,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// buildContext loads the project's files, drops excluded ones, and packs the most relevant for userQuery into
+// the token budget, returning the context and the names of the files in it. A nil exclude uses the service's
+// patterns; a non-nil one replaces them.
+func (r *RAGService) buildContext(ctx context.Context, projectID, userQuery string, exclude []string) (string, []string, error) {
+	files, err := r.loadFiles(projectID)
+	if err != nil {
+		return "", nil, err
+	}
+	if exclude == nil {
+		exclude = r.exclude
+	}
+	files = ExcludeFiles(files, exclude)
+	text, packed := packFiles(files, r.rankFiles(ctx, projectID, files, userQuery), r.tokenBudget)
+	return text, packed, nil
+}
+
+// rankFiles orders files by embedding similarity to userQuery, falling back to lexical ranking when
+// embeddings are unavailable. Files without an embedding follow in lexical order.
+func (r *RAGService) rankFiles(ctx context.Context, projectID string, files []types.GeneratedFile, userQuery string) []string {
+	lexical := RankFilesByQuery(files, userQuery)
+	semantic, err := r.semanticRank(ctx, projectID, files, userQuery)
+	if err != nil {
+		log.Printf("WARN: Semantic ranking unavailable for project %s, using lexical ranking: %v", projectID, err)
+		return lexical
+	}
+
+	seen := make(map[string]bool, len(semantic))
+	for _, name := range semantic {
+		seen[name] = true
+	}
+	for _, name := range lexical {
+		if !seen[name] {
+			semantic = append(semantic, name)
+		}
+	}
+	return semantic
+}
+
+// semanticRank brings the project's embedding index up to date (embedding only new or changed files)
+// and returns the indexed files ranked by similarity to userQuery.
+func (r *RAGService) semanticRank(ctx context.Context, projectID string, files []types.GeneratedFile, userQuery string) ([]string, error) {
+	if _, err := r.SaveToRAG(ctx, projectID, files); err != nil {
+		return nil, err
+	}
+	ix := r.projectIndex(projectID)
+
+	queryVec, err := r.aiGenerator.GenerateEmbedding(ctx, userQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	results, err := ix.Search(queryVec, 0)
+	if err != nil {
+		return nil, err
+	}
+	order := make([]string, 0, len(results))
+	for _, res := range results {
+		order = append(order, res.Filename)
+	}
+	return order, nil
+}
+
+// projectIndex returns the (cached) embedding index for a project, dropping the least recently used index
+// when the cache is full.
+func (r *RAGService) projectIndex(projectID string) *Index {
+	r.indexesMu.Lock()
+	defer r.indexesMu.Unlock()
+	if el, ok := r.indexes[projectID]; ok {
+		r.indexLRU.MoveToFront(el)
+		return el.Value.(*cachedIndex).index
+	}
+	model, dims := r.aiGenerator.EmbeddingSpace()
+	ix := NewIndex(IndexPath(utils.ProjectDir(projectID)), model, dims)
+	r.indexes[projectID] = r.indexLRU.PushFront(&cachedIndex{projectID: projectID, index: ix})
+	for r.indexLRU.Len() > r.maxIndexes {
+		oldest := r.indexLRU.Back()
+		r.indexLRU.Remove(oldest)
+		delete(r.indexes, oldest.Value.(*cachedIndex).projectID)
+	}
+	return ix
+}
+
+// Forget drops a project's cached embedding index, e.g. once the project is deleted or its local copy purged.
+// The index file on disk is left alone.
+func (r *RAGService) Forget(projectID string) {
+	r.indexesMu.Lock()
+	defer r.indexesMu.Unlock()
+	if el, ok := r.indexes[projectID]; ok {
+		r.indexLRU.Remove(el)
+		delete(r.indexes, projectID)
+	}
+}
+
+// QueryProject generates a *textual* answer about the project's code. exclude overrides the service's
+// exclusion patterns when non-nil.
+func (r *RAGService) QueryProject(ctx context.Context, projectID, userQuery string, exclude []string) (string, error) {
+	log.Printf("RAG Query (Text Answer) for project %s", projectID)
+
+	answer, _, err := r.query(ctx, projectID, userQuery, exclude, queryPrompt)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Generated RAG text answer for project %s query.", projectID)
+	return answer, nil
+}
+
+// CitedAnswer is a query answer together with the project files it drew on.
+type CitedAnswer struct {
+	Answer  string   `json:"answer"`
+	Sources []string `json:"sources"` // Filenames from the context the model cited; never names outside it
+}
+
+// QueryProjectCited is QueryProject with the model asked to cite the files it used, which are parsed out of
+// the answer into Sources.
+func (r *RAGService) QueryProjectCited(ctx context.Context, projectID, userQuery string, exclude []string) (*CitedAnswer, error) {
+	log.Printf("RAG Query (Cited Answer) for project %s", projectID)
+
+	raw, packed, err := r.query(ctx, projectID, userQuery, exclude, queryPrompt+citeSourcesInstruction)
+	if err != nil {
+		return nil, err
+	}
+	answer, sources := parseSources(raw, packed)
+	log.Printf("Generated RAG cited answer for project %s query (%d sources).", projectID, len(sources))
+	return &CitedAnswer{Answer: answer, Sources: sources}, nil
+}
+
+const queryPrompt = "You are an AI assistant helping a user understand code for a web project. Use the provided code context to answer the user's query accurately and concisely. If the context doesn't fully answer the question, say so, but try to be helpful based on what is available."
+
+// query answers userQuery over the project's packed context with systemPrompt, returning the answer and the
+// files that were in the context.
+func (r *RAGService) query(ctx context.Context, projectID, userQuery string, exclude []string, systemPrompt string) (string, []string, error) {
+	contextText, packed, err := r.buildContext(ctx, projectID, userQuery, exclude)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to build context for project %s: %w", projectID, err)
+	}
+	if contextText == "" {
+		contextText = "No specific file context available."
+	}
+
+	answer, err := r.aiGenerator.GenerateWithContext(ctx, systemPrompt, userQuery, contextText)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate final text answer with LLM: %w", err)
+	}
+	return answer, packed, nil
+}
+
+// RefineProjectCode asks the LLM for code modifications to the project based on userQuery.
+// It only returns the suggested files; applying them is up to the caller. exclude overrides the service's
+// exclusion patterns when non-nil.
+func (r *RAGService) RefineProjectCode(ctx context.Context, projectID, userQuery string, exclude []string) ([]types.GeneratedFile, error) {
+	return r.refine(ctx, projectID, userQuery, exclude, r.aiGenerator.GenerateCodeChanges)
+}
+
+// RefineProjectCodeStream is RefineProjectCode with the model's raw output passed to onToken as it streams in.
+// The suggested files are only returned once the complete output has been parsed.
+func (r *RAGService) RefineProjectCodeStream(ctx context.Context, projectID, userQuery string, exclude []string, onToken func(string)) ([]types.GeneratedFile, error) {
+	return r.refine(ctx, projectID, userQuery, exclude, func(ctx context.Context, userQuery, contextText string) ([]types.GeneratedFile, error) {
+		return r.aiGenerator.GenerateCodeChangesStream(ctx, userQuery, contextText, onToken)
+	})
+}
+
+func (r *RAGService) refine(ctx context.Context, projectID, userQuery string, exclude []string,
+	generate func(ctx context.Context, userQuery, contextText string) ([]types.GeneratedFile, error)) ([]types.GeneratedFile, error) {
+	log.Printf("RAG Code Refinement for project %s", projectID)
+
+	contextText, _, err := r.buildContext(ctx, projectID, userQuery, exclude)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build context for project %s: %w", projectID, err)
+	}
+	if contextText == "" {
+		log.Printf("No files fit the context budget for project %s. Cannot generate code changes without context.", projectID)
+		return []types.GeneratedFile{}, nil
+	}
+
+	changedFiles, err := generate(ctx, userQuery, contextText)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate code changes using LLM: %w", err)
+	}
+
+	// Add fallback type determination if LLM didn't provide it
+	for i := range changedFiles {
+		if changedFiles[i].Type == "" {
+			changedFiles[i].Type = utils.DetermineFileType(changedFiles[i].Filename)
+		}
+	}
+
+	log.Printf("Generated %d potential file changes/additions for project %s.", len(changedFiles), projectID)
+	return changedFiles, nil
+}
@@ -0,0 +1,263 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"sui_ai_server/internal/types"
+)
+
+// inTempWorkDir runs the test from a fresh directory, so utils.WorkDir (a relative path) lands in it.
+func inTempWorkDir(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// fakeGenerator embeds text as a small vector derived from it and records the context each answer was
+// generated from, without calling a model.
+type fakeGenerator struct {
+	embedDelay time.Duration    // How long each GenerateChunkedEmbeddings call takes
+	embedErrs  map[string]error // Errors returned for these texts instead of embedding them
+
+	mu       sync.Mutex
+	contexts []string // contextText of every GenerateWithContext and GenerateCodeChanges call
+	embedded []string // Text of every GenerateChunkedEmbeddings call
+	active   int      // GenerateChunkedEmbeddings calls in progress
+	peak     int      // Most calls in progress at once
+}
+
+func (g *fakeGenerator) GenerateWithContext(ctx context.Context, systemPrompt, userPrompt, contextText string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.contexts = append(g.contexts, contextText)
+	return "answer", nil
+}
+
+func (g *fakeGenerator) GenerateCodeChanges(ctx context.Context, userQuery, contextFiles string) ([]types.GeneratedFile, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.contexts = append(g.contexts, contextFiles)
+	return []types.GeneratedFile{{Filename: "src/App.tsx", Content: "changed"}}, nil
+}
+
+func (g *fakeGenerator) GenerateCodeChangesStream(ctx context.Context, userQuery, contextFiles string, onToken func(string)) ([]types.GeneratedFile, error) {
+	return g.GenerateCodeChanges(ctx, userQuery, contextFiles)
+}
+
+func (g *fakeGenerator) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	return fakeVector(text), nil
+}
+
+func (g *fakeGenerator) GenerateChunkedEmbeddings(ctx context.Context, text string) ([]types.Chunk, error) {
+	g.mu.Lock()
+	g.embedded = append(g.embedded, text)
+	g.active++
+	g.peak = max(g.peak, g.active)
+	g.mu.Unlock()
+	defer func() {
+		g.mu.Lock()
+		g.active--
+		g.mu.Unlock()
+	}()
+
+	time.Sleep(g.embedDelay)
+	if err := g.embedErrs[text]; err != nil {
+		return nil, err
+	}
+	return []types.Chunk{{End: len(text), Vector: fakeVector(text)}}, nil
+}
+
+func (g *fakeGenerator) EmbeddingSpace() (string, int) { return "fake-embedder", 3 }
+
+func (g *fakeGenerator) lastContext() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.contexts) == 0 {
+		return ""
+	}
+	return g.contexts[len(g.contexts)-1]
+}
+
+// fakeVector is a 3-dimensional embedding that only depends on text.
+func fakeVector(text string) []float32 {
+	return []float32{float32(len(text)%7) + 1, float32(strings.Count(text, "e")) + 1, 1}
+}
+
+// filesLoader serves files for every project.
+func filesLoader(files []types.GeneratedFile) FileLoader {
+	return func(projectID string) ([]types.GeneratedFile, error) {
+		return files, nil
+	}
+}
+
+func projectFiles() []types.GeneratedFile {
+	return []types.GeneratedFile{
+		{Filename: "src/App.tsx", Content: "export default function App() {}"},
+		{Filename: "package.json", Content: `{"name":"site"}`},
+		{Filename: "package-lock.json", Content: `{"lockfileVersion":3}`},
+		{Filename: "node_modules/react/index.js", Content: "module.exports = React"},
+		{Filename: "dist/assets/index.js", Content: "built()"},
+		{Filename: "public/vendor.min.js", Content: "minified()"},
+		{Filename: "public/logo.png", Content: "\x89PNG"},
+	}
+}
+
+func TestQueryContextExcludesFiles(t *testing.T) {
+	inTempWorkDir(t)
+	gen := &fakeGenerator{}
+	r := NewRAGService(gen, filesLoader(projectFiles()), 100_000, nil)
+
+	if _, err := r.QueryProject(context.Background(), "p1", "what does App do?", nil); err != nil {
+		t.Fatalf("QueryProject: %v", err)
+	}
+	packed := gen.lastContext()
+	for _, name := range []string{"src/App.tsx", "package.json"} {
+		if !strings.Contains(packed, "// File: "+name+"\n") {
+			t.Errorf("context is missing %s", name)
+		}
+	}
+	for _, name := range []string{"package-lock.json", "node_modules/react/index.js", "dist/assets/index.js", "public/vendor.min.js", "public/logo.png"} {
+		if strings.Contains(packed, name) {
+			t.Errorf("context includes excluded file %s", name)
+		}
+	}
+}
+
+func TestRefineContextExcludeOverride(t *testing.T) {
+	inTempWorkDir(t)
+	gen := &fakeGenerator{}
+	r := NewRAGService(gen, filesLoader(projectFiles()), 100_000, nil)
+
+	if _, err := r.RefineProjectCode(context.Background(), "p1", "update the app", []string{"src/**"}); err != nil {
+		t.Fatalf("RefineProjectCode: %v", err)
+	}
+	packed := gen.lastContext()
+	if strings.Contains(packed, "src/App.tsx") {
+		t.Error("context includes a file excluded by the request")
+	}
+	if !strings.Contains(packed, "// File: package-lock.json\n") {
+		t.Error("the request's patterns should replace the defaults, but package-lock.json was excluded")
+	}
+	if strings.Contains(packed, "public/logo.png") {
+		t.Error("binary files are excluded whatever the patterns")
+	}
+}
+
+func TestExcludeFilesPatterns(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		filename string
+		want     bool
+	}{
+		{"package-lock.json", "package-lock.json", true},
+		{"package-lock.json", "apps/web/package-lock.json", true},
+		{"*.min.js", "public/js/app.min.js", true},
+		{"*.min.js", "public/js/app.js", false},
+		{"node_modules/**", "node_modules/react/index.js", true},
+		{"node_modules/**", "apps/web/node_modules/react/index.js", true},
+		{"node_modules/**", "src/node_modules.ts", false},
+		{"src/generated/*.ts", "src/generated/api.ts", true},
+		{"src/generated/*.ts", "lib/src/generated/api.ts", false},
+	}
+	for _, tt := range tests {
+		if got := matchExclude(tt.pattern, tt.filename); got != tt.want {
+			t.Errorf("matchExclude(%q, %q) = %v, want %v", tt.pattern, tt.filename, got, tt.want)
+		}
+	}
+}
+
+// manyFiles is a project of n small source files.
+func manyFiles(n int) []types.GeneratedFile {
+	files := make([]types.GeneratedFile, n)
+	for i := range files {
+		files[i] = types.GeneratedFile{Filename: fmt.Sprintf("src/file%d.ts", i), Content: fmt.Sprintf("export const value%d = %d;", i, i)}
+	}
+	return files
+}
+
+func TestSaveToRAGEmbedsConcurrently(t *testing.T) {
+	inTempWorkDir(t)
+	gen := &fakeGenerator{embedDelay: 5 * time.Millisecond}
+	files := manyFiles(12)
+	r := NewRAGService(gen, filesLoader(files), 100_000, nil, WithEmbeddingConcurrency(3))
+
+	stats, err := r.SaveToRAG(context.Background(), "p1", files)
+	if err != nil {
+		t.Fatalf("SaveToRAG: %v", err)
+	}
+	if stats.Embedded != len(files) || len(gen.embedded) != len(files) {
+		t.Errorf("embedded %d files in %d calls, want %d", stats.Embedded, len(gen.embedded), len(files))
+	}
+	if gen.peak > 3 {
+		t.Errorf("%d embeddings ran at once, want at most 3", gen.peak)
+	}
+	if gen.peak < 2 {
+		t.Errorf("at most %d embedding ran at once, want them to overlap", gen.peak)
+	}
+
+	again, err := r.SaveToRAG(context.Background(), "p1", files)
+	if err != nil || again.Unchanged != len(files) || again.Embedded != 0 {
+		t.Errorf("second SaveToRAG = %+v, %v; want every file unchanged", again, err)
+	}
+}
+
+func TestSaveToRAGIsolatesFailures(t *testing.T) {
+	inTempWorkDir(t)
+	files := manyFiles(6)
+	gen := &fakeGenerator{embedErrs: map[string]error{files[2].Content: errors.New("input rejected")}}
+	r := NewRAGService(gen, filesLoader(files), 100_000, nil, WithEmbeddingConcurrency(2))
+
+	stats, err := r.SaveToRAG(context.Background(), "p1", files)
+	if err == nil {
+		t.Fatal("SaveToRAG succeeded with a failing file")
+	}
+	if stats.Embedded != 5 || len(stats.Failed) != 1 || stats.Failed[0] != files[2].Filename {
+		t.Errorf("stats = %+v, want 5 embedded and %s failed", stats, files[2].Filename)
+	}
+}
+
+func TestProjectIndexEvictsLeastRecentlyUsed(t *testing.T) {
+	r := NewRAGService(&fakeGenerator{}, filesLoader(nil), 100_000, nil, WithMaxCachedIndexes(2))
+	a := r.projectIndex("a")
+	r.projectIndex("b")
+	if r.projectIndex("a") != a {
+		t.Fatal("cached index for a was not reused")
+	}
+	r.projectIndex("c") // Evicts b, the least recently used
+	if len(r.indexes) != 2 {
+		t.Errorf("%d indexes cached, want 2", len(r.indexes))
+	}
+	if _, ok := r.indexes["b"]; ok {
+		t.Error("b is still cached after eviction")
+	}
+	if r.projectIndex("a") != a {
+		t.Error("a was evicted although it was used more recently than b")
+	}
+}
+
+func TestForgetDropsCachedIndex(t *testing.T) {
+	r := NewRAGService(&fakeGenerator{}, filesLoader(nil), 100_000, nil)
+	a := r.projectIndex("a")
+	r.projectIndex("b")
+	r.Forget("a")
+	r.Forget("missing")
+	if _, ok := r.indexes["a"]; ok || r.indexLRU.Len() != 1 {
+		t.Errorf("after Forget: cached %v, %d in LRU; want only b", r.indexes, r.indexLRU.Len())
+	}
+	if r.projectIndex("a") == a {
+		t.Error("projectIndex returned the forgotten index")
+	}
+}
@@ -0,0 +1,137 @@
+package rag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+)
+
+// SaveStats counts what SaveToRAG did with a project's files.
+type SaveStats struct {
+	Embedded  int      `json:"embedded"`  // Files embedded because they were new or changed
+	Unchanged int      `json:"unchanged"` // Files already indexed with the same content
+	Skipped   int      `json:"skipped"`   // Images, unknown types and empty files, which aren't indexed
+	Removed   int      `json:"removed"`   // Stale entries dropped for files that changed or no longer exist
+	Failed    []string `json:"failed,omitempty"`
+}
+
+// SaveToRAG brings the project's embedding index up to date with files: entries for removed or changed files
+// are dropped, and new or changed files are embedded and stored. It is idempotent; files already indexed with
+// the same content cost nothing. Up to the service's embedding concurrency files are embedded at once. A file
+// that fails to embed is recorded in Failed and the rest still run, but a cancelled context or an upstream rate
+// limit stops the run early. The returned error joins every failure.
+func (r *RAGService) SaveToRAG(ctx context.Context, projectID string, files []types.GeneratedFile) (SaveStats, error) {
+	var stats SaveStats
+	ix := r.projectIndex(projectID)
+
+	current := make(map[string]string, len(files))
+	indexable := make([]types.GeneratedFile, 0, len(files))
+	for _, f := range files {
+		if f.Content == "" || isBinaryFile(f.Filename) {
+			stats.Skipped++
+			continue
+		}
+		current[f.Filename] = ContentHash(f.Content)
+		indexable = append(indexable, f)
+	}
+	removed, err := ix.Invalidate(current)
+	if err != nil {
+		return stats, err
+	}
+	stats.Removed = removed
+
+	pending := make([]types.GeneratedFile, 0, len(indexable))
+	for _, f := range indexable {
+		if ix.Has(f.Filename, current[f.Filename]) {
+			stats.Unchanged++
+			continue
+		}
+		pending = append(pending, f)
+	}
+
+	// Embed up to embedConcurrency files at a time. Each result is reported back here, so stats and errs
+	// are only touched by this goroutine.
+	type result struct {
+		filename string
+		err      error
+	}
+	work := make(chan types.GeneratedFile)
+	results := make(chan result)
+	var stop atomic.Bool // Set once the remaining files would fail the same way
+	var wg sync.WaitGroup
+	for i := 0; i < min(r.embedConcurrency, len(pending)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range work {
+				results <- result{f.Filename, r.embedFile(ctx, ix, f, current[f.Filename])}
+			}
+		}()
+	}
+	go func() {
+		defer close(work)
+		for _, f := range pending {
+			if stop.Load() {
+				return
+			}
+			select {
+			case work <- f:
+			case <-ctx.Done(): // Don't pay for embeddings nobody is waiting for
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var errs []error
+	for res := range results {
+		if res.err == nil {
+			stats.Embedded++
+			continue
+		}
+		log.Printf("WARN: Failed to index %s for project %s: %v", res.filename, projectID, res.err)
+		stats.Failed = append(stats.Failed, res.filename)
+		errs = append(errs, fmt.Errorf("failed to embed %s: %w", res.filename, res.err))
+		var rateLimitErr *utils.RateLimitError
+		if errors.As(res.err, &rateLimitErr) {
+			stop.Store(true)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if stats.Embedded > 0 || stats.Removed > 0 {
+		if err := ix.Save(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to save embedding index: %w", err))
+		}
+	}
+	if stats.Embedded > 0 || stats.Removed > 0 || len(stats.Failed) > 0 {
+		log.Printf("Indexed project %s: %d embedded, %d unchanged, %d skipped, %d removed, %d failed",
+			projectID, stats.Embedded, stats.Unchanged, stats.Skipped, stats.Removed, len(stats.Failed))
+	}
+	return stats, errors.Join(errs...)
+}
+
+// embedFile embeds f's content (hash) and stores the vectors in ix. Large files are embedded in chunks so they
+// stay under the model's input limit.
+func (r *RAGService) embedFile(ctx context.Context, ix *Index, f types.GeneratedFile, hash string) error {
+	chunks, err := r.aiGenerator.GenerateChunkedEmbeddings(ctx, f.Content)
+	if err != nil {
+		return err
+	}
+	vectors := make([][]float32, len(chunks))
+	for i, c := range chunks {
+		vectors[i] = c.Vector
+	}
+	return ix.Upsert(f.Filename, hash, vectors)
+}
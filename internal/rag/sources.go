@@ -0,0 +1,38 @@
+package rag
+
+import "strings"
+
+// citeSourcesInstruction is appended to the query system prompt when the caller wants cited sources.
+const citeSourcesInstruction = " End your answer with a final line of the form \"Sources: <path>, <path>\" listing the files from the context you relied on, using the paths exactly as they appear in the \"// File:\" headers, or \"Sources: none\" if you used none."
+
+// sourcesPrefix starts the line parseSources looks for.
+const sourcesPrefix = "sources:"
+
+// parseSources splits the model's "Sources: ..." line off raw and returns the answer without it and the cited
+// files. Only names of files that were in the context (packed) are kept, so a hallucinated path never reaches
+// the client. Without such a line the answer is returned whole with no sources.
+func parseSources(raw string, packed []string) (string, []string) {
+	inContext := make(map[string]bool, len(packed))
+	for _, name := range packed {
+		inContext[name] = true
+	}
+
+	lines := strings.Split(strings.TrimRight(raw, " \t\r\n"), "\n")
+	last := len(lines) - 1
+	line := strings.TrimSpace(strings.Trim(strings.TrimSpace(lines[last]), "*_"))
+	if !strings.HasPrefix(strings.ToLower(line), sourcesPrefix) {
+		return raw, []string{}
+	}
+
+	sources := []string{}
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(line[len(sourcesPrefix):], ",") {
+		name = strings.TrimPrefix(strings.Trim(name, " \t`'\"*"), "./")
+		if inContext[name] && !seen[name] {
+			seen[name] = true
+			sources = append(sources, name)
+		}
+	}
+	answer := strings.TrimRight(strings.Join(lines[:last], "\n"), " \t\r\n")
+	return answer, sources
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Limiter decides whether a request for key may proceed.
+// When it may not, retryAfter says how long until it would be allowed.
+// Implementations must be safe for concurrent use; the in-memory one below can be swapped for a shared
+// (e.g. Redis-backed) one when running multiple instances.
+type Limiter interface {
+	Allow(key string) (allowed bool, retryAfter time.Duration)
+}
+
+type bucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+// MemoryLimiter is a per-key token bucket held in process memory.
+type MemoryLimiter struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucket
+	capacity float64       // Burst size
+	refill   float64       // Tokens added per second
+	idleTTL  time.Duration // Buckets unused for this long are dropped by Cleanup
+	now      func() time.Time
+}
+
+// NewMemoryLimiter allows perMinute requests per key per minute, with bursts up to perMinute.
+func NewMemoryLimiter(perMinute int, idleTTL time.Duration) *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:  make(map[string]*bucket),
+		capacity: float64(perMinute),
+		refill:   float64(perMinute) / 60,
+		idleTTL:  idleTTL,
+		now:      time.Now,
+	}
+}
+
+// Allow takes a token from key's bucket if one is available.
+func (l *MemoryLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.capacity, lastSeen: now}
+		l.buckets[key] = b
+	} else {
+		b.tokens += now.Sub(b.lastSeen).Seconds() * l.refill
+		if b.tokens > l.capacity {
+			b.tokens = l.capacity
+		}
+		b.lastSeen = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / l.refill * float64(time.Second))
+	return false, wait
+}
+
+// Cleanup drops buckets that have been idle longer than idleTTL. An idle bucket is full again anyway.
+func (l *MemoryLimiter) Cleanup() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cutoff := l.now().Add(-l.idleTTL)
+	for key, b := range l.buckets {
+		if b.lastSeen.Before(cutoff) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RunCleanup calls Cleanup every interval until ctx is cancelled.
+func (l *MemoryLimiter) RunCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Rate limiter cleanup stopping.")
+			return
+		case <-ticker.C:
+			l.Cleanup()
+		}
+	}
+}
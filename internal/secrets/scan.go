@@ -0,0 +1,115 @@
+package secrets
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"sui_ai_server/internal/types"
+)
+
+// Pattern is a named regular expression matching one kind of secret.
+type Pattern struct {
+	Name   string
+	Regexp *regexp.Regexp
+}
+
+// DefaultPatterns covers common credential shapes the LLM tends to bake into config and .env files.
+var DefaultPatterns = []Pattern{
+	{Name: "aws_access_key_id", Regexp: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "openai_api_key", Regexp: regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9_-]{20,}\b`)},
+	{Name: "github_token", Regexp: regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{Name: "slack_token", Regexp: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}\b`)},
+	{Name: "google_api_key", Regexp: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+	{Name: "stripe_secret_key", Regexp: regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{20,}\b`)},
+	{Name: "private_key", Regexp: regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |PGP )?PRIVATE KEY(?: BLOCK)?-----`)},
+}
+
+// Mode controls what the scanner does with a match.
+type Mode string
+
+const (
+	ModeRedact Mode = "redact" // Replace the secret in the file and report it
+	ModeWarn   Mode = "warn"   // Leave the content alone and report it
+	ModeOff    Mode = "off"    // Don't scan
+)
+
+// ParseMode validates a configured mode string. Empty means ModeRedact.
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case "":
+		return ModeRedact, nil
+	case ModeRedact, ModeWarn, ModeOff:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown secret scan mode %q (expected redact, warn or off)", s)
+	}
+}
+
+// Finding records one detected secret. The secret itself is never included.
+type Finding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Pattern  string `json:"pattern"`
+	Redacted bool   `json:"redacted"`
+}
+
+// Scanner detects (and optionally redacts) secrets in generated files.
+type Scanner struct {
+	patterns []Pattern
+	mode     Mode
+}
+
+// NewScanner creates a scanner using the given patterns. An empty mode defaults to redacting.
+func NewScanner(patterns []Pattern, mode Mode) *Scanner {
+	if mode == "" {
+		mode = ModeRedact
+	}
+	return &Scanner{patterns: patterns, mode: mode}
+}
+
+// ParsePatterns compiles "name:regex" entries, as used in the SECRET_PATTERNS config.
+func ParsePatterns(specs []string) ([]Pattern, error) {
+	patterns := make([]Pattern, 0, len(specs))
+	for _, spec := range specs {
+		name, expr, ok := strings.Cut(spec, ":")
+		if !ok || strings.TrimSpace(name) == "" || expr == "" {
+			return nil, fmt.Errorf("invalid secret pattern %q (expected name:regex)", spec)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex for secret pattern %q: %w", name, err)
+		}
+		patterns = append(patterns, Pattern{Name: strings.TrimSpace(name), Regexp: re})
+	}
+	return patterns, nil
+}
+
+// Scan checks every file and returns the (possibly redacted) files along with what was found.
+// The input slice is not modified.
+func (s *Scanner) Scan(files []types.GeneratedFile) ([]types.GeneratedFile, []Finding) {
+	if s == nil || s.mode == ModeOff {
+		return files, nil
+	}
+
+	var findings []Finding
+	out := make([]types.GeneratedFile, len(files))
+	for i, file := range files {
+		out[i] = file
+		for _, p := range s.patterns {
+			locs := p.Regexp.FindAllStringIndex(file.Content, -1)
+			for _, loc := range locs {
+				findings = append(findings, Finding{
+					File:     file.Filename,
+					Line:     strings.Count(file.Content[:loc[0]], "\n") + 1,
+					Pattern:  p.Name,
+					Redacted: s.mode == ModeRedact,
+				})
+			}
+			if len(locs) > 0 && s.mode == ModeRedact {
+				out[i].Content = p.Regexp.ReplaceAllString(out[i].Content, "REDACTED_"+strings.ToUpper(p.Name))
+			}
+		}
+	}
+	return out, findings
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+
+	aiutils "sui_ai_server/internal/ai/utils"
+	"sui_ai_server/internal/project"
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+)
+
+// LocalStore keeps files in each project's directory under utils.WorkDir. It only works for a single instance.
+type LocalStore struct{}
+
+// NewLocalStore returns the local-disk file store.
+func NewLocalStore() *LocalStore {
+	return &LocalStore{}
+}
+
+// Save writes changed files and removes source files that are no longer part of the project.
+// node_modules, dist and the RAG index are left alone.
+func (s *LocalStore) Save(ctx context.Context, projectID string, files []types.GeneratedFile) error {
+	existing, err := aiutils.LoadFilesDisk(projectID)
+	if err != nil && !errors.Is(err, project.ErrProjectNotFound) {
+		return err
+	}
+	keep := make(map[string]bool, len(files))
+	for _, f := range files {
+		keep[f.Filename] = true
+	}
+	for _, f := range existing {
+		if keep[f.Filename] {
+			continue
+		}
+		path, err := utils.SafeJoin(utils.ProjectDir(projectID), f.Filename)
+		if err != nil {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		log.Printf("Removed stale file %s from project %s", f.Filename, projectID)
+	}
+	_, _, _, err = aiutils.ApplyFilesDisk(ctx, projectID, files)
+	return err
+}
+
+// Load reads the project's source files from disk.
+func (s *LocalStore) Load(ctx context.Context, projectID string) ([]types.GeneratedFile, error) {
+	return aiutils.LoadFilesDisk(projectID)
+}
+
+// Delete removes the project's directory, including build output and dependencies.
+func (s *LocalStore) Delete(ctx context.Context, projectID string) error {
+	return os.RemoveAll(utils.ProjectDir(projectID))
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"sui_ai_server/internal/project"
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+)
+
+// buildDirs are the rebuildable directories PurgeSource always removes.
+var buildDirs = []string{"node_modules", "dist"}
+
+// PurgeSource frees the disk used by a project's local directory while keeping everything needed to rebuild
+// it. With a LocalStore the directory is the store, so only node_modules and dist go; with any other store the
+// whole directory is removed and Restore brings the source back on demand. It returns the bytes freed.
+func PurgeSource(store FileStore, projectID string) (int64, error) {
+	dir := utils.ProjectDir(projectID)
+	targets := []string{dir}
+	if _, ok := store.(*LocalStore); ok {
+		targets = targets[:0]
+		for _, name := range buildDirs {
+			targets = append(targets, filepath.Join(dir, name))
+		}
+	}
+
+	var freed int64
+	for _, target := range targets {
+		size := utils.DirSize(target)
+		if err := os.RemoveAll(target); err != nil {
+			return freed, fmt.Errorf("failed to remove %s: %w", target, err)
+		}
+		freed += size
+	}
+	log.Printf("Purged local source of project %s (%d bytes freed)", projectID, freed)
+	return freed, nil
+}
+
+// Restore returns the project's source files, writing them back into its local directory from store when
+// PurgeSource removed them. It returns project.ErrProjectNotFound when neither has the project.
+func Restore(ctx context.Context, store FileStore, projectID string) ([]types.GeneratedFile, error) {
+	files, err := store.Load(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := store.(*LocalStore); ok {
+		return files, nil
+	}
+	if err := writeFiles(ctx, utils.ProjectDir(projectID), files); err != nil {
+		return nil, fmt.Errorf("failed to restore project %s: %w", projectID, err)
+	}
+	log.Printf("Restored %d files for project %s from the file store", len(files), projectID)
+	return files, nil
+}
+
+// LoadOrRestore reads the project's source from its local directory through load, restoring it from store
+// first if it was purged.
+func LoadOrRestore(ctx context.Context, store FileStore, projectID string, load func(projectID string) ([]types.GeneratedFile, error)) ([]types.GeneratedFile, error) {
+	files, err := load(projectID)
+	if !errors.Is(err, project.ErrProjectNotFound) {
+		return files, err
+	}
+	if _, err := Restore(ctx, store, projectID); err != nil {
+		return nil, err
+	}
+	return load(projectID)
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"sui_ai_server/internal/project"
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config configures an S3-compatible bucket (AWS S3, MinIO, R2, ...).
+type S3Config struct {
+	Endpoint        string // Host[:port] without scheme, e.g. "s3.amazonaws.com" or "minio:9000"
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	Region          string // Optional for most S3-compatible services
+	UseSSL          bool
+	Prefix          string // Key prefix for all projects, e.g. "projects/"
+}
+
+// S3Store keeps each project's files as objects under <prefix><projectID>/.
+type S3Store struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+// NewS3Store connects to the bucket described by cfg. The bucket must already exist.
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, errors.New("S3 endpoint and bucket are required")
+	}
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %w", err)
+	}
+	return &S3Store{client: client, bucket: cfg.Bucket, prefix: cfg.Prefix}, nil
+}
+
+func (s *S3Store) projectPrefix(projectID string) string {
+	return s.prefix + projectID + "/"
+}
+
+// Save uploads every file and then removes objects for files no longer in the set.
+func (s *S3Store) Save(ctx context.Context, projectID string, files []types.GeneratedFile) error {
+	prefix := s.projectPrefix(projectID)
+	keep := make(map[string]bool, len(files))
+	for _, f := range files {
+		// Reuse SafeJoin's checks so keys can't climb out of the project's prefix.
+		if _, err := utils.SafeJoin(prefix, f.Filename); err != nil {
+			log.Printf("Skipping file with unsafe path %q: %v", f.Filename, err)
+			continue
+		}
+		key := prefix + f.Filename
+		_, err := s.client.PutObject(ctx, s.bucket, key, strings.NewReader(f.Content), int64(len(f.Content)),
+			minio.PutObjectOptions{ContentType: "text/plain; charset=utf-8"})
+		if err != nil {
+			return fmt.Errorf("failed to upload %s: %w", key, err)
+		}
+		keep[key] = true
+	}
+
+	var stale []string
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return fmt.Errorf("failed to list files for project %s: %w", projectID, obj.Err)
+		}
+		if !keep[obj.Key] {
+			stale = append(stale, obj.Key)
+		}
+	}
+	if err := s.removeKeys(ctx, stale); err != nil {
+		return err
+	}
+	log.Printf("Stored %d files for project %s in bucket %s", len(keep), projectID, s.bucket)
+	return nil
+}
+
+// Load downloads all of the project's files.
+func (s *S3Store) Load(ctx context.Context, projectID string) ([]types.GeneratedFile, error) {
+	prefix := s.projectPrefix(projectID)
+	var files []types.GeneratedFile
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("failed to list files for project %s: %w", projectID, obj.Err)
+		}
+		content, err := s.getObject(ctx, obj.Key)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimPrefix(obj.Key, prefix)
+		files = append(files, types.GeneratedFile{
+			Filename: name,
+			Type:     utils.DetermineFileType(name),
+			Content:  content,
+		})
+	}
+	if len(files) == 0 {
+		return nil, project.ErrProjectNotFound
+	}
+	return files, nil
+}
+
+// Delete removes every object under the project's prefix.
+func (s *S3Store) Delete(ctx context.Context, projectID string) error {
+	var keys []string
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: s.projectPrefix(projectID), Recursive: true}) {
+		if obj.Err != nil {
+			return fmt.Errorf("failed to list files for project %s: %w", projectID, obj.Err)
+		}
+		keys = append(keys, obj.Key)
+	}
+	return s.removeKeys(ctx, keys)
+}
+
+func (s *S3Store) getObject(ctx context.Context, key string) (string, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	defer obj.Close()
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", key, err)
+	}
+	return string(data), nil
+}
+
+func (s *S3Store) removeKeys(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	objects := make(chan minio.ObjectInfo, len(keys))
+	for _, key := range keys {
+		objects <- minio.ObjectInfo{Key: key}
+	}
+	close(objects)
+	for result := range s.client.RemoveObjects(ctx, s.bucket, objects, minio.RemoveObjectsOptions{}) {
+		if result.Err != nil {
+			return fmt.Errorf("failed to remove %s: %w", result.ObjectName, result.Err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// Package storage keeps each project's generated source files in a backend shared by every server instance,
+// so a deploy on one instance can build files generated on another.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+)
+
+// Backend names accepted by FILE_STORE.
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// FileStore persists a project's source files.
+type FileStore interface {
+	// Save makes files the project's stored file set, replacing any previous version.
+	Save(ctx context.Context, projectID string, files []types.GeneratedFile) error
+	// Load returns the project's stored files, or project.ErrProjectNotFound if it has none.
+	Load(ctx context.Context, projectID string) ([]types.GeneratedFile, error)
+	// Delete removes all of the project's stored files. Deleting an unknown project is not an error.
+	Delete(ctx context.Context, projectID string) error
+}
+
+// Checkout makes the project's files available in a local directory for building. Local stores build in
+// place (keeping node_modules between builds); other stores are hydrated into a fresh temp dir under
+// utils.WorkDir. Call cleanup once the directory is no longer needed.
+func Checkout(ctx context.Context, store FileStore, projectID string) (dir string, cleanup func(), err error) {
+	if _, ok := store.(*LocalStore); ok {
+		return utils.ProjectDir(projectID), func() {}, nil
+	}
+
+	files, err := store.Load(ctx, projectID)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := os.MkdirAll(utils.WorkDir, os.ModePerm); err != nil {
+		return "", nil, err
+	}
+	dir, err = os.MkdirTemp(utils.WorkDir, "checkout-"+projectID+"-")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup = func() {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("WARN: Failed to remove checkout %s: %v", dir, err)
+		}
+	}
+	if err := writeFiles(ctx, dir, files); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to check out project %s: %w", projectID, err)
+	}
+	log.Printf("Checked out %d files for project %s into %s", len(files), projectID, dir)
+	return dir, cleanup, nil
+}
+
+// writeFiles writes files under dir as stored, refusing names that would escape it.
+func writeFiles(ctx context.Context, dir string, files []types.GeneratedFile) error {
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		path, err := utils.SafeJoin(dir, f.Filename)
+		if err != nil {
+			return fmt.Errorf("file %q: %w", f.Filename, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(f.Content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"sui_ai_server/internal/project"
+	"sui_ai_server/internal/types"
+	"sui_ai_server/internal/utils"
+)
+
+// inTempWorkDir runs the test from a fresh directory, so utils.WorkDir (a relative path) lands in it.
+func inTempWorkDir(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// memoryStore is a FileStore kept in memory, standing in for a remote backend.
+type memoryStore struct {
+	files map[string][]types.GeneratedFile
+}
+
+func (s *memoryStore) Save(ctx context.Context, projectID string, files []types.GeneratedFile) error {
+	s.files[projectID] = files
+	return nil
+}
+
+func (s *memoryStore) Load(ctx context.Context, projectID string) ([]types.GeneratedFile, error) {
+	files, ok := s.files[projectID]
+	if !ok {
+		return nil, project.ErrProjectNotFound
+	}
+	return files, nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, projectID string) error {
+	delete(s.files, projectID)
+	return nil
+}
+
+func filenames(files []types.GeneratedFile) []string {
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = f.Filename
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestLocalStoreSaveLoad(t *testing.T) {
+	inTempWorkDir(t)
+	ctx := context.Background()
+	store := NewLocalStore()
+
+	if _, err := store.Load(ctx, "p1"); !errors.Is(err, project.ErrProjectNotFound) {
+		t.Fatalf("Load of a missing project = %v, want %v", err, project.ErrProjectNotFound)
+	}
+
+	first := []types.GeneratedFile{
+		{Filename: "index.html", Content: "<h1>One</h1>"},
+		{Filename: "src/old.js", Content: "old()"},
+	}
+	if err := store.Save(ctx, "p1", first); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	nodeModule := filepath.Join(utils.ProjectDir("p1"), "node_modules", "dep", "index.js")
+	if err := os.MkdirAll(filepath.Dir(nodeModule), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(nodeModule, []byte("dep()"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	second := []types.GeneratedFile{
+		{Filename: "index.html", Content: "<h1>Two</h1>"},
+		{Filename: "src/new.js", Content: "fresh()"},
+	}
+	if err := store.Save(ctx, "p1", second); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := store.Load(ctx, "p1")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := filenames(loaded); len(got) != 2 || got[0] != "index.html" || got[1] != "src/new.js" {
+		t.Errorf("Load = %v, want the second file set only", got)
+	}
+	for _, f := range loaded {
+		if f.Filename == "index.html" && f.Content != "<h1>Two</h1>" {
+			t.Errorf("index.html = %q, want the saved content", f.Content)
+		}
+	}
+	if _, err := os.Stat(nodeModule); err != nil {
+		t.Errorf("Save removed node_modules: %v", err)
+	}
+
+	if err := store.Delete(ctx, "p1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := os.Stat(utils.ProjectDir("p1")); !os.IsNotExist(err) {
+		t.Errorf("project directory still exists after Delete: %v", err)
+	}
+}
+
+func TestCheckoutLocalBuildsInPlace(t *testing.T) {
+	inTempWorkDir(t)
+	dir, cleanup, err := Checkout(context.Background(), NewLocalStore(), "p1")
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	cleanup()
+	if dir != utils.ProjectDir("p1") {
+		t.Errorf("Checkout dir = %s, want the project directory", dir)
+	}
+}
+
+func TestCheckoutHydratesRemoteStore(t *testing.T) {
+	inTempWorkDir(t)
+	store := &memoryStore{files: map[string][]types.GeneratedFile{
+		"p1": {{Filename: "src/App.tsx", Content: "app()"}},
+	}}
+
+	dir, cleanup, err := Checkout(context.Background(), store, "p1")
+	if err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+	if dir == utils.ProjectDir("p1") {
+		t.Fatal("a remote store was checked out into the project directory")
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "src", "App.tsx")); err != nil || string(content) != "app()" {
+		t.Errorf("checked-out file = %q, %v; want app()", content, err)
+	}
+	cleanup()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("checkout still exists after cleanup: %v", err)
+	}
+
+	if _, _, err := Checkout(context.Background(), store, "missing"); !errors.Is(err, project.ErrProjectNotFound) {
+		t.Errorf("Checkout of a missing project = %v, want %v", err, project.ErrProjectNotFound)
+	}
+}
+
+func TestCheckoutRejectsUnsafePaths(t *testing.T) {
+	inTempWorkDir(t)
+	store := &memoryStore{files: map[string][]types.GeneratedFile{
+		"p1": {{Filename: "../escape.txt", Content: "x"}},
+	}}
+	if _, _, err := Checkout(context.Background(), store, "p1"); !errors.Is(err, utils.ErrUnsafePath) {
+		t.Fatalf("Checkout = %v, want %v", err, utils.ErrUnsafePath)
+	}
+	if _, err := os.Stat(filepath.Join(utils.WorkDir, "escape.txt")); !os.IsNotExist(err) {
+		t.Error("a file escaped the checkout directory")
+	}
+}
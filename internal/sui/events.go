@@ -0,0 +1,40 @@
+package sui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// EventID identifies an event and doubles as the cursor for paging through events.
+type EventID struct {
+	TxDigest string `json:"txDigest"`
+	EventSeq string `json:"eventSeq"`
+}
+
+// Event is a Move event as returned by suix_queryEvents.
+type Event struct {
+	ID          EventID         `json:"id"`
+	Type        string          `json:"type"`
+	Sender      string          `json:"sender"`
+	ParsedJSON  json.RawMessage `json:"parsedJson"`
+	TimestampMs string          `json:"timestampMs,omitempty"`
+}
+
+// EventPage is one page of events; NextCursor continues after the last event returned.
+type EventPage struct {
+	Data        []Event  `json:"data"`
+	NextCursor  *EventID `json:"nextCursor"`
+	HasNextPage bool     `json:"hasNextPage"`
+}
+
+// QueryEvents returns up to limit events of moveEventType after cursor (nil starts from the first event),
+// oldest first, or newest first when descending is set.
+func (s *Service) QueryEvents(ctx context.Context, moveEventType string, cursor *EventID, limit int, descending bool) (*EventPage, error) {
+	query := map[string]any{"MoveEventType": moveEventType}
+	var page EventPage
+	if err := s.call(ctx, "suix_queryEvents", []any{query, cursor, limit, descending}, &page); err != nil {
+		return nil, fmt.Errorf("failed to query %s events: %w", moveEventType, err)
+	}
+	return &page, nil
+}
@@ -0,0 +1,16 @@
+package sui
+
+import (
+	"context"
+)
+
+// CheckNFTOwnership reports whether wallet owns at least one object of nftType (e.g. a subscription NFT,
+// "0xPKG::module::Pass").
+func (s *Service) CheckNFTOwnership(ctx context.Context, wallet, nftType string) (bool, error) {
+	// The RPC filters by type, so any object returned is a match.
+	obj, err := s.findOwnedObject(ctx, wallet, nftType, false, false, func(OwnedObject) bool { return true })
+	if err != nil {
+		return false, err
+	}
+	return obj != nil, nil
+}
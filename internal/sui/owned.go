@@ -0,0 +1,82 @@
+package sui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// ErrOwnedObjectsLimit means a wallet holds more matching objects than an ownership check is willing to scan.
+var ErrOwnedObjectsLimit = errors.New("too many owned objects to scan")
+
+const (
+	// ownedObjectsPageSize is how many objects are fetched per suix_getOwnedObjects page.
+	ownedObjectsPageSize = 50
+	// maxOwnedObjectPages caps how many pages one ownership check reads.
+	maxOwnedObjectPages = 20
+)
+
+// OwnedObject is an object from suix_getOwnedObjects with the parts ownership checks look at.
+type OwnedObject struct {
+	ObjectID string
+	Type     string
+	Fields   map[string]any // Move struct fields, when content was requested
+	Display  map[string]any // Display metadata, when display was requested
+}
+
+type ownedObjectsPage struct {
+	Data []struct {
+		Data *struct {
+			ObjectID string `json:"objectId"`
+			Type     string `json:"type"`
+			Content  *struct {
+				Fields map[string]any `json:"fields"`
+			} `json:"content"`
+			Display *struct {
+				Data map[string]any `json:"data"`
+			} `json:"display"`
+		} `json:"data"`
+	} `json:"data"`
+	NextCursor  json.RawMessage `json:"nextCursor"`
+	HasNextPage bool            `json:"hasNextPage"`
+}
+
+// findOwnedObject pages through wallet's objects of structType, following the RPC cursor, and returns the
+// first one match accepts, or nil when none does. It stops as soon as a match is found and fails with
+// ErrOwnedObjectsLimit after maxOwnedObjectPages pages. content and display choose which object data is fetched.
+func (s *Service) findOwnedObject(ctx context.Context, wallet, structType string, content, display bool, match func(OwnedObject) bool) (*OwnedObject, error) {
+	query := map[string]any{
+		"filter":  map[string]any{"StructType": structType},
+		"options": map[string]any{"showType": true, "showContent": content, "showDisplay": display},
+	}
+	var cursor any
+	for page := 1; page <= maxOwnedObjectPages; page++ {
+		var resp ownedObjectsPage
+		if err := s.call(ctx, "suix_getOwnedObjects", []any{wallet, query, cursor, ownedObjectsPageSize}, &resp); err != nil {
+			return nil, fmt.Errorf("failed to list %s objects owned by %s (page %d): %w", structType, wallet, page, err)
+		}
+		for _, item := range resp.Data {
+			if item.Data == nil {
+				continue
+			}
+			obj := OwnedObject{ObjectID: item.Data.ObjectID, Type: item.Data.Type}
+			if item.Data.Content != nil {
+				obj.Fields = item.Data.Content.Fields
+			}
+			if item.Data.Display != nil {
+				obj.Display = item.Data.Display.Data
+			}
+			if match(obj) {
+				return &obj, nil
+			}
+		}
+		if !resp.HasNextPage || len(resp.NextCursor) == 0 || string(resp.NextCursor) == "null" {
+			return nil, nil
+		}
+		cursor = resp.NextCursor
+	}
+	log.Printf("WARN: Stopped scanning %s objects owned by %s after %d pages", structType, wallet, maxOwnedObjectPages)
+	return nil, fmt.Errorf("%w: %s objects owned by %s", ErrOwnedObjectsLimit, structType, wallet)
+}
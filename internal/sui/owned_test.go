@@ -0,0 +1,98 @@
+package sui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+const passType = "0xabc::pass::Pass"
+
+// pagedOwner serves pages of objects, page i (from 0) at cursor "c<i>", with the NFT on page found (or none
+// when found is negative).
+func pagedOwner(t *testing.T, pages, found int) *fakeRPC {
+	return &fakeRPC{handle: func(method string, params []json.RawMessage) (any, *RPCError) {
+		_, cursor := ownedQuery(t, params)
+		page := 0
+		if cursor != "" {
+			fmt.Sscanf(cursor, "c%d", &page)
+		}
+		next := ""
+		if page+1 < pages {
+			next = fmt.Sprintf("c%d", page+1)
+		}
+		if page == found {
+			return ownedPage(next, map[string]any{"objectId": "0xnft", "type": passType}), nil
+		}
+		return ownedPage(next), nil
+	}}
+}
+
+func TestCheckNFTOwnershipFollowsCursor(t *testing.T) {
+	fake := pagedOwner(t, 3, 2)
+	s := newTestService(t, fake)
+
+	owned, err := s.CheckNFTOwnership(context.Background(), "0xwallet", passType)
+	if err != nil || !owned {
+		t.Fatalf("CheckNFTOwnership = %v, %v; want owned", owned, err)
+	}
+	calls := fake.methodCalls("suix_getOwnedObjects")
+	if len(calls) != 3 {
+		t.Fatalf("read %d pages, want 3", len(calls))
+	}
+	for i, call := range calls {
+		if _, cursor := ownedQuery(t, call.Params); i > 0 && cursor != fmt.Sprintf("c%d", i) {
+			t.Errorf("page %d requested with cursor %q, want the previous page's", i+1, cursor)
+		}
+	}
+}
+
+func TestCheckNFTOwnershipStopsAtMatch(t *testing.T) {
+	fake := pagedOwner(t, 5, 1)
+	s := newTestService(t, fake)
+
+	if owned, err := s.CheckNFTOwnership(context.Background(), "0xwallet", passType); err != nil || !owned {
+		t.Fatalf("CheckNFTOwnership = %v, %v; want owned", owned, err)
+	}
+	if n := len(fake.methodCalls("suix_getOwnedObjects")); n != 2 {
+		t.Errorf("read %d pages, want 2 (stopping at the match)", n)
+	}
+}
+
+func TestCheckNFTOwnershipNotOwned(t *testing.T) {
+	fake := pagedOwner(t, 3, -1)
+	s := newTestService(t, fake)
+
+	if owned, err := s.CheckNFTOwnership(context.Background(), "0xwallet", passType); err != nil || owned {
+		t.Fatalf("CheckNFTOwnership = %v, %v; want not owned", owned, err)
+	}
+	if n := len(fake.methodCalls("suix_getOwnedObjects")); n != 3 {
+		t.Errorf("read %d pages, want all 3", n)
+	}
+}
+
+func TestCheckNFTOwnershipPageCap(t *testing.T) {
+	fake := pagedOwner(t, maxOwnedObjectPages+5, -1)
+	s := newTestService(t, fake)
+
+	if _, err := s.CheckNFTOwnership(context.Background(), "0xwallet", passType); !errors.Is(err, ErrOwnedObjectsLimit) {
+		t.Fatalf("CheckNFTOwnership error = %v, want %v", err, ErrOwnedObjectsLimit)
+	}
+	if n := len(fake.methodCalls("suix_getOwnedObjects")); n != maxOwnedObjectPages {
+		t.Errorf("read %d pages, want the %d-page cap", n, maxOwnedObjectPages)
+	}
+}
+
+func TestCheckNFTOwnershipRPCError(t *testing.T) {
+	fake := &fakeRPC{handle: func(string, []json.RawMessage) (any, *RPCError) {
+		return nil, &RPCError{Code: -32000, Message: "node overloaded"}
+	}}
+	s := newTestService(t, fake)
+
+	var rpcErr *RPCError
+	if _, err := s.CheckNFTOwnership(context.Background(), "0xwallet", passType); !errors.As(err, &rpcErr) {
+		t.Fatalf("CheckNFTOwnership error = %v, want the RPC error", err)
+	}
+}
@@ -0,0 +1,111 @@
+package sui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// fakeRPC is an httptest server standing in for a Sui full node. handle answers each JSON-RPC call; every
+// call is recorded.
+type fakeRPC struct {
+	handle func(method string, params []json.RawMessage) (any, *RPCError)
+
+	mu    sync.Mutex
+	calls []rpcCall
+}
+
+type rpcCall struct {
+	Method string
+	Params []json.RawMessage
+}
+
+// newTestService starts fake and returns a Service that talks to it, looking up SUINS registrations under
+// nftTypes.
+func newTestService(t *testing.T, fake *fakeRPC, nftTypes ...string) *Service {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	s, err := NewService(server.URL, "0xsuins", nftTypes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func (f *fakeRPC) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID     int64             `json:"id"`
+		Method string            `json:"method"`
+		Params []json.RawMessage `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	f.calls = append(f.calls, rpcCall{Method: req.Method, Params: req.Params})
+	f.mu.Unlock()
+
+	result, rpcErr := f.handle(req.Method, req.Params)
+	resp := map[string]any{"jsonrpc": "2.0", "id": req.ID}
+	if rpcErr != nil {
+		resp["error"] = rpcErr
+	} else {
+		resp["result"] = result
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (f *fakeRPC) methodCalls(method string) []rpcCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var calls []rpcCall
+	for _, c := range f.calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// ownedPage is a suix_getOwnedObjects result holding objects, continuing at next ("" for the last page).
+func ownedPage(next string, objects ...map[string]any) map[string]any {
+	data := make([]map[string]any, len(objects))
+	for i, obj := range objects {
+		data[i] = map[string]any{"data": obj}
+	}
+	page := map[string]any{"data": data, "hasNextPage": next != "", "nextCursor": nil}
+	if next != "" {
+		page["nextCursor"] = next
+	}
+	return page
+}
+
+// registration is an owned SUINS registration object of nftType for domain.
+func registration(id, nftType, domain string) map[string]any {
+	return map[string]any{
+		"objectId": id,
+		"type":     nftType,
+		"content":  map[string]any{"fields": map[string]any{"domain_name": domain}},
+	}
+}
+
+// ownedQuery decodes the struct type and cursor of a suix_getOwnedObjects call.
+func ownedQuery(t *testing.T, params []json.RawMessage) (structType, cursor string) {
+	t.Helper()
+	var query struct {
+		Filter struct {
+			StructType string `json:"StructType"`
+		} `json:"filter"`
+	}
+	if len(params) < 3 {
+		t.Fatalf("suix_getOwnedObjects called with %d params", len(params))
+	}
+	json.Unmarshal(params[1], &query)
+	json.Unmarshal(params[2], &cursor)
+	return query.Filter.StructType, cursor
+}
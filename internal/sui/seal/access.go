@@ -5,27 +5,172 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"sync"
 	"time"
 )
 
+// DefaultPingPath is the lightweight status path used by Ping unless overridden.
+const DefaultPingPath = "/v1/health"
+
+// Errors returned (wrapped, with the response status and body) for Seal API failures, so callers can tell
+// them apart with errors.Is.
+var (
+	ErrSealNotConfigured = errors.New("seal client not configured")
+	ErrSealUnauthorized  = errors.New("seal rejected the API key") // 401 or 403
+	ErrSealNotFound      = errors.New("seal resource not found")   // 404, e.g. an unknown policy
+	ErrSealServer        = errors.New("seal server error")         // 5xx
+)
+
+// maxErrorBodyChars caps how much of an error response body is quoted in returned errors.
+const maxErrorBodyChars = 512
+
+// statusError turns a non-success response into an error wrapping the matching sentinel, with the status and
+// (truncated) body in its message.
+func statusError(op string, resp *http.Response) error {
+	return bodyStatusError(op, resp, readErrorBody(resp))
+}
+
+// bodyStatusError is statusError for a response whose body has already been read.
+func bodyStatusError(op string, resp *http.Response, body string) error {
+	if len(body) > maxErrorBodyChars {
+		body = body[:maxErrorBodyChars] + "..."
+	}
+	var sentinel error
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		sentinel = ErrSealUnauthorized
+	case resp.StatusCode == http.StatusNotFound:
+		sentinel = ErrSealNotFound
+	case resp.StatusCode >= 500:
+		sentinel = ErrSealServer
+	default:
+		return fmt.Errorf("Seal %s returned %s: %s", op, resp.Status, body)
+	}
+	return fmt.Errorf("%w: Seal %s returned %s: %s", sentinel, op, resp.Status, body)
+}
+
 // Client struct to interact with Seal API
 type Client struct {
 	apiKey     string
 	endpoint   string
 	httpClient *http.Client
+	pingPath   string
+
+	// Last observed reachability, so outages and recoveries are logged once rather than on every ping.
+	stateMu   sync.Mutex
+	checked   bool
+	reachable bool
+}
+
+// Option configures optional Client settings.
+type Option func(*Client)
+
+// WithPingPath sets the path (relative to the endpoint) that Ping requests. Empty values are ignored.
+func WithPingPath(path string) Option {
+	return func(c *Client) {
+		if path != "" {
+			c.pingPath = path
+		}
+	}
+}
+
+// WithHTTPClient makes the client send requests through httpClient, e.g. one configured with a proxy, mTLS or a
+// test transport. Without it a plain client with a 15s timeout is used. Nil is ignored.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient != nil {
+			c.httpClient = httpClient
+		}
+	}
 }
 
 // NewClient creates a new Seal API client.
-func NewClient(apiKey, endpoint string) *Client {
-	return &Client{
+func NewClient(apiKey, endpoint string, opts ...Option) *Client {
+	c := &Client{
 		apiKey:   apiKey,
 		endpoint: endpoint,
 		httpClient: &http.Client{
 			Timeout: 15 * time.Second, // Set a reasonable timeout
 		},
+		pingPath: DefaultPingPath,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Configured reports whether the client has an endpoint and API key; unconfigured clients no-op or deny.
+func (c *Client) Configured() bool {
+	return c.apiKey != "" && c.endpoint != ""
+}
+
+// Ping checks that the Seal endpoint is reachable and healthy. Transitions between reachable and
+// unreachable are logged, so operators can see when policy registration would silently no-op.
+func (c *Client) Ping(ctx context.Context) error {
+	if !c.Configured() {
+		return ErrSealNotConfigured
+	}
+	err := c.ping(ctx)
+	c.recordPing(err)
+	return err
+}
+
+func (c *Client) ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint+c.pingPath, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create Seal ping request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("Seal ping failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return statusError("ping", resp)
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096)) // Drain so the connection can be reused
+	return nil
+}
+
+func (c *Client) recordPing(err error) {
+	c.stateMu.Lock()
+	defer c.stateMu.Unlock()
+	reachable := err == nil
+	switch {
+	case !c.checked && !reachable:
+		log.Printf("WARN: Seal endpoint %s is unreachable: %v", c.endpoint, err)
+	case c.checked && c.reachable && !reachable:
+		log.Printf("WARN: Seal endpoint %s went down: %v", c.endpoint, err)
+	case c.checked && !c.reachable && reachable:
+		log.Printf("Seal endpoint %s is reachable again.", c.endpoint)
+	}
+	c.checked = true
+	c.reachable = reachable
+}
+
+// RunPing pings Seal every interval until ctx is cancelled, keeping the logged up/down state current
+// even when nothing else is talking to Seal.
+func (c *Client) RunPing(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		c.Ping(pingCtx)
+		cancel()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
 	}
 }
 
@@ -40,7 +185,6 @@ type SealPolicyRequest struct {
 // SealPolicyResponse structure (if needed)
 // type SealPolicyResponse struct { ... }
 
-
 // RegisterPolicy registers a new access policy with Seal.
 func (c *Client) RegisterPolicy(ctx context.Context, policyName, contentCID string, nftCriteria map[string]interface{}) error {
 	if c.apiKey == "" || c.endpoint == "" {
@@ -80,11 +224,7 @@ func (c *Client) RegisterPolicy(ctx context.Context, policyName, contentCID stri
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-        // Read response body for more details
-        var bodyBytes []byte
-        resp.Body.Read(bodyBytes)
-        log.Printf("Seal API error response body: %s", string(bodyBytes))
-		return fmt.Errorf("Seal API returned non-success status: %s", resp.Status)
+		return statusError("policy registration", resp)
 	}
 
 	log.Printf("Successfully registered Seal policy '%s' for CID %s", policyName, contentCID)
@@ -94,65 +234,128 @@ func (c *Client) RegisterPolicy(ctx context.Context, policyName, contentCID stri
 
 // SealVerifyRequest structure (adjust based on API)
 type SealVerifyRequest struct {
-    WalletAddress string `json:"walletAddress"`
-    ContentCID    string `json:"contentCid"`
+	WalletAddress string `json:"walletAddress"`
+	ContentCID    string `json:"contentCid"`
 }
 
 // SealVerifyResponse structure (adjust based on API)
 type SealVerifyResponse struct {
-    HasAccess bool `json:"hasAccess"`
-    // Add other fields if provided by the API
+	HasAccess bool `json:"hasAccess"`
+	// Add other fields if provided by the API
 }
 
+// ErrSealUnavailable means Seal could not give an answer (5xx or network failure after retries); a 5xx also
+// matches ErrSealServer. Callers should treat it as a temporary outage (e.g. 503), not as an access denial.
+var ErrSealUnavailable = errors.New("seal service unavailable")
+
+// verifyMaxAttempts and verifyBaseBackoff control retries of transient VerifyAccess failures.
+const (
+	verifyMaxAttempts = 3
+	verifyBaseBackoff = 500 * time.Millisecond
+)
+
 // VerifyAccess checks if a wallet has access to a specific CID via Seal.
 // Note: Seal verification is often done client-side using their SDK.
 // This backend implementation is for cases where backend verification is desired.
+//
+// It returns false only on an authoritative answer: a "hasAccess": false response, with a 200 or a 403. Any other
+// 401 or 403 means Seal rejected our API key rather than the wallet and is reported as ErrSealUnauthorized.
+// Transient failures (5xx, network errors) are retried with exponential backoff and then reported as
+// ErrSealUnavailable. Callers must fail closed on errors, answering 502 or 503 rather than granting or
+// denying access.
 func (c *Client) VerifyAccess(ctx context.Context, walletAddress, contentCID string) (bool, error) {
 	if c.apiKey == "" || c.endpoint == "" {
 		log.Println("WARN: Seal API Key or Endpoint not configured. Assuming access denied for verification.")
-		return false, fmt.Errorf("Seal client not configured")
+		return false, ErrSealNotConfigured
 	}
 
 	// This endpoint is hypothetical - check Seal documentation for actual verification API
 	apiURL := fmt.Sprintf("%s/v1/verify", c.endpoint)
 
-    requestBody := SealVerifyRequest{
-        WalletAddress: walletAddress,
-        ContentCID:    contentCID,
-    }
+	requestBody := SealVerifyRequest{
+		WalletAddress: walletAddress,
+		ContentCID:    contentCID,
+	}
 
-    jsonData, err := json.Marshal(requestBody)
+	jsonData, err := json.Marshal(requestBody)
 	if err != nil {
 		return false, fmt.Errorf("failed to marshal Seal verify request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewBuffer(jsonData))
+	log.Printf("Verifying Seal access for wallet %s on CID %s via %s", walletAddress, contentCID, apiURL)
+
+	var lastErr error
+	backoff := verifyBaseBackoff
+	for attempt := 1; attempt <= verifyMaxAttempts; attempt++ {
+		if attempt > 1 {
+			log.Printf("Seal verify attempt %d/%d failed (%v), retrying in %s", attempt-1, verifyMaxAttempts, lastErr, backoff)
+			select {
+			case <-ctx.Done():
+				return false, ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		hasAccess, retryable, err := c.verifyOnce(ctx, apiURL, jsonData)
+		if err == nil {
+			return hasAccess, nil
+		}
+		if !retryable {
+			return false, err
+		}
+		lastErr = err
+	}
+
+	return false, fmt.Errorf("%w: %w", ErrSealUnavailable, lastErr)
+}
+
+// verifyOnce performs a single verify call. retryable reports whether a failure is transient.
+func (c *Client) verifyOnce(ctx context.Context, apiURL string, jsonData []byte) (hasAccess bool, retryable bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, bytes.NewReader(jsonData))
 	if err != nil {
-		return false, fmt.Errorf("failed to create Seal verify API request: %w", err)
+		return false, false, fmt.Errorf("failed to create Seal verify API request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+c.apiKey)
 
-    log.Printf("Verifying Seal access for wallet %s on CID %s via %s", walletAddress, contentCID, apiURL)
-
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return false, fmt.Errorf("failed to send verify request to Seal API: %w", err)
+		if ctx.Err() != nil {
+			return false, false, ctx.Err()
+		}
+		return false, true, fmt.Errorf("failed to send verify request to Seal API: %w", err)
 	}
 	defer resp.Body.Close()
 
-    if resp.StatusCode != http.StatusOK {
-        var bodyBytes []byte
-        resp.Body.Read(bodyBytes)
-        log.Printf("Seal verify API error response body: %s", string(bodyBytes))
-        return false, fmt.Errorf("Seal verify API returned non-success status: %s", resp.Status)
-    }
-
-    var verifyResp SealVerifyResponse
-    if err := json.NewDecoder(resp.Body).Decode(&verifyResp); err != nil {
-        return false, fmt.Errorf("failed to decode Seal verify response: %w", err)
-    }
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		var verifyResp SealVerifyResponse
+		if err := json.NewDecoder(resp.Body).Decode(&verifyResp); err != nil {
+			return false, false, fmt.Errorf("failed to decode Seal verify response: %w", err)
+		}
+		return verifyResp.HasAccess, false, nil
+	case resp.StatusCode == http.StatusForbidden:
+		// Only a 403 carrying a verify answer is Seal refusing this wallet; otherwise it refused our credentials.
+		body := readErrorBody(resp)
+		var denial struct {
+			HasAccess *bool `json:"hasAccess"`
+		}
+		if json.Unmarshal([]byte(body), &denial) == nil && denial.HasAccess != nil && !*denial.HasAccess {
+			return false, false, nil
+		}
+		return false, false, bodyStatusError("verify", resp, body)
+	default:
+		return false, resp.StatusCode >= 500, statusError("verify", resp)
+	}
+}
 
-	return verifyResp.HasAccess, nil
+// readErrorBody reads (a bounded amount of) an error response body.
+func readErrorBody(resp *http.Response) string {
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return fmt.Sprintf("<failed to read body: %v>", err)
+	}
+	return string(body)
 }
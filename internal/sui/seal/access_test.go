@@ -0,0 +1,47 @@
+package seal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerifyAccessResponses(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    bool
+		wantErr error
+	}{
+		{"granted", http.StatusOK, `{"hasAccess":true}`, true, nil},
+		{"denied", http.StatusOK, `{"hasAccess":false}`, false, nil},
+		{"wallet refused", http.StatusForbidden, `{"hasAccess":false}`, false, nil},
+		{"API key refused", http.StatusForbidden, `{"error":"invalid API key"}`, false, ErrSealUnauthorized},
+		{"empty 403", http.StatusForbidden, ``, false, ErrSealUnauthorized},
+		{"unauthenticated", http.StatusUnauthorized, `{"error":"missing API key"}`, false, ErrSealUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			got, err := NewClient("key", srv.URL).VerifyAccess(context.Background(), "0xabc", "cid")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("VerifyAccess: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("hasAccess = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// Package sui talks to a Sui full node over JSON-RPC for SUINS lookups and for building the transactions
+// users sign themselves. The server holds no keys, so it never executes transactions.
+package sui
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrNotConfigured means the SUINS settings a call needs are missing.
+var ErrNotConfigured = errors.New("sui service not configured for SUINS")
+
+// Service handles interactions with the Sui blockchain.
+type Service struct {
+	rpcURL        string
+	httpClient    *http.Client
+	suinsPackage  string   // Package ID whose subdomains module is called
+	suinsObjectID string   // Shared SuiNS registry object passed to SUINS calls
+	suinsNftTypes []string // Full type strings of SUINS registrations, e.g. 0xPKG::suins_registration::SuinsRegistration
+}
+
+// Option configures optional Service settings.
+type Option func(*Service)
+
+// WithSuinsObject sets the shared SuiNS registry object ID that subdomain calls mutate.
+func WithSuinsObject(objectID string) Option {
+	return func(s *Service) {
+		s.suinsObjectID = strings.TrimSpace(objectID)
+	}
+}
+
+// WithHTTPClient sends RPC requests through httpClient instead of a plain client with a 20s timeout. Nil is ignored.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(s *Service) {
+		if httpClient != nil {
+			s.httpClient = httpClient
+		}
+	}
+}
+
+// NewService creates a Sui service for the full node at rpcURL. Registrations are looked up under every type in
+// suinsNftTypes, since the registry's NFT type differs across versions and networks. No connection is made
+// until the first call.
+func NewService(rpcURL, suinsPackage string, suinsNftTypes []string, opts ...Option) (*Service, error) {
+	if rpcURL == "" {
+		return nil, fmt.Errorf("Sui RPC endpoint URL cannot be empty")
+	}
+	var nftTypes []string
+	for _, nftType := range suinsNftTypes {
+		nftType = strings.TrimSpace(nftType)
+		if nftType == "" {
+			continue
+		}
+		if !strings.HasPrefix(nftType, "0x") || strings.Count(nftType, "::") != 2 {
+			return nil, fmt.Errorf("invalid SUINS NFT type %q: expected 0xPACKAGE::MODULE::STRUCT", nftType)
+		}
+		nftTypes = append(nftTypes, nftType)
+	}
+	s := &Service{
+		rpcURL:        rpcURL,
+		httpClient:    &http.Client{Timeout: 20 * time.Second},
+		suinsPackage:  strings.TrimSpace(suinsPackage),
+		suinsNftTypes: nftTypes,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	log.Printf("Sui Service initialized. RPC: %s, SUINS package: %s, SUINS NFT types: %s", rpcURL, s.suinsPackage, strings.Join(s.suinsNftTypes, ", "))
+	return s, nil
+}
@@ -0,0 +1,137 @@
+package sui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrInvalidSubdomain means a subdomain name or target isn't usable (e.g. not of the form label.parent.sui).
+	ErrInvalidSubdomain = errors.New("invalid SUINS subdomain")
+	// ErrNameNotOwned means the wallet does not hold the registration NFT for the name.
+	ErrNameNotOwned = errors.New("wallet does not own this SUINS name")
+)
+
+// Subdomain transactions call the SUINS subdomains module of the configured package. Leaf subdomains have no
+// NFT of their own: the parent's owner controls them and they point straight at a target address.
+//
+//	<package>::subdomains::new_leaf(suins: &mut SuiNS, parent: &SuinsRegistration, clock: &Clock, subdomain_name: String, target: address)
+//	<package>::subdomains::remove_leaf(suins: &mut SuiNS, parent: &SuinsRegistration, clock: &Clock, subdomain_name: String)
+//
+// An existing leaf can't be retargeted in place, so updating one removes and recreates it in the same transaction.
+const (
+	subdomainsModule = "subdomains"
+	newLeafFunction  = "new_leaf"
+	removeLeafFunc   = "remove_leaf"
+	clockObjectID    = "0x6"
+	// subdomainGasBudget is the gas budget (in MIST) for the subdomain transaction; unused gas is refunded.
+	subdomainGasBudget = "50000000"
+)
+
+var (
+	suinsLabel   = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+	suiAddressRe = regexp.MustCompile(`^0x[0-9a-fA-F]{1,64}$`)
+)
+
+// SubdomainRecord describes a leaf subdomain record and the unsigned transaction that creates it.
+type SubdomainRecord struct {
+	Name        string   `json:"name"`                     // Full subdomain, e.g. app.mysite.sui
+	Parent      string   `json:"parent"`                   // Parent name the wallet owns
+	Target      string   `json:"target"`                   // Address (the site object) the subdomain points to
+	ParentNFTID string   `json:"parentNftId"`              // The wallet's registration NFT for Parent
+	Previous    string   `json:"previousTarget,omitempty"` // Target being replaced when the subdomain already existed
+	MoveCalls   []string `json:"moveCalls"`                // The calls the transaction makes, in order
+	TxBytes     string   `json:"txBytes"`                  // Base64 transaction data for the wallet to sign and execute
+}
+
+type moveCallParams struct {
+	PackageObjectID string   `json:"packageObjectId"`
+	Module          string   `json:"module"`
+	Function        string   `json:"function"`
+	TypeArguments   []string `json:"typeArguments"`
+	Arguments       []any    `json:"arguments"`
+}
+
+type transactionBlockBytes struct {
+	TxBytes string `json:"txBytes"`
+}
+
+// SplitSubdomain validates a subdomain name and returns it normalized along with its parent name.
+func SplitSubdomain(name string) (subdomain, parent string, err error) {
+	subdomain = NormalizeName(name)
+	labels := strings.Split(strings.TrimSuffix(subdomain, ".sui"), ".")
+	if len(labels) < 2 {
+		return "", "", fmt.Errorf("%w: %q has no parent name (expected e.g. app.mysite.sui)", ErrInvalidSubdomain, name)
+	}
+	for _, label := range labels {
+		if !suinsLabel.MatchString(label) {
+			return "", "", fmt.Errorf("%w: invalid label %q in %q", ErrInvalidSubdomain, label, name)
+		}
+	}
+	return subdomain, strings.Join(labels[1:], ".") + ".sui", nil
+}
+
+// CreateSubdomain builds the transaction that points subdomain (e.g. app.mysite.sui) at target for wallet, after
+// checking that wallet owns the parent name. An existing subdomain is replaced. The server can't sign for the
+// wallet, so the returned record carries unsigned transaction bytes for the client to sign and execute.
+func (s *Service) CreateSubdomain(ctx context.Context, wallet, subdomain, target string) (*SubdomainRecord, error) {
+	if s.suinsPackage == "" || s.suinsObjectID == "" {
+		return nil, fmt.Errorf("%w: SUINS_CONTRACT_ADDRESS and SUINS_OBJECT_ID are required for subdomains", ErrNotConfigured)
+	}
+	name, parent, err := SplitSubdomain(subdomain)
+	if err != nil {
+		return nil, err
+	}
+	if !suiAddressRe.MatchString(target) {
+		return nil, fmt.Errorf("%w: target %q is not a Sui address", ErrInvalidSubdomain, target)
+	}
+
+	parentNFT, err := s.findRegistration(ctx, wallet, parent)
+	if err != nil {
+		return nil, err
+	}
+	if parentNFT == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNameNotOwned, parent)
+	}
+	previous, err := s.resolveAddress(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up existing record for %s: %w", name, err)
+	}
+
+	record := &SubdomainRecord{Name: name, Parent: parent, Target: target, ParentNFTID: parentNFT, Previous: previous}
+	var calls []moveCallParams
+	if previous != "" {
+		calls = append(calls, s.subdomainCall(removeLeafFunc, parentNFT, name))
+	}
+	calls = append(calls, s.subdomainCall(newLeafFunction, parentNFT, name, target))
+
+	requests := make([]any, 0, len(calls))
+	for _, call := range calls {
+		requests = append(requests, map[string]any{"moveCallRequestParams": call})
+		record.MoveCalls = append(record.MoveCalls, fmt.Sprintf("%s::%s::%s", call.PackageObjectID, call.Module, call.Function))
+	}
+	var tx transactionBlockBytes
+	if err := s.call(ctx, "unsafe_batchTransaction", []any{wallet, requests, nil, subdomainGasBudget}, &tx); err != nil {
+		return nil, fmt.Errorf("failed to build subdomain transaction for %s: %w", name, err)
+	}
+	record.TxBytes = tx.TxBytes
+
+	log.Printf("Built SUINS subdomain transaction: %s -> %s for wallet %s", name, target, wallet)
+	return record, nil
+}
+
+// subdomainCall is a call into the subdomains module with the shared arguments every leaf function takes.
+func (s *Service) subdomainCall(function, parentNFT, name string, extra ...any) moveCallParams {
+	args := append([]any{s.suinsObjectID, parentNFT, clockObjectID, name}, extra...)
+	return moveCallParams{
+		PackageObjectID: s.suinsPackage,
+		Module:          subdomainsModule,
+		Function:        function,
+		TypeArguments:   []string{},
+		Arguments:       args,
+	}
+}
@@ -0,0 +1,98 @@
+package sui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// ErrNameNotFound means a SUINS name has no record on chain (it isn't registered, or resolves to nothing).
+var ErrNameNotFound = errors.New("SUINS name not found")
+
+// NormalizeName lowercases a SUINS name and adds the .sui suffix when missing.
+func NormalizeName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !strings.HasSuffix(name, ".sui") {
+		name += ".sui"
+	}
+	return name
+}
+
+// VerifySuinsOwnership reports whether wallet holds the SUINS registration NFT (of the configured NFT type)
+// for name. When wallet doesn't own it and the name resolves to nothing, ErrNameNotFound is returned so callers
+// can tell a typo from someone else's name. RPC failures are returned as errors, never as "not owned".
+func (s *Service) VerifySuinsOwnership(ctx context.Context, wallet, name string) (bool, error) {
+	nftID, err := s.findRegistration(ctx, wallet, name)
+	if err != nil {
+		return false, err
+	}
+	if nftID != "" {
+		return true, nil
+	}
+	address, err := s.resolveAddress(ctx, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to resolve SUINS name %s: %w", NormalizeName(name), err)
+	}
+	if address == "" {
+		return false, fmt.Errorf("%w: %s", ErrNameNotFound, NormalizeName(name))
+	}
+	log.Printf("Wallet %s does not own SUINS name %s", wallet, NormalizeName(name))
+	return false, nil
+}
+
+// findRegistration returns the object ID of the SUINS registration NFT for name owned by wallet, or "" when
+// wallet owns none. Each configured NFT type is searched in order. Registrations are matched on their
+// domain_name (or name) content field, falling back to the display name.
+func (s *Service) findRegistration(ctx context.Context, wallet, name string) (string, error) {
+	if len(s.suinsNftTypes) == 0 {
+		return "", fmt.Errorf("%w: SUINS_NFT_TYPES is not set", ErrNotConfigured)
+	}
+	name = NormalizeName(name)
+
+	for _, nftType := range s.suinsNftTypes {
+		obj, err := s.findOwnedObject(ctx, wallet, nftType, true, true, func(obj OwnedObject) bool {
+			for _, candidate := range []any{obj.Fields["domain_name"], obj.Fields["name"], obj.Display["name"]} {
+				if str, ok := candidate.(string); ok && str != "" && NormalizeName(str) == name {
+					return true
+				}
+			}
+			return false
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to find SUINS registration for %s: %w", name, err)
+		}
+		if obj != nil {
+			log.Printf("Found SUINS registration %s (%s) for %s owned by %s", obj.ObjectID, nftType, name, wallet)
+			return obj.ObjectID, nil
+		}
+	}
+	return "", nil
+}
+
+// ResolveSuinsToTarget returns the target address name points to on chain; for a Walrus site this is the site
+// object ID. Unregistered names, and names without a target, return ErrNameNotFound.
+func (s *Service) ResolveSuinsToTarget(ctx context.Context, name string) (string, error) {
+	name = NormalizeName(name)
+	target, err := s.resolveAddress(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve SUINS name %s: %w", name, err)
+	}
+	if target == "" {
+		return "", fmt.Errorf("%w: %s", ErrNameNotFound, name)
+	}
+	return target, nil
+}
+
+// resolveAddress returns the address a SUINS name points to, or "" when the name has no record.
+func (s *Service) resolveAddress(ctx context.Context, name string) (string, error) {
+	var address *string
+	if err := s.call(ctx, "suix_resolveNameServiceAddress", []any{NormalizeName(name)}, &address); err != nil {
+		return "", err
+	}
+	if address == nil {
+		return "", nil
+	}
+	return *address, nil
+}
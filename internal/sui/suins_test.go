@@ -0,0 +1,109 @@
+package sui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+const (
+	oldRegistrationType = "0xold::suins_registration::SuinsRegistration"
+	newRegistrationType = "0xnew::suins_registration::SuinsRegistration"
+)
+
+// suinsNode serves owned registrations by type and resolves names in addresses (others resolve to nothing).
+func suinsNode(t *testing.T, owned map[string][]map[string]any, addresses map[string]string) *fakeRPC {
+	return &fakeRPC{handle: func(method string, params []json.RawMessage) (any, *RPCError) {
+		switch method {
+		case "suix_getOwnedObjects":
+			structType, _ := ownedQuery(t, params)
+			return ownedPage("", owned[structType]...), nil
+		case "suix_resolveNameServiceAddress":
+			var name string
+			json.Unmarshal(params[0], &name)
+			if address, ok := addresses[name]; ok {
+				return address, nil
+			}
+			return nil, nil
+		}
+		return nil, &RPCError{Code: -32601, Message: "method not found"}
+	}}
+}
+
+func TestVerifySuinsOwnershipAcrossTypes(t *testing.T) {
+	tests := []struct {
+		name      string
+		owned     map[string][]map[string]any
+		wantOwned bool
+		wantErr   error
+	}{
+		{"match on the second type", map[string][]map[string]any{
+			oldRegistrationType: {registration("0x1", oldRegistrationType, "other.sui")},
+			newRegistrationType: {registration("0x2", newRegistrationType, "mysite.sui")},
+		}, true, nil},
+		{"match on the first type", map[string][]map[string]any{
+			oldRegistrationType: {registration("0x1", oldRegistrationType, "MySite")},
+		}, true, nil},
+		{"no type matches", map[string][]map[string]any{
+			oldRegistrationType: {registration("0x1", oldRegistrationType, "other.sui")},
+			newRegistrationType: {registration("0x2", newRegistrationType, "another.sui")},
+		}, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := suinsNode(t, tt.owned, map[string]string{"mysite.sui": "0xsomeoneelse"})
+			s := newTestService(t, fake, oldRegistrationType, newRegistrationType)
+
+			owned, err := s.VerifySuinsOwnership(context.Background(), "0xwallet", "mysite")
+			if owned != tt.wantOwned || !errors.Is(err, tt.wantErr) {
+				t.Errorf("VerifySuinsOwnership = %v, %v; want %v, %v", owned, err, tt.wantOwned, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifySuinsOwnershipSearchOrder(t *testing.T) {
+	fake := suinsNode(t, map[string][]map[string]any{
+		oldRegistrationType: {registration("0x1", oldRegistrationType, "mysite.sui")},
+	}, nil)
+	s := newTestService(t, fake, oldRegistrationType, newRegistrationType)
+
+	if owned, err := s.VerifySuinsOwnership(context.Background(), "0xwallet", "mysite.sui"); err != nil || !owned {
+		t.Fatalf("VerifySuinsOwnership = %v, %v; want owned", owned, err)
+	}
+	calls := fake.methodCalls("suix_getOwnedObjects")
+	if len(calls) != 1 {
+		t.Errorf("searched %d types, want to stop after the first matched", len(calls))
+	}
+}
+
+func TestVerifySuinsOwnershipUnregisteredName(t *testing.T) {
+	fake := suinsNode(t, nil, nil)
+	s := newTestService(t, fake, oldRegistrationType, newRegistrationType)
+
+	if _, err := s.VerifySuinsOwnership(context.Background(), "0xwallet", "nobody"); !errors.Is(err, ErrNameNotFound) {
+		t.Fatalf("VerifySuinsOwnership error = %v, want %v", err, ErrNameNotFound)
+	}
+	if n := len(fake.methodCalls("suix_getOwnedObjects")); n != 2 {
+		t.Errorf("searched %d types, want both", n)
+	}
+}
+
+func TestVerifySuinsOwnershipNotConfigured(t *testing.T) {
+	s := newTestService(t, suinsNode(t, nil, nil))
+	if _, err := s.VerifySuinsOwnership(context.Background(), "0xwallet", "mysite"); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("VerifySuinsOwnership error = %v, want %v", err, ErrNotConfigured)
+	}
+}
+
+func TestNewServiceValidatesNftTypes(t *testing.T) {
+	if _, err := NewService("http://node", "0xsuins", []string{oldRegistrationType, " ", newRegistrationType}); err != nil {
+		t.Errorf("valid types rejected: %v", err)
+	}
+	for _, bad := range []string{"suins_registration::SuinsRegistration", "0xabc::SuinsRegistration"} {
+		if _, err := NewService("http://node", "0xsuins", []string{bad}); err == nil {
+			t.Errorf("NewService accepted NFT type %q", bad)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package walrus
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxBuildEnvVars limits how many variables one deploy may set.
+	maxBuildEnvVars = 50
+	// maxBuildEnvValueBytes limits each value; build-time settings are URLs and keys, not payloads.
+	maxBuildEnvValueBytes = 4096
+)
+
+// buildEnvKeyPattern admits only variables Vite exposes to client code, so a request can't reach npm, node or
+// the shell (PATH, NODE_OPTIONS, npm_config_*) through the build environment.
+var buildEnvKeyPattern = regexp.MustCompile(`^VITE_[A-Z0-9_]+$`)
+
+// ValidateBuildEnv checks build-time variables from a deploy request: names like VITE_API_URL, single-line
+// values, and sizes within limits.
+func ValidateBuildEnv(env map[string]string) error {
+	if len(env) > maxBuildEnvVars {
+		return fmt.Errorf("at most %d build environment variables are allowed", maxBuildEnvVars)
+	}
+	for key, value := range env {
+		if !buildEnvKeyPattern.MatchString(key) {
+			return fmt.Errorf("build environment variable %q must be named VITE_ followed by uppercase letters, digits or underscores", key)
+		}
+		if len(value) > maxBuildEnvValueBytes {
+			return fmt.Errorf("build environment variable %s is longer than %d bytes", key, maxBuildEnvValueBytes)
+		}
+		if strings.ContainsAny(value, "\x00\r\n") {
+			return fmt.Errorf("build environment variable %s must be a single line", key)
+		}
+	}
+	return nil
+}
+
+// buildCommandEnv is the environment for npm run build: the Deployer's own additions followed by the
+// request's variables, all on top of the server's environment (see ExecRunner).
+func (d *Deployer) buildCommandEnv(buildEnv map[string]string) []string {
+	keys := make([]string, 0, len(buildEnv))
+	for key := range buildEnv {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	env := append([]string(nil), d.env...)
+	for _, key := range keys {
+		env = append(env, key+"="+buildEnv[key])
+	}
+	return env
+}
@@ -2,115 +2,255 @@
 package walrus
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
+
+	"sui_ai_server/internal/types"
 )
 
 type Deployer struct {
 	siteBuilderPath string
 	walrusCLIPath   string
+	sitesConfigPath string        // site-builder's --config; DefaultSitesConfigPath unless set with WithSitesConfig
+	bundleWarnBytes int64         // Published size that adds a manifest warning; 0 disables it
+	npmPath         string        // npm binary for builds; "npm" from PATH unless pinned with WithNodeToolchain
+	nodePath        string        // node binary whose version is checked against the project's requirement
+	env             []string      // Extra environment for every command, e.g. PATH with the pinned node first
+	runner          CommandRunner // Executes npm, walrus and site-builder; ExecRunner unless overridden
+	getWalAttempts  int           // Tries of get-wal per publish; 0 skips it
+	getWalBackoff   time.Duration // Wait before the first get-wal retry, doubled after each failure
 	// Add fields for wallet management / WAL token funding if needed
+
+	jsonMu        sync.Mutex
+	jsonSupported *bool // Whether site-builder publish accepts --json; detected on first publish
 }
 
-func NewDeployer(siteBuilderPath, walrusCLIPath string) *Deployer {
-	return &Deployer{
+// Option configures optional Deployer settings.
+type Option func(*Deployer)
+
+// WithCommandRunner replaces how external commands are executed, e.g. with a fake in tests.
+func WithCommandRunner(r CommandRunner) Option {
+	return func(d *Deployer) {
+		d.runner = r
+	}
+}
+
+func NewDeployer(siteBuilderPath, walrusCLIPath string, opts ...Option) *Deployer {
+	d := &Deployer{
 		siteBuilderPath: siteBuilderPath,
 		walrusCLIPath:   walrusCLIPath,
+		sitesConfigPath: DefaultSitesConfigPath,
+		bundleWarnBytes: DefaultBundleWarnBytes,
+		npmPath:         "npm",
+		nodePath:        "node",
+		runner:          ExecRunner{},
+		getWalAttempts:  DefaultGetWalAttempts,
+		getWalBackoff:   DefaultGetWalBackoff,
+	}
+	for _, opt := range opts {
+		opt(d)
 	}
+	return d
 }
 
-// DeployFiles takes a map of filename->content, saves them, runs npm install, npm build, site-builder, and walrus publish.
-func (d *Deployer) DeployFiles(ctx context.Context) (string, error) {
-	// 1. Create a temporary directory for the project files
-	tempDir := "tmp"
+// ProgressFunc receives each line of output from a deploy stage as it is produced.
+// stream is "stdout" or "stderr". It may be called concurrently from the stdout and stderr copiers.
+type ProgressFunc func(stage, stream, line string)
 
-	// 3. Run npm install
-	npmInstallCmd := exec.CommandContext(ctx, "npm", "install")
-	npmInstallCmd.Dir = tempDir // Set working directory to our temp folder
-	var npmInstallStdErr bytes.Buffer
-	npmInstallCmd.Stderr = &npmInstallStdErr
+// DeployOptions tunes a single deploy. The zero value builds an npm project quietly.
+type DeployOptions struct {
+	Static     bool              // Publish the site files in projectDir (see stageStatic), skipping npm install/build
+	CleanBuild bool              // Remove node_modules before installing, for reproducible builds
+	BasePath   string            // Vite base for sub-path hosting, e.g. "/sites/demo/"; empty keeps the config's own
+	BuildEnv   map[string]string // VITE_* variables for npm run build (see ValidateBuildEnv)
+	Progress   ProgressFunc      // Optional; receives every output line as it is produced
+	// Run npm with --ignore-scripts, for source the server didn't generate: dependencies' install scripts and
+	// the project's own lifecycle hooks (preinstall, postinstall, prebuild, ...) are skipped
+	IgnoreScripts bool
+}
 
-	log.Printf("Running npm install in %s", tempDir)
-	if err := npmInstallCmd.Run(); err != nil {
-		log.Printf("npm install stderr: %s", npmInstallStdErr.String())
-		return "", fmt.Errorf("npm install failed: %w (stderr: %s)", err, npmInstallStdErr.String())
+// DeployFiles builds the project in projectDir (npm install, npm build) and publishes dist with site-builder,
+// returning the publish result and a manifest of the published files. Static projects skip the build and
+// publish a copy of their site files. The tools (see CheckTools) and site-builder config are checked first, so a
+// missing binary or broken config fails the deploy before the build rather than after it.
+func (d *Deployer) DeployFiles(ctx context.Context, projectDir string, opts DeployOptions) (*PublishResult, *types.BuildManifest, error) {
+	if err := d.CheckTools(); err != nil {
+		return nil, nil, err
+	}
+	if err := d.CheckConfig(); err != nil {
+		return nil, nil, err
+	}
+	publishDir, err := d.Build(ctx, projectDir, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	manifest := d.manifestOrNil(publishDir, opts.Progress)
+	result, err := d.publish(ctx, publishDir, opts.Progress)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, manifest, nil
+}
+
+// Deploy is DeployFiles reporting a backend-neutral result.
+func (d *Deployer) Deploy(ctx context.Context, projectDir string, opts DeployOptions) (*types.DeployResult, error) {
+	result, manifest, err := d.DeployFiles(ctx, projectDir, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &types.DeployResult{
+		Backend:       types.DeployBackendWalrus,
+		SiteID:        result.SiteObjectID,
+		SiteObjectID:  result.SiteObjectID,
+		BlobIDs:       result.BlobIDs,
+		ResourceCount: result.ResourceCount,
+		Artifacts:     manifest,
+	}, nil
+}
+
+// Build prepares projectDir for publishing and returns the directory to publish, projectDir's dist: the output
+// of an npm build, or for static projects a copy of the site's files (see stageStatic). Other deploy backends
+// reuse it for the build step.
+func (d *Deployer) Build(ctx context.Context, projectDir string, opts DeployOptions) (string, error) {
+	if opts.Static {
+		if _, err := os.Stat(filepath.Join(projectDir, "index.html")); err != nil {
+			return "", fmt.Errorf("static project has no index.html in %s: %w", projectDir, err)
+		}
+		log.Printf("Static project in %s, skipping npm install/build.", projectDir)
+		return stageStatic(projectDir)
+	}
+	if opts.BasePath != "" {
+		if err := patchViteBase(projectDir, opts.BasePath); err != nil {
+			return "", fmt.Errorf("failed to set Vite base path: %w", err)
+		}
+		log.Printf("Set Vite base path to %s in %s", opts.BasePath, projectDir)
+	}
+	if err := ValidateBuildEnv(opts.BuildEnv); err != nil {
+		return "", err
+	}
+	return d.buildProject(ctx, projectDir, opts)
+}
+
+// buildProject runs npm install and npm run build in projectDir and returns the dist directory.
+// Any dist left by a previous run is removed first, so a build that produces nothing can't pass on stale output.
+func (d *Deployer) buildProject(ctx context.Context, projectDir string, opts DeployOptions) (string, error) {
+	progress := opts.Progress
+	distDir := filepath.Join(projectDir, "dist")
+	if err := os.RemoveAll(distDir); err != nil {
+		return "", fmt.Errorf("failed to remove stale dist directory %s: %w", distDir, err)
+	}
+	if opts.CleanBuild {
+		log.Printf("Clean build requested, removing node_modules in %s", projectDir)
+		if err := os.RemoveAll(filepath.Join(projectDir, "node_modules")); err != nil {
+			return "", fmt.Errorf("failed to remove node_modules in %s: %w", projectDir, err)
+		}
 	}
-	log.Println("npm install completed successfully.")
 
-	// 4. Run npm run build
-	npmBuildCmd := exec.CommandContext(ctx, "npm", "run", "build")
-	npmBuildCmd.Dir = tempDir // Set working directory to our temp folder
-	var npmBuildStdErr bytes.Buffer
-	npmBuildCmd.Stderr = &npmBuildStdErr
+	if err := d.checkNodeVersion(ctx, projectDir); err != nil {
+		return "", err
+	}
 
-	log.Printf("Running npm run build in %s", tempDir)
-	if err := npmBuildCmd.Run(); err != nil {
-		log.Printf("npm run build stderr: %s", npmBuildStdErr.String())
-		return "", fmt.Errorf("npm run build failed: %w (stderr: %s)", err, npmBuildStdErr.String())
+	// 1. Run npm install in the project folder
+	log.Printf("Running npm install in %s", projectDir)
+	if err := d.install(ctx, projectDir, progress, opts.IgnoreScripts); err != nil {
+		return "", err
+	}
+	log.Println("npm install completed successfully.")
+
+	// 2. Run npm run build in the project folder
+	log.Printf("Running npm run build in %s", projectDir)
+	buildEnv := d.buildCommandEnv(opts.BuildEnv)
+	buildArgs := []string{"run", "build"}
+	if opts.IgnoreScripts {
+		buildArgs = append(buildArgs, "--ignore-scripts")
+	}
+	if _, stderr, err := d.runStageEnv(ctx, projectDir, buildEnv, "npm run build", progress, d.npmPath, buildArgs...); err != nil {
+		log.Printf("npm run build stderr: %s", stderr)
+		return "", fmt.Errorf("npm run build failed: %w (stderr: %s)", err, stderr)
 	}
 	log.Println("npm run build completed successfully.")
 
-	// 5. The build output should now be in tempDir/dist
-	distDir := filepath.Join(tempDir, "dist")
-	if _, err := os.Stat(distDir); os.IsNotExist(err) {
-		return "", fmt.Errorf("build process did not create expected dist directory at %s", distDir)
-	}
-
-	// 8. Get Wal token
-	getWal := exec.CommandContext(ctx, d.walrusCLIPath, "get-wal")
-	var publishStdOut, publishStdErr bytes.Buffer
-	getWal.Stdout = &publishStdOut
-	getWal.Stderr = &publishStdErr
-
-	// 6. Run site-builder with the dist directory as input
-	// builderCmd := exec.CommandContext(ctx, d.siteBuilderPath, distDir) // Use dist directory as input
-	sitesConfigPath := "sites-config.yaml"
-	builderCmd := exec.CommandContext(
-		ctx,
-		d.siteBuilderPath,
-		"--config",
-		sitesConfigPath,
-		"publish",
-		distDir,
-		"--epochs",
-		"2",
-	)
-	var builderStdOut, builderStdErr bytes.Buffer
-	builderCmd.Stderr = &builderStdErr
-	builderCmd.Stdout = &builderStdOut
-
-	log.Printf("Running site-builder with tmp/dist folder: %s", builderCmd.String())
-	if err := builderCmd.Run(); err != nil {
-		log.Printf("site-builder stderr: %s", builderStdErr.String())
-		return "", fmt.Errorf("site-builder failed: %w (stderr: %s)", err, builderStdErr.String())
+	// 3. The build output should now be in projectDir/dist, with an entry point
+	if _, err := os.Stat(filepath.Join(distDir, "index.html")); err != nil {
+		return "", fmt.Errorf("build process did not produce %s: %w", filepath.Join(distDir, "index.html"), err)
 	}
-	log.Println("site-builder completed successfully.")
+	return distDir, nil
+}
 
-	println("site-builder stdout: ", builderStdOut.String())
+// publish funds the wallet and publishes publishDir with site-builder, returning the parsed publish result.
+// A wallet that can't be funded (see getWal) aborts before the publish.
+func (d *Deployer) publish(ctx context.Context, publishDir string, progress ProgressFunc) (*PublishResult, error) {
+	// 4. Get Wal token
+	if err := d.getWal(ctx, progress); err != nil {
+		log.Printf("Aborting publish of %s: %v", publishDir, err)
+		return nil, err
+	}
 
-	// Extract the site object ID from the output
-	builderOutput := builderStdOut.String()
-	log.Printf("site-builder stdout: %s", builderOutput)
-	siteObjectID := extractSiteObjectID(builderOutput)
-	if siteObjectID == "" {
-		return "", fmt.Errorf("failed to extract site object ID from site-builder output")
+	// 5. Run site-builder with the publish directory as input
+	builderArgs := []string{"--config", d.sitesConfigPath, "publish", publishDir, "--epochs", "2"}
+	if d.supportsJSON(ctx) {
+		builderArgs = append(builderArgs, "--json") // Structured output is more reliable than scraping text
 	}
 
-	log.Printf("Site object ID: %s", siteObjectID)
+	log.Printf("Running site-builder with %s folder: %s %s", publishDir, d.siteBuilderPath, strings.Join(builderArgs, " "))
+	builderOutput, builderStdErr, err := d.runStage(ctx, "", "site-builder", progress, d.siteBuilderPath, builderArgs...)
+	if err != nil {
+		log.Printf("site-builder stderr: %s", builderStdErr)
+		return nil, fmt.Errorf("site-builder failed: %w (stderr: %s)", err, builderStdErr)
+	}
 	log.Println("site-builder completed successfully.")
 
+	// Extract the site object ID (and, from JSON output, blob IDs and resource counts)
+	log.Printf("site-builder stdout: %s", builderOutput)
+	result := parsePublishOutput(builderOutput)
+	if result.SiteObjectID == "" {
+		return nil, fmt.Errorf("failed to extract site object ID from site-builder output")
+	}
+
+	log.Printf("Site object ID: %s (%d resources, %d blobs)", result.SiteObjectID, result.ResourceCount, len(result.BlobIDs))
+
 	// Since we now want to return the site object ID instead of a CID,
 	// we'll skip the walrus publish step and return the site object ID directly
-	return siteObjectID, nil
+	return result, nil
+}
+
+// supportsJSON reports whether this site-builder's publish command accepts --json, checking its help once.
+func (d *Deployer) supportsJSON(ctx context.Context) bool {
+	d.jsonMu.Lock()
+	defer d.jsonMu.Unlock()
+	if d.jsonSupported == nil {
+		stdout, stderr, err := d.runner.Run(ctx, RunOptions{}, d.siteBuilderPath, "publish", "--help")
+		supported := err == nil && strings.Contains(stdout+stderr, "--json")
+		if ctx.Err() != nil {
+			return false // Don't cache an answer from an aborted check
+		}
+		log.Printf("site-builder JSON output supported: %v", supported)
+		d.jsonSupported = &supported
+	}
+	return *d.jsonSupported
+}
+
+// runStage runs one deploy stage through the Deployer's runner, reporting output lines to progress if set.
+func (d *Deployer) runStage(ctx context.Context, dir, stage string, progress ProgressFunc, name string, args ...string) (stdout, stderr string, err error) {
+	return d.runStageEnv(ctx, dir, d.env, stage, progress, name, args...)
+}
+
+// runStageEnv is runStage with env (on top of the server's environment) instead of the Deployer's own.
+func (d *Deployer) runStageEnv(ctx context.Context, dir string, env []string, stage string, progress ProgressFunc, name string, args ...string) (stdout, stderr string, err error) {
+	opts := RunOptions{Dir: dir, Env: env}
+	if progress != nil {
+		opts.OnLine = func(stream, line string) { progress(stage, stream, line) }
+	}
+	return d.runner.Run(ctx, opts, name, args...)
 }
 
-// extractSiteObjectID parses the output of site-builder to find the site object ID.
+// extractSiteObjectID parses the text output of older site-builder versions to find the site object ID.
 func extractSiteObjectID(output string) string {
 	// Looking for the line with "New site object ID: 0x..."
 	lines := strings.Split(output, "\n")
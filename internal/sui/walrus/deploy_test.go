@@ -0,0 +1,233 @@
+package walrus
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testDeployer returns a Deployer whose site-builder and walrus binaries are executable stubs in a temp dir,
+// with a valid sites config, running commands through runner. get-wal retries don't wait.
+func testDeployer(t *testing.T, runner CommandRunner) *Deployer {
+	t.Helper()
+	dir := t.TempDir()
+	siteBuilder := filepath.Join(dir, "site-builder")
+	walrusCLI := filepath.Join(dir, "walrus")
+	for _, path := range []string{siteBuilder, walrusCLI} {
+		if err := os.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := filepath.Join(dir, "sites-config.yaml")
+	if err := os.WriteFile(config, []byte("package: \"0x1\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return NewDeployer(siteBuilder, walrusCLI, WithCommandRunner(runner), WithSitesConfig(config),
+		WithGetWalRetry(DefaultGetWalAttempts, 1))
+}
+
+// writeProject creates a project directory containing files (name to content).
+func writeProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// npmProjectRunner answers like a working toolchain: the build writes dist/index.html, and site-builder prints
+// the text output of older versions (publishOutput).
+func npmProjectRunner(publishOutput string) func(c call) fakeResult {
+	return func(c call) fakeResult {
+		switch {
+		case strings.HasPrefix(c.String(), "npm run build"):
+			if err := os.MkdirAll(filepath.Join(c.Dir, "dist"), os.ModePerm); err != nil {
+				return fakeResult{err: err}
+			}
+			return fakeResult{err: os.WriteFile(filepath.Join(c.Dir, "dist", "index.html"), []byte("<html></html>"), 0644)}
+		case strings.HasSuffix(c.Name, "site-builder") && slices.Contains(c.Args, "--help"):
+			return fakeResult{stdout: "Usage: site-builder publish [OPTIONS] <DIRECTORY>"}
+		case strings.HasSuffix(c.Name, "site-builder"):
+			return fakeResult{stdout: publishOutput}
+		}
+		return fakeResult{}
+	}
+}
+
+func TestDeployFilesCommandSequence(t *testing.T) {
+	runner := &fakeRunner{respond: npmProjectRunner("Publishing...\nNew site object ID: 0xsite\n")}
+	d := testDeployer(t, runner)
+	projectDir := writeProject(t, map[string]string{"package.json": `{"name":"demo"}`, "index.html": "<div></div>"})
+
+	result, _, err := d.DeployFiles(context.Background(), projectDir, DeployOptions{})
+	if err != nil {
+		t.Fatalf("DeployFiles: %v", err)
+	}
+	if result.SiteObjectID != "0xsite" {
+		t.Errorf("SiteObjectID = %q, want 0xsite", result.SiteObjectID)
+	}
+
+	distDir := filepath.Join(projectDir, "dist")
+	want := []string{
+		"npm install",
+		"npm run build",
+		d.walrusCLIPath + " get-wal",
+		d.siteBuilderPath + " publish --help",
+		d.siteBuilderPath + " --config " + d.sitesConfigPath + " publish " + distDir + " --epochs 2",
+	}
+	if got := runner.commands(); !slices.Equal(got, want) {
+		t.Errorf("commands:\n got %q\nwant %q", got, want)
+	}
+	for _, c := range runner.calls[:2] {
+		if c.Dir != projectDir {
+			t.Errorf("%s ran in %q, want the project directory %q", c, c.Dir, projectDir)
+		}
+	}
+}
+
+func TestDeployFilesJSONOutput(t *testing.T) {
+	runner := &fakeRunner{respond: func(c call) fakeResult {
+		if slices.Contains(c.Args, "--help") {
+			return fakeResult{stdout: "Options:\n  --json  Print the result as JSON"}
+		}
+		if strings.HasSuffix(c.Name, "site-builder") {
+			return fakeResult{stdout: "log line\n" + `{"siteObjectId":"0xjson","resources":[{"blobId":"b1"},{"blob_id":"b2"}]}` + "\n"}
+		}
+		return fakeResult{}
+	}}
+	d := testDeployer(t, runner)
+	projectDir := writeProject(t, map[string]string{"index.html": "<h1>Hi</h1>"})
+
+	result, _, err := d.DeployFiles(context.Background(), projectDir, DeployOptions{Static: true})
+	if err != nil {
+		t.Fatalf("DeployFiles: %v", err)
+	}
+	if result.SiteObjectID != "0xjson" || !slices.Equal(result.BlobIDs, []string{"b1", "b2"}) || result.ResourceCount != 2 {
+		t.Errorf("result = %+v, want site 0xjson with blobs b1, b2", result)
+	}
+	publish := runner.commands()[len(runner.calls)-1]
+	if !strings.HasSuffix(publish, " --json") {
+		t.Errorf("publish command %q lacks --json although site-builder supports it", publish)
+	}
+	for _, cmd := range runner.commands() {
+		if strings.HasPrefix(cmd, "npm ") {
+			t.Errorf("static deploy ran %q", cmd)
+		}
+	}
+}
+
+func TestDeployFilesBuildFailureAborts(t *testing.T) {
+	buildErr := errors.New("exit status 1")
+	runner := &fakeRunner{respond: func(c call) fakeResult {
+		if c.String() == "npm run build" {
+			return fakeResult{stderr: "vite: syntax error in App.tsx", err: buildErr}
+		}
+		return fakeResult{}
+	}}
+	d := testDeployer(t, runner)
+	projectDir := writeProject(t, map[string]string{"package.json": `{"name":"demo"}`})
+
+	_, _, err := d.DeployFiles(context.Background(), projectDir, DeployOptions{})
+	if !errors.Is(err, buildErr) {
+		t.Fatalf("DeployFiles error = %v, want the build's error", err)
+	}
+	if !strings.Contains(err.Error(), "syntax error in App.tsx") {
+		t.Errorf("error %q does not include the build's stderr", err)
+	}
+	want := []string{"npm install", "npm run build"}
+	if got := runner.commands(); !slices.Equal(got, want) {
+		t.Errorf("commands = %q, want only %q (nothing after the failed build)", got, want)
+	}
+}
+
+func TestDeployFilesMissingSiteObjectID(t *testing.T) {
+	runner := &fakeRunner{respond: npmProjectRunner("Publishing...\nDone.\n")}
+	d := testDeployer(t, runner)
+	projectDir := writeProject(t, map[string]string{"package.json": `{"name":"demo"}`})
+
+	if _, _, err := d.DeployFiles(context.Background(), projectDir, DeployOptions{}); err == nil {
+		t.Fatal("DeployFiles succeeded without a site object ID in the output")
+	}
+}
+
+func TestExtractSiteObjectID(t *testing.T) {
+	tests := []struct {
+		name, output, want string
+	}{
+		{"text output", "Uploading 3 resources\nNew site object ID: 0xabc123\nBrowse at ...", "0xabc123"},
+		{"indented", "  New site object ID: 0xdef  \n", "0xdef"},
+		{"missing", "Publishing failed\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractSiteObjectID(tt.output); got != tt.want {
+				t.Errorf("extractSiteObjectID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStaticDeployPublishesOnlySiteFiles(t *testing.T) {
+	runner := &fakeRunner{respond: npmProjectRunner("New site object ID: 0xsite\n")}
+	d := testDeployer(t, runner)
+	projectDir := writeProject(t, map[string]string{
+		"index.html":              "<h1>Hi</h1>",
+		"css/style.css":           "h1 {}",
+		"README.md":               "# Demo",
+		".rag/index.json":         `{"prompt":"secret prompt"}`,
+		".env":                    "API_KEY=x",
+		".gitignore":              "node_modules",
+		"node_modules/x/index.js": "",
+		"dist/old.html":           "stale",
+	})
+
+	if _, _, err := d.DeployFiles(context.Background(), projectDir, DeployOptions{Static: true}); err != nil {
+		t.Fatalf("DeployFiles: %v", err)
+	}
+	distDir := filepath.Join(projectDir, "dist")
+	publish := runner.commands()[len(runner.calls)-1]
+	if !strings.Contains(publish, " publish "+distDir+" ") {
+		t.Fatalf("publish command %q does not publish the staging directory %s", publish, distDir)
+	}
+
+	var published []string
+	filepath.WalkDir(distDir, func(path string, entry os.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			rel, _ := filepath.Rel(distDir, path)
+			published = append(published, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	slices.Sort(published)
+	if want := []string{"README.md", "css/style.css", "index.html"}; !slices.Equal(published, want) {
+		t.Errorf("published %q, want %q", published, want)
+	}
+	if _, err := os.Stat(filepath.Join(projectDir, ".rag", "index.json")); err != nil {
+		t.Errorf("source project lost its RAG index: %v", err)
+	}
+}
+
+func TestDeployFilesIgnoreScripts(t *testing.T) {
+	runner := &fakeRunner{respond: npmProjectRunner("Publishing...\nNew site object ID: 0xsite\n")}
+	d := testDeployer(t, runner)
+	projectDir := writeProject(t, map[string]string{"package.json": `{"name":"demo"}`, "index.html": "<div></div>"})
+
+	if _, _, err := d.DeployFiles(context.Background(), projectDir, DeployOptions{IgnoreScripts: true}); err != nil {
+		t.Fatalf("DeployFiles: %v", err)
+	}
+	want := []string{"npm install --ignore-scripts", "npm run build --ignore-scripts"}
+	if got := runner.commands(); !slices.Equal(got[:2], want) {
+		t.Errorf("npm commands = %q, want %q", got[:2], want)
+	}
+}
@@ -0,0 +1,64 @@
+package walrus
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// corruptInstallSignatures are npm install errors that usually mean node_modules (or the lockfile) was left
+// half-written by an interrupted install, rather than a problem with the project's dependencies.
+var corruptInstallSignatures = []string{
+	"ENOTEMPTY",                    // Renaming over a directory a previous install left behind
+	"EINTEGRITY",                   // Checksum mismatch with the lockfile
+	"TAR_BAD_ARCHIVE",              // Truncated package tarball
+	"Unexpected end of JSON input", // Truncated package.json or lockfile
+	"reading 'edgesOut'",           // npm's dependency tree is inconsistent with node_modules
+}
+
+// looksCorrupt reports whether npm install's stderr matches one of corruptInstallSignatures.
+func looksCorrupt(stderr string) bool {
+	for _, sig := range corruptInstallSignatures {
+		if strings.Contains(stderr, sig) {
+			return true
+		}
+	}
+	return false
+}
+
+// install runs npm install in projectDir, with --ignore-scripts when ignoreScripts is set. If it fails in a way
+// that points at a corrupt node_modules, both node_modules and package-lock.json are removed and a clean
+// install is tried once before giving up.
+func (d *Deployer) install(ctx context.Context, projectDir string, progress ProgressFunc, ignoreScripts bool) error {
+	args := []string{"install"}
+	if ignoreScripts {
+		args = append(args, "--ignore-scripts")
+	}
+	_, stderr, err := d.runStage(ctx, projectDir, "npm install", progress, d.npmPath, args...)
+	if err == nil {
+		return nil
+	}
+	log.Printf("npm install stderr: %s", stderr)
+	if !looksCorrupt(stderr) || ctx.Err() != nil {
+		return fmt.Errorf("npm install failed: %w (stderr: %s)", err, stderr)
+	}
+
+	log.Printf("npm install in %s failed with signs of a corrupt node_modules; removing it and the lockfile and retrying a clean install", projectDir)
+	if progress != nil {
+		progress("npm install", "stderr", "Install looks corrupt; retrying from a clean node_modules")
+	}
+	for _, name := range []string{"node_modules", "package-lock.json"} {
+		if rmErr := os.RemoveAll(filepath.Join(projectDir, name)); rmErr != nil {
+			return fmt.Errorf("npm install failed: %w; removing %s for a clean retry also failed: %v", err, name, rmErr)
+		}
+	}
+	if _, stderr, err := d.runStage(ctx, projectDir, "npm install", progress, d.npmPath, args...); err != nil {
+		log.Printf("Recovery npm install stderr: %s", stderr)
+		return fmt.Errorf("npm install failed, also after a clean retry: %w (stderr: %s)", err, stderr)
+	}
+	log.Printf("Recovery npm install in %s succeeded", projectDir)
+	return nil
+}
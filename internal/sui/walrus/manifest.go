@@ -0,0 +1,71 @@
+package walrus
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"path/filepath"
+
+	"sui_ai_server/internal/types"
+)
+
+// DefaultBundleWarnBytes is the published size above which a deploy's manifest carries a warning, unless
+// WithBundleWarnBytes says otherwise. Walrus storage is paid per byte, so large bundles are worth a look.
+const DefaultBundleWarnBytes = 5 << 20
+
+// WithBundleWarnBytes sets the published size that triggers a manifest warning. 0 disables the warning;
+// negative values are ignored.
+func WithBundleWarnBytes(n int64) Option {
+	return func(d *Deployer) {
+		if n >= 0 {
+			d.bundleWarnBytes = n
+		}
+	}
+}
+
+// Manifest lists every file under publishDir (the output of Build) with its size, and warns when the total
+// is over the bundle warning threshold.
+func (d *Deployer) Manifest(publishDir string) (*types.BuildManifest, error) {
+	manifest := &types.BuildManifest{Assets: []types.BuildAsset{}}
+	err := filepath.WalkDir(publishDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(publishDir, path)
+		if err != nil {
+			return err
+		}
+		manifest.Assets = append(manifest.Assets, types.BuildAsset{Path: filepath.ToSlash(rel), Bytes: info.Size()})
+		manifest.TotalBytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list build output in %s: %w", publishDir, err)
+	}
+	if d.bundleWarnBytes > 0 && manifest.TotalBytes > d.bundleWarnBytes {
+		manifest.Warnings = append(manifest.Warnings, fmt.Sprintf(
+			"Bundle is %d bytes, over the %d byte warning threshold; storage cost grows with size", manifest.TotalBytes, d.bundleWarnBytes))
+	}
+	return manifest, nil
+}
+
+// manifestOrNil is Manifest for deploys, where a listing failure is logged rather than failing the deploy.
+func (d *Deployer) manifestOrNil(publishDir string, progress ProgressFunc) *types.BuildManifest {
+	manifest, err := d.Manifest(publishDir)
+	if err != nil {
+		log.Printf("WARN: %v", err)
+		return nil
+	}
+	log.Printf("Build output in %s: %d files, %d bytes", publishDir, len(manifest.Assets), manifest.TotalBytes)
+	if progress != nil {
+		progress("manifest", "stdout", fmt.Sprintf("%d files, %d bytes", len(manifest.Assets), manifest.TotalBytes))
+		for _, warning := range manifest.Warnings {
+			progress("manifest", "stderr", warning)
+		}
+	}
+	return manifest
+}
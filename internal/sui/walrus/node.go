@@ -0,0 +1,195 @@
+package walrus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// WithNodeToolchain pins the npm and node binaries used for builds instead of whatever is on PATH. Either may be
+// empty to keep the default. A pinned node is also put first on the build's PATH, so npm and the scripts it runs
+// (which start node through "#!/usr/bin/env node") use it too.
+func WithNodeToolchain(npmPath, nodePath string) Option {
+	return func(d *Deployer) {
+		if npmPath != "" {
+			d.npmPath = npmPath
+		}
+		if nodePath != "" {
+			d.nodePath = nodePath
+			d.env = []string{"PATH=" + filepath.Dir(nodePath) + string(os.PathListSeparator) + os.Getenv("PATH")}
+		}
+	}
+}
+
+// checkNodeVersion fails early when the project asks for a node version (.nvmrc, or "engines.node" in
+// package.json) that the build's node doesn't satisfy, instead of letting npm fail obscurely later. Requirements
+// it can't interpret, such as nvm aliases, are logged and ignored.
+func (d *Deployer) checkNodeVersion(ctx context.Context, projectDir string) error {
+	wanted, source := nodeRequirement(projectDir)
+	if wanted == "" {
+		return nil
+	}
+	stdout, stderr, err := d.runner.Run(ctx, RunOptions{Env: d.env}, d.nodePath, "--version")
+	if err != nil {
+		log.Printf("WARN: Could not determine node version to check %s %q: %v (stderr: %s)", source, wanted, err, stderr)
+		return nil
+	}
+	have, ok := parseVersion(strings.TrimSpace(stdout))
+	if !ok {
+		log.Printf("WARN: Unrecognized node version %q, skipping %s check", strings.TrimSpace(stdout), source)
+		return nil
+	}
+	satisfied, err := satisfiesRange(have, wanted)
+	if err != nil {
+		log.Printf("WARN: Ignoring %s %q: %v", source, wanted, err)
+		return nil
+	}
+	if !satisfied {
+		return fmt.Errorf("project requires node %s (from %s) but the build uses %s; set NODE_BIN_PATH to a matching node",
+			wanted, source, strings.TrimSpace(stdout))
+	}
+	return nil
+}
+
+// nodeRequirement returns the node version range the project asks for and where it came from. .nvmrc wins over
+// package.json; a bare version there ("20" or "20.11") means any release with that prefix.
+func nodeRequirement(projectDir string) (wanted, source string) {
+	if data, err := os.ReadFile(filepath.Join(projectDir, ".nvmrc")); err == nil {
+		if v := strings.TrimSpace(string(data)); v != "" {
+			if _, ok := parseVersion(v); ok {
+				return strings.TrimPrefix(v, "v"), ".nvmrc"
+			}
+			log.Printf("Ignoring .nvmrc alias %q in %s", v, projectDir)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(projectDir, "package.json"))
+	if err != nil {
+		return "", ""
+	}
+	var pkg struct {
+		Engines struct {
+			Node string `json:"node"`
+		} `json:"engines"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return "", ""
+	}
+	return strings.TrimSpace(pkg.Engines.Node), "package.json engines.node"
+}
+
+// version is a node release; parts not given in a partial version are -1.
+type version [3]int
+
+// parseVersion parses "v20.11.1", "20.11" or "20", ignoring any prerelease suffix.
+func parseVersion(s string) (version, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	s, _, _ = strings.Cut(s, "-")
+	v := version{-1, -1, -1}
+	parts := strings.Split(s, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return v, false
+	}
+	for i, p := range parts {
+		if p == "x" || p == "X" || p == "*" {
+			break
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		v[i] = n
+	}
+	return v, v[0] >= 0
+}
+
+func (v version) compare(o version) int {
+	for i := range v {
+		a, b := max(v[i], 0), max(o[i], 0)
+		if a != b {
+			if a < b {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// satisfiesRange reports whether have is within an npm-style range: "||"-separated alternatives of
+// space-separated comparators (>=, >, <=, <, =, ^, ~, x-ranges such as "18.x", or "*").
+func satisfiesRange(have version, rng string) (bool, error) {
+	for _, alt := range strings.Split(rng, "||") {
+		ok := true
+		op := ""
+		for _, comp := range strings.Fields(alt) {
+			if strings.Trim(comp, "<>=^~") == "" {
+				op += comp // ">= 18": the operator is its own field
+				continue
+			}
+			comp, op = op+comp, ""
+			match, err := satisfiesComparator(have, comp)
+			if err != nil {
+				return false, err
+			}
+			ok = ok && match
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func satisfiesComparator(have version, comp string) (bool, error) {
+	if comp == "*" || comp == "x" || comp == "" {
+		return true, nil
+	}
+	op := strings.TrimRight(comp[:min(2, len(comp))], "0123456789vxX*.")
+	v, ok := parseVersion(comp[len(op):])
+	if !ok {
+		return false, fmt.Errorf("unsupported version %q", comp)
+	}
+	c := have.compare(v)
+	switch op {
+	case ">=":
+		return c >= 0, nil
+	case ">":
+		return c > 0 && !prefixMatch(have, v), nil
+	case "<=":
+		return c <= 0 || prefixMatch(have, v), nil
+	case "<":
+		return c < 0, nil
+	case "", "=":
+		return prefixMatch(have, v), nil
+	case "^":
+		// Compatible with v: same major (or same minor for 0.x)
+		upper := version{v[0] + 1, 0, 0}
+		if v[0] == 0 && v[1] >= 0 {
+			upper = version{0, v[1] + 1, 0}
+		}
+		return c >= 0 && have.compare(upper) < 0, nil
+	case "~":
+		// Same minor when one is given, otherwise same major
+		if v[1] < 0 {
+			return have[0] == v[0], nil
+		}
+		return c >= 0 && have[0] == v[0] && have[1] == v[1], nil
+	}
+	return false, errors.New("unsupported comparator " + strconv.Quote(comp))
+}
+
+// prefixMatch reports whether have matches v in every part v specifies, so "20" matches 20.11.1.
+func prefixMatch(have, v version) bool {
+	for i := range v {
+		if v[i] >= 0 && have[i] != v[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package walrus
+
+import "os/exec"
+
+// killWithProcessGroup leaves cmd as is where process groups aren't available; cancelling kills only the
+// command itself.
+func killWithProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package walrus
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killWithProcessGroup starts cmd in its own process group and makes cancelling its context kill the whole
+// group, so the node processes npm and the build scripts spawn die with it instead of running on orphaned.
+func killWithProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,83 @@
+package walrus
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// PublishResult is what a site-builder publish produced.
+type PublishResult struct {
+	SiteObjectID  string   `json:"siteObjectId"`
+	BlobIDs       []string `json:"blobIds,omitempty"`       // Only known from JSON output
+	ResourceCount int      `json:"resourceCount,omitempty"` // Only known from JSON output
+}
+
+// publishJSON accepts the field spellings used across site-builder versions.
+type publishJSON struct {
+	SiteObjectID      string   `json:"siteObjectId"`
+	SiteObjectIDSnake string   `json:"site_object_id"`
+	ObjectID          string   `json:"objectId"`
+	BlobIDs           []string `json:"blobIds"`
+	BlobIDsSnake      []string `json:"blob_ids"`
+	Resources         []struct {
+		BlobID      string `json:"blobId"`
+		BlobIDSnake string `json:"blob_id"`
+	} `json:"resources"`
+	ResourceCount int `json:"resourceCount"`
+}
+
+// parsePublishOutput reads site-builder's JSON result if stdout contains one, falling back to scanning
+// the human-readable text of older versions.
+func parsePublishOutput(output string) *PublishResult {
+	if result, ok := parsePublishJSON(output); ok {
+		return result
+	}
+	return &PublishResult{SiteObjectID: extractSiteObjectID(output)}
+}
+
+// parsePublishJSON looks for a JSON object in stdout: either the whole output, or the last line that is one
+// (log lines may precede it).
+func parsePublishJSON(output string) (*PublishResult, bool) {
+	candidates := []string{strings.TrimSpace(output)}
+	lines := strings.Split(output, "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); strings.HasPrefix(line, "{") {
+			candidates = append(candidates, line)
+		}
+	}
+
+	for _, candidate := range candidates {
+		if !strings.HasPrefix(candidate, "{") {
+			continue
+		}
+		var raw publishJSON
+		if err := json.Unmarshal([]byte(candidate), &raw); err != nil {
+			continue
+		}
+		result := &PublishResult{SiteObjectID: firstNonEmpty(raw.SiteObjectID, raw.SiteObjectIDSnake, raw.ObjectID)}
+		if result.SiteObjectID == "" {
+			continue
+		}
+		result.BlobIDs = append(raw.BlobIDs, raw.BlobIDsSnake...)
+		for _, res := range raw.Resources {
+			if id := firstNonEmpty(res.BlobID, res.BlobIDSnake); id != "" {
+				result.BlobIDs = append(result.BlobIDs, id)
+			}
+		}
+		result.ResourceCount = raw.ResourceCount
+		if result.ResourceCount == 0 {
+			result.ResourceCount = len(raw.Resources)
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
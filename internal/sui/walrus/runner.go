@@ -0,0 +1,88 @@
+package walrus
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// RunOptions tunes how a single command is run.
+type RunOptions struct {
+	Dir    string                    // Working directory; empty uses the server's
+	Env    []string                  // "KEY=value" entries overriding the server's environment
+	OnLine func(stream, line string) // Optional; receives each output line ("stdout" or "stderr") as it is produced
+}
+
+// CommandRunner executes external commands (npm, walrus, site-builder) for the Deployer.
+// Implementations return the complete stdout and stderr along with the command's error.
+type CommandRunner interface {
+	Run(ctx context.Context, opts RunOptions, name string, args ...string) (stdout, stderr string, err error)
+}
+
+// processWaitDelay bounds how long Run waits for output after a cancelled command is killed, in case something
+// outside its process group still holds its pipes.
+const processWaitDelay = 5 * time.Second
+
+// ExecRunner runs commands as local processes via os/exec. Cancelling ctx kills the command together with
+// everything it started.
+type ExecRunner struct{}
+
+func (ExecRunner) Run(ctx context.Context, opts RunOptions, name string, args ...string) (stdout, stderr string, err error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	killWithProcessGroup(cmd)
+	cmd.WaitDelay = processWaitDelay
+	cmd.Dir = opts.Dir
+	if len(opts.Env) > 0 {
+		cmd.Env = append(os.Environ(), opts.Env...)
+	}
+
+	var stdoutBuf, stderrBuf bytes.Buffer
+	cmd.Stdout = &stdoutBuf
+	cmd.Stderr = &stderrBuf
+
+	var stdoutLines, stderrLines *lineWriter
+	if opts.OnLine != nil {
+		stdoutLines = &lineWriter{emit: func(line string) { opts.OnLine("stdout", line) }}
+		stderrLines = &lineWriter{emit: func(line string) { opts.OnLine("stderr", line) }}
+		cmd.Stdout = io.MultiWriter(&stdoutBuf, stdoutLines)
+		cmd.Stderr = io.MultiWriter(&stderrBuf, stderrLines)
+	}
+
+	err = cmd.Run()
+	if opts.OnLine != nil {
+		stdoutLines.Flush()
+		stderrLines.Flush()
+	}
+	return stdoutBuf.String(), stderrBuf.String(), err
+}
+
+// lineWriter splits written bytes into lines and hands each complete line to emit.
+type lineWriter struct {
+	emit func(line string)
+	buf  []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(strings.TrimRight(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// Flush emits any trailing output that didn't end in a newline.
+func (w *lineWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.emit(strings.TrimRight(string(w.buf), "\r"))
+		w.buf = nil
+	}
+}
@@ -0,0 +1,64 @@
+package walrus
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// call is one command run through a fakeRunner.
+type call struct {
+	Dir  string
+	Name string
+	Args []string
+}
+
+// String renders the command as typed, e.g. "npm run build".
+func (c call) String() string {
+	return strings.Join(append([]string{c.Name}, c.Args...), " ")
+}
+
+// fakeResult is what a fakeRunner returns for one command.
+type fakeResult struct {
+	stdout, stderr string
+	err            error
+}
+
+// fakeRunner records every command and answers it with respond, which may also touch the filesystem like the
+// real command would (e.g. create dist/index.html for "npm run build"). A nil respond succeeds silently.
+type fakeRunner struct {
+	respond func(c call) fakeResult
+
+	mu    sync.Mutex
+	calls []call
+}
+
+func (r *fakeRunner) Run(ctx context.Context, opts RunOptions, name string, args ...string) (string, string, error) {
+	c := call{Dir: opts.Dir, Name: name, Args: args}
+	r.mu.Lock()
+	r.calls = append(r.calls, c)
+	r.mu.Unlock()
+	if r.respond == nil {
+		return "", "", nil
+	}
+	res := r.respond(c)
+	if opts.OnLine != nil {
+		for _, line := range strings.Split(strings.TrimRight(res.stdout, "\n"), "\n") {
+			if line != "" {
+				opts.OnLine("stdout", line)
+			}
+		}
+	}
+	return res.stdout, res.stderr, res.err
+}
+
+// commands returns the recorded commands as strings, in order.
+func (r *fakeRunner) commands() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]string, len(r.calls))
+	for i, c := range r.calls {
+		out[i] = c.String()
+	}
+	return out
+}
@@ -0,0 +1,69 @@
+package walrus
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultSitesConfigPath is the site-builder config used unless WithSitesConfig says otherwise.
+const DefaultSitesConfigPath = "sites-config.yaml"
+
+// ErrInvalidSitesConfig is returned when the site-builder config is missing or can't be what site-builder expects.
+var ErrInvalidSitesConfig = errors.New("invalid site-builder config")
+
+// WithSitesConfig sets the sites-config.yaml passed to site-builder. Empty keeps DefaultSitesConfigPath.
+func WithSitesConfig(path string) Option {
+	return func(d *Deployer) {
+		if path != "" {
+			d.sitesConfigPath = path
+		}
+	}
+}
+
+// CheckConfig validates the Deployer's site-builder config (see ValidateSitesConfig).
+func (d *Deployer) CheckConfig() error {
+	return ValidateSitesConfig(d.sitesConfigPath)
+}
+
+// ValidateSitesConfig checks that path exists and parses as a site-builder config: either "contexts" mapping
+// names to settings with a "package" (and a "default_context" naming one of them, when set), or the older flat
+// layout with a top-level "package". site-builder only reads it at publish time, after a full build, so
+// checking up front turns a late failure into an immediate one.
+func ValidateSitesConfig(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSitesConfig, err)
+	}
+	var config struct {
+		Contexts       map[string]map[string]any `yaml:"contexts"`
+		DefaultContext string                    `yaml:"default_context"`
+		Package        string                    `yaml:"package"`
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidSitesConfig, path, err)
+	}
+
+	if config.Contexts == nil {
+		if config.Package == "" {
+			return fmt.Errorf("%w: %s has neither \"contexts\" nor a top-level \"package\"", ErrInvalidSitesConfig, path)
+		}
+		return nil
+	}
+	if len(config.Contexts) == 0 {
+		return fmt.Errorf("%w: %s defines no contexts", ErrInvalidSitesConfig, path)
+	}
+	for name, settings := range config.Contexts {
+		if pkg, _ := settings["package"].(string); pkg == "" {
+			return fmt.Errorf("%w: %s: context %q has no \"package\"", ErrInvalidSitesConfig, path, name)
+		}
+	}
+	if config.DefaultContext != "" {
+		if _, ok := config.Contexts[config.DefaultContext]; !ok {
+			return fmt.Errorf("%w: %s: default_context %q is not one of its contexts", ErrInvalidSitesConfig, path, config.DefaultContext)
+		}
+	}
+	return nil
+}
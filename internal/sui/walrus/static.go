@@ -0,0 +1,79 @@
+package walrus
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// stageStatic copies a static project's site files into projectDir/dist and returns it, so the publish holds
+// only what the site serves. Hidden entries (.rag with the project's prompt and embeddings, .git, .env, ...),
+// node_modules and any previous dist are left out, as are symlinks.
+func stageStatic(projectDir string) (string, error) {
+	distDir := filepath.Join(projectDir, "dist")
+	if err := os.RemoveAll(distDir); err != nil {
+		return "", fmt.Errorf("failed to remove stale dist directory %s: %w", distDir, err)
+	}
+	copied := 0
+	err := filepath.WalkDir(projectDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(projectDir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if !publishable(rel, entry) {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		copied++
+		return copyFile(path, filepath.Join(distDir, rel))
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to stage static site from %s: %w", projectDir, err)
+	}
+	log.Printf("Staged %d static site files from %s into %s", copied, projectDir, distDir)
+	return distDir, nil
+}
+
+// publishable reports whether a path (relative to the project) belongs in a static site's publish directory.
+func publishable(rel string, entry fs.DirEntry) bool {
+	name := entry.Name()
+	if strings.HasPrefix(name, ".") {
+		return false
+	}
+	if entry.IsDir() {
+		return name != "node_modules" && rel != "dist"
+	}
+	return entry.Type().IsRegular()
+}
+
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
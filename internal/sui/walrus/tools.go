@@ -0,0 +1,28 @@
+package walrus
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// ErrNotConfigured is returned when the site-builder or walrus CLI is unset or not an executable, as on
+// generation-only servers. Deploys fail with it before building.
+var ErrNotConfigured = errors.New("deployment not configured")
+
+// CheckTools verifies that the site-builder and walrus binaries exist and are executable. Bare names are looked
+// up on PATH.
+func (d *Deployer) CheckTools() error {
+	for _, tool := range []struct{ name, path string }{
+		{"site-builder", d.siteBuilderPath},
+		{"walrus CLI", d.walrusCLIPath},
+	} {
+		if tool.path == "" {
+			return fmt.Errorf("%w: no %s path is set", ErrNotConfigured, tool.name)
+		}
+		if _, err := exec.LookPath(tool.path); err != nil {
+			return fmt.Errorf("%w: %s: %w", ErrNotConfigured, tool.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package walrus
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ErrInvalidBasePath is returned for base paths that don't look like "/" or "/some/sub/path/".
+var ErrInvalidBasePath = errors.New("base path must start and end with '/' and contain only URL-safe path characters")
+
+// basePathPattern also keeps quotes and backslashes out, since the value is spliced into vite.config.
+var basePathPattern = regexp.MustCompile(`^/([A-Za-z0-9._~-]+/)*$`)
+
+// ValidateBasePath checks a Vite base path such as "/" or "/sites/demo/".
+func ValidateBasePath(basePath string) error {
+	if !basePathPattern.MatchString(basePath) {
+		return ErrInvalidBasePath
+	}
+	return nil
+}
+
+// viteConfigNames are the config files Vite looks for, in its own order of preference.
+var viteConfigNames = []string{"vite.config.js", "vite.config.mjs", "vite.config.ts", "vite.config.mts"}
+
+var (
+	// viteBaseProperty matches an existing `base: '...'` setting.
+	viteBaseProperty = regexp.MustCompile("(base\\s*:\\s*)(['\"`])[^'\"`]*(['\"`])")
+	// viteConfigObject matches the start of the object passed to defineConfig, including the function form
+	// `defineConfig(({ mode }) => ({`.
+	viteConfigObject = regexp.MustCompile(`defineConfig\(\s*(?:\([^)]*\)\s*=>\s*\(?\s*)?\{`)
+)
+
+// patchViteBase sets `base` in the project's vite.config so built asset URLs resolve under basePath.
+// An existing base is replaced; otherwise it is injected into the defineConfig object.
+func patchViteBase(projectDir, basePath string) error {
+	if err := ValidateBasePath(basePath); err != nil {
+		return err
+	}
+
+	var configPath string
+	for _, name := range viteConfigNames {
+		candidate := filepath.Join(projectDir, name)
+		if _, err := os.Stat(candidate); err == nil {
+			configPath = candidate
+			break
+		}
+	}
+	if configPath == "" {
+		return fmt.Errorf("no vite.config found in %s to set base path", projectDir)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	config := string(data)
+
+	switch {
+	case viteBaseProperty.MatchString(config):
+		config = viteBaseProperty.ReplaceAllString(config, "${1}'"+basePath+"'")
+	case viteConfigObject.MatchString(config):
+		loc := viteConfigObject.FindStringIndex(config)
+		config = config[:loc[1]] + "\n  base: '" + basePath + "'," + config[loc[1]:]
+	default:
+		return fmt.Errorf("could not find where to set base in %s", configPath)
+	}
+
+	return os.WriteFile(configPath, []byte(config), 0644)
+}
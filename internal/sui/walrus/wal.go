@@ -0,0 +1,83 @@
+package walrus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"sui_ai_server/internal/utils"
+)
+
+// Defaults for funding the wallet with "walrus get-wal" before each publish, unless WithGetWalRetry says otherwise.
+const (
+	DefaultGetWalAttempts = 3
+	DefaultGetWalBackoff  = 2 * time.Second
+)
+
+// ErrGetWalFailed is returned when "walrus get-wal" failed on every attempt, so the deploy is aborted before
+// publishing, which would fail anyway without WAL.
+var ErrGetWalFailed = errors.New("walrus get-wal failed")
+
+// WithGetWalRetry sets how many times get-wal is tried per deploy and the wait before the first retry, which
+// doubles after each further failure. attempts of 0 skips get-wal, for wallets funded some other way; negative
+// values and a non-positive backoff are ignored.
+func WithGetWalRetry(attempts int, backoff time.Duration) Option {
+	return func(d *Deployer) {
+		if attempts >= 0 {
+			d.getWalAttempts = attempts
+		}
+		if backoff > 0 {
+			d.getWalBackoff = backoff
+		}
+	}
+}
+
+// getWal exchanges SUI for WAL with "walrus get-wal", retrying with exponential backoff. An attempt fails on a
+// non-zero exit or when the CLI reports an error in its output.
+func (d *Deployer) getWal(ctx context.Context, progress ProgressFunc) error {
+	if d.getWalAttempts == 0 {
+		return nil
+	}
+	delay := d.getWalBackoff
+	var lastErr error
+	for attempt := 1; attempt <= d.getWalAttempts; attempt++ {
+		stdout, stderr, err := d.runStage(ctx, "", "get-wal", progress, d.walrusCLIPath, "get-wal")
+		if err == nil {
+			err = getWalOutputError(stdout, stderr)
+		}
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if stderr := strings.TrimSpace(stderr); stderr != "" {
+			lastErr = fmt.Errorf("%w (stderr: %s)", err, stderr)
+		}
+		if ctx.Err() != nil || attempt == d.getWalAttempts {
+			break
+		}
+		log.Printf("WARN: walrus get-wal attempt %d of %d failed: %v; retrying in %s", attempt, d.getWalAttempts, lastErr, delay)
+		if progress != nil {
+			progress("get-wal", "stderr", fmt.Sprintf("get-wal failed, retrying in %s", delay))
+		}
+		if err := utils.SleepContext(ctx, delay); err != nil {
+			return fmt.Errorf("%w: %w", ErrGetWalFailed, err)
+		}
+		delay *= 2
+	}
+	return fmt.Errorf("%w after %d attempts: %w", ErrGetWalFailed, d.getWalAttempts, lastErr)
+}
+
+// getWalOutputError returns the error walrus reports on a line starting with "Error" even though it exited
+// successfully, or nil when there is none.
+func getWalOutputError(stdout, stderr string) error {
+	for _, line := range strings.Split(stdout+"\n"+stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(strings.ToLower(line), "error") {
+			return errors.New(line)
+		}
+	}
+	return nil
+}
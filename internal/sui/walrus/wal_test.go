@@ -0,0 +1,116 @@
+package walrus
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// getWalRunner fails the first failures get-wal calls (alternating a non-zero exit and an "Error" line on a
+// clean exit) and otherwise behaves like npmProjectRunner.
+func getWalRunner(failures int) func(c call) fakeResult {
+	succeed := npmProjectRunner("New site object ID: 0xsite\n")
+	attempts := 0
+	return func(c call) fakeResult {
+		if !slices.Equal(c.Args, []string{"get-wal"}) {
+			return succeed(c)
+		}
+		attempts++
+		switch {
+		case attempts > failures:
+			return fakeResult{stdout: "Exchanged 0.5 SUI for 0.5 WAL"}
+		case attempts%2 == 1:
+			return fakeResult{stderr: "faucet unavailable", err: errors.New("exit status 1")}
+		default:
+			return fakeResult{stdout: "Error: insufficient gas"}
+		}
+	}
+}
+
+func countCommands(runner *fakeRunner, match func(string) bool) int {
+	n := 0
+	for _, cmd := range runner.commands() {
+		if match(cmd) {
+			n++
+		}
+	}
+	return n
+}
+
+func isGetWal(cmd string) bool { return strings.HasSuffix(cmd, " get-wal") }
+
+func isPublish(cmd string) bool {
+	return strings.Contains(cmd, " publish ") && !strings.HasSuffix(cmd, " --help")
+}
+
+func TestDeployGetWalRetriesThenSucceeds(t *testing.T) {
+	runner := &fakeRunner{respond: getWalRunner(1)}
+	d := testDeployer(t, runner)
+	projectDir := writeProject(t, map[string]string{"package.json": `{"name":"demo"}`})
+
+	result, _, err := d.DeployFiles(context.Background(), projectDir, DeployOptions{})
+	if err != nil {
+		t.Fatalf("DeployFiles: %v", err)
+	}
+	if result.SiteObjectID != "0xsite" {
+		t.Errorf("SiteObjectID = %q, want 0xsite", result.SiteObjectID)
+	}
+	if n := countCommands(runner, isGetWal); n != 2 {
+		t.Errorf("get-wal ran %d times, want 2 (one failure, one success)", n)
+	}
+	if n := countCommands(runner, isPublish); n != 1 {
+		t.Errorf("site-builder publish ran %d times, want 1", n)
+	}
+}
+
+func TestDeployGetWalFailsEveryAttempt(t *testing.T) {
+	runner := &fakeRunner{respond: getWalRunner(DefaultGetWalAttempts)}
+	d := testDeployer(t, runner)
+	projectDir := writeProject(t, map[string]string{"package.json": `{"name":"demo"}`})
+
+	_, _, err := d.DeployFiles(context.Background(), projectDir, DeployOptions{})
+	if !errors.Is(err, ErrGetWalFailed) {
+		t.Fatalf("DeployFiles error = %v, want %v", err, ErrGetWalFailed)
+	}
+	if n := countCommands(runner, isGetWal); n != DefaultGetWalAttempts {
+		t.Errorf("get-wal ran %d times, want %d", n, DefaultGetWalAttempts)
+	}
+	for _, cmd := range runner.commands() {
+		if strings.HasSuffix(strings.Fields(cmd)[0], "site-builder") {
+			t.Errorf("site-builder ran (%q) after get-wal failed", cmd)
+		}
+	}
+}
+
+func TestGetWalDisabled(t *testing.T) {
+	runner := &fakeRunner{}
+	d := testDeployer(t, runner)
+	WithGetWalRetry(0, 0)(d)
+	if err := d.getWal(context.Background(), nil); err != nil {
+		t.Fatalf("getWal = %v, want nil", err)
+	}
+	if len(runner.calls) != 0 {
+		t.Errorf("commands = %q, want none with get-wal disabled", runner.commands())
+	}
+}
+
+func TestGetWalOutputError(t *testing.T) {
+	tests := []struct {
+		name, stdout, stderr string
+		wantErr              bool
+	}{
+		{"success", "Exchanged 0.5 SUI for 0.5 WAL", "", false},
+		{"error on stdout", "Error: no gas coins found", "", true},
+		{"error on stderr", "", "  error: rpc timeout", true},
+		{"error mid-line", "No error here", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := getWalOutputError(tt.stdout, tt.stderr); (err != nil) != tt.wantErr {
+				t.Errorf("getWalOutputError = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package types
+
+import "strings"
+
+// fileTypeAliases maps the spellings models and tools use for a file type to its canonical token.
+var fileTypeAliases = map[string]string{
+	"typescript":      "ts",
+	"typescriptreact": "tsx",
+	"javascript":      "js",
+	"javascriptreact": "jsx",
+	"mjs":             "js",
+	"cjs":             "js",
+	"htm":             "html",
+	"markdown":        "md",
+	"text":            "txt",
+	"plaintext":       "txt",
+	"yml":             "yaml",
+	"shell":           "sh",
+	"bash":            "sh",
+	"python":          "py",
+	"golang":          "go",
+	"png":             "image",
+	"jpg":             "image",
+	"jpeg":            "image",
+	"gif":             "image",
+	"webp":            "image",
+}
+
+// NormalizeFileType returns the canonical token for a file type: the lowercase extension without its dot
+// ("tsx", "json", "yaml"), with language names and other aliases ("TypeScript", "yml", ".md") mapped to it.
+// GeneratedFile.Type and utils.DetermineFileType always hold this form, so types can be compared directly.
+func NormalizeFileType(fileType string) string {
+	fileType = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(fileType), "."))
+	if canonical, ok := fileTypeAliases[fileType]; ok {
+		return canonical
+	}
+	return fileType
+}
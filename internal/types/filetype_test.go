@@ -0,0 +1,52 @@
+package types
+
+import "testing"
+
+func TestNormalizeFileType(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"tsx", "tsx"},
+		{"TSX", "tsx"},
+		{".tsx", "tsx"},
+		{" .TSX ", "tsx"},
+		{"TypeScript", "ts"},
+		{"typescriptreact", "tsx"},
+		{"JavaScript", "js"},
+		{"javascriptreact", "jsx"},
+		{"mjs", "js"},
+		{"cjs", "js"},
+		{"htm", "html"},
+		{"HTML", "html"},
+		{"Markdown", "md"},
+		{".md", "md"},
+		{"yml", "yaml"},
+		{".YAML", "yaml"},
+		{"text", "txt"},
+		{"plaintext", "txt"},
+		{"bash", "sh"},
+		{"shell", "sh"},
+		{"python", "py"},
+		{"golang", "go"},
+		{"PNG", "image"},
+		{".jpeg", "image"},
+		{"webp", "image"},
+		{"json", "json"},
+		{"", ""},
+		{"vue", "vue"}, // Unknown types pass through in canonical case
+	}
+	for _, tt := range tests {
+		if got := NormalizeFileType(tt.in); got != tt.want {
+			t.Errorf("NormalizeFileType(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeFileTypeIsIdempotent(t *testing.T) {
+	for alias, canonical := range fileTypeAliases {
+		if again := NormalizeFileType(canonical); again != canonical {
+			t.Errorf("%s normalizes to %s, which normalizes again to %s", alias, canonical, again)
+		}
+	}
+}
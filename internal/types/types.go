@@ -1,8 +1,123 @@
 package types
 
+import "encoding/json"
+
 // GeneratedFile represents the structure expected from the LLM for each file.
 type GeneratedFile struct {
 	Filename string `json:"filename"`
-	Type     string `json:"type"` // e.g., "tsx", "css", "json"
+	Type     string `json:"type"` // Canonical token (see NormalizeFileType), e.g. "tsx", "css", "json"
 	Content  string `json:"content"`
 }
+
+// UnmarshalJSON accepts the canonical field names plus the aliases some models emit instead: "path" for
+// filename, "code" or "body" for content, and "lang" or "language" for type. A canonical field wins when both
+// are present, and the type is normalized (see NormalizeFileType). Files always marshal with the canonical names.
+func (f *GeneratedFile) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Filename *string `json:"filename"`
+		Path     *string `json:"path"`
+		Type     *string `json:"type"`
+		Lang     *string `json:"lang"`
+		Language *string `json:"language"`
+		Content  *string `json:"content"`
+		Code     *string `json:"code"`
+		Body     *string `json:"body"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*f = GeneratedFile{
+		Filename: firstSet(raw.Filename, raw.Path),
+		Type:     NormalizeFileType(firstSet(raw.Type, raw.Lang, raw.Language)),
+		Content:  firstSet(raw.Content, raw.Code, raw.Body),
+	}
+	return nil
+}
+
+// firstSet returns the first non-nil value, or "" when none is set.
+func firstSet(values ...*string) string {
+	for _, v := range values {
+		if v != nil {
+			return *v
+		}
+	}
+	return ""
+}
+
+// Chunk is the embedding of one window of a larger text. Start and End are byte offsets into the text.
+type Chunk struct {
+	Index  int       `json:"index"`
+	Start  int       `json:"start"`
+	End    int       `json:"end"`
+	Vector []float32 `json:"vector"`
+}
+
+// Project types supported by generation and deployment.
+const (
+	ProjectTypeReact  = "react"  // React + Vite project that needs npm install/build
+	ProjectTypeStatic = "static" // Plain HTML/CSS/JS published as-is
+)
+
+// SiteOptions tunes how a site is generated. The zero value is a React project.
+type SiteOptions struct {
+	ProjectType string   // ProjectTypeReact (default) or ProjectTypeStatic
+	Pages       []string // Extra pages beyond the built-in set, as sanitized filename stems (e.g. "pricing")
+
+	TailwindVersion int      // Tailwind major version for React projects (3 or 4); 0 uses the server default
+	TailwindPlugins []string // Tailwind plugins such as "forms" or "typography"; nil uses the server default
+	Template        string   // Base template under templates/<name> for the model to adapt; empty starts from scratch
+	Locale          string   // Language of the site's user-facing copy (see prompts.Locales); empty means English
+	ReferenceImage  string   // Design mockup as an https or data URL, sent as an image part; not persisted with the project
+	Seed            *int     // OpenAI sampling seed for reproducible output; nil lets the provider choose
+	Theme           string   // Visual direction from a generation profile; empty leaves it to the prompt
+	Instructions    string   // Extra requirements from a generation profile, appended to the prompt
+	SystemPrompt    string   // Replaces the default system message; the output format rules are still appended
+	DesignSystem    string   // Spec of the components and tokens the site must use; not persisted with the project
+}
+
+// GenerationProfile is an operator-defined preset for generations (GENERATION_PROFILES), selected per request
+// by name. Settings the request gives itself win over the profile's.
+type GenerationProfile struct {
+	Framework    string   `mapstructure:"framework" json:"framework,omitempty"`       // ProjectTypeReact or ProjectTypeStatic; empty means React
+	Pages        []string `mapstructure:"pages" json:"pages,omitempty"`               // Extra pages generated unless the request lists its own
+	Theme        string   `mapstructure:"theme" json:"theme,omitempty"`               // Visual direction, e.g. "dark, minimal, monospace headings"
+	Instructions string   `mapstructure:"instructions" json:"instructions,omitempty"` // Extra requirements appended to the prompt
+}
+
+// IsStatic reports whether the options ask for a plain static site.
+func (o SiteOptions) IsStatic() bool {
+	return o.ProjectType == ProjectTypeStatic
+}
+
+// Deploy backends a site can be published to (DEPLOY_BACKEND).
+const (
+	DeployBackendWalrus = "walrus" // Walrus Sites via site-builder
+	DeployBackendIPFS   = "ipfs"   // Pinned to an IPFS node or pinning service
+)
+
+// DeployResult describes a published site, whichever backend published it.
+type DeployResult struct {
+	Backend string `json:"backend"`       // DeployBackendWalrus or DeployBackendIPFS
+	SiteID  string `json:"siteId"`        // Walrus site object ID or IPFS root CID
+	URL     string `json:"url,omitempty"` // Gateway URL of the site, when the backend has one
+
+	// Walrus only
+	SiteObjectID  string   `json:"siteObjectId,omitempty"` // Same as SiteID, under the name Walrus clients already read
+	BlobIDs       []string `json:"blobIds,omitempty"`
+	ResourceCount int      `json:"resourceCount,omitempty"`
+
+	Artifacts *BuildManifest `json:"artifacts,omitempty"` // What was published; nil if it couldn't be listed
+}
+
+// BuildManifest describes the files a deploy published.
+type BuildManifest struct {
+	TotalBytes int64        `json:"totalBytes"`
+	Assets     []BuildAsset `json:"assets"`
+	Warnings   []string     `json:"warnings,omitempty"` // E.g. a bundle over BUNDLE_WARN_BYTES
+}
+
+// BuildAsset is one published file.
+type BuildAsset struct {
+	Path  string `json:"path"` // Relative to the published directory, with forward slashes
+	Bytes int64  `json:"bytes"`
+}
@@ -0,0 +1,53 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGeneratedFileUnmarshalAliases(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want GeneratedFile
+	}{
+		{"canonical", `{"filename":"src/App.tsx","type":"tsx","content":"app"}`, GeneratedFile{"src/App.tsx", "tsx", "app"}},
+		{"path", `{"path":"src/App.tsx","type":"tsx","content":"app"}`, GeneratedFile{"src/App.tsx", "tsx", "app"}},
+		{"code", `{"filename":"index.js","type":"js","code":"run()"}`, GeneratedFile{"index.js", "js", "run()"}},
+		{"body", `{"filename":"index.js","type":"js","body":"run()"}`, GeneratedFile{"index.js", "js", "run()"}},
+		{"lang", `{"filename":"index.js","lang":"javascript","content":"run()"}`, GeneratedFile{"index.js", "js", "run()"}},
+		{"language", `{"filename":"README.md","language":"Markdown","content":"# Hi"}`, GeneratedFile{"README.md", "md", "# Hi"}},
+		{"all aliases", `{"path":"a.ts","lang":"TypeScript","code":"x"}`, GeneratedFile{"a.ts", "ts", "x"}},
+		{"canonical wins", `{"filename":"a.ts","path":"b.ts","type":"ts","lang":"js","content":"x","code":"y"}`, GeneratedFile{"a.ts", "ts", "x"}},
+		{"empty canonical still wins", `{"filename":"a.ts","content":"","code":"y"}`, GeneratedFile{"a.ts", "", ""}},
+		{"missing fields", `{}`, GeneratedFile{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got GeneratedFile
+			if err := json.Unmarshal([]byte(tt.json), &got); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeneratedFileUnmarshalInvalid(t *testing.T) {
+	var f GeneratedFile
+	if err := json.Unmarshal([]byte(`{"filename":42}`), &f); err == nil {
+		t.Error("a numeric filename was accepted")
+	}
+}
+
+func TestGeneratedFileMarshalCanonical(t *testing.T) {
+	data, err := json.Marshal(GeneratedFile{Filename: "a.ts", Type: "ts", Content: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"filename":"a.ts","type":"ts","content":"x"}`; string(data) != want {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+}
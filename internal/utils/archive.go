@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsupportedArchive is returned for uploads that are not .zip, .tar, .tar.gz or .tgz.
+var ErrUnsupportedArchive = errors.New("unsupported archive format")
+
+// ErrArchiveTooLarge is returned when an archive's extracted contents exceed the allowed size.
+var ErrArchiveTooLarge = errors.New("archive contents too large")
+
+// Archive is an uploaded archive: zip needs random access, tar only a stream.
+type Archive interface {
+	io.Reader
+	io.ReaderAt
+}
+
+// ExtractArchive unpacks archive (detected from filename's extension) into destDir.
+// Entries that would land outside destDir (zip-slip) fail with ErrUnsafePath, and extraction stops with
+// ErrArchiveTooLarge once more than maxBytes would be written. Symlinks and other special entries are skipped.
+func ExtractArchive(archive Archive, size int64, filename, destDir string, maxBytes int64) error {
+	name := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return extractZip(archive, size, destDir, maxBytes)
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		gz, err := gzip.NewReader(archive)
+		if err != nil {
+			return fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer gz.Close()
+		return extractTar(gz, destDir, maxBytes)
+	case strings.HasSuffix(name, ".tar"):
+		return extractTar(archive, destDir, maxBytes)
+	default:
+		return ErrUnsupportedArchive
+	}
+}
+
+func extractZip(r io.ReaderAt, size int64, destDir string, maxBytes int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return fmt.Errorf("failed to open zip archive: %w", err)
+	}
+	remaining := maxBytes
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !f.Mode().IsRegular() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open %s in archive: %w", f.Name, err)
+		}
+		written, err := writeArchiveEntry(destDir, f.Name, rc, remaining)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		remaining -= written
+	}
+	return nil
+}
+
+func extractTar(r io.Reader, destDir string, maxBytes int64) error {
+	tr := tar.NewReader(r)
+	remaining := maxBytes
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		written, err := writeArchiveEntry(destDir, hdr.Name, tr, remaining)
+		if err != nil {
+			return err
+		}
+		remaining -= written
+	}
+}
+
+// writeArchiveEntry writes one file under destDir, copying at most remaining bytes.
+func writeArchiveEntry(destDir, name string, r io.Reader, remaining int64) (int64, error) {
+	target, err := SafeJoin(destDir, name)
+	if err != nil {
+		return 0, fmt.Errorf("archive entry %q: %w", name, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return 0, err
+	}
+	out, err := os.Create(target)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	// Copy one byte past the budget so oversized entries are detected rather than silently truncated.
+	written, err := io.Copy(out, io.LimitReader(r, remaining+1))
+	if err != nil {
+		return written, fmt.Errorf("failed to extract %s: %w", name, err)
+	}
+	if written > remaining {
+		return written, ErrArchiveTooLarge
+	}
+	return written, nil
+}
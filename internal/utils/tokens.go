@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"log"
+	"sync"
+	"unicode/utf8"
+
+	tiktoken "github.com/pkoukk/tiktoken-go"
+	tiktoken_loader "github.com/pkoukk/tiktoken-go-loader"
+)
+
+func init() {
+	// Use the embedded BPE files so token counting never depends on downloading encodings at runtime.
+	tiktoken.SetBpeLoader(tiktoken_loader.NewOfflineLoader())
+}
+
+// charsPerToken is the heuristic used when no tokenizer is available (about right for code and English).
+const charsPerToken = 4
+
+// modelEncodings names the tokenizer for models tiktoken doesn't recognise by name.
+var modelEncodings = map[string]string{
+	"chatgpt-4o-latest": "o200k_base",
+}
+
+var (
+	encodingsMu sync.Mutex
+	encodings   = map[string]*tiktoken.Tiktoken{} // nil entries mark models without a tokenizer
+)
+
+// encodingFor returns the (cached) tokenizer for a model, or nil when there is none. Loading an encoding is
+// expensive, so it is done once per model; so is logging a missing one.
+func encodingFor(model string) *tiktoken.Tiktoken {
+	encodingsMu.Lock()
+	defer encodingsMu.Unlock()
+	if enc, ok := encodings[model]; ok {
+		return enc
+	}
+	var enc *tiktoken.Tiktoken
+	var err error
+	if name, ok := modelEncodings[model]; ok {
+		enc, err = tiktoken.GetEncoding(name)
+	} else {
+		enc, err = tiktoken.EncodingForModel(model)
+	}
+	if err != nil {
+		log.Printf("WARN: No tokenizer for model %s, estimating tokens at %d characters each: %v", model, charsPerToken, err)
+		enc = nil
+	}
+	encodings[model] = enc
+	return enc
+}
+
+// EstimateTokens approximates a token count from text length, without a tokenizer.
+func EstimateTokens(text string) int {
+	return (len(text) + charsPerToken - 1) / charsPerToken
+}
+
+// CountTokens returns how many tokens text is for model. Models without a known tokenizer fall back to
+// EstimateTokens rather than failing.
+func CountTokens(model, text string) (int, error) {
+	enc := encodingFor(model)
+	if enc == nil {
+		return EstimateTokens(text), nil
+	}
+	return len(enc.Encode(text, nil, nil)), nil
+}
+
+// TruncateToTokens cuts text down to at most maxTokens tokens for model, reporting whether it did. Without a
+// tokenizer it cuts at maxTokens*4 bytes, on a character boundary.
+func TruncateToTokens(model, text string, maxTokens int) (string, bool, error) {
+	if maxTokens <= 0 {
+		return "", text != "", nil
+	}
+	enc := encodingFor(model)
+	if enc == nil {
+		limit := maxTokens * charsPerToken
+		if len(text) <= limit {
+			return text, false, nil
+		}
+		for limit > 0 && !utf8.RuneStart(text[limit]) {
+			limit--
+		}
+		return text[:limit], true, nil
+	}
+	tokens := enc.Encode(text, nil, nil)
+	if len(tokens) <= maxTokens {
+		return text, false, nil
+	}
+	return enc.Decode(tokens[:maxTokens]), true, nil
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestCountTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		model    string
+		text     string
+		min, max int
+	}{
+		{"empty", "gpt-4o", "", 0, 0},
+		{"one word", "gpt-4o", "hello", 1, 1},
+		{"sentence", "gpt-4o", "The quick brown fox jumps over the lazy dog.", 9, 11},
+		{"code", "gpt-4o", "export default function App() { return <h1>Hello</h1>; }", 12, 22},
+		{"older tokenizer", "gpt-4", "The quick brown fox jumps over the lazy dog.", 9, 11},
+		{"aliased model", "chatgpt-4o-latest", "The quick brown fox jumps over the lazy dog.", 9, 11},
+		{"long text", "gpt-4o", strings.Repeat("hello world ", 1000), 1500, 2500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CountTokens(tt.model, tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got < tt.min || got > tt.max {
+				t.Errorf("CountTokens = %d, want %d-%d", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestCountTokensUnknownModelEstimates(t *testing.T) {
+	text := strings.Repeat("abcd", 25) + "ab"
+	got, err := CountTokens("not-a-real-model", text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := EstimateTokens(text); got != want || want != 26 {
+		t.Errorf("CountTokens = %d, want the %d-token estimate (26)", got, want)
+	}
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	text := strings.Repeat("hello world ", 100)
+	cut, truncated, err := TruncateToTokens("gpt-4o", text, 10)
+	if err != nil || !truncated {
+		t.Fatalf("TruncateToTokens = %v, %v; want truncated", truncated, err)
+	}
+	if n, _ := CountTokens("gpt-4o", cut); n > 10 || !strings.HasPrefix(text, cut) {
+		t.Errorf("truncated to %q (%d tokens), want a prefix of at most 10 tokens", cut, n)
+	}
+	if same, truncated, _ := TruncateToTokens("gpt-4o", "short", 10); truncated || same != "short" {
+		t.Errorf("short text = %q, %v; want it unchanged", same, truncated)
+	}
+
+	multibyte := strings.Repeat("é", 10)
+	cut, truncated, _ = TruncateToTokens("not-a-real-model", multibyte, 1)
+	if !truncated || !utf8.ValidString(cut) || len(cut) > charsPerToken {
+		t.Errorf("estimated truncation = %q, want at most %d bytes on a character boundary", cut, charsPerToken)
+	}
+}
@@ -1,100 +1,334 @@
 package utils
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/sashabaranov/go-openai"
 )
 
-// Simple retry check (customize as needed)
+// WorkDir is the root directory that holds every generated project's files.
+const WorkDir = "tmp"
+
+// ProjectDir returns the directory holding a project's source files.
+func ProjectDir(projectID string) string {
+	return filepath.Join(WorkDir, projectID)
+}
+
+// DirSize sums the sizes of the regular files under dir; unreadable entries are skipped.
+func DirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// ErrUnsafePath is returned for relative paths that are absolute or escape their base directory.
+var ErrUnsafePath = errors.New("unsafe path")
+
+// SafeJoin joins a client- or LLM-supplied relative path onto base, rejecting absolute paths and any path
+// that would resolve outside base (e.g. "../../etc/passwd").
+func SafeJoin(base, rel string) (string, error) {
+	if rel == "" || strings.ContainsRune(rel, 0) {
+		return "", ErrUnsafePath
+	}
+	rel = filepath.FromSlash(rel)
+	if filepath.IsAbs(rel) || filepath.VolumeName(rel) != "" {
+		return "", ErrUnsafePath
+	}
+	cleaned := filepath.Clean(rel)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", ErrUnsafePath
+	}
+	return filepath.Join(base, cleaned), nil
+}
+
+// MaxRetryDelay caps how long we are willing to wait before retrying, even if the provider asks for longer.
+const MaxRetryDelay = 30 * time.Second
+
+// RateLimitError is returned once we give up retrying a rate-limited call.
+// It carries the provider's requested backoff so the API layer can forward it as a Retry-After header.
+type RateLimitError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited (retry after %s): %v", e.RetryAfter, e.Err)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// RetryReason is why a failed provider call is worth retrying, used to label retry metrics.
+type RetryReason string
+
+const (
+	RetryRateLimit   RetryReason = "rate_limit"   // 429 / rate limit messages
+	RetryServerError RetryReason = "server_error" // 5xx from the provider
+	RetryTimeout     RetryReason = "timeout"      // Timeouts and deadline exceeded, including 504
+	RetryNetwork     RetryReason = "network"      // Connection-level failures
+)
+
+// retryReasons lists every RetryReason, so RetryCounts always reports all of them.
+var retryReasons = []RetryReason{RetryRateLimit, RetryServerError, RetryTimeout, RetryNetwork}
+
+// ShouldRetry reports whether err is a transient failure worth retrying. See ClassifyRetry for the reason.
 func ShouldRetry(err error) bool {
+	_, retry := ClassifyRetry(err)
+	return retry
+}
+
+// ClassifyRetry reports whether err is worth retrying and, if so, why.
+func ClassifyRetry(err error) (RetryReason, bool) {
 	if err == nil {
-		return false
+		return "", false
 	}
-	// Example: Retry on specific transient errors like rate limits or server errors
-	errMsg := strings.ToLower(err.Error())
-	if strings.Contains(errMsg, "rate limit") ||
-		strings.Contains(errMsg, "500 internal server error") ||
-		strings.Contains(errMsg, "502 bad gateway") ||
-		strings.Contains(errMsg, "503 service unavailable") ||
-		strings.Contains(errMsg, "504 gateway timeout") ||
-		strings.Contains(errMsg, "timeout") ||
-		strings.Contains(errMsg, "connection reset by peer") ||
-		strings.Contains(errMsg, "context deadline exceeded") { // Context deadline might indicate temporary overload
-		return true
+	if IsRateLimited(err) {
+		return RetryRateLimit, true
 	}
 	// Check for specific OpenAI error types if available in the client library
 	var openAIErr *openai.APIError
-	if errors.As(err, &openAIErr) {
-		if openAIErr.HTTPStatusCode >= 500 || openAIErr.HTTPStatusCode == 429 {
-			return true
+	if errors.As(err, &openAIErr) && openAIErr.HTTPStatusCode >= 500 {
+		if openAIErr.HTTPStatusCode == http.StatusGatewayTimeout {
+			return RetryTimeout, true
 		}
+		return RetryServerError, true
+	}
+	// Fall back to the message for errors without a status (transport errors, wrapped text)
+	errMsg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(errMsg, "rate limit"):
+		return RetryRateLimit, true
+	case strings.Contains(errMsg, "504 gateway timeout"),
+		strings.Contains(errMsg, "timeout"),
+		strings.Contains(errMsg, "context deadline exceeded"): // Context deadline might indicate temporary overload
+		return RetryTimeout, true
+	case strings.Contains(errMsg, "500 internal server error"),
+		strings.Contains(errMsg, "502 bad gateway"),
+		strings.Contains(errMsg, "503 service unavailable"):
+		return RetryServerError, true
+	case strings.Contains(errMsg, "connection reset by peer"):
+		return RetryNetwork, true
+	}
+	return "", false
+}
+
+// retryCounts counts retries per RetryReason since startup.
+var retryCounts = func() map[RetryReason]*atomic.Int64 {
+	counts := make(map[RetryReason]*atomic.Int64, len(retryReasons))
+	for _, reason := range retryReasons {
+		counts[reason] = new(atomic.Int64)
+	}
+	return counts
+}()
+
+// CountRetry records that a call is being retried for reason.
+func CountRetry(reason RetryReason) {
+	if counter, ok := retryCounts[reason]; ok {
+		counter.Add(1)
+	}
+}
+
+// RetryCounts returns the number of retries per reason since startup, for the metrics endpoint.
+func RetryCounts() map[RetryReason]int64 {
+	counts := make(map[RetryReason]int64, len(retryCounts))
+	for reason, counter := range retryCounts {
+		counts[reason] = counter.Load()
+	}
+	return counts
+}
+
+// IsRateLimited reports whether err is a 429 from the provider.
+func IsRateLimited(err error) bool {
+	var openAIErr *openai.APIError
+	if errors.As(err, &openAIErr) && openAIErr.HTTPStatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) && reqErr.HTTPStatusCode == http.StatusTooManyRequests {
+		return true
 	}
 	return false
 }
 
-// determineFileType provides a fallback if the LLM doesn't specify a type.
+// RetryDelay works out how long to wait before retrying from the provider's response headers.
+// It checks Retry-After (seconds or HTTP date), retry-after-ms, and OpenAI's x-ratelimit-reset-* headers,
+// falling back to the given delay when none are present. The result is capped at MaxRetryDelay.
+func RetryDelay(header http.Header, fallback time.Duration) time.Duration {
+	delay, ok := parseRetryAfter(header)
+	if !ok {
+		delay = fallback
+	}
+	if delay > MaxRetryDelay {
+		delay = MaxRetryDelay
+	}
+	return delay
+}
+
+func parseRetryAfter(header http.Header) (time.Duration, bool) {
+	if header == nil {
+		return 0, false
+	}
+	if v := header.Get("retry-after-ms"); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms >= 0 {
+			return time.Duration(ms * float64(time.Millisecond)), true
+		}
+	}
+	if v := header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.ParseFloat(v, 64); err == nil && secs >= 0 {
+			return time.Duration(secs * float64(time.Second)), true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			if d := time.Until(t); d > 0 {
+				return d, true
+			}
+			return 0, true
+		}
+	}
+	// OpenAI reports resets as Go-style durations, e.g. "1s", "6m0s", "20ms". Wait for the later of the two.
+	var longest time.Duration
+	found := false
+	for _, key := range []string{"x-ratelimit-reset-requests", "x-ratelimit-reset-tokens"} {
+		if v := header.Get(key); v != "" {
+			if d, err := time.ParseDuration(v); err == nil {
+				found = true
+				if d > longest {
+					longest = d
+				}
+			}
+		}
+	}
+	return longest, found
+}
+
+// WrapRateLimit wraps err in a RateLimitError carrying the header-derived delay if err is a 429.
+// Other errors are returned unchanged.
+func WrapRateLimit(err error, header http.Header, fallback time.Duration) error {
+	if err == nil || !IsRateLimited(err) {
+		return err
+	}
+	return &RateLimitError{RetryAfter: RetryDelay(header, fallback), Err: err}
+}
+
+// SleepContext waits for d or until ctx is done, whichever comes first.
+func SleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ContentTypeForFile picks an HTTP Content-Type for serving a project file, based on DetermineFileType.
+func ContentTypeForFile(filename string) string {
+	switch DetermineFileType(filename) {
+	case "html":
+		return "text/html; charset=utf-8"
+	case "css":
+		return "text/css; charset=utf-8"
+	case "js", "jsx":
+		return "text/javascript; charset=utf-8"
+	case "json":
+		return "application/json; charset=utf-8"
+	case "md":
+		return "text/markdown; charset=utf-8"
+	case "yaml":
+		return "application/yaml; charset=utf-8"
+	case "svg":
+		return "image/svg+xml"
+	case "image":
+		if ct := mime.TypeByExtension(filepath.Ext(filename)); ct != "" {
+			return ct
+		}
+		return "application/octet-stream"
+	default:
+		// ts, tsx, go, config files etc. are served as plain source text.
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// DetermineFileType infers a file's type from its name, as a canonical token (see types.NormalizeFileType), for
+// files whose type the LLM didn't give and for files read back from disk. Unrecognized files are "unknown".
 func DetermineFileType(filename string) string {
 	lowerFilename := strings.ToLower(filename)
 	ext := filepath.Ext(lowerFilename)
 	switch ext {
 	case ".html":
-		return "HTML"
+		return "html"
 	case ".css":
-		return "CSS"
+		return "css"
 	case ".js":
-		return "JavaScript"
+		return "js"
 	case ".jsx":
-		return "JSX"
+		return "jsx"
 	case ".ts":
-		return "TypeScript"
+		return "ts"
 	case ".tsx":
-		return "TSX"
+		return "tsx"
 	case ".json":
-		return "JSON"
+		return "json"
 	case ".md":
-		return "Markdown"
+		return "md"
 	case ".txt":
-		return "Text"
+		return "txt"
 	case ".yaml", ".yml":
-		return "YAML"
+		return "yaml"
 	case ".toml":
-		return "TOML"
+		return "toml"
 	case ".sh":
-		return "Shell"
+		return "sh"
 	case ".py":
-		return "Python"
+		return "py"
 	case ".go":
-		return "Go"
+		return "go"
 	case ".env":
-		return "Env"
+		return "env"
 	case ".gitignore":
-		return "GitIgnore"
+		return "gitignore"
 	case ".svg":
-		return "SVG"
+		return "svg"
 	case ".png", ".jpg", ".jpeg", ".gif", ".webp":
-		return "Image" // May not want embeddings for images
+		return "image" // May not want embeddings for images
 	default:
 		// Try getting type from common config file names
 		base := filepath.Base(lowerFilename)
 		if strings.Contains(base, "dockerfile") {
-			return "Dockerfile"
+			return "dockerfile"
 		}
 		if strings.Contains(base, "vite.config") {
-			return "Config"
+			return "config"
 		} // Generic config
 		if strings.Contains(base, "tailwind.config") {
-			return "Config"
+			return "config"
 		}
 		if strings.Contains(base, "package.json") {
-			return "JSON"
+			return "json"
 		}
 		if strings.Contains(base, "tsconfig.json") {
-			return "JSON"
+			return "json"
 		}
 
-		return "Unknown"
+		return "unknown"
 	}
 }
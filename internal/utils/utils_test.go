@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"sui_ai_server/internal/types"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+		ok     bool
+	}{
+		{"none", http.Header{}, 0, false},
+		{"nil", nil, 0, false},
+		{"seconds", http.Header{"Retry-After": {"3"}}, 3 * time.Second, true},
+		{"fractional seconds", http.Header{"Retry-After": {"1.5"}}, 1500 * time.Millisecond, true},
+		{"milliseconds", http.Header{"Retry-After-Ms": {"250"}}, 250 * time.Millisecond, true},
+		{"milliseconds win over seconds", http.Header{"Retry-After-Ms": {"250"}, "Retry-After": {"3"}}, 250 * time.Millisecond, true},
+		{"date in the past", http.Header{"Retry-After": {"Mon, 02 Jan 2006 15:04:05 GMT"}}, 0, true},
+		{"reset durations take the later", http.Header{
+			"X-Ratelimit-Reset-Requests": {"1s"},
+			"X-Ratelimit-Reset-Tokens":   {"6m0s"},
+		}, 6 * time.Minute, true},
+		{"unparseable", http.Header{"Retry-After": {"soon"}, "X-Ratelimit-Reset-Tokens": {"later"}}, 0, false},
+		{"negative seconds", http.Header{"Retry-After": {"-1"}}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseRetryAfter(tt.header)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseRetryAfter = %s, %v; want %s, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfterFutureDate(t *testing.T) {
+	header := http.Header{"Retry-After": {time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat)}}
+	got, ok := parseRetryAfter(header)
+	if !ok || got <= 8*time.Second || got > 10*time.Second {
+		t.Errorf("parseRetryAfter = %s, %v; want about 10s", got, ok)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	if got := RetryDelay(http.Header{}, 2*time.Second); got != 2*time.Second {
+		t.Errorf("no header: got %s, want the 2s fallback", got)
+	}
+	if got := RetryDelay(http.Header{"Retry-After": {"5"}}, 2*time.Second); got != 5*time.Second {
+		t.Errorf("Retry-After 5: got %s, want 5s", got)
+	}
+	if got := RetryDelay(http.Header{"Retry-After": {"3600"}}, 2*time.Second); got != MaxRetryDelay {
+		t.Errorf("Retry-After 3600: got %s, want the %s cap", got, MaxRetryDelay)
+	}
+	if got := RetryDelay(nil, time.Hour); got != MaxRetryDelay {
+		t.Errorf("long fallback: got %s, want the %s cap", got, MaxRetryDelay)
+	}
+}
+
+func TestWrapRateLimit(t *testing.T) {
+	limited := &openai.APIError{HTTPStatusCode: http.StatusTooManyRequests, Message: "slow down"}
+	err := WrapRateLimit(limited, http.Header{"Retry-After": {"7"}}, time.Second)
+	var rle *RateLimitError
+	if !errors.As(err, &rle) || rle.RetryAfter != 7*time.Second {
+		t.Fatalf("WrapRateLimit(429) = %v, want a RateLimitError retrying after 7s", err)
+	}
+	if !errors.Is(err, limited) {
+		t.Error("the wrapped error no longer matches the provider error")
+	}
+
+	other := &openai.APIError{HTTPStatusCode: http.StatusInternalServerError}
+	if err := WrapRateLimit(other, http.Header{"Retry-After": {"7"}}, time.Second); err != other {
+		t.Errorf("WrapRateLimit(500) = %v, want it unchanged", err)
+	}
+}
+
+func TestSafeJoin(t *testing.T) {
+	base := filepath.Join("tmp", "project")
+	tests := []struct {
+		rel  string
+		want string // Empty when rel must be rejected
+	}{
+		{"index.html", filepath.Join(base, "index.html")},
+		{"src/App.tsx", filepath.Join(base, "src", "App.tsx")},
+		{"./src/../index.html", filepath.Join(base, "index.html")},
+		{"src//nested/./file.js", filepath.Join(base, "src", "nested", "file.js")},
+		{"..foo/bar", filepath.Join(base, "..foo", "bar")},
+		{"../../etc/passwd", ""},
+		{"..", ""},
+		{".", ""},
+		{"src/../../outside", ""},
+		{"/etc/passwd", ""},
+		{"", ""},
+		{"file\x00.txt", ""},
+	}
+	for _, tt := range tests {
+		got, err := SafeJoin(base, tt.rel)
+		if tt.want == "" {
+			if !errors.Is(err, ErrUnsafePath) {
+				t.Errorf("SafeJoin(%q) = %q, %v; want ErrUnsafePath", tt.rel, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("SafeJoin(%q) = %q, %v; want %q", tt.rel, got, err, tt.want)
+		}
+	}
+}
+
+func TestDetermineFileTypeIsCanonical(t *testing.T) {
+	for _, name := range []string{"index.html", "App.TSX", "main.ts", "app.jsx", "index.js", "style.css", "package.json",
+		"README.md", "notes.txt", "config.yml", "vite.config.yaml", "run.sh", "logo.PNG", "photo.jpeg", "icon.svg"} {
+		got := DetermineFileType(name)
+		if want := types.NormalizeFileType(filepath.Ext(name)); got != want {
+			t.Errorf("DetermineFileType(%q) = %q, want the canonical %q", name, got, want)
+		}
+	}
+	if got := DetermineFileType("archive.zip"); got != "unknown" {
+		t.Errorf("DetermineFileType(archive.zip) = %q, want unknown", got)
+	}
+}
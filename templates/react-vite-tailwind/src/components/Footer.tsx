@@ -0,0 +1,7 @@
+export default function Footer() {
+  return (
+    <footer className="border-t bg-white">
+      <div className="mx-auto max-w-6xl px-4 py-6 text-sm text-gray-500">© {new Date().getFullYear()} Site</div>
+    </footer>
+  )
+}
@@ -0,0 +1,21 @@
+import { Link } from 'react-router-dom'
+
+export default function Navbar() {
+  return (
+    <nav className="bg-white shadow-sm">
+      <div className="mx-auto flex max-w-6xl items-center justify-between px-4 py-4">
+        <Link to="/" className="text-xl font-bold text-primary">
+          Site
+        </Link>
+        <div className="flex gap-6">
+          <Link to="/" className="hover:text-primary">
+            Home
+          </Link>
+          <Link to="/about" className="hover:text-primary">
+            About
+          </Link>
+        </div>
+      </div>
+    </nav>
+  )
+}
@@ -0,0 +1,8 @@
+export default function About() {
+  return (
+    <section className="mx-auto max-w-3xl px-4 py-16">
+      <h1 className="text-3xl font-bold">About</h1>
+      <p className="mt-4 text-gray-600">Tell visitors about the project.</p>
+    </section>
+  )
+}
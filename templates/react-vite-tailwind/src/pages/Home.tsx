@@ -0,0 +1,19 @@
+import { motion } from 'framer-motion'
+
+export default function Home() {
+  return (
+    <section className="mx-auto max-w-6xl px-4 py-24 text-center">
+      <motion.h1
+        className="text-4xl font-bold sm:text-5xl"
+        initial={{ opacity: 0, y: 20 }}
+        animate={{ opacity: 1, y: 0 }}
+      >
+        Welcome
+      </motion.h1>
+      <p className="mt-4 text-lg text-gray-600">Describe what this site is about.</p>
+      <a href="#" className="mt-8 inline-block rounded-lg bg-accent px-6 py-3 font-semibold text-white shadow-md">
+        Get started
+      </a>
+    </section>
+  )
+}